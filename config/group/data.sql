@@ -1,14 +1,32 @@
 CREATE TABLE IF NOT EXISTS groups (
     id varchar(255) PRIMARY KEY,
-    group_name varchar(200) NOT NULL UNIQUE,
+    group_name varchar(200) NOT NULL,
+    type varchar(20) NOT NULL DEFAULT 'group' CHECK (type IN ('group', 'channel')),
+    description varchar(1000) NOT NULL DEFAULT '',
+    avatar varchar(255) NOT NULL DEFAULT '', -- fid in the asset service
     conv_id varchar(255) REFERENCES conversations (id),
     created_at timestamp DEFAULT current_timestamp,
     last_updated timestamp DEFAULT current_timestamp,
     admins uuid[] NOT NULL check (array_length(admins, 1) > 0),
-    deleted bool DEFAULT false
+    deleted bool DEFAULT false,
+    who_can_post varchar(20) NOT NULL DEFAULT 'member' CHECK (who_can_post IN ('owner', 'admin', 'moderator', 'member')),
+    who_can_add_members varchar(20) NOT NULL DEFAULT 'moderator' CHECK (who_can_add_members IN ('owner', 'admin', 'moderator', 'member')),
+    slow_mode_interval int NOT NULL DEFAULT 0 CHECK (slow_mode_interval >= 0), -- seconds, 0 disables slow mode
+    message_retention int NOT NULL DEFAULT 0 CHECK (message_retention >= 0) -- seconds, 0 keeps messages forever
 );
 
-CREATE UNIQUE INDEX group_name_idx ON groups(group_name);
+-- Group names used to be unique across the whole service.
+ALTER TABLE groups DROP CONSTRAINT IF EXISTS groups_group_name_key;
+DROP INDEX IF EXISTS group_name_idx;
+CREATE INDEX IF NOT EXISTS groups_group_name_idx ON groups(group_name);
+
+ALTER TABLE groups ADD COLUMN IF NOT EXISTS description varchar(1000) NOT NULL DEFAULT '';
+ALTER TABLE groups ADD COLUMN IF NOT EXISTS avatar varchar(255) NOT NULL DEFAULT '';
+ALTER TABLE groups ADD COLUMN IF NOT EXISTS who_can_post varchar(20) NOT NULL DEFAULT 'member';
+ALTER TABLE groups ADD COLUMN IF NOT EXISTS who_can_add_members varchar(20) NOT NULL DEFAULT 'moderator';
+ALTER TABLE groups ADD COLUMN IF NOT EXISTS slow_mode_interval int NOT NULL DEFAULT 0;
+ALTER TABLE groups ADD COLUMN IF NOT EXISTS message_retention int NOT NULL DEFAULT 0;
+ALTER TABLE groups ADD COLUMN IF NOT EXISTS type varchar(20) NOT NULL DEFAULT 'group';
 
 CREATE TABLE IF NOT EXISTS conversations (
     id varchar(255) PRIMARY KEY,
@@ -17,5 +35,108 @@ CREATE TABLE IF NOT EXISTS conversations (
 CREATE TABLE IF NOT EXISTS conv_map_user (
     id varchar(255) PRIMARY KEY,
     conv_id varchar(255) REFERENCES conversations(id),
-    user_id varchar(255) REFERENCES users(id)
+    user_id varchar(255) REFERENCES users(id),
+    -- Per-user state of the conversation
+    muted_until timestamp,
+    archived bool NOT NULL DEFAULT false,
+    pinned bool NOT NULL DEFAULT false,
+    pin_order int NOT NULL DEFAULT 0
+);
+
+ALTER TABLE conv_map_user ADD COLUMN IF NOT EXISTS muted_until timestamp;
+ALTER TABLE conv_map_user ADD COLUMN IF NOT EXISTS archived bool NOT NULL DEFAULT false;
+ALTER TABLE conv_map_user ADD COLUMN IF NOT EXISTS pinned bool NOT NULL DEFAULT false;
+ALTER TABLE conv_map_user ADD COLUMN IF NOT EXISTS pin_order int NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS conv_map_user_user_idx ON conv_map_user(user_id);
+
+-- Channels count their subscribers and look a single one up on every post.
+CREATE INDEX IF NOT EXISTS conv_map_user_conv_user_idx ON conv_map_user(conv_id, user_id);
+
+-- A pair of users has at most one direct conversation, user_low < user_high.
+CREATE TABLE IF NOT EXISTS direct_conversations (
+    user_low varchar(255) NOT NULL,
+    user_high varchar(255) NOT NULL CHECK (user_low < user_high),
+    conv_id varchar(255) NOT NULL UNIQUE REFERENCES conversations(id),
+    created_at timestamp DEFAULT current_timestamp,
+    PRIMARY KEY (user_low, user_high)
+);
+
+-- Backfill from the two-member conversations without a group, keeping the oldest mapping of a pair.
+INSERT INTO direct_conversations (user_low, user_high, conv_id)
+SELECT min(m.user_id), max(m.user_id), m.conv_id FROM conv_map_user m
+WHERE NOT EXISTS (SELECT 1 FROM groups g WHERE g.conv_id = m.conv_id)
+GROUP BY m.conv_id HAVING count(DISTINCT m.user_id) = 2
+ON CONFLICT DO NOTHING;
+
+-- Users without a row are plain members; every group has exactly one owner.
+CREATE TABLE IF NOT EXISTS group_roles (
+    group_id varchar(255) REFERENCES groups(id),
+    user_id varchar(255) NOT NULL,
+    role varchar(20) NOT NULL CHECK (role IN ('owner', 'admin', 'moderator')),
+    last_updated timestamp DEFAULT current_timestamp,
+    PRIMARY KEY (group_id, user_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS group_roles_owner_idx ON group_roles(group_id) WHERE role = 'owner';
+
+-- Backfill roles of groups created before group_roles existed: the first admin becomes the owner.
+INSERT INTO group_roles (group_id, user_id, role)
+SELECT id, admins[1]::varchar, 'owner' FROM groups WHERE deleted = false
+ON CONFLICT DO NOTHING;
+
+INSERT INTO group_roles (group_id, user_id, role)
+SELECT id, unnest(admins[2:])::varchar, 'admin' FROM groups WHERE deleted = false
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS group_invite_links (
+    id varchar(255) PRIMARY KEY,
+    group_id varchar(255) REFERENCES groups(id),
+    token varchar(64) NOT NULL UNIQUE,
+    created_by varchar(255) NOT NULL,
+    created_at timestamp DEFAULT current_timestamp,
+    expires_at timestamp,
+    max_uses int NOT NULL DEFAULT 0, -- 0 means unlimited
+    uses int NOT NULL DEFAULT 0,
+    require_approval bool DEFAULT false,
+    revoked bool DEFAULT false
+);
+
+CREATE TABLE IF NOT EXISTS group_join_requests (
+    id varchar(255) PRIMARY KEY,
+    group_id varchar(255) REFERENCES groups(id),
+    user_id varchar(255) NOT NULL,
+    link_id varchar(255) REFERENCES group_invite_links(id),
+    status varchar(20) NOT NULL CHECK (status IN ('pending', 'approved', 'rejected')),
+    created_at timestamp DEFAULT current_timestamp,
+    last_updated timestamp DEFAULT current_timestamp,
+    handled_by varchar(255)
 );
+
+CREATE UNIQUE INDEX IF NOT EXISTS group_join_requests_pending_idx ON group_join_requests(group_id, user_id) WHERE status = 'pending';
+
+-- Active bans and mutes, a row without expires_at lasts until it is lifted.
+CREATE TABLE IF NOT EXISTS group_restrictions (
+    group_id varchar(255) REFERENCES groups(id),
+    user_id varchar(255) NOT NULL,
+    type varchar(20) NOT NULL CHECK (type IN ('ban', 'mute')),
+    reason varchar(500) NOT NULL DEFAULT '',
+    created_by varchar(255) NOT NULL,
+    created_at timestamp DEFAULT current_timestamp,
+    expires_at timestamp,
+    PRIMARY KEY (group_id, user_id, type)
+);
+
+-- Append-only, rows are never updated nor deleted.
+CREATE TABLE IF NOT EXISTS group_moderation_log (
+    id bigserial PRIMARY KEY,
+    group_id varchar(255) REFERENCES groups(id),
+    actor varchar(255) NOT NULL,
+    action varchar(20) NOT NULL CHECK (action IN ('kick', 'ban', 'unban', 'mute', 'unmute')),
+    target varchar(255) NOT NULL,
+    reason varchar(500) NOT NULL DEFAULT '',
+    expires_at timestamp,
+    created_at timestamp DEFAULT current_timestamp
+);
+
+CREATE INDEX IF NOT EXISTS group_moderation_log_group_idx ON group_moderation_log(group_id, id);
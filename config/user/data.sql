@@ -9,7 +9,50 @@ CREATE TABLE IF NOT EXISTS users {
     created_at timestamp DEFAULT current_timestamp,
     last_updated timestamp DEFAULT current_timestamp,
     avatar varchar(255),
-    public_key text
+    public_key text,
+    verified_at timestamp,
+    email_verified_at timestamp,
+    phone_verified_at timestamp
 };
+
+-- Accounts created before verification existed count as verified.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS verified_at timestamp DEFAULT current_timestamp;
+ALTER TABLE users ALTER COLUMN verified_at DROP DEFAULT;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at timestamp DEFAULT current_timestamp;
+ALTER TABLE users ALTER COLUMN email_verified_at DROP DEFAULT;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS phone_verified_at timestamp;
 CREATE UNIQUE INDEX username_idx ON users (username);
 CREATE UNIQUE INDEX email_idx ON users (email);
+
+-- user_id blocked blocked_user_id
+CREATE TABLE IF NOT EXISTS user_blocks (
+    user_id varchar(255) REFERENCES users(id),
+    blocked_user_id varchar(255) REFERENCES users(id) CHECK (blocked_user_id <> user_id),
+    created_at timestamp DEFAULT current_timestamp,
+    PRIMARY KEY (user_id, blocked_user_id)
+);
+
+CREATE INDEX IF NOT EXISTS user_blocks_blocked_user_idx ON user_blocks(blocked_user_id);
+
+CREATE TABLE IF NOT EXISTS login_audit_events (
+    id varchar(255) PRIMARY KEY,
+    username varchar(255) NOT NULL,
+    user_id varchar(255) REFERENCES users(id),
+    ip varchar(255) NOT NULL,
+    success boolean NOT NULL,
+    reason varchar(255) NOT NULL DEFAULT '',
+    created_at timestamp DEFAULT current_timestamp
+);
+
+CREATE INDEX IF NOT EXISTS login_audit_events_username_idx ON login_audit_events(username, created_at);
+
+-- Links the subject of an identity provider to a user.
+CREATE TABLE IF NOT EXISTS external_identities (
+    provider varchar(255) NOT NULL,
+    subject varchar(255) NOT NULL,
+    user_id varchar(255) NOT NULL REFERENCES users(id),
+    created_at timestamp DEFAULT current_timestamp,
+    PRIMARY KEY (provider, subject)
+);
+
+CREATE INDEX IF NOT EXISTS external_identities_user_idx ON external_identities(user_id);
@@ -1,12 +1,24 @@
 package authenticator
 
 import (
+	"context"
 	"fmt"
+	"graduation-thesis/internal/authenticator/grpc_handler"
 	"graduation-thesis/internal/authenticator/handler"
 	"graduation-thesis/internal/authenticator/repository"
 	"graduation-thesis/internal/authenticator/service"
 	"graduation-thesis/pkg/custom_error"
+	"graduation-thesis/pkg/encrypt/aes"
+	"graduation-thesis/pkg/jwks"
+	"graduation-thesis/pkg/logger"
+	"graduation-thesis/pkg/mail"
+	"graduation-thesis/pkg/middleware"
+	"graduation-thesis/pkg/oidc"
+	"graduation-thesis/pkg/pb/authenticatorpb"
+	"graduation-thesis/pkg/pb/userpb"
+	"graduation-thesis/pkg/rpc"
 	"graduation-thesis/pkg/storage"
+	"net"
 	"net/http"
 	"sync"
 	"time"
@@ -28,19 +40,122 @@ func Run() {
 	redis := storage.GetRedisClient(viper.GetString("redis.url"))
 	defer redis.Close()
 
+	logger, err := logger.GetLogger(
+		viper.GetString("logger.level"),
+		viper.GetString("logger.path"),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	atExpires := viper.GetInt64("at_expires")
+	policy := jwks.RotationPolicy{
+		Algorithm:   viper.GetString("signing.algorithm"),
+		Interval:    viper.GetDuration("signing.rotation_interval"),
+		PublishLead: viper.GetDuration("signing.publish_lead"),
+		Overlap:     viper.GetDuration("signing.overlap"),
+	}
+	if policy.Overlap < time.Duration(atExpires)*time.Second {
+		panic("signing.overlap must not be shorter than at_expires")
+	}
+	rtExpires, familyExpires := viper.GetInt64("rt_expires"), viper.GetInt64("family_expires")
+	if familyExpires < rtExpires {
+		panic("family_expires must not be shorter than rt_expires")
+	}
+
+	keyRepo := repository.NewKeyRepo(redis)
+	keyService := service.NewKeyService(keyRepo, policy, logger)
+	if err := keyService.Rotate(context.Background()); err != nil {
+		panic(err)
+	}
+	go keyService.Run(context.Background(), viper.GetDuration("signing.check_interval"))
+
+	kafkaProducer := storage.NewKafkaProducer(
+		viper.GetString("kafka.bootstrap_server"),
+		viper.GetInt("kafka.message_max_bytes"),
+	)
+	defer kafkaProducer.Close()
+
 	tokenRepo := repository.NewTokenRepo(redis)
+	sessionEventRepo := repository.NewSessionEventRepo(kafkaProducer, viper.GetString("kafka.session_topic"))
 	tokenService := service.NewTokenService(
 		tokenRepo,
-		viper.GetInt64("at_expires"),
-		viper.GetInt64("rt_expires"),
-		viper.GetString("access_secret"),
+		keyService,
+		sessionEventRepo,
+		atExpires,
+		rtExpires,
+		familyExpires,
 		viper.GetString("refresh_secret"),
 		custom_error.MappingError(),
+		logger,
+	)
+	aes.InitAes(viper.GetString("two_factor.encryption_key"))
+	userClient := userpb.NewUserServiceClient(rpc.GetClientConn(viper.GetString("user_service_address")))
+	twoFactorService := service.NewTwoFactorService(
+		repository.NewTwoFactorRepo(redis),
+		userClient,
+		service.TwoFactorConfig{
+			Issuer:            viper.GetString("two_factor.issuer"),
+			EnrollmentTTL:     viper.GetDuration("two_factor.enrollment_ttl"),
+			ChallengeTTL:      viper.GetDuration("two_factor.challenge_ttl"),
+			RecoveryCodeCount: viper.GetInt("two_factor.recovery_codes"),
+		},
+		custom_error.MappingError(),
+	)
+	authService := service.NewAuthService(tokenService, twoFactorService, custom_error.MappingError(), userClient)
+	mailer, err := mail.NewMailer(mail.Config{
+		Driver: viper.GetString("mail.driver"),
+		From:   viper.GetString("mail.from"),
+		Path:   viper.GetString("mail.path"),
+		SMTP: mail.SMTPConfig{
+			Host:     viper.GetString("mail.smtp.host"),
+			Port:     viper.GetInt("mail.smtp.port"),
+			Username: viper.GetString("mail.smtp.username"),
+			Password: viper.GetString("mail.smtp.password"),
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+	passwordService := service.NewPasswordService(
+		tokenRepo,
+		tokenService,
+		userClient,
+		mailer,
+		viper.GetDuration("password_reset.token_ttl"),
+		viper.GetString("password_reset.url"),
+		custom_error.MappingError(),
 	)
-	authService := service.NewAuthService(tokenService, custom_error.MappingError(), viper.GetString("user_service_url"))
-	authHandler := handler.NewAuthHandler(authService, tokenService)
+	oidcService := service.NewOIDCService(
+		repository.NewOIDCRepo(redis),
+		oidcProviders(),
+		&http.Client{Timeout: 10 * time.Second},
+		userClient,
+		authService,
+		viper.GetDuration("oidc.state_ttl"),
+		custom_error.MappingError(),
+		logger,
+	)
+	apiTokenService := service.NewAPITokenService(
+		repository.NewAPITokenRepo(redis),
+		viper.GetStringSlice("admin.user_ids"),
+		custom_error.MappingError(),
+	)
+	authHandler := handler.NewAuthHandler(authService, tokenService, keyService, passwordService)
+
+	router := handler.GetRouter(
+		authHandler,
+		handler.NewTwoFactorHandler(twoFactorService),
+		handler.NewOIDCHandler(oidcService),
+		handler.NewAPITokenHandler(apiTokenService),
+	)
+	if err := middleware.TrustProxies(router, viper.GetStringSlice("trusted_proxies")); err != nil {
+		panic(err)
+	}
+
+	grpcSrv := rpc.NewServer()
+	authenticatorpb.RegisterAuthenticatorServiceServer(grpcSrv, grpc_handler.NewAuthenticatorServer(tokenService, keyService, apiTokenService))
 
-	router := handler.GetRouter(authHandler)
 	srv := http.Server{
 		Addr:         fmt.Sprintf(":%d", viper.GetInt("app.port")),
 		ReadTimeout:  5 * time.Minute,
@@ -50,7 +165,7 @@ func Run() {
 	}
 
 	var wg sync.WaitGroup
-	wg.Add(1)
+	wg.Add(2)
 
 	go func(wg *sync.WaitGroup) {
 		defer wg.Done()
@@ -58,6 +173,46 @@ func Run() {
 			panic(err)
 		}
 	}(&wg)
+	go func(wg *sync.WaitGroup) {
+		defer wg.Done()
+		lis, err := net.Listen("tcp", fmt.Sprintf(":%d", viper.GetInt("app.grpc_port")))
+		if err != nil {
+			panic(err)
+		}
+		if err := grpcSrv.Serve(lis); err != nil {
+			panic(err)
+		}
+	}(&wg)
 
 	wg.Wait()
 }
+
+// oidcProviders reads the providers users may log in with, keyed by the name
+// used in their login and callback urls.
+func oidcProviders() []service.OIDCProviderConfig {
+	var providers []service.OIDCProviderConfig
+	for name := range viper.GetStringMap("oidc.providers") {
+		key := "oidc.providers." + name
+		providers = append(providers, service.OIDCProviderConfig{
+			Name: name,
+			Config: oidc.Config{
+				Issuer:       viper.GetString(key + ".issuer"),
+				ClientID:     viper.GetString(key + ".client_id"),
+				ClientSecret: viper.GetString(key + ".client_secret"),
+				RedirectURL:  viper.GetString(key + ".redirect_url"),
+				Scopes:       viper.GetStringSlice(key + ".scopes"),
+			},
+			LinkByEmail: viper.GetBool(key + ".link_by_email"),
+			Claims: service.OIDCClaims{
+				Username:      viper.GetString(key + ".claims.username"),
+				Email:         viper.GetString(key + ".claims.email"),
+				EmailVerified: viper.GetString(key + ".claims.email_verified"),
+				FirstName:     viper.GetString(key + ".claims.first_name"),
+				LastName:      viper.GetString(key + ".claims.last_name"),
+				PhoneNumber:   viper.GetString(key + ".claims.phone_number"),
+				Avatar:        viper.GetString(key + ".claims.avatar"),
+			},
+		})
+	}
+	return providers
+}
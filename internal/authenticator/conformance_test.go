@@ -0,0 +1,232 @@
+package authenticator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"graduation-thesis/internal/authenticator/grpc_handler"
+	"graduation-thesis/internal/authenticator/handler"
+	"graduation-thesis/internal/authenticator/model"
+	"graduation-thesis/internal/authenticator/repository"
+	"graduation-thesis/internal/authenticator/service"
+	"graduation-thesis/pkg/auth"
+	"graduation-thesis/pkg/auth/authtest"
+	"graduation-thesis/pkg/custom_error"
+	"graduation-thesis/pkg/jwks"
+	responseModel "graduation-thesis/pkg/model"
+	"graduation-thesis/pkg/pb/authenticatorpb"
+	"graduation-thesis/pkg/pb/userpb"
+	request "graduation-thesis/pkg/requests"
+	"graduation-thesis/pkg/storage/redistest"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+const (
+	testUsername = "alice"
+	testPassword = "correct horse battery staple"
+	testUserID   = "user-1"
+	// testAtExpires is at_expires, in seconds.
+	testAtExpires = 15 * 60
+)
+
+type nopPublisher struct{}
+
+func (nopPublisher) Publish(userID string, sessionIDs []string) error {
+	return nil
+}
+
+// fakeUserClient answers VerifyCredential like the user service:
+// Unauthenticated for any credentials but the test user's.
+type fakeUserClient struct {
+	userpb.UserServiceClient
+}
+
+func (fakeUserClient) VerifyCredential(ctx context.Context, in *userpb.VerifyCredentialRequest, opts ...grpc.CallOption) (*userpb.VerifyCredentialResponse, error) {
+	if in.Username != testUsername || in.Password != testPassword {
+		return nil, custom_error.StatusToGRPCError(http.StatusUnauthorized, custom_error.ErrNoPermission.Error())
+	}
+	return &userpb.VerifyCredentialResponse{UserId: testUserID}, nil
+}
+
+type stack struct {
+	authService  *service.AuthService
+	tokenService *service.TokenService
+	keyService   *service.KeyService
+}
+
+func newStack(t *testing.T) *stack {
+	t.Helper()
+	client, _ := redistest.NewClient(t)
+	keyService := service.NewKeyService(repository.NewKeyRepo(client), jwks.RotationPolicy{
+		Algorithm:   jwks.AlgorithmEdDSA,
+		Interval:    7 * 24 * time.Hour,
+		PublishLead: time.Hour,
+		Overlap:     time.Hour,
+	}, zap.NewNop().Sugar())
+	if err := keyService.Rotate(context.Background()); err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+
+	tokenService := service.NewTokenService(repository.NewTokenRepo(client), keyService, nopPublisher{}, testAtExpires, 24*60*60, 30*24*60*60, "refresh-secret", custom_error.MappingError(), zap.NewNop().Sugar())
+	twoFactorService := service.NewTwoFactorService(repository.NewTwoFactorRepo(client), nil, service.TwoFactorConfig{}, custom_error.MappingError())
+	authService := service.NewAuthService(tokenService, twoFactorService, custom_error.MappingError(), fakeUserClient{})
+	return &stack{
+		authService:  authService,
+		tokenService: tokenService,
+		keyService:   keyService,
+	}
+}
+
+// grpcClient serves the authenticator rpc on a local port.
+func (s *stack) grpcClient(t *testing.T) authenticatorpb.AuthenticatorServiceClient {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	server := grpc.NewServer()
+	authenticatorpb.RegisterAuthenticatorServiceServer(server, grpc_handler.NewAuthenticatorServer(s.tokenService, s.keyService, nil))
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return authenticatorpb.NewAuthenticatorServiceClient(conn)
+}
+
+func statusError(errorResponse *responseModel.ErrorResponse) error {
+	err, ok := custom_error.MappingStatusError()[errorResponse.Status]
+	if !ok {
+		err = custom_error.ErrUnknown
+	}
+	return fmt.Errorf("%w: %s", err, errorResponse.ErrorMessage)
+}
+
+// serviceSubject uses the authenticator as a library, validating through the
+// ValidateToken rpc the websocket handlers use.
+type serviceSubject struct {
+	*stack
+	client authenticatorpb.AuthenticatorServiceClient
+}
+
+func tokensOf(result interface{}) *authtest.Tokens {
+	tokenDetails := result.(*model.TokenDetails)
+	return &authtest.Tokens{AccessToken: tokenDetails.AccessToken, RefreshToken: tokenDetails.RefreshToken}
+}
+
+func (s *serviceSubject) Login(ctx context.Context, username, password string) (*authtest.Tokens, error) {
+	successResponse, errorResponse := s.authService.Login(ctx, &model.LoginRequest{Username: username, Password: password}, model.SessionMetadata{IP: "198.51.100.1"})
+	if errorResponse != nil {
+		return nil, statusError(errorResponse)
+	}
+	return tokensOf(successResponse.Result), nil
+}
+
+func (s *serviceSubject) Refresh(ctx context.Context, refreshToken string) (*authtest.Tokens, error) {
+	successResponse, errorResponse := s.tokenService.Refresh(ctx, refreshToken)
+	if errorResponse != nil {
+		return nil, statusError(errorResponse)
+	}
+	return tokensOf(successResponse.Result), nil
+}
+
+func (s *serviceSubject) Logout(ctx context.Context, accessToken string) error {
+	claims, errorResponse := s.tokenService.Authenticate(ctx, accessToken)
+	if errorResponse == nil {
+		_, errorResponse = s.authService.Logout(ctx, claims)
+	}
+	if errorResponse != nil {
+		return statusError(errorResponse)
+	}
+	return nil
+}
+
+func (s *serviceSubject) Validate(ctx context.Context, accessToken string) (*auth.Claims, error) {
+	response, err := s.client.ValidateToken(ctx, &authenticatorpb.ValidateTokenRequest{Token: accessToken})
+	if err != nil {
+		return nil, custom_error.HandleGRPCError(err)
+	}
+	return &auth.Claims{UserID: response.UserId, SessionID: response.SessionId}, nil
+}
+
+// httpSubject goes through the HTTP API, as the clients do.
+type httpSubject struct {
+	url    string
+	client *request.Client
+}
+
+func (h *httpSubject) Login(ctx context.Context, username, password string) (*authtest.Tokens, error) {
+	tokenDetails, err := request.Post[model.TokenDetails](ctx, h.client, h.url+"/v1/login", "", model.LoginRequest{Username: username, Password: password})
+	if err != nil {
+		return nil, err
+	}
+	return tokensOf(&tokenDetails), nil
+}
+
+func (h *httpSubject) Refresh(ctx context.Context, refreshToken string) (*authtest.Tokens, error) {
+	tokenDetails, err := request.Post[model.TokenDetails](ctx, h.client, h.url+"/v1/refresh", "", model.RefreshRequest{RefreshToken: refreshToken})
+	if err != nil {
+		return nil, err
+	}
+	return tokensOf(&tokenDetails), nil
+}
+
+func (h *httpSubject) Logout(ctx context.Context, accessToken string) error {
+	_, err := request.Get[json.RawMessage](ctx, h.client, h.url+"/v1/logout", accessToken)
+	return err
+}
+
+func (h *httpSubject) Validate(ctx context.Context, accessToken string) (*auth.Claims, error) {
+	userID, err := request.Post[string](ctx, h.client, h.url+"/v1/validate", accessToken, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &auth.Claims{UserID: userID}, nil
+}
+
+func TestConformanceService(t *testing.T) {
+	authtest.Run(t, func(t *testing.T) authtest.Harness {
+		s := newStack(t)
+		client := s.grpcClient(t)
+		return authtest.Harness{
+			Subject:   &serviceSubject{stack: s, client: client},
+			Username:  testUsername,
+			Password:  testPassword,
+			UserID:    testUserID,
+			Verifier:  auth.NewVerifier(auth.KeySetFetcher(client)),
+			AccessTTL: testAtExpires * time.Second,
+		}
+	})
+}
+
+func TestConformanceHTTP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	authtest.Run(t, func(t *testing.T) authtest.Harness {
+		s := newStack(t)
+		router := handler.NewRouter(handler.NewAuthHandler(s.authService, s.tokenService, s.keyService, nil), handler.NewTwoFactorHandler(nil), handler.NewOIDCHandler(nil), handler.NewAPITokenHandler(nil))
+		server := httptest.NewServer(router)
+		t.Cleanup(server.Close)
+
+		return authtest.Harness{
+			Subject:   &httpSubject{url: server.URL, client: request.NewClient(request.DefaultConfig())},
+			Username:  testUsername,
+			Password:  testPassword,
+			UserID:    testUserID,
+			Verifier:  auth.NewVerifier(jwks.HTTPFetcher(server.Client(), server.URL+"/.well-known/jwks.json")),
+			AccessTTL: testAtExpires * time.Second,
+		}
+	})
+}
@@ -0,0 +1,66 @@
+package grpc_handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"graduation-thesis/internal/authenticator/service"
+	"graduation-thesis/pkg/custom_error"
+	"graduation-thesis/pkg/pb/authenticatorpb"
+)
+
+type AuthenticatorServer struct {
+	authenticatorpb.UnimplementedAuthenticatorServiceServer
+	tokenService    *service.TokenService
+	keyService      *service.KeyService
+	apiTokenService *service.APITokenService
+}
+
+func NewAuthenticatorServer(tokenService *service.TokenService, keyService *service.KeyService, apiTokenService *service.APITokenService) *AuthenticatorServer {
+	return &AuthenticatorServer{
+		tokenService:    tokenService,
+		keyService:      keyService,
+		apiTokenService: apiTokenService,
+	}
+}
+
+func (a *AuthenticatorServer) ValidateToken(ctx context.Context, request *authenticatorpb.ValidateTokenRequest) (*authenticatorpb.ValidateTokenResponse, error) {
+	claims, errorResponse := a.tokenService.Authenticate(ctx, request.Token)
+	if errorResponse != nil {
+		return nil, custom_error.StatusToGRPCError(errorResponse.Status, errorResponse.ErrorMessage)
+	}
+
+	return &authenticatorpb.ValidateTokenResponse{
+		UserId:    claims.UserID,
+		SessionId: claims.SessionID,
+	}, nil
+}
+
+func (a *AuthenticatorServer) GetKeySet(ctx context.Context, request *authenticatorpb.GetKeySetRequest) (*authenticatorpb.GetKeySetResponse, error) {
+	keySet, err := a.keyService.KeySet()
+	if err != nil {
+		return nil, custom_error.StatusToGRPCError(http.StatusInternalServerError, err.Error())
+	}
+
+	value, err := json.Marshal(keySet)
+	if err != nil {
+		return nil, custom_error.StatusToGRPCError(http.StatusInternalServerError, err.Error())
+	}
+	return &authenticatorpb.GetKeySetResponse{Jwks: value}, nil
+}
+
+func (a *AuthenticatorServer) AuthenticateAPIToken(ctx context.Context, request *authenticatorpb.AuthenticateAPITokenRequest) (*authenticatorpb.AuthenticateAPITokenResponse, error) {
+	claims, apiToken, errorResponse := a.apiTokenService.Authenticate(ctx, request.Token)
+	if errorResponse != nil {
+		return nil, custom_error.StatusToGRPCError(errorResponse.Status, errorResponse.ErrorMessage)
+	}
+
+	return &authenticatorpb.AuthenticateAPITokenResponse{
+		AccountId: claims.UserID,
+		TokenId:   claims.AccessUuid,
+		Kind:      claims.Kind,
+		Scopes:    claims.Scopes,
+		ExpiresAt: apiToken.ExpiresAt,
+	}, nil
+}
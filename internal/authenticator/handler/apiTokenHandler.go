@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"net/http"
+
+	"graduation-thesis/internal/authenticator/model"
+	"graduation-thesis/internal/authenticator/service"
+	"graduation-thesis/pkg/auth"
+	responseModel "graduation-thesis/pkg/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+type APITokenHandler struct {
+	apiTokenService *service.APITokenService
+}
+
+func NewAPITokenHandler(apiTokenService *service.APITokenService) *APITokenHandler {
+	return &APITokenHandler{
+		apiTokenService: apiTokenService,
+	}
+}
+
+func (a *APITokenHandler) CreateAccount(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+	var createRequest model.CreateServiceAccountRequest
+	if err := c.ShouldBindJSON(&createRequest); err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       http.StatusBadRequest,
+			ErrorMessage: err.Error(),
+		}
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	successResponse, errorResponse := a.apiTokenService.CreateAccount(c, claims.UserID, &createRequest)
+	if errorResponse != nil {
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	c.JSON(successResponse.Status, successResponse)
+}
+
+func (a *APITokenHandler) ListAccounts(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+	successResponse, errorResponse := a.apiTokenService.ListAccounts(c, claims.UserID)
+	if errorResponse != nil {
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	c.JSON(successResponse.Status, successResponse)
+}
+
+func (a *APITokenHandler) DeleteAccount(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+	successResponse, errorResponse := a.apiTokenService.DeleteAccount(c, claims.UserID, c.Param("account_id"))
+	if errorResponse != nil {
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	c.JSON(successResponse.Status, successResponse)
+}
+
+func (a *APITokenHandler) CreateToken(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+	var createRequest model.CreateAPITokenRequest
+	if err := c.ShouldBindJSON(&createRequest); err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       http.StatusBadRequest,
+			ErrorMessage: err.Error(),
+		}
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	successResponse, errorResponse := a.apiTokenService.CreateToken(c, claims.UserID, c.Param("account_id"), &createRequest)
+	if errorResponse != nil {
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	c.JSON(successResponse.Status, successResponse)
+}
+
+func (a *APITokenHandler) ListTokens(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+	successResponse, errorResponse := a.apiTokenService.ListTokens(c, claims.UserID, c.Param("account_id"))
+	if errorResponse != nil {
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	c.JSON(successResponse.Status, successResponse)
+}
+
+func (a *APITokenHandler) RevokeToken(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+	successResponse, errorResponse := a.apiTokenService.RevokeToken(c, claims.UserID, c.Param("account_id"), c.Param("token_id"))
+	if errorResponse != nil {
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	c.JSON(successResponse.Status, successResponse)
+}
@@ -2,9 +2,11 @@ package handler
 
 import (
 	"net/http"
+	"strconv"
 
 	"graduation-thesis/internal/authenticator/model"
 	"graduation-thesis/internal/authenticator/service"
+	"graduation-thesis/pkg/auth"
 	"graduation-thesis/pkg/custom_error"
 	responseModel "graduation-thesis/pkg/model"
 
@@ -12,14 +14,22 @@ import (
 )
 
 type AuthHandler struct {
-	authService  *service.AuthService
-	tokenService *service.TokenService
+	authService     *service.AuthService
+	tokenService    *service.TokenService
+	keyService      *service.KeyService
+	passwordService *service.PasswordService
 }
 
-func NewAuthHandler(authService *service.AuthService, tokenService *service.TokenService) *AuthHandler {
+func NewAuthHandler(
+	authService *service.AuthService,
+	tokenService *service.TokenService,
+	keyService *service.KeyService,
+	passwordService *service.PasswordService) *AuthHandler {
 	return &AuthHandler{
-		authService:  authService,
-		tokenService: tokenService,
+		authService:     authService,
+		tokenService:    tokenService,
+		keyService:      keyService,
+		passwordService: passwordService,
 	}
 }
 
@@ -34,7 +44,7 @@ func (a *AuthHandler) Refresh(c *gin.Context) {
 		return
 	}
 
-	successResponse, errorResponse := a.tokenService.Refresh(refreshRequest.RefreshToken)
+	successResponse, errorResponse := a.tokenService.Refresh(c, refreshRequest.RefreshToken)
 	if errorResponse != nil {
 		c.JSON(errorResponse.Status, errorResponse)
 		return
@@ -44,7 +54,7 @@ func (a *AuthHandler) Refresh(c *gin.Context) {
 }
 
 func (a *AuthHandler) Validate(c *gin.Context) {
-	token := a.tokenService.ExtractTokenFromRequest(c.Request)
+	token := auth.ExtractToken(c.Request)
 	successResponse, errorResponse := a.tokenService.ValidateToken(token)
 	if errorResponse != nil {
 		c.JSON(errorResponse.Status, errorResponse)
@@ -65,8 +75,15 @@ func (a *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	successResponse, errorResponse := a.authService.Login(c, &loginRequest)
+	successResponse, errorResponse := a.authService.Login(c, &loginRequest, model.SessionMetadata{
+		DeviceName: loginRequest.DeviceName,
+		UserAgent:  c.Request.UserAgent(),
+		IP:         c.ClientIP(),
+	})
 	if errorResponse != nil {
+		if errorResponse.RetryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(errorResponse.RetryAfter))
+		}
 		c.JSON(errorResponse.Status, errorResponse)
 		return
 	}
@@ -74,6 +91,137 @@ func (a *AuthHandler) Login(c *gin.Context) {
 	c.JSON(successResponse.Status, successResponse)
 }
 
+// JWKS publishes the keys that verify access tokens. Verifiers may cache the
+// set for a few minutes since new keys are published well before they sign.
+func (a *AuthHandler) JWKS(c *gin.Context) {
+	keySet, err := a.keyService.KeySet()
+	if err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       http.StatusInternalServerError,
+			ErrorMessage: custom_error.ErrInternalServerError.Error(),
+		}
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, keySet)
+}
+
+func (a *AuthHandler) LoginTwoFactor(c *gin.Context) {
+	var loginRequest model.TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&loginRequest); err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       http.StatusBadRequest,
+			ErrorMessage: err.Error(),
+		}
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	successResponse, errorResponse := a.authService.LoginTwoFactor(c, &loginRequest)
+	if errorResponse != nil {
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	c.JSON(successResponse.Status, successResponse)
+}
+
+// Authenticate only lets requests with a live access token through and stores
+// its claims in the context as claims.
+func (a *AuthHandler) Authenticate(c *gin.Context) {
+	token := auth.ExtractToken(c.Request)
+	claims, errorResponse := a.tokenService.Authenticate(c, token)
+	if errorResponse != nil {
+		c.AbortWithStatusJSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	c.Set("claims", claims)
+	c.Next()
+}
+
 func (a *AuthHandler) Logout(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+	successResponse, errorResponse := a.authService.Logout(c, claims)
+	if errorResponse != nil {
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	c.JSON(successResponse.Status, successResponse)
+}
+
+func (a *AuthHandler) ListSessions(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+	successResponse, errorResponse := a.tokenService.ListSessions(c, claims.UserID, claims.SessionID)
+	if errorResponse != nil {
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	c.JSON(successResponse.Status, successResponse)
+}
 
+func (a *AuthHandler) RevokeSession(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+	successResponse, errorResponse := a.tokenService.RevokeSession(c, claims.UserID, c.Param("session_id"))
+	if errorResponse != nil {
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	c.JSON(successResponse.Status, successResponse)
+}
+
+func (a *AuthHandler) RevokeAllSessions(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+	successResponse, errorResponse := a.tokenService.RevokeAllSessions(c, claims.UserID)
+	if errorResponse != nil {
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	c.JSON(successResponse.Status, successResponse)
+}
+
+func (a *AuthHandler) ForgotPassword(c *gin.Context) {
+	var forgotPasswordRequest model.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&forgotPasswordRequest); err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       http.StatusBadRequest,
+			ErrorMessage: err.Error(),
+		}
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	successResponse, errorResponse := a.passwordService.ForgotPassword(c, forgotPasswordRequest.Username)
+	if errorResponse != nil {
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	c.JSON(successResponse.Status, successResponse)
+}
+
+func (a *AuthHandler) ResetPassword(c *gin.Context) {
+	var resetPasswordRequest model.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&resetPasswordRequest); err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       http.StatusBadRequest,
+			ErrorMessage: err.Error(),
+		}
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	successResponse, errorResponse := a.passwordService.ResetPassword(c, resetPasswordRequest.Token, resetPasswordRequest.Password)
+	if errorResponse != nil {
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	c.JSON(successResponse.Status, successResponse)
 }
@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"net/http"
+
+	"graduation-thesis/internal/authenticator/model"
+	"graduation-thesis/internal/authenticator/service"
+	"graduation-thesis/pkg/custom_error"
+	responseModel "graduation-thesis/pkg/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OIDCHandler struct {
+	oidcService *service.OIDCService
+}
+
+func NewOIDCHandler(oidcService *service.OIDCService) *OIDCHandler {
+	return &OIDCHandler{
+		oidcService: oidcService,
+	}
+}
+
+// Login sends the user to the provider to log in.
+func (o *OIDCHandler) Login(c *gin.Context) {
+	successResponse, errorResponse := o.oidcService.Begin(c, c.Param("provider"), c.Query("device_name"))
+	if errorResponse != nil {
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	c.Redirect(http.StatusFound, successResponse.Result.(*model.OIDCLogin).AuthURL)
+}
+
+// Callback is where the provider sends the user back to. It answers like
+// a password login.
+func (o *OIDCHandler) Callback(c *gin.Context) {
+	// The user refused or the provider failed the login.
+	if c.Query("error") != "" || c.Query("code") == "" || c.Query("state") == "" {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       http.StatusUnauthorized,
+			ErrorMessage: custom_error.ErrNoPermission.Error(),
+		}
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	successResponse, errorResponse := o.oidcService.Callback(c, c.Param("provider"), c.Query("code"), c.Query("state"), model.SessionMetadata{
+		UserAgent: c.Request.UserAgent(),
+		IP:        c.ClientIP(),
+	})
+	if errorResponse != nil {
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	c.JSON(successResponse.Status, successResponse)
+}
@@ -8,26 +8,62 @@ import (
 
 var router *gin.Engine
 
-func NewRouter(authHandler *AuthHandler) *gin.Engine {
+func NewRouter(authHandler *AuthHandler, twoFactorHandler *TwoFactorHandler, oidcHandler *OIDCHandler, apiTokenHandler *APITokenHandler) *gin.Engine {
 	r := gin.Default()
 	r.Use(middleware.Headers())
 	r.Use(middleware.SetupCors())
 
+	r.GET("/.well-known/jwks.json", authHandler.JWKS)
+
 	absolutePath := r.Group("/v1/")
 	{
 		absolutePath.POST("/refresh", authHandler.Refresh)
 		absolutePath.POST("/validate", authHandler.Validate)
 		absolutePath.POST("/login", authHandler.Login)
-		absolutePath.GET("/logout", authHandler.Logout)
-		absolutePath.POST("/reset-password")
+		absolutePath.POST("/login/2fa", authHandler.LoginTwoFactor)
+		absolutePath.GET("/logout", authHandler.Authenticate, authHandler.Logout)
+		absolutePath.POST("/forgot-password", authHandler.ForgotPassword)
+		absolutePath.POST("/reset-password", authHandler.ResetPassword)
+	}
+
+	oidc := r.Group("/v1/oidc/:provider")
+	{
+		oidc.GET("/login", oidcHandler.Login)
+		oidc.GET("/callback", oidcHandler.Callback)
+	}
+
+	sessions := r.Group("/v1/sessions", authHandler.Authenticate)
+	{
+		sessions.GET("", authHandler.ListSessions)
+		sessions.DELETE("", authHandler.RevokeAllSessions)
+		sessions.DELETE("/:session_id", authHandler.RevokeSession)
+	}
+
+	twoFactor := r.Group("/v1/2fa", authHandler.Authenticate)
+	{
+		twoFactor.GET("", twoFactorHandler.Status)
+		twoFactor.POST("/enroll", twoFactorHandler.Enroll)
+		twoFactor.POST("/confirm", twoFactorHandler.Confirm)
+		twoFactor.POST("/disable", twoFactorHandler.Disable)
+		twoFactor.POST("/recovery-codes", twoFactorHandler.RegenerateRecoveryCodes)
+	}
+
+	serviceAccounts := r.Group("/v1/service-accounts", authHandler.Authenticate)
+	{
+		serviceAccounts.GET("", apiTokenHandler.ListAccounts)
+		serviceAccounts.POST("", apiTokenHandler.CreateAccount)
+		serviceAccounts.DELETE("/:account_id", apiTokenHandler.DeleteAccount)
+		serviceAccounts.GET("/:account_id/tokens", apiTokenHandler.ListTokens)
+		serviceAccounts.POST("/:account_id/tokens", apiTokenHandler.CreateToken)
+		serviceAccounts.DELETE("/:account_id/tokens/:token_id", apiTokenHandler.RevokeToken)
 	}
 
 	return r
 }
 
-func GetRouter(authHandler *AuthHandler) *gin.Engine {
+func GetRouter(authHandler *AuthHandler, twoFactorHandler *TwoFactorHandler, oidcHandler *OIDCHandler, apiTokenHandler *APITokenHandler) *gin.Engine {
 	if router == nil {
-		router = NewRouter(authHandler)
+		router = NewRouter(authHandler, twoFactorHandler, oidcHandler, apiTokenHandler)
 	}
 
 	return router
@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"net/http"
+
+	"graduation-thesis/internal/authenticator/model"
+	"graduation-thesis/internal/authenticator/service"
+	"graduation-thesis/pkg/auth"
+	responseModel "graduation-thesis/pkg/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TwoFactorHandler struct {
+	twoFactorService *service.TwoFactorService
+}
+
+func NewTwoFactorHandler(twoFactorService *service.TwoFactorService) *TwoFactorHandler {
+	return &TwoFactorHandler{
+		twoFactorService: twoFactorService,
+	}
+}
+
+func (t *TwoFactorHandler) Status(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+	successResponse, errorResponse := t.twoFactorService.Status(c, claims.UserID)
+	if errorResponse != nil {
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	c.JSON(successResponse.Status, successResponse)
+}
+
+func (t *TwoFactorHandler) Enroll(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+	successResponse, errorResponse := t.twoFactorService.Enroll(c, claims.UserID)
+	if errorResponse != nil {
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	c.JSON(successResponse.Status, successResponse)
+}
+
+func (t *TwoFactorHandler) Confirm(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+	var twoFactorRequest model.TwoFactorRequest
+	if err := c.ShouldBindJSON(&twoFactorRequest); err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       http.StatusBadRequest,
+			ErrorMessage: err.Error(),
+		}
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	successResponse, errorResponse := t.twoFactorService.Confirm(c, claims.UserID, twoFactorRequest.Code)
+	if errorResponse != nil {
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	c.JSON(successResponse.Status, successResponse)
+}
+
+func (t *TwoFactorHandler) Disable(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+	var twoFactorRequest model.TwoFactorRequest
+	if err := c.ShouldBindJSON(&twoFactorRequest); err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       http.StatusBadRequest,
+			ErrorMessage: err.Error(),
+		}
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	successResponse, errorResponse := t.twoFactorService.Disable(c, claims.UserID, &twoFactorRequest)
+	if errorResponse != nil {
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	c.JSON(successResponse.Status, successResponse)
+}
+
+func (t *TwoFactorHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	claims := c.MustGet("claims").(*auth.Claims)
+	var twoFactorRequest model.TwoFactorRequest
+	if err := c.ShouldBindJSON(&twoFactorRequest); err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       http.StatusBadRequest,
+			ErrorMessage: err.Error(),
+		}
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	successResponse, errorResponse := t.twoFactorService.RegenerateRecoveryCodes(c, claims.UserID, &twoFactorRequest)
+	if errorResponse != nil {
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	c.JSON(successResponse.Status, successResponse)
+}
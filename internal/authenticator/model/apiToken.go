@@ -0,0 +1,40 @@
+package model
+
+// ServiceAccount is who API tokens are issued to: an internal service, or a
+// bot a user runs. Services are managed by admins, bots by their owner.
+type ServiceAccount struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Kind      string `json:"kind"`
+	OwnerID   string `json:"owner_id"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+// APIToken is an issued API token, without the token itself, which is only
+// shown once and stored as its hash.
+type APIToken struct {
+	ID        string   `json:"id"`
+	AccountID string   `json:"account_id"`
+	Name      string   `json:"name"`
+	Scopes    []string `json:"scopes"`
+	CreatedAt int64    `json:"created_at"`
+	// ExpiresAt is 0 for tokens that don't expire.
+	ExpiresAt int64 `json:"expires_at"`
+}
+
+type IssuedAPIToken struct {
+	APIToken
+	Token string `json:"token"`
+}
+
+type CreateServiceAccountRequest struct {
+	Name string `json:"name" binding:"required,max=64"`
+	Kind string `json:"kind" binding:"required,oneof=service bot"`
+}
+
+type CreateAPITokenRequest struct {
+	Name   string   `json:"name" binding:"required,max=64"`
+	Scopes []string `json:"scopes" binding:"required,min=1"`
+	// ExpiresIn is in seconds, 0 for a token that doesn't expire.
+	ExpiresIn int64 `json:"expires_in" binding:"gte=0"`
+}
@@ -0,0 +1,16 @@
+package model
+
+// OIDCLoginState is what a login with an identity provider needs to remember
+// between sending the user to the provider and the provider sending them
+// back. It is stored under the hash of the state sent along.
+type OIDCLoginState struct {
+	Provider     string `json:"provider"`
+	CodeVerifier string `json:"code_verifier"`
+	Nonce        string `json:"nonce"`
+	DeviceName   string `json:"device_name"`
+}
+
+// OIDCLogin is where to send the user to log in with a provider.
+type OIDCLogin struct {
+	AuthURL string `json:"auth_url"`
+}
@@ -0,0 +1,20 @@
+package model
+
+// Session is the token family started by one login, along with what the user
+// needs to recognise the device it belongs to.
+type Session struct {
+	ID         string `json:"id"`
+	UserID     string `json:"user_id"`
+	DeviceName string `json:"device_name"`
+	UserAgent  string `json:"user_agent"`
+	IP         string `json:"ip"`
+	CreatedAt  int64  `json:"created_at"`
+	LastUsedAt int64  `json:"last_used_at"`
+	Current    bool   `json:"current"`
+}
+
+type SessionMetadata struct {
+	DeviceName string
+	UserAgent  string
+	IP         string
+}
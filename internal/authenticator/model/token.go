@@ -11,9 +11,22 @@ type TokenDetails struct {
 	RefreshUuid  string `json:"refresh_uuid,omitempty"`
 	AtExpires    int64  `json:"at_expires,omitempty"`
 	RtExpires    int64  `json:"rt_expires,omitempty"`
+	FamilyID     string `json:"-"`
+	// FamilyExpires is when the family ends, however often it is refreshed.
+	FamilyExpires int64 `json:"-"`
 }
 
 type LoginRequest struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
+	Username   string `json:"username"`
+	Password   string `json:"password"`
+	DeviceName string `json:"device_name,omitempty"`
+}
+
+type ForgotPasswordRequest struct {
+	Username string `json:"username" binding:"required"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
 }
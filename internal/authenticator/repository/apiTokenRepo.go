@@ -0,0 +1,233 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"graduation-thesis/internal/authenticator/model"
+	"graduation-thesis/pkg/custom_error"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type APITokenRepo struct {
+	redis *redis.Client
+}
+
+func NewAPITokenRepo(redisClient *redis.Client) *APITokenRepo {
+	return &APITokenRepo{
+		redis: redisClient,
+	}
+}
+
+// The service accounts key indexes every account and the account tokens key
+// the ids of the tokens of an account. A token is stored under its hash, which
+// the token id key points to so it can be revoked by id; both keys expire
+// with the token and the index is pruned as they do.
+const serviceAccountsKey = "service_accounts"
+
+func serviceAccountKey(accountID string) string {
+	return "service_account:" + accountID
+}
+
+func accountTokensKey(accountID string) string {
+	return "service_account:" + accountID + ":tokens"
+}
+
+func apiTokenKey(tokenHash string) string {
+	return "api_token:" + tokenHash
+}
+
+func apiTokenIDKey(tokenID string) string {
+	return "api_token_id:" + tokenID
+}
+
+func (a *APITokenRepo) CreateAccount(ctx context.Context, account *model.ServiceAccount) error {
+	value, err := json.Marshal(account)
+	if err != nil {
+		return err
+	}
+	_, err = a.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, serviceAccountKey(account.ID), value, 0)
+		pipe.SAdd(ctx, serviceAccountsKey, account.ID)
+		return nil
+	})
+	return err
+}
+
+// GetAccount returns custom_error.ErrNotFound if there is no such account.
+func (a *APITokenRepo) GetAccount(ctx context.Context, accountID string) (*model.ServiceAccount, error) {
+	value, err := a.redis.Get(ctx, serviceAccountKey(accountID)).Bytes()
+	if err != nil {
+		return nil, custom_error.HandleRedisError(err)
+	}
+
+	var account model.ServiceAccount
+	if err := json.Unmarshal(value, &account); err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+func (a *APITokenRepo) ListAccounts(ctx context.Context) ([]*model.ServiceAccount, error) {
+	accountIDs, err := a.redis.SMembers(ctx, serviceAccountsKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := make([]*model.ServiceAccount, 0, len(accountIDs))
+	for _, accountID := range accountIDs {
+		account, err := a.GetAccount(ctx, accountID)
+		if errors.Is(err, custom_error.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts, nil
+}
+
+// DeleteAccount deletes the account along with its tokens.
+func (a *APITokenRepo) DeleteAccount(ctx context.Context, accountID string) error {
+	tokenIDs, err := a.redis.SMembers(ctx, accountTokensKey(accountID)).Result()
+	if err != nil {
+		return err
+	}
+	tokenHashes, err := a.tokenHashes(ctx, tokenIDs)
+	if err != nil {
+		return err
+	}
+
+	_, err = a.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, tokenID := range tokenIDs {
+			pipe.Del(ctx, apiTokenIDKey(tokenID))
+			if tokenHashes[i] != "" {
+				pipe.Del(ctx, apiTokenKey(tokenHashes[i]))
+			}
+		}
+		pipe.Del(ctx, serviceAccountKey(accountID), accountTokensKey(accountID))
+		pipe.SRem(ctx, serviceAccountsKey, accountID)
+		return nil
+	})
+	return err
+}
+
+// StoreToken stores token under its hash. A ttl of 0 keeps it until it is
+// revoked.
+func (a *APITokenRepo) StoreToken(ctx context.Context, tokenHash string, token *model.APIToken, ttl time.Duration) error {
+	value, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	_, err = a.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, apiTokenKey(tokenHash), value, ttl)
+		pipe.Set(ctx, apiTokenIDKey(token.ID), tokenHash, ttl)
+		pipe.SAdd(ctx, accountTokensKey(token.AccountID), token.ID)
+		return nil
+	})
+	return err
+}
+
+// GetToken returns custom_error.ErrNotFound if the token has expired, been
+// revoked or never existed.
+func (a *APITokenRepo) GetToken(ctx context.Context, tokenHash string) (*model.APIToken, error) {
+	value, err := a.redis.Get(ctx, apiTokenKey(tokenHash)).Bytes()
+	if err != nil {
+		return nil, custom_error.HandleRedisError(err)
+	}
+
+	var token model.APIToken
+	if err := json.Unmarshal(value, &token); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// ListTokens returns the live tokens of the account and forgets the ones
+// that expired.
+func (a *APITokenRepo) ListTokens(ctx context.Context, accountID string) ([]*model.APIToken, error) {
+	tokenIDs, err := a.redis.SMembers(ctx, accountTokensKey(accountID)).Result()
+	if err != nil {
+		return nil, err
+	}
+	tokenHashes, err := a.tokenHashes(ctx, tokenIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := make([]*model.APIToken, 0, len(tokenIDs))
+	var expired []interface{}
+	for i, tokenHash := range tokenHashes {
+		if tokenHash == "" {
+			expired = append(expired, tokenIDs[i])
+			continue
+		}
+		token, err := a.GetToken(ctx, tokenHash)
+		if errors.Is(err, custom_error.ErrNotFound) {
+			expired = append(expired, tokenIDs[i])
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+
+	if len(expired) > 0 {
+		if err := a.redis.SRem(ctx, accountTokensKey(accountID), expired...).Err(); err != nil {
+			return nil, err
+		}
+	}
+	return tokens, nil
+}
+
+// RevokeToken deletes the token of the account. It returns
+// custom_error.ErrNotFound if the account has no such token.
+func (a *APITokenRepo) RevokeToken(ctx context.Context, accountID, tokenID string) error {
+	isMember, err := a.redis.SIsMember(ctx, accountTokensKey(accountID), tokenID).Result()
+	if err != nil {
+		return err
+	}
+	if !isMember {
+		return custom_error.ErrNotFound
+	}
+	tokenHashes, err := a.tokenHashes(ctx, []string{tokenID})
+	if err != nil {
+		return err
+	}
+
+	_, err = a.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if tokenHashes[0] != "" {
+			pipe.Del(ctx, apiTokenKey(tokenHashes[0]))
+		}
+		pipe.Del(ctx, apiTokenIDKey(tokenID))
+		pipe.SRem(ctx, accountTokensKey(accountID), tokenID)
+		return nil
+	})
+	return err
+}
+
+// tokenHashes returns the hash of each token, or "" for expired ones.
+func (a *APITokenRepo) tokenHashes(ctx context.Context, tokenIDs []string) ([]string, error) {
+	if len(tokenIDs) == 0 {
+		return nil, nil
+	}
+	keys := make([]string, len(tokenIDs))
+	for i, tokenID := range tokenIDs {
+		keys[i] = apiTokenIDKey(tokenID)
+	}
+	values, err := a.redis.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	tokenHashes := make([]string, len(values))
+	for i, value := range values {
+		tokenHashes[i], _ = value.(string)
+	}
+	return tokenHashes, nil
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"graduation-thesis/pkg/jwks"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// keyRingKey holds the signing keys shared by every authenticator replica.
+const keyRingKey = "jwks:keys"
+
+type KeyRepo struct {
+	redis *redis.Client
+}
+
+func NewKeyRepo(redisClient *redis.Client) *KeyRepo {
+	return &KeyRepo{
+		redis: redisClient,
+	}
+}
+
+// GetKeys returns an empty ring if no key has been generated yet.
+func (k *KeyRepo) GetKeys(ctx context.Context) ([]*jwks.Key, error) {
+	return getKeys(ctx, k.redis)
+}
+
+// UpdateKeys replaces the ring with what update returns when it reports a
+// change. Concurrent updates from other replicas are retried, so update may
+// run more than once. It returns the ring as stored.
+func (k *KeyRepo) UpdateKeys(ctx context.Context, update func(keys []*jwks.Key) ([]*jwks.Key, bool, error)) ([]*jwks.Key, error) {
+	var ring []*jwks.Key
+	err := watch(ctx, k.redis, func(tx *redis.Tx) error {
+		keys, err := getKeys(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		updated, changed, err := update(keys)
+		if err != nil {
+			return err
+		}
+		if !changed {
+			ring = keys
+			return nil
+		}
+
+		value, err := json.Marshal(updated)
+		if err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, keyRingKey, value, 0)
+			return nil
+		})
+		ring = updated
+		return err
+	}, keyRingKey)
+	if err != nil {
+		return nil, err
+	}
+	return ring, nil
+}
+
+func getKeys(ctx context.Context, client redis.Cmdable) ([]*jwks.Key, error) {
+	value, err := client.Get(ctx, keyRingKey).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []*jwks.Key
+	if err := json.Unmarshal(value, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"graduation-thesis/internal/authenticator/model"
+	"graduation-thesis/pkg/custom_error"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type OIDCRepo struct {
+	redis *redis.Client
+}
+
+func NewOIDCRepo(redisClient *redis.Client) *OIDCRepo {
+	return &OIDCRepo{
+		redis: redisClient,
+	}
+}
+
+func oidcStateKey(stateHash string) string {
+	return "oidc_state:" + stateHash
+}
+
+func (o *OIDCRepo) StoreState(ctx context.Context, stateHash string, state *model.OIDCLoginState, ttl time.Duration) error {
+	value, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return o.redis.Set(ctx, oidcStateKey(stateHash), value, ttl).Err()
+}
+
+// ConsumeState returns the login of the state and deletes it, so that a
+// provider's answer is accepted once. It returns custom_error.ErrNotFound if
+// the login has expired or already finished.
+func (o *OIDCRepo) ConsumeState(ctx context.Context, stateHash string) (*model.OIDCLoginState, error) {
+	value, err := o.redis.GetDel(ctx, oidcStateKey(stateHash)).Bytes()
+	if err != nil {
+		return nil, custom_error.HandleRedisError(err)
+	}
+
+	var state model.OIDCLoginState
+	if err := json.Unmarshal(value, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"encoding/json"
+	"time"
+
+	"graduation-thesis/pkg/session"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+type SessionEventRepo struct {
+	producer   *kafka.Producer
+	kafkaTopic string
+}
+
+func NewSessionEventRepo(producer *kafka.Producer, kafkaTopic string) *SessionEventRepo {
+	return &SessionEventRepo{
+		producer:   producer,
+		kafkaTopic: kafkaTopic,
+	}
+}
+
+func (s *SessionEventRepo) Publish(userID string, sessionIDs []string) error {
+	value, err := json.Marshal(session.Event{
+		UserID:     userID,
+		SessionIDs: sessionIDs,
+		Timestamp:  time.Now().UnixMilli(),
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.producer.Produce(&kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &s.kafkaTopic, Partition: int32(kafka.PartitionAny)},
+		Key:            []byte(userID),
+		Value:          value,
+	}, nil)
+}
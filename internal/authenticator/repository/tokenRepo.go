@@ -2,12 +2,22 @@ package repository
 
 import (
 	"context"
-	"fmt"
+	"errors"
+	"strconv"
 	"time"
 
+	"graduation-thesis/internal/authenticator/model"
+	"graduation-thesis/pkg/custom_error"
+
 	"github.com/redis/go-redis/v9"
 )
 
+// ErrRefreshTokenReused is returned when a refresh token that has already
+// been exchanged is presented again while its family is still alive.
+var ErrRefreshTokenReused = errors.New("refresh token reused")
+
+const maxTxAttempts = 5
+
 type TokenRepo struct {
 	redis *redis.Client
 }
@@ -45,34 +55,244 @@ func (t *TokenRepo) DeleteToken(tokenUuid string) (int64, error) {
 	return deleted, nil
 }
 
-func (t *TokenRepo) DeleteAllToken(userId string) error {
-	length, err := t.redis.SCard(context.Background(), userId).Result()
-	if err != nil {
+// A password reset token is only stored as a hash. The reset key maps the
+// hash to the user and the user's reset key holds the hash of the latest token,
+// so that asking for another token invalidates the previous one.
+func resetTokenKey(tokenHash string) string {
+	return "password_reset:" + tokenHash
+}
+
+func userResetTokenKey(userId string) string {
+	return "password_reset_user:" + userId
+}
+
+func (t *TokenRepo) StoreResetToken(ctx context.Context, userId, tokenHash string, ttl time.Duration) error {
+	return watch(ctx, t.redis, func(tx *redis.Tx) error {
+		previous, err := tx.Get(ctx, userResetTokenKey(userId)).Result()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			if previous != "" {
+				pipe.Del(ctx, resetTokenKey(previous))
+			}
+			pipe.Set(ctx, resetTokenKey(tokenHash), userId, ttl)
+			pipe.Set(ctx, userResetTokenKey(userId), tokenHash, ttl)
+			return nil
+		})
 		return err
+	}, userResetTokenKey(userId))
+}
+
+// ConsumeResetToken returns the user of the reset token and deletes the token,
+// so it can be used only once. It returns custom_error.ErrNotFound if the
+// token has expired, been used or been replaced.
+func (t *TokenRepo) ConsumeResetToken(ctx context.Context, tokenHash string) (string, error) {
+	userId, err := t.redis.GetDel(ctx, resetTokenKey(tokenHash)).Result()
+	if err != nil {
+		return "", custom_error.HandleRedisError(err)
+	}
+
+	if err := t.redis.Del(ctx, userResetTokenKey(userId)).Err(); err != nil {
+		return "", err
 	}
+	return userId, nil
+}
+
+// A token family is the chain of tokens issued from one login. The family key
+// holds the uuid of the only refresh token that may still be exchanged and the
+// tokens key holds the access uuids issued in the family, so that the whole
+// family can be revoked at once. The session key holds the metadata of the
+// login and lives as long as the family; the user's sessions key indexes the
+// families of a user and is pruned as they expire. No family outlives its
+// FamilyExpires, so the index expires with the family started last.
+func familyKey(familyID string) string {
+	return "family:" + familyID
+}
+
+func familyTokensKey(familyID string) string {
+	return "family:" + familyID + ":tokens"
+}
+
+func sessionKey(familyID string) string {
+	return "session:" + familyID
+}
+
+func userSessionsKey(userId string) string {
+	return "user_sessions:" + userId
+}
+
+// StoreFamily starts a new family from the tokens of a login made at
+// createdAt.
+func (t *TokenRepo) StoreFamily(ctx context.Context, userId string, td *model.TokenDetails, metadata model.SessionMetadata, createdAt time.Time) error {
+	_, err := t.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, sessionKey(td.FamilyID),
+			"user_id", userId,
+			"device_name", metadata.DeviceName,
+			"user_agent", metadata.UserAgent,
+			"ip", metadata.IP,
+			"created_at", createdAt.Unix(),
+		)
+		pipe.SAdd(ctx, userSessionsKey(userId), td.FamilyID)
+		pipe.ExpireAt(ctx, userSessionsKey(userId), time.Unix(td.FamilyExpires, 0))
+		storeFamilyTokens(ctx, pipe, userId, td)
+		return nil
+	})
+	return err
+}
+
+// RotateFamily exchanges refreshUuid for the tokens in next. It returns
+// ErrRefreshTokenReused if refreshUuid is no longer the current refresh token
+// of the family and custom_error.ErrNotFound if the family has expired or been
+// revoked.
+func (t *TokenRepo) RotateFamily(ctx context.Context, userId, refreshUuid string, next *model.TokenDetails) error {
+	return watch(ctx, t.redis, func(tx *redis.Tx) error {
+		current, err := tx.Get(ctx, familyKey(next.FamilyID)).Result()
+		if err != nil {
+			return custom_error.HandleRedisError(err)
+		}
+		if current != refreshUuid {
+			return ErrRefreshTokenReused
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			storeFamilyTokens(ctx, pipe, userId, next)
+			return nil
+		})
+		return err
+	}, familyKey(next.FamilyID))
+}
 
-	for i := 0; i < int(length); i++ {
-		if err := t.redis.SPop(context.Background(), userId).Err(); err != nil {
+// RevokeFamily deletes the family along with its session and every access
+// token issued in it.
+func (t *TokenRepo) RevokeFamily(ctx context.Context, familyID string) error {
+	return watch(ctx, t.redis, func(tx *redis.Tx) error {
+		accessUuids, err := tx.SMembers(ctx, familyTokensKey(familyID)).Result()
+		if err != nil {
+			return err
+		}
+		userId, err := tx.HGet(ctx, sessionKey(familyID), "user_id").Result()
+		if err != nil && !errors.Is(err, redis.Nil) {
 			return err
 		}
-	}
 
-	return nil
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Del(ctx, append(accessUuids, familyKey(familyID), familyTokensKey(familyID), sessionKey(familyID))...)
+			if userId != "" {
+				pipe.SRem(ctx, userSessionsKey(userId), familyID)
+			}
+			return nil
+		})
+		return err
+	}, familyKey(familyID), sessionKey(familyID))
 }
 
-func (t *TokenRepo) AddToken(userId string, tokenString string) error {
-	added, err := t.redis.SAdd(context.Background(), userId, tokenString).Result()
+// GetSession returns custom_error.ErrNotFound once the session has expired or
+// been revoked.
+func (t *TokenRepo) GetSession(ctx context.Context, familyID string) (*model.Session, error) {
+	fields, err := t.redis.HGetAll(ctx, sessionKey(familyID)).Result()
 	if err != nil {
-		return err
+		return nil, err
 	}
+	session := parseSession(familyID, fields)
+	if session == nil {
+		return nil, custom_error.ErrNotFound
+	}
+	return session, nil
+}
 
-	if added == 0 {
-		return fmt.Errorf("add token error")
+// ListSessions returns the live sessions of a user and prunes the ones that
+// have expired from the index.
+func (t *TokenRepo) ListSessions(ctx context.Context, userId string) ([]*model.Session, error) {
+	familyIDs, err := t.redis.SMembers(ctx, userSessionsKey(userId)).Result()
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	cmds := make([]*redis.MapStringStringCmd, len(familyIDs))
+	_, err = t.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, familyID := range familyIDs {
+			cmds[i] = pipe.HGetAll(ctx, sessionKey(familyID))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]*model.Session, 0, len(familyIDs))
+	var expired []interface{}
+	for i, familyID := range familyIDs {
+		session := parseSession(familyID, cmds[i].Val())
+		if session == nil {
+			expired = append(expired, familyID)
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+	if len(expired) > 0 {
+		if err := t.redis.SRem(ctx, userSessionsKey(userId), expired...).Err(); err != nil {
+			return nil, err
+		}
+	}
+	return sessions, nil
 }
 
-func (t *TokenRepo) IsForgotTokenOf(forgotToken string, userId string) (bool, error) {
-	return t.redis.SIsMember(context.Background(), userId, forgotToken).Result()
+// TouchSession records that the session was used at usedAt. Sessions that no
+// longer exist are left alone.
+func (t *TokenRepo) TouchSession(ctx context.Context, familyID string, usedAt time.Time) error {
+	return watch(ctx, t.redis, func(tx *redis.Tx) error {
+		exists, err := tx.Exists(ctx, sessionKey(familyID)).Result()
+		if err != nil || exists == 0 {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HSet(ctx, sessionKey(familyID), "last_used_at", usedAt.Unix())
+			return nil
+		})
+		return err
+	}, sessionKey(familyID))
+}
+
+func parseSession(familyID string, fields map[string]string) *model.Session {
+	if fields["user_id"] == "" {
+		return nil
+	}
+	createdAt, _ := strconv.ParseInt(fields["created_at"], 10, 64)
+	lastUsedAt, _ := strconv.ParseInt(fields["last_used_at"], 10, 64)
+	return &model.Session{
+		ID:         familyID,
+		UserID:     fields["user_id"],
+		DeviceName: fields["device_name"],
+		UserAgent:  fields["user_agent"],
+		IP:         fields["ip"],
+		CreatedAt:  createdAt,
+		LastUsedAt: lastUsedAt,
+	}
+}
+
+// storeFamilyTokens also counts as a use of the session, since it runs on
+// login and on every refresh.
+func storeFamilyTokens(ctx context.Context, pipe redis.Pipeliner, userId string, td *model.TokenDetails) {
+	refreshExpires := time.Unix(td.RtExpires, 0)
+	pipe.Set(ctx, td.AccessUuid, userId, time.Until(time.Unix(td.AtExpires, 0)))
+	pipe.Set(ctx, familyKey(td.FamilyID), td.RefreshUuid, time.Until(refreshExpires))
+	pipe.SAdd(ctx, familyTokensKey(td.FamilyID), td.AccessUuid)
+	pipe.ExpireAt(ctx, familyTokensKey(td.FamilyID), refreshExpires)
+	pipe.HSet(ctx, sessionKey(td.FamilyID), "last_used_at", time.Now().Unix())
+	pipe.ExpireAt(ctx, sessionKey(td.FamilyID), refreshExpires)
+}
+
+// watch runs fn in an optimistic transaction on keys and retries it when one
+// of the keys changes before the transaction commits.
+func watch(ctx context.Context, client *redis.Client, fn func(tx *redis.Tx) error, keys ...string) error {
+	for i := 0; i < maxTxAttempts; i++ {
+		err := client.Watch(ctx, fn, keys...)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+	}
+	return redis.TxFailedErr
 }
@@ -0,0 +1,194 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"graduation-thesis/internal/authenticator/model"
+	"graduation-thesis/pkg/custom_error"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrCodeReused is returned when a one-time password of a time step that was
+// already accepted is presented again.
+var ErrCodeReused = errors.New("code already used")
+
+type TwoFactorRepo struct {
+	redis *redis.Client
+}
+
+func NewTwoFactorRepo(redisClient *redis.Client) *TwoFactorRepo {
+	return &TwoFactorRepo{
+		redis: redisClient,
+	}
+}
+
+// The two factor key exists while 2FA is enabled and holds the encrypted
+// secret and the last accepted time step. An enrollment keeps its secret in
+// the pending key until it is confirmed. Recovery codes are kept as hashes.
+func twoFactorKey(userId string) string {
+	return "two_factor:" + userId
+}
+
+func pendingTwoFactorKey(userId string) string {
+	return "two_factor_pending:" + userId
+}
+
+func recoveryCodesKey(userId string) string {
+	return "two_factor_recovery:" + userId
+}
+
+// A login challenge is what a login of a user with 2FA gets instead of
+// tokens; it is stored under the hash of its token.
+func loginChallengeKey(tokenHash string) string {
+	return "login_challenge:" + tokenHash
+}
+
+func (t *TwoFactorRepo) IsEnabled(ctx context.Context, userId string) (bool, error) {
+	exists, err := t.redis.Exists(ctx, twoFactorKey(userId)).Result()
+	return exists == 1, err
+}
+
+// GetSecret returns the encrypted secret, or custom_error.ErrNotFound if 2FA
+// is not enabled.
+func (t *TwoFactorRepo) GetSecret(ctx context.Context, userId string) (string, error) {
+	secret, err := t.redis.HGet(ctx, twoFactorKey(userId), "secret").Result()
+	return secret, custom_error.HandleRedisError(err)
+}
+
+func (t *TwoFactorRepo) StorePendingSecret(ctx context.Context, userId, secret string, ttl time.Duration) error {
+	return t.redis.Set(ctx, pendingTwoFactorKey(userId), secret, ttl).Err()
+}
+
+func (t *TwoFactorRepo) GetPendingSecret(ctx context.Context, userId string) (string, error) {
+	secret, err := t.redis.Get(ctx, pendingTwoFactorKey(userId)).Result()
+	return secret, custom_error.HandleRedisError(err)
+}
+
+// Enable turns 2FA on with the confirmed secret. step is the time step of the
+// code that confirmed it.
+func (t *TwoFactorRepo) Enable(ctx context.Context, userId, secret string, step int64, recoveryCodeHashes []string) error {
+	_, err := t.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, twoFactorKey(userId), "secret", secret, "last_step", step)
+		pipe.Del(ctx, pendingTwoFactorKey(userId), recoveryCodesKey(userId))
+		pipe.SAdd(ctx, recoveryCodesKey(userId), toInterfaces(recoveryCodeHashes)...)
+		return nil
+	})
+	return err
+}
+
+func (t *TwoFactorRepo) Disable(ctx context.Context, userId string) error {
+	return t.redis.Del(ctx, twoFactorKey(userId), pendingTwoFactorKey(userId), recoveryCodesKey(userId)).Err()
+}
+
+// UseStep records that a code of step was accepted. It returns ErrCodeReused
+// unless step is later than every step accepted before.
+func (t *TwoFactorRepo) UseStep(ctx context.Context, userId string, step int64) error {
+	return watch(ctx, t.redis, func(tx *redis.Tx) error {
+		value, err := tx.HGet(ctx, twoFactorKey(userId), "last_step").Result()
+		if err != nil {
+			return custom_error.HandleRedisError(err)
+		}
+		lastStep, _ := strconv.ParseInt(value, 10, 64)
+		if step <= lastStep {
+			return ErrCodeReused
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HSet(ctx, twoFactorKey(userId), "last_step", step)
+			return nil
+		})
+		return err
+	}, twoFactorKey(userId))
+}
+
+func (t *TwoFactorRepo) GetRecoveryCodeHashes(ctx context.Context, userId string) ([]string, error) {
+	return t.redis.SMembers(ctx, recoveryCodesKey(userId)).Result()
+}
+
+// ConsumeRecoveryCode removes the recovery code and reports whether it was
+// still there, so a code can be used once even by concurrent logins.
+func (t *TwoFactorRepo) ConsumeRecoveryCode(ctx context.Context, userId, recoveryCodeHash string) (bool, error) {
+	removed, err := t.redis.SRem(ctx, recoveryCodesKey(userId), recoveryCodeHash).Result()
+	return removed == 1, err
+}
+
+func (t *TwoFactorRepo) ReplaceRecoveryCodes(ctx context.Context, userId string, recoveryCodeHashes []string) error {
+	_, err := t.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, recoveryCodesKey(userId))
+		pipe.SAdd(ctx, recoveryCodesKey(userId), toInterfaces(recoveryCodeHashes)...)
+		return nil
+	})
+	return err
+}
+
+func (t *TwoFactorRepo) StoreChallenge(ctx context.Context, tokenHash, userId string, metadata model.SessionMetadata, ttl time.Duration) error {
+	_, err := t.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, loginChallengeKey(tokenHash),
+			"user_id", userId,
+			"device_name", metadata.DeviceName,
+			"user_agent", metadata.UserAgent,
+			"ip", metadata.IP,
+		)
+		pipe.Expire(ctx, loginChallengeKey(tokenHash), ttl)
+		return nil
+	})
+	return err
+}
+
+// AttemptChallenge counts an attempt to answer the challenge and returns the
+// user and login metadata of the challenge along with the number of attempts
+// so far. It returns custom_error.ErrNotFound if the challenge has expired or
+// been answered.
+func (t *TwoFactorRepo) AttemptChallenge(ctx context.Context, tokenHash string) (string, model.SessionMetadata, int64, error) {
+	var fields map[string]string
+	var attempts int64
+	err := watch(ctx, t.redis, func(tx *redis.Tx) error {
+		var err error
+		fields, err = tx.HGetAll(ctx, loginChallengeKey(tokenHash)).Result()
+		if err != nil {
+			return err
+		}
+		if fields["user_id"] == "" {
+			return custom_error.ErrNotFound
+		}
+
+		var incr *redis.IntCmd
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			incr = pipe.HIncrBy(ctx, loginChallengeKey(tokenHash), "attempts", 1)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		attempts = incr.Val()
+		return nil
+	}, loginChallengeKey(tokenHash))
+	if err != nil {
+		return "", model.SessionMetadata{}, 0, err
+	}
+
+	return fields["user_id"], model.SessionMetadata{
+		DeviceName: fields["device_name"],
+		UserAgent:  fields["user_agent"],
+		IP:         fields["ip"],
+	}, attempts, nil
+}
+
+// DeleteChallenge reports whether the challenge was still there, so that only
+// one answer to it issues tokens.
+func (t *TwoFactorRepo) DeleteChallenge(ctx context.Context, tokenHash string) (bool, error) {
+	deleted, err := t.redis.Del(ctx, loginChallengeKey(tokenHash)).Result()
+	return deleted == 1, err
+}
+
+func toInterfaces(values []string) []interface{} {
+	result := make([]interface{}, len(values))
+	for i, value := range values {
+		result[i] = value
+	}
+	return result
+}
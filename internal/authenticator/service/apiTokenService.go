@@ -0,0 +1,242 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"graduation-thesis/internal/authenticator/model"
+	"graduation-thesis/internal/authenticator/repository"
+	"graduation-thesis/pkg/auth"
+	"graduation-thesis/pkg/custom_error"
+	responseModel "graduation-thesis/pkg/model"
+
+	"github.com/twinj/uuid"
+)
+
+// APITokenService manages service accounts and bots and the API tokens they
+// authenticate with. Admins manage every account, users the bots they own.
+type APITokenService struct {
+	apiTokenRepo *repository.APITokenRepo
+	admins       map[string]struct{}
+	mapError     map[error]int
+}
+
+func NewAPITokenService(apiTokenRepo *repository.APITokenRepo, admins []string, mapError map[error]int) *APITokenService {
+	adminSet := make(map[string]struct{}, len(admins))
+	for _, admin := range admins {
+		adminSet[admin] = struct{}{}
+	}
+	return &APITokenService{
+		apiTokenRepo: apiTokenRepo,
+		admins:       adminSet,
+		mapError:     mapError,
+	}
+}
+
+func (a *APITokenService) isAdmin(userID string) bool {
+	_, ok := a.admins[userID]
+	return ok
+}
+
+func (a *APITokenService) CreateAccount(ctx context.Context, userID string, request *model.CreateServiceAccountRequest) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	if request.Kind == auth.KindService && !a.isAdmin(userID) {
+		return nil, a.errorResponse(custom_error.ErrNoPermission)
+	}
+
+	account := &model.ServiceAccount{
+		ID:        uuid.NewV4().String(),
+		Name:      request.Name,
+		Kind:      request.Kind,
+		OwnerID:   userID,
+		CreatedAt: time.Now().Unix(),
+	}
+	if err := a.apiTokenRepo.CreateAccount(ctx, account); err != nil {
+		return nil, a.errorResponse(err)
+	}
+
+	successResponse := responseModel.SuccessResponse{
+		Status: http.StatusCreated,
+		Result: account,
+	}
+	return &successResponse, nil
+}
+
+// ListAccounts returns every account to admins and their bots to users.
+func (a *APITokenService) ListAccounts(ctx context.Context, userID string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	accounts, err := a.apiTokenRepo.ListAccounts(ctx)
+	if err != nil {
+		return nil, a.errorResponse(err)
+	}
+
+	visible := make([]*model.ServiceAccount, 0, len(accounts))
+	for _, account := range accounts {
+		if a.canManage(userID, account) {
+			visible = append(visible, account)
+		}
+	}
+
+	successResponse := responseModel.SuccessResponse{
+		Status: http.StatusOK,
+		Result: visible,
+	}
+	return &successResponse, nil
+}
+
+// DeleteAccount deletes the account and revokes its tokens.
+func (a *APITokenService) DeleteAccount(ctx context.Context, userID, accountID string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	if _, err := a.getAccount(ctx, userID, accountID); err != nil {
+		return nil, a.errorResponse(err)
+	}
+	if err := a.apiTokenRepo.DeleteAccount(ctx, accountID); err != nil {
+		return nil, a.errorResponse(err)
+	}
+
+	successResponse := responseModel.SuccessResponse{
+		Status: http.StatusNoContent,
+	}
+	return &successResponse, nil
+}
+
+// CreateToken issues an API token to the account. The token is only ever
+// returned here.
+func (a *APITokenService) CreateToken(ctx context.Context, userID, accountID string, request *model.CreateAPITokenRequest) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	if _, err := a.getAccount(ctx, userID, accountID); err != nil {
+		return nil, a.errorResponse(err)
+	}
+	for _, scope := range request.Scopes {
+		if !auth.IsScope(scope) {
+			return nil, a.errorResponse(custom_error.ErrInvalidParameter)
+		}
+	}
+
+	token, tokenHash, err := newAPIToken()
+	if err != nil {
+		return nil, a.errorResponse(err)
+	}
+	now := time.Now()
+	issued := &model.IssuedAPIToken{
+		APIToken: model.APIToken{
+			ID:        uuid.NewV4().String(),
+			AccountID: accountID,
+			Name:      request.Name,
+			Scopes:    request.Scopes,
+			CreatedAt: now.Unix(),
+		},
+		Token: token,
+	}
+	ttl := time.Duration(request.ExpiresIn) * time.Second
+	if ttl > 0 {
+		issued.ExpiresAt = now.Add(ttl).Unix()
+	}
+	if err := a.apiTokenRepo.StoreToken(ctx, tokenHash, &issued.APIToken, ttl); err != nil {
+		return nil, a.errorResponse(err)
+	}
+
+	successResponse := responseModel.SuccessResponse{
+		Status: http.StatusCreated,
+		Result: issued,
+	}
+	return &successResponse, nil
+}
+
+func (a *APITokenService) ListTokens(ctx context.Context, userID, accountID string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	if _, err := a.getAccount(ctx, userID, accountID); err != nil {
+		return nil, a.errorResponse(err)
+	}
+	tokens, err := a.apiTokenRepo.ListTokens(ctx, accountID)
+	if err != nil {
+		return nil, a.errorResponse(err)
+	}
+
+	successResponse := responseModel.SuccessResponse{
+		Status: http.StatusOK,
+		Result: tokens,
+	}
+	return &successResponse, nil
+}
+
+// RevokeToken revokes the token at once in the authenticator; services that
+// cached it accept it for up to auth.APITokenCacheTTL more.
+func (a *APITokenService) RevokeToken(ctx context.Context, userID, accountID, tokenID string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	if _, err := a.getAccount(ctx, userID, accountID); err != nil {
+		return nil, a.errorResponse(err)
+	}
+	if err := a.apiTokenRepo.RevokeToken(ctx, accountID, tokenID); err != nil {
+		return nil, a.errorResponse(err)
+	}
+
+	successResponse := responseModel.SuccessResponse{
+		Status: http.StatusNoContent,
+	}
+	return &successResponse, nil
+}
+
+// Authenticate returns the claims of a live API token.
+func (a *APITokenService) Authenticate(ctx context.Context, token string) (*auth.Claims, *model.APIToken, *responseModel.ErrorResponse) {
+	if !auth.IsAPIToken(token) {
+		return nil, nil, a.errorResponse(custom_error.ErrNoPermission)
+	}
+
+	apiToken, err := a.apiTokenRepo.GetToken(ctx, hashOpaqueToken(token))
+	if errors.Is(err, custom_error.ErrNotFound) {
+		err = custom_error.ErrNoPermission
+	}
+	if err != nil {
+		return nil, nil, a.errorResponse(err)
+	}
+	// A token issued while its account was being deleted may outlive it.
+	account, err := a.apiTokenRepo.GetAccount(ctx, apiToken.AccountID)
+	if errors.Is(err, custom_error.ErrNotFound) {
+		err = custom_error.ErrNoPermission
+	}
+	if err != nil {
+		return nil, nil, a.errorResponse(err)
+	}
+
+	return &auth.Claims{
+		UserID:     account.ID,
+		AccessUuid: apiToken.ID,
+		Kind:       account.Kind,
+		Scopes:     apiToken.Scopes,
+	}, apiToken, nil
+}
+
+// getAccount returns the account if userID may manage it. Other users' bots
+// are reported as not found.
+func (a *APITokenService) getAccount(ctx context.Context, userID, accountID string) (*model.ServiceAccount, error) {
+	account, err := a.apiTokenRepo.GetAccount(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if !a.canManage(userID, account) {
+		return nil, custom_error.ErrNotFound
+	}
+	return account, nil
+}
+
+func (a *APITokenService) canManage(userID string, account *model.ServiceAccount) bool {
+	return a.isAdmin(userID) || (account.Kind == auth.KindBot && account.OwnerID == userID)
+}
+
+func (a *APITokenService) errorResponse(err error) *responseModel.ErrorResponse {
+	status, ok := a.mapError[err]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+	return &responseModel.ErrorResponse{
+		Status:       status,
+		ErrorMessage: err.Error(),
+	}
+}
+
+// newAPIToken returns an API token and its hash, which is what is stored.
+func newAPIToken() (string, string, error) {
+	token, _, err := newOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+	token = auth.APITokenPrefix + token
+	return token, hashOpaqueToken(token), nil
+}
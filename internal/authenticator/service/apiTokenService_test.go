@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"graduation-thesis/internal/authenticator/model"
+	"graduation-thesis/internal/authenticator/repository"
+	"graduation-thesis/pkg/auth"
+	"graduation-thesis/pkg/custom_error"
+	"graduation-thesis/pkg/storage/redistest"
+)
+
+func newTestAPITokenService(t *testing.T) (*APITokenService, *redistest.Server) {
+	t.Helper()
+	client, server := redistest.NewClient(t)
+	return NewAPITokenService(repository.NewAPITokenRepo(client), []string{"admin-1"}, custom_error.MappingError()), server
+}
+
+func createAccount(t *testing.T, apiTokenService *APITokenService, userID, kind string) *model.ServiceAccount {
+	t.Helper()
+	successResponse, errorResponse := apiTokenService.CreateAccount(context.Background(), userID, &model.CreateServiceAccountRequest{Name: "notifier", Kind: kind})
+	if errorResponse != nil {
+		t.Fatalf("CreateAccount: %d %s", errorResponse.Status, errorResponse.ErrorMessage)
+	}
+	return successResponse.Result.(*model.ServiceAccount)
+}
+
+func createAPIToken(t *testing.T, apiTokenService *APITokenService, userID, accountID string, expiresIn int64, scopes ...string) *model.IssuedAPIToken {
+	t.Helper()
+	successResponse, errorResponse := apiTokenService.CreateToken(context.Background(), userID, accountID, &model.CreateAPITokenRequest{Name: "ci", Scopes: scopes, ExpiresIn: expiresIn})
+	if errorResponse != nil {
+		t.Fatalf("CreateToken: %d %s", errorResponse.Status, errorResponse.ErrorMessage)
+	}
+	return successResponse.Result.(*model.IssuedAPIToken)
+}
+
+func authenticateAPIToken(apiTokenService *APITokenService, token string) (*auth.Claims, int) {
+	claims, _, errorResponse := apiTokenService.Authenticate(context.Background(), token)
+	if errorResponse != nil {
+		return nil, errorResponse.Status
+	}
+	return claims, http.StatusOK
+}
+
+func TestAPITokenAuthenticate(t *testing.T) {
+	apiTokenService, server := newTestAPITokenService(t)
+	bot := createAccount(t, apiTokenService, "user-1", auth.KindBot)
+	issued := createAPIToken(t, apiTokenService, "user-1", bot.ID, 0, auth.ScopeMessagesSend, auth.ScopeConversationsRead)
+	if !strings.HasPrefix(issued.Token, auth.APITokenPrefix) {
+		t.Fatalf("token %q", issued.Token)
+	}
+	for _, key := range server.Keys() {
+		if strings.Contains(key, strings.TrimPrefix(issued.Token, auth.APITokenPrefix)) {
+			t.Fatalf("token stored in the clear under %s", key)
+		}
+	}
+
+	claims, status := authenticateAPIToken(apiTokenService, issued.Token)
+	if status != http.StatusOK {
+		t.Fatalf("Authenticate = %d", status)
+	}
+	want := &auth.Claims{
+		UserID:     bot.ID,
+		AccessUuid: issued.ID,
+		Kind:       auth.KindBot,
+		Scopes:     []string{auth.ScopeMessagesSend, auth.ScopeConversationsRead},
+	}
+	if !reflect.DeepEqual(claims, want) {
+		t.Fatalf("claims = %+v, want %+v", claims, want)
+	}
+	if !claims.HasScopes(auth.ScopeMessagesSend) || claims.HasScopes(auth.ScopeMessagesSend, auth.ScopeGroupsWrite) {
+		t.Fatalf("scopes of %+v", claims)
+	}
+
+	for _, token := range []string{"", issued.Token[:len(issued.Token)-1], strings.TrimPrefix(issued.Token, auth.APITokenPrefix)} {
+		if _, status := authenticateAPIToken(apiTokenService, token); status != http.StatusUnauthorized {
+			t.Fatalf("Authenticate(%q) = %d", token, status)
+		}
+	}
+}
+
+func TestAPITokenRevocation(t *testing.T) {
+	apiTokenService, server := newTestAPITokenService(t)
+	ctx := context.Background()
+	bot := createAccount(t, apiTokenService, "user-1", auth.KindBot)
+	revoked := createAPIToken(t, apiTokenService, "user-1", bot.ID, 0, auth.ScopeMessagesRead)
+	expiring := createAPIToken(t, apiTokenService, "user-1", bot.ID, 60, auth.ScopeMessagesRead)
+	kept := createAPIToken(t, apiTokenService, "user-1", bot.ID, 0, auth.ScopeMessagesRead)
+
+	if _, errorResponse := apiTokenService.RevokeToken(ctx, "user-1", bot.ID, revoked.ID); errorResponse != nil {
+		t.Fatalf("RevokeToken: %d", errorResponse.Status)
+	}
+	if _, status := authenticateAPIToken(apiTokenService, revoked.Token); status != http.StatusUnauthorized {
+		t.Fatalf("revoked token = %d", status)
+	}
+
+	server.FastForward(time.Minute)
+	if _, status := authenticateAPIToken(apiTokenService, expiring.Token); status != http.StatusUnauthorized {
+		t.Fatalf("expired token = %d", status)
+	}
+
+	successResponse, _ := apiTokenService.ListTokens(ctx, "user-1", bot.ID)
+	tokens := successResponse.Result.([]*model.APIToken)
+	if len(tokens) != 1 || tokens[0].ID != kept.ID {
+		t.Fatalf("tokens = %+v", tokens)
+	}
+
+	// Deleting the account revokes its tokens.
+	if _, errorResponse := apiTokenService.DeleteAccount(ctx, "user-1", bot.ID); errorResponse != nil {
+		t.Fatalf("DeleteAccount: %d", errorResponse.Status)
+	}
+	if _, status := authenticateAPIToken(apiTokenService, kept.Token); status != http.StatusUnauthorized {
+		t.Fatalf("token of a deleted account = %d", status)
+	}
+}
+
+func TestAPITokenPermissions(t *testing.T) {
+	apiTokenService, _ := newTestAPITokenService(t)
+	ctx := context.Background()
+
+	if _, errorResponse := apiTokenService.CreateAccount(ctx, "user-1", &model.CreateServiceAccountRequest{Name: "worker", Kind: auth.KindService}); errorResponse == nil || errorResponse.Status != http.StatusUnauthorized {
+		t.Fatalf("user creating a service account = %+v", errorResponse)
+	}
+	service := createAccount(t, apiTokenService, "admin-1", auth.KindService)
+	bot := createAccount(t, apiTokenService, "user-1", auth.KindBot)
+
+	// Users only see and manage their own bots; admins manage everything.
+	for _, test := range []struct {
+		userID    string
+		accountID string
+		want      int
+	}{
+		{"user-1", bot.ID, http.StatusCreated},
+		{"user-2", bot.ID, http.StatusNotFound},
+		{"user-1", service.ID, http.StatusNotFound},
+		{"admin-1", bot.ID, http.StatusCreated},
+		{"admin-1", service.ID, http.StatusCreated},
+	} {
+		successResponse, errorResponse := apiTokenService.CreateToken(ctx, test.userID, test.accountID, &model.CreateAPITokenRequest{Name: "ci", Scopes: []string{auth.ScopeMessagesSend}})
+		status := 0
+		if errorResponse != nil {
+			status = errorResponse.Status
+		} else {
+			status = successResponse.Status
+		}
+		if status != test.want {
+			t.Fatalf("%s creating a token of %s = %d, want %d", test.userID, test.accountID, status, test.want)
+		}
+	}
+
+	successResponse, _ := apiTokenService.ListAccounts(ctx, "user-2")
+	if accounts := successResponse.Result.([]*model.ServiceAccount); len(accounts) != 0 {
+		t.Fatalf("user-2 sees %+v", accounts)
+	}
+	successResponse, _ = apiTokenService.ListAccounts(ctx, "admin-1")
+	if accounts := successResponse.Result.([]*model.ServiceAccount); len(accounts) != 2 {
+		t.Fatalf("admin sees %+v", accounts)
+	}
+
+	if _, errorResponse := apiTokenService.CreateToken(ctx, "user-1", bot.ID, &model.CreateAPITokenRequest{Name: "ci", Scopes: []string{"admin:everything"}}); errorResponse == nil || errorResponse.Status != http.StatusUnprocessableEntity {
+		t.Fatalf("unknown scope = %+v", errorResponse)
+	}
+}
@@ -1,47 +1,88 @@
 package service
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
+	"math"
 	"net/http"
-	"time"
 
 	"graduation-thesis/internal/authenticator/model"
+	"graduation-thesis/pkg/auth"
 	"graduation-thesis/pkg/custom_error"
 	responseModel "graduation-thesis/pkg/model"
-	request "graduation-thesis/pkg/requests"
+	"graduation-thesis/pkg/pb/userpb"
 )
 
 type AuthService struct {
-	tokenService   *TokenService
-	mapError       map[error]int
-	userServiceUrl string
+	tokenService     *TokenService
+	twoFactorService *TwoFactorService
+	mapError         map[error]int
+	userClient       userpb.UserServiceClient
 }
 
-func NewAuthService(tokenService *TokenService, mapError map[error]int, userServiceUrl string) *AuthService {
+func NewAuthService(tokenService *TokenService, twoFactorService *TwoFactorService, mapError map[error]int, userClient userpb.UserServiceClient) *AuthService {
 	return &AuthService{
-		tokenService:   tokenService,
-		mapError:       mapError,
-		userServiceUrl: userServiceUrl,
+		tokenService:     tokenService,
+		twoFactorService: twoFactorService,
+		mapError:         mapError,
+		userClient:       userClient,
 	}
 }
 
-func (a *AuthService) Login(ctx context.Context, loginRequest *model.LoginRequest) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+// Login checks the credentials with the user service, which limits failed
+// logins per username and per client ip, and starts a session.
+func (a *AuthService) Login(ctx context.Context, loginRequest *model.LoginRequest, metadata model.SessionMetadata) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	var errorResponse responseModel.ErrorResponse
+
+	verified, err := a.userClient.VerifyCredential(ctx, &userpb.VerifyCredentialRequest{
+		Username: loginRequest.Username,
+		Password: loginRequest.Password,
+		Ip:       metadata.IP,
+	})
+	if err != nil {
+		retryAfter := custom_error.GRPCRetryAfter(err)
+		err = custom_error.HandleGRPCError(err)
+		status, ok := a.mapError[err]
+		if !ok {
+			status = http.StatusInternalServerError
+		}
+		errorResponse.Status = status
+		errorResponse.ErrorMessage = err.Error()
+		errorResponse.RetryAfter = int(math.Ceil(retryAfter.Seconds()))
+		return nil, &errorResponse
+	}
+
+	return a.StartSession(ctx, verified.UserId, metadata)
+}
+
+// StartSession logs in a user whose credentials have been checked. Users with
+// 2FA get a challenge to answer with their second factor instead of tokens.
+func (a *AuthService) StartSession(ctx context.Context, userID string, metadata model.SessionMetadata) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
 	var successResponse responseModel.SuccessResponse
 	var errorResponse responseModel.ErrorResponse
 
-	userID, err := a.verifyCredential(ctx, loginRequest)
+	twoFactorEnabled, err := a.twoFactorService.IsEnabled(ctx, userID)
 	if err != nil {
-		errorResponse.Status = a.mapError[err]
+		errorResponse.Status = http.StatusInternalServerError
 		errorResponse.ErrorMessage = err.Error()
 		return nil, &errorResponse
 	}
+	if twoFactorEnabled {
+		challenge, err := a.twoFactorService.NewChallenge(ctx, userID, metadata)
+		if err != nil {
+			errorResponse.Status = http.StatusInternalServerError
+			errorResponse.ErrorMessage = err.Error()
+			return nil, &errorResponse
+		}
+
+		successResponse.Result = challenge
+		successResponse.Status = http.StatusOK
+		return &successResponse, nil
+	}
 
-	tokenDetails, tokenErr := a.tokenService.CreateToken(userID)
+	tokenDetails, tokenErr := a.tokenService.CreateToken(ctx, userID, metadata)
 	if tokenErr != nil {
-		errorResponse.Status = a.mapError[err]
-		errorResponse.ErrorMessage = err.Error()
+		errorResponse.Status = http.StatusInternalServerError
+		errorResponse.ErrorMessage = tokenErr.Error()
 		return nil, &errorResponse
 	}
 
@@ -50,26 +91,35 @@ func (a *AuthService) Login(ctx context.Context, loginRequest *model.LoginReques
 	return &successResponse, nil
 }
 
-func (a *AuthService) verifyCredential(ctx context.Context, loginRequest *model.LoginRequest) (string, error) {
-	body := new(bytes.Buffer)
-	if err := json.NewEncoder(body).Encode(&loginRequest); err != nil {
-		return "", custom_error.ErrInvalidParameter
-	}
+// LoginTwoFactor finishes the login of a user with 2FA.
+func (a *AuthService) LoginTwoFactor(ctx context.Context, loginRequest *model.TwoFactorLoginRequest) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	var successResponse responseModel.SuccessResponse
+	var errorResponse responseModel.ErrorResponse
 
-	result, err := request.HTTPRequestCall(
-		a.userServiceUrl,
-		http.MethodPost,
-		"",
-		body,
-		5*time.Second,
-	)
+	userID, metadata, err := a.twoFactorService.CompleteChallenge(ctx, loginRequest.ChallengeToken, &loginRequest.TwoFactorRequest)
 	if err != nil {
-		return "", err
+		status, ok := a.mapError[err]
+		if !ok {
+			status = http.StatusInternalServerError
+		}
+		errorResponse.Status = status
+		errorResponse.ErrorMessage = err.Error()
+		return nil, &errorResponse
 	}
 
-	return result.(string), nil
-}
+	tokenDetails, tokenErr := a.tokenService.CreateToken(ctx, userID, metadata)
+	if tokenErr != nil {
+		errorResponse.Status = http.StatusInternalServerError
+		errorResponse.ErrorMessage = tokenErr.Error()
+		return nil, &errorResponse
+	}
 
-func (a *AuthService) Logout(ctx context.Context) {
+	successResponse.Result = tokenDetails
+	successResponse.Status = http.StatusOK
+	return &successResponse, nil
+}
 
+// Logout revokes the session the access token belongs to.
+func (a *AuthService) Logout(ctx context.Context, claims *auth.Claims) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	return a.tokenService.RevokeSession(ctx, claims.UserID, claims.SessionID)
 }
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"graduation-thesis/internal/authenticator/model"
+	"graduation-thesis/pkg/custom_error"
+	"graduation-thesis/pkg/pb/userpb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// lockedUserServer refuses every login as the user service does once the
+// client is locked out.
+type lockedUserServer struct {
+	userpb.UnimplementedUserServiceServer
+	calls atomic.Int32
+	ip    atomic.Value
+}
+
+func (l *lockedUserServer) VerifyCredential(ctx context.Context, request *userpb.VerifyCredentialRequest) (*userpb.VerifyCredentialResponse, error) {
+	l.calls.Add(1)
+	l.ip.Store(request.Ip)
+	return nil, custom_error.StatusToGRPCErrorWithRetry(http.StatusTooManyRequests, custom_error.ErrTooManyRequests.Error(), 30)
+}
+
+// serveUsers serves server as the user service rpc on a local port.
+func serveUsers(t *testing.T, server userpb.UserServiceServer) userpb.UserServiceClient {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	grpcServer := grpc.NewServer()
+	userpb.RegisterUserServiceServer(grpcServer, server)
+	go grpcServer.Serve(lis)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return userpb.NewUserServiceClient(conn)
+}
+
+func TestLoginPassesOnLockout(t *testing.T) {
+	users := &lockedUserServer{}
+	authService := NewAuthService(nil, nil, custom_error.MappingError(), serveUsers(t, users))
+
+	_, errorResponse := authService.Login(context.Background(), &model.LoginRequest{Username: "alice", Password: "guess"}, model.SessionMetadata{IP: "203.0.113.7"})
+	if errorResponse == nil || errorResponse.Status != http.StatusTooManyRequests {
+		t.Fatalf("Login = %+v, want 429", errorResponse)
+	}
+	if errorResponse.RetryAfter != 30 {
+		t.Fatalf("RetryAfter = %d, want 30", errorResponse.RetryAfter)
+	}
+	// Failures are counted against the client, not the authenticator.
+	if ip := users.ip.Load(); ip != "203.0.113.7" {
+		t.Fatalf("user service got ip %v", ip)
+	}
+	if calls := users.calls.Load(); calls != 1 {
+		t.Fatalf("user service called %d times", calls)
+	}
+}
+
+func TestLoginRefusesBadCredentials(t *testing.T) {
+	authService, _, _ := newTestAuthService(t)
+	authService.userClient.(*fakeUserClient).passwords = map[string]string{"user-1": "correct"}
+
+	_, errorResponse := authService.Login(context.Background(), &model.LoginRequest{Username: "alice", Password: "guess"}, model.SessionMetadata{})
+	if errorResponse == nil || errorResponse.Status != http.StatusUnauthorized || errorResponse.RetryAfter != 0 {
+		t.Fatalf("Login = %+v, want 401", errorResponse)
+	}
+	_, errorResponse = authService.Login(context.Background(), &model.LoginRequest{Username: "mallory", Password: "guess"}, model.SessionMetadata{})
+	if errorResponse == nil || errorResponse.Status != http.StatusUnauthorized {
+		t.Fatalf("Login of an unknown user = %+v, want 401", errorResponse)
+	}
+}
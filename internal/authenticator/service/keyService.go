@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"graduation-thesis/internal/authenticator/repository"
+	"graduation-thesis/pkg/jwks"
+	"graduation-thesis/pkg/logger"
+)
+
+var errNoSigningKey = errors.New("no signing key")
+
+// KeyService keeps a local copy of the signing key ring, which every replica
+// shares through the KeyRepo, and rotates it on schedule.
+type KeyService struct {
+	keyRepo *repository.KeyRepo
+	policy  jwks.RotationPolicy
+	logger  logger.Logger
+
+	mu   sync.RWMutex
+	keys []*jwks.Key
+}
+
+func NewKeyService(keyRepo *repository.KeyRepo, policy jwks.RotationPolicy, logger logger.Logger) *KeyService {
+	return &KeyService{
+		keyRepo: keyRepo,
+		policy:  policy,
+		logger:  logger,
+	}
+}
+
+// Rotate applies the rotation policy to the shared ring and reloads the local
+// copy. Every replica runs it; the transaction on the ring keeps them from
+// generating the same key twice.
+func (k *KeyService) Rotate(ctx context.Context) error {
+	keys, err := k.keyRepo.UpdateKeys(ctx, func(keys []*jwks.Key) ([]*jwks.Key, bool, error) {
+		return k.policy.Rotate(keys, time.Now())
+	})
+	if err != nil {
+		return err
+	}
+
+	k.mu.Lock()
+	k.keys = keys
+	k.mu.Unlock()
+	return nil
+}
+
+// Run rotates the ring every interval until ctx is done. New keys are
+// published well ahead of use, so an interval much shorter than the publish
+// lead keeps every replica signing with the same key.
+func (k *KeyService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := k.Rotate(ctx); err != nil {
+				k.logger.Errorf("[Run] Cannot rotate signing keys: %v", err)
+			}
+		}
+	}
+}
+
+// SigningKey returns the key that signs tokens now.
+func (k *KeyService) SigningKey() (*jwks.Key, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	key := jwks.Current(k.keys, time.Now())
+	if key == nil {
+		return nil, errNoSigningKey
+	}
+	return key, nil
+}
+
+// Key returns the key with the given id if it is still published.
+func (k *KeyService) Key(kid string) (*jwks.Key, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	for _, key := range k.keys {
+		if key.ID == kid {
+			return key, nil
+		}
+	}
+	return nil, jwks.ErrUnknownKey
+}
+
+// KeySet returns the public keys to publish.
+func (k *KeyService) KeySet() (*jwks.Set, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	return jwks.Publish(k.keys)
+}
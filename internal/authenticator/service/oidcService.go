@@ -0,0 +1,212 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"graduation-thesis/internal/authenticator/model"
+	"graduation-thesis/internal/authenticator/repository"
+	"graduation-thesis/pkg/custom_error"
+	"graduation-thesis/pkg/logger"
+	responseModel "graduation-thesis/pkg/model"
+	"graduation-thesis/pkg/oidc"
+	"graduation-thesis/pkg/pb/userpb"
+)
+
+// OIDCClaims names the id token claims the profile of a provisioned user is
+// filled from. Empty names fall back to the standard claims.
+type OIDCClaims struct {
+	Username      string
+	Email         string
+	EmailVerified string
+	FirstName     string
+	LastName      string
+	PhoneNumber   string
+	Avatar        string
+}
+
+func (c OIDCClaims) withDefaults() OIDCClaims {
+	return OIDCClaims{
+		Username:      orDefault(c.Username, "preferred_username"),
+		Email:         orDefault(c.Email, "email"),
+		EmailVerified: orDefault(c.EmailVerified, "email_verified"),
+		FirstName:     orDefault(c.FirstName, "given_name"),
+		LastName:      orDefault(c.LastName, "family_name"),
+		PhoneNumber:   orDefault(c.PhoneNumber, "phone_number"),
+		Avatar:        orDefault(c.Avatar, "picture"),
+	}
+}
+
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+type OIDCProviderConfig struct {
+	oidc.Config
+	Name string
+	// LinkByEmail lets a first login with the provider log in as the existing
+	// account of the same email, if both the provider and the account have
+	// verified it. Only turn it on for providers trusted to verify emails.
+	LinkByEmail bool
+	Claims      OIDCClaims
+}
+
+type oidcProvider struct {
+	*oidc.Provider
+	config OIDCProviderConfig
+}
+
+type OIDCService struct {
+	oidcRepo    *repository.OIDCRepo
+	providers   map[string]*oidcProvider
+	userClient  userpb.UserServiceClient
+	authService *AuthService
+	stateTTL    time.Duration
+	mapError    map[error]int
+	logger      logger.Logger
+}
+
+func NewOIDCService(
+	oidcRepo *repository.OIDCRepo,
+	providers []OIDCProviderConfig,
+	client *http.Client,
+	userClient userpb.UserServiceClient,
+	authService *AuthService,
+	stateTTL time.Duration,
+	mapError map[error]int,
+	logger logger.Logger) *OIDCService {
+	o := &OIDCService{
+		oidcRepo:    oidcRepo,
+		providers:   make(map[string]*oidcProvider, len(providers)),
+		userClient:  userClient,
+		authService: authService,
+		stateTTL:    stateTTL,
+		mapError:    mapError,
+		logger:      logger,
+	}
+	for _, config := range providers {
+		config.Claims = config.Claims.withDefaults()
+		o.providers[config.Name] = &oidcProvider{
+			Provider: oidc.NewProvider(config.Config, client),
+			config:   config,
+		}
+	}
+	return o
+}
+
+// Begin starts a login with the provider and returns where to send the user.
+// The provider sends them back to the callback with the state stored here.
+func (o *OIDCService) Begin(ctx context.Context, providerName, deviceName string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	provider, ok := o.providers[providerName]
+	if !ok {
+		return nil, o.errorResponse(custom_error.ErrNotFound)
+	}
+
+	state, stateHash, err := newOpaqueToken()
+	if err != nil {
+		return nil, o.errorResponse(err)
+	}
+	loginState := &model.OIDCLoginState{
+		Provider:   providerName,
+		DeviceName: deviceName,
+	}
+	if loginState.CodeVerifier, err = oidc.RandomString(); err != nil {
+		return nil, o.errorResponse(err)
+	}
+	if loginState.Nonce, err = oidc.RandomString(); err != nil {
+		return nil, o.errorResponse(err)
+	}
+
+	authURL, err := provider.AuthCodeURL(ctx, state, loginState.Nonce, loginState.CodeVerifier)
+	if err != nil {
+		return nil, o.errorResponse(o.providerError(providerName, err))
+	}
+	if err := o.oidcRepo.StoreState(ctx, stateHash, loginState, o.stateTTL); err != nil {
+		return nil, o.errorResponse(err)
+	}
+
+	successResponse := responseModel.SuccessResponse{
+		Status: http.StatusOK,
+		Result: &model.OIDCLogin{AuthURL: authURL},
+	}
+	return &successResponse, nil
+}
+
+// Callback finishes a login the provider sent the user back from. The user the
+// provider's subject is linked to logs in, and is created on the first login.
+// Logins answer like a password login from there on, so users with 2FA still
+// get a challenge.
+func (o *OIDCService) Callback(ctx context.Context, providerName, code, state string, metadata model.SessionMetadata) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	provider, ok := o.providers[providerName]
+	if !ok {
+		return nil, o.errorResponse(custom_error.ErrNotFound)
+	}
+
+	loginState, err := o.oidcRepo.ConsumeState(ctx, hashOpaqueToken(state))
+	if errors.Is(err, custom_error.ErrNotFound) {
+		err = custom_error.ErrNoPermission
+	}
+	if err != nil {
+		return nil, o.errorResponse(err)
+	}
+	if loginState.Provider != providerName {
+		return nil, o.errorResponse(custom_error.ErrNoPermission)
+	}
+
+	idToken, err := provider.Exchange(ctx, code, loginState.CodeVerifier, loginState.Nonce)
+	if err != nil {
+		return nil, o.errorResponse(o.providerError(providerName, err))
+	}
+
+	claims := provider.config.Claims
+	linked, err := o.userClient.LinkExternalIdentity(ctx, &userpb.LinkExternalIdentityRequest{
+		Provider:    providerName,
+		Subject:     idToken.Subject,
+		LinkByEmail: provider.config.LinkByEmail,
+		Profile: &userpb.ExternalProfile{
+			Username:      idToken.String(claims.Username),
+			Email:         idToken.String(claims.Email),
+			EmailVerified: idToken.Bool(claims.EmailVerified),
+			FirstName:     idToken.String(claims.FirstName),
+			LastName:      idToken.String(claims.LastName),
+			PhoneNumber:   idToken.String(claims.PhoneNumber),
+			Avatar:        idToken.String(claims.Avatar),
+		},
+	})
+	if err != nil {
+		return nil, o.errorResponse(custom_error.HandleGRPCError(err))
+	}
+
+	metadata.DeviceName = loginState.DeviceName
+	return o.authService.StartSession(ctx, linked.UserId, metadata)
+}
+
+// providerError logs what went wrong with the provider and returns what to
+// tell the user: a token the provider won't exchange or that fails to verify
+// doesn't log in, and a provider that can't be reached is unavailable.
+func (o *OIDCService) providerError(providerName string, err error) error {
+	o.logger.Errorf("[providerError] Cannot log in with oidc provider %s: %v", providerName, err)
+	switch {
+	case errors.Is(err, oidc.ErrExchange), errors.Is(err, oidc.ErrInvalidToken):
+		return custom_error.ErrNoPermission
+	case errors.Is(err, oidc.ErrDiscovery):
+		return custom_error.ErrConnectionErr
+	}
+	return err
+}
+
+func (o *OIDCService) errorResponse(err error) *responseModel.ErrorResponse {
+	status, ok := o.mapError[err]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+	return &responseModel.ErrorResponse{
+		Status:       status,
+		ErrorMessage: err.Error(),
+	}
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"graduation-thesis/internal/authenticator/model"
+	"graduation-thesis/internal/authenticator/repository"
+	"graduation-thesis/pkg/custom_error"
+	"graduation-thesis/pkg/oidc"
+	"graduation-thesis/pkg/oidc/oidctest"
+	"graduation-thesis/pkg/pb/userpb"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+// fakeLinkClient links every subject to user-1 unless told to refuse.
+type fakeLinkClient struct {
+	userpb.UserServiceClient
+	mu       sync.Mutex
+	requests []*userpb.LinkExternalIdentityRequest
+	refuse   int
+}
+
+func (f *fakeLinkClient) LinkExternalIdentity(ctx context.Context, in *userpb.LinkExternalIdentityRequest, opts ...grpc.CallOption) (*userpb.LinkExternalIdentityResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, in)
+	if f.refuse != 0 {
+		return nil, custom_error.StatusToGRPCError(f.refuse, "refused")
+	}
+	return &userpb.LinkExternalIdentityResponse{UserId: "user-1", Created: len(f.requests) == 1}, nil
+}
+
+func newTestOIDCService(t *testing.T) (*OIDCService, *oidctest.Provider, *fakeLinkClient, *TwoFactorService) {
+	t.Helper()
+	authService, twoFactorService, client := newTestAuthService(t)
+	mock := oidctest.NewProvider(t, "chat", "s3cret")
+	mock.LogIn(&oidctest.User{Subject: "sub-1", Claims: map[string]interface{}{
+		"email":          "alice@corp.example.com",
+		"email_verified": true,
+		"nickname":       "alice",
+		"given_name":     "Alice",
+		"picture":        "https://idp.example.com/alice.png",
+	}})
+	users := &fakeLinkClient{}
+	oidcService := NewOIDCService(
+		repository.NewOIDCRepo(client),
+		[]OIDCProviderConfig{{
+			Name: "corp",
+			Config: oidc.Config{
+				Issuer:       mock.Issuer(),
+				ClientID:     "chat",
+				ClientSecret: "s3cret",
+				RedirectURL:  "http://localhost/v1/oidc/corp/callback",
+			},
+			LinkByEmail: true,
+			Claims:      OIDCClaims{Username: "nickname"},
+		}},
+		http.DefaultClient,
+		users,
+		authService,
+		10*time.Minute,
+		custom_error.MappingError(),
+		zap.NewNop().Sugar(),
+	)
+	return oidcService, mock, users, twoFactorService
+}
+
+// beginOIDCLogin logs in at the provider and returns the code and state it
+// sends the user back with.
+func beginOIDCLogin(t *testing.T, oidcService *OIDCService, mock *oidctest.Provider) (string, string) {
+	t.Helper()
+	successResponse, errorResponse := oidcService.Begin(context.Background(), "corp", "laptop")
+	if errorResponse != nil {
+		t.Fatalf("Begin: %d %s", errorResponse.Status, errorResponse.ErrorMessage)
+	}
+	callback := mock.Authorize(t, successResponse.Result.(*model.OIDCLogin).AuthURL)
+	return callback.Query().Get("code"), callback.Query().Get("state")
+}
+
+func oidcCallback(oidcService *OIDCService, code, state string) (interface{}, int) {
+	successResponse, errorResponse := oidcService.Callback(context.Background(), "corp", code, state, model.SessionMetadata{UserAgent: "test"})
+	if errorResponse != nil {
+		return nil, errorResponse.Status
+	}
+	return successResponse.Result, successResponse.Status
+}
+
+func TestOIDCLogin(t *testing.T) {
+	oidcService, mock, users, _ := newTestOIDCService(t)
+
+	code, state := beginOIDCLogin(t, oidcService, mock)
+	result, status := oidcCallback(oidcService, code, state)
+	if status != http.StatusOK {
+		t.Fatalf("Callback = %d", status)
+	}
+	tokens, ok := result.(*model.TokenDetails)
+	if !ok || tokens.AccessToken == "" || tokens.RefreshToken == "" {
+		t.Fatalf("Callback result = %+v", result)
+	}
+
+	request := users.requests[0]
+	if request.Provider != "corp" || request.Subject != "sub-1" || !request.LinkByEmail {
+		t.Fatalf("linked %+v", request)
+	}
+	profile := request.Profile
+	if profile.Username != "alice" || profile.Email != "alice@corp.example.com" || !profile.EmailVerified ||
+		profile.FirstName != "Alice" || profile.Avatar != "https://idp.example.com/alice.png" {
+		t.Fatalf("profile = %+v", profile)
+	}
+
+	// The state is good for one answer of the provider.
+	if _, status := oidcCallback(oidcService, code, state); status != http.StatusUnauthorized {
+		t.Fatalf("replayed Callback = %d", status)
+	}
+}
+
+func TestOIDCLoginTwoFactor(t *testing.T) {
+	oidcService, mock, _, twoFactorService := newTestOIDCService(t)
+	enable(t, twoFactorService)
+
+	code, state := beginOIDCLogin(t, oidcService, mock)
+	result, status := oidcCallback(oidcService, code, state)
+	if status != http.StatusOK {
+		t.Fatalf("Callback = %d", status)
+	}
+	if challenge, ok := result.(*model.LoginChallenge); !ok || !challenge.TwoFactorRequired {
+		t.Fatalf("Callback result = %+v, want a challenge", result)
+	}
+}
+
+func TestOIDCCallbackRejects(t *testing.T) {
+	t.Run("unknown state", func(t *testing.T) {
+		oidcService, mock, _, _ := newTestOIDCService(t)
+		code, _ := beginOIDCLogin(t, oidcService, mock)
+		if _, status := oidcCallback(oidcService, code, "forged"); status != http.StatusUnauthorized {
+			t.Fatalf("Callback = %d", status)
+		}
+	})
+
+	t.Run("code of another login", func(t *testing.T) {
+		oidcService, mock, users, _ := newTestOIDCService(t)
+		code, _ := beginOIDCLogin(t, oidcService, mock)
+		_, state := beginOIDCLogin(t, oidcService, mock)
+		if _, status := oidcCallback(oidcService, code, state); status != http.StatusUnauthorized {
+			t.Fatalf("Callback = %d", status)
+		}
+		if len(users.requests) != 0 {
+			t.Fatal("linked an identity of a failed login")
+		}
+	})
+
+	t.Run("unknown provider", func(t *testing.T) {
+		oidcService, _, _, _ := newTestOIDCService(t)
+		if _, errorResponse := oidcService.Begin(context.Background(), "other", ""); errorResponse == nil || errorResponse.Status != http.StatusNotFound {
+			t.Fatalf("Begin = %+v", errorResponse)
+		}
+	})
+
+	t.Run("email taken", func(t *testing.T) {
+		oidcService, mock, users, _ := newTestOIDCService(t)
+		users.refuse = http.StatusConflict
+		code, state := beginOIDCLogin(t, oidcService, mock)
+		if _, status := oidcCallback(oidcService, code, state); status != http.StatusConflict {
+			t.Fatalf("Callback = %d", status)
+		}
+	})
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"graduation-thesis/internal/authenticator/repository"
+	"graduation-thesis/pkg/custom_error"
+	"graduation-thesis/pkg/mail"
+	responseModel "graduation-thesis/pkg/model"
+	"graduation-thesis/pkg/pb/userpb"
+)
+
+const opaqueTokenBytes = 32
+
+type PasswordService struct {
+	tokenRepo     *repository.TokenRepo
+	tokenService  *TokenService
+	userClient    userpb.UserServiceClient
+	mailer        mail.Mailer
+	resetTokenTTL time.Duration
+	resetURL      string
+	mapError      map[error]int
+}
+
+func NewPasswordService(
+	tokenRepo *repository.TokenRepo,
+	tokenService *TokenService,
+	userClient userpb.UserServiceClient,
+	mailer mail.Mailer,
+	resetTokenTTL time.Duration,
+	resetURL string,
+	mapError map[error]int) *PasswordService {
+	return &PasswordService{
+		tokenRepo:     tokenRepo,
+		tokenService:  tokenService,
+		userClient:    userClient,
+		mailer:        mailer,
+		resetTokenTTL: resetTokenTTL,
+		resetURL:      resetURL,
+		mapError:      mapError,
+	}
+}
+
+// ForgotPassword mails a reset link to the user. It answers the same whether
+// or not the user exists so that it can't be used to find accounts.
+func (p *PasswordService) ForgotPassword(ctx context.Context, username string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	successResponse := responseModel.SuccessResponse{
+		Status: http.StatusAccepted,
+	}
+
+	account, err := p.userClient.GetAccount(ctx, &userpb.GetAccountRequest{Username: username})
+	if err != nil {
+		err = custom_error.HandleGRPCError(err)
+		if errors.Is(err, custom_error.ErrNotFound) {
+			return &successResponse, nil
+		}
+		return nil, p.errorResponse(err)
+	}
+	if account.Email == "" {
+		return &successResponse, nil
+	}
+
+	token, tokenHash, err := newOpaqueToken()
+	if err != nil {
+		return nil, p.errorResponse(err)
+	}
+	if err := p.tokenRepo.StoreResetToken(ctx, account.UserId, tokenHash, p.resetTokenTTL); err != nil {
+		return nil, p.errorResponse(err)
+	}
+
+	link := fmt.Sprintf("%s?token=%s", p.resetURL, url.QueryEscape(token))
+	err = p.mailer.Send(ctx, mail.Message{
+		To:      account.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Someone asked to reset the password of %s.\n\n"+
+			"Follow this link within %s to choose a new password:\n%s\n\n"+
+			"If it wasn't you, you can ignore this email.\n", username, p.resetTokenTTL, link),
+	})
+	if err != nil {
+		return nil, p.errorResponse(err)
+	}
+
+	return &successResponse, nil
+}
+
+// ResetPassword sets a new password with a reset token and logs the user out
+// of every session, since whoever knew the old password may still hold one.
+func (p *PasswordService) ResetPassword(ctx context.Context, token, password string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	userID, err := p.tokenRepo.ConsumeResetToken(ctx, hashOpaqueToken(token))
+	if errors.Is(err, custom_error.ErrNotFound) {
+		err = custom_error.ErrNoPermission
+	}
+	if err != nil {
+		return nil, p.errorResponse(err)
+	}
+
+	_, err = p.userClient.SetPassword(ctx, &userpb.SetPasswordRequest{UserId: userID, Password: password})
+	if err != nil {
+		return nil, p.errorResponse(custom_error.HandleGRPCError(err))
+	}
+
+	if err := p.tokenService.revokeAllSessions(ctx, userID); err != nil {
+		return nil, p.errorResponse(err)
+	}
+
+	successResponse := responseModel.SuccessResponse{
+		Status: http.StatusNoContent,
+	}
+	return &successResponse, nil
+}
+
+func (p *PasswordService) errorResponse(err error) *responseModel.ErrorResponse {
+	status, ok := p.mapError[err]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+	return &responseModel.ErrorResponse{
+		Status:       status,
+		ErrorMessage: err.Error(),
+	}
+}
+
+// newOpaqueToken returns a random token to hand out and the hash that is
+// stored in its place, as for reset tokens and login challenges.
+func newOpaqueToken() (string, string, error) {
+	b := make([]byte, opaqueTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	return token, hashOpaqueToken(token), nil
+}
+
+func hashOpaqueToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
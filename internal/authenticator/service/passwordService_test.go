@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"graduation-thesis/internal/authenticator/repository"
+	"graduation-thesis/pkg/custom_error"
+	"graduation-thesis/pkg/mail"
+	"graduation-thesis/pkg/pb/userpb"
+	"graduation-thesis/pkg/storage/redistest"
+
+	"google.golang.org/grpc"
+)
+
+type fakeUserClient struct {
+	userpb.UserServiceClient
+	mu        sync.Mutex
+	accounts  map[string]*userpb.Account
+	passwords map[string]string
+}
+
+// VerifyCredential accepts the password set for the account, or any password
+// if none was set.
+func (f *fakeUserClient) VerifyCredential(ctx context.Context, in *userpb.VerifyCredentialRequest, opts ...grpc.CallOption) (*userpb.VerifyCredentialResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	account, ok := f.accounts[in.Username]
+	if password, set := f.passwords[account.GetUserId()]; !ok || (set && password != in.Password) {
+		return nil, custom_error.StatusToGRPCError(http.StatusUnauthorized, custom_error.ErrNoPermission.Error())
+	}
+	return &userpb.VerifyCredentialResponse{UserId: account.UserId}, nil
+}
+
+func (f *fakeUserClient) GetAccount(ctx context.Context, in *userpb.GetAccountRequest, opts ...grpc.CallOption) (*userpb.Account, error) {
+	for _, account := range f.accounts {
+		if account.Username == in.Username || (in.UserId != "" && account.UserId == in.UserId) {
+			return account, nil
+		}
+	}
+	return nil, custom_error.StatusToGRPCError(http.StatusNotFound, custom_error.ErrNotFound.Error())
+}
+
+func (f *fakeUserClient) SetPassword(ctx context.Context, in *userpb.SetPasswordRequest, opts ...grpc.CallOption) (*userpb.SetPasswordResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.passwords[in.UserId] = in.Password
+	return &userpb.SetPasswordResponse{}, nil
+}
+
+type recordingMailer struct {
+	messages []mail.Message
+}
+
+func (r *recordingMailer) Send(ctx context.Context, message mail.Message) error {
+	r.messages = append(r.messages, message)
+	return nil
+}
+
+func newTestPasswordService(t *testing.T) (*PasswordService, *TokenService, *fakeUserClient, *recordingMailer, *redistest.Server) {
+	t.Helper()
+	tokenService, server := newTestTokenService(t)
+	users := &fakeUserClient{
+		accounts: map[string]*userpb.Account{
+			"alice": {UserId: "user-1", Email: "alice@example.com", Username: "alice"},
+		},
+		passwords: make(map[string]string),
+	}
+	mailer := &recordingMailer{}
+	passwordService := NewPasswordService(
+		tokenService.tokenRepo,
+		tokenService,
+		users,
+		mailer,
+		30*time.Minute,
+		"https://chat.example.com/reset-password",
+		custom_error.MappingError(),
+	)
+	return passwordService, tokenService, users, mailer, server
+}
+
+func forgotPassword(t *testing.T, passwordService *PasswordService, username string) {
+	t.Helper()
+	successResponse, errorResponse := passwordService.ForgotPassword(context.Background(), username)
+	if errorResponse != nil {
+		t.Fatalf("ForgotPassword: %d %s", errorResponse.Status, errorResponse.ErrorMessage)
+	}
+	if successResponse.Status != http.StatusAccepted {
+		t.Fatalf("ForgotPassword status = %d, want %d", successResponse.Status, http.StatusAccepted)
+	}
+}
+
+func mailedToken(t *testing.T, message mail.Message) string {
+	t.Helper()
+	for _, field := range strings.Fields(message.Body) {
+		if link, err := url.Parse(field); err == nil && link.Query().Get("token") != "" {
+			return link.Query().Get("token")
+		}
+	}
+	t.Fatalf("no reset link in %q", message.Body)
+	return ""
+}
+
+func resetPasswordStatus(passwordService *PasswordService, token, password string) int {
+	successResponse, errorResponse := passwordService.ResetPassword(context.Background(), token, password)
+	if errorResponse != nil {
+		return errorResponse.Status
+	}
+	return successResponse.Status
+}
+
+func TestResetPassword(t *testing.T) {
+	passwordService, tokenService, users, mailer, _ := newTestPasswordService(t)
+	phone := login(t, tokenService, "user-1")
+	laptop := login(t, tokenService, "user-1")
+
+	forgotPassword(t, passwordService, "alice")
+	if len(mailer.messages) != 1 || mailer.messages[0].To != "alice@example.com" {
+		t.Fatalf("mails = %+v", mailer.messages)
+	}
+	token := mailedToken(t, mailer.messages[0])
+
+	if status := resetPasswordStatus(passwordService, token, "n3w-p4ss"); status != http.StatusNoContent {
+		t.Fatalf("ResetPassword status = %d", status)
+	}
+	if users.passwords["user-1"] != "n3w-p4ss" {
+		t.Errorf("password = %q", users.passwords["user-1"])
+	}
+
+	// Every session is logged out.
+	expectInvalid(t, tokenService, phone.AccessToken)
+	expectInvalid(t, tokenService, laptop.AccessToken)
+	expectRefreshStatus(t, tokenService, laptop.RefreshToken, http.StatusUnauthorized)
+
+	// The token can be used once.
+	if status := resetPasswordStatus(passwordService, token, "an0ther"); status != http.StatusUnauthorized {
+		t.Fatalf("second ResetPassword status = %d, want %d", status, http.StatusUnauthorized)
+	}
+}
+
+func TestResetTokenIsStoredHashed(t *testing.T) {
+	client, server := redistest.NewClient(t)
+	tokenRepo := repository.NewTokenRepo(client)
+	token, tokenHash, err := newOpaqueToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tokenRepo.StoreResetToken(context.Background(), "user-1", tokenHash, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range server.Keys() {
+		if strings.Contains(key, token) {
+			t.Errorf("key %s contains the token", key)
+		}
+		if value, _ := client.Get(context.Background(), key).Result(); value == token {
+			t.Errorf("key %s holds the token", key)
+		}
+	}
+}
+
+func TestResetTokenIsReplaced(t *testing.T) {
+	passwordService, _, _, mailer, _ := newTestPasswordService(t)
+
+	forgotPassword(t, passwordService, "alice")
+	forgotPassword(t, passwordService, "alice")
+	first, second := mailedToken(t, mailer.messages[0]), mailedToken(t, mailer.messages[1])
+	if status := resetPasswordStatus(passwordService, first, "n3w-p4ss"); status != http.StatusUnauthorized {
+		t.Errorf("replaced token status = %d, want %d", status, http.StatusUnauthorized)
+	}
+	if status := resetPasswordStatus(passwordService, second, "n3w-p4ss"); status != http.StatusNoContent {
+		t.Errorf("latest token status = %d, want %d", status, http.StatusNoContent)
+	}
+}
+
+func TestResetTokenExpires(t *testing.T) {
+	passwordService, _, _, mailer, server := newTestPasswordService(t)
+
+	forgotPassword(t, passwordService, "alice")
+	server.FastForward(31 * time.Minute)
+	for _, token := range []string{mailedToken(t, mailer.messages[0]), "garbage"} {
+		if status := resetPasswordStatus(passwordService, token, "n3w-p4ss"); status != http.StatusUnauthorized {
+			t.Errorf("ResetPassword(%s) status = %d, want %d", token, status, http.StatusUnauthorized)
+		}
+	}
+}
+
+func TestForgotPasswordForUnknownUser(t *testing.T) {
+	passwordService, _, _, mailer, _ := newTestPasswordService(t)
+
+	forgotPassword(t, passwordService, "nobody")
+	if len(mailer.messages) != 0 {
+		t.Errorf("mails = %+v, want none", mailer.messages)
+	}
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"sort"
+
+	"graduation-thesis/pkg/custom_error"
+	responseModel "graduation-thesis/pkg/model"
+)
+
+// ListSessions returns the sessions of a user, most recently used first, and
+// marks the one the request was made with.
+func (t *TokenService) ListSessions(ctx context.Context, userID, currentSessionID string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	sessions, err := t.tokenRepo.ListSessions(ctx, userID)
+	if err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       http.StatusInternalServerError,
+			ErrorMessage: err.Error(),
+		}
+		return nil, &errorResponse
+	}
+
+	for _, session := range sessions {
+		session.Current = session.ID == currentSessionID
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastUsedAt > sessions[j].LastUsedAt
+	})
+
+	successResponse := responseModel.SuccessResponse{
+		Status: http.StatusOK,
+		Result: sessions,
+	}
+	return &successResponse, nil
+}
+
+// RevokeSession logs one session of a user out. Sessions of other users are
+// reported as not found.
+func (t *TokenService) RevokeSession(ctx context.Context, userID, sessionID string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	session, err := t.tokenRepo.GetSession(ctx, sessionID)
+	if err == nil && session.UserID != userID {
+		err = custom_error.ErrNotFound
+	}
+	if err == nil {
+		err = t.revokeSessions(ctx, userID, []string{sessionID})
+	}
+	if err != nil {
+		status, ok := t.mapError[err]
+		if !ok {
+			status = http.StatusInternalServerError
+		}
+		errorResponse := responseModel.ErrorResponse{
+			Status:       status,
+			ErrorMessage: err.Error(),
+		}
+		return nil, &errorResponse
+	}
+
+	successResponse := responseModel.SuccessResponse{
+		Status: http.StatusNoContent,
+	}
+	return &successResponse, nil
+}
+
+// RevokeAllSessions logs the user out everywhere, including the session the
+// request was made with.
+func (t *TokenService) RevokeAllSessions(ctx context.Context, userID string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	if err := t.revokeAllSessions(ctx, userID); err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       http.StatusInternalServerError,
+			ErrorMessage: err.Error(),
+		}
+		return nil, &errorResponse
+	}
+
+	successResponse := responseModel.SuccessResponse{
+		Status: http.StatusNoContent,
+	}
+	return &successResponse, nil
+}
+
+func (t *TokenService) revokeAllSessions(ctx context.Context, userID string) error {
+	sessions, err := t.tokenRepo.ListSessions(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	sessionIDs := make([]string, 0, len(sessions))
+	for _, session := range sessions {
+		sessionIDs = append(sessionIDs, session.ID)
+	}
+	return t.revokeSessions(ctx, userID, sessionIDs)
+}
+
+// revokeSessions revokes the token families of the sessions and tells the
+// websocket handlers to close the connections opened with them.
+func (t *TokenService) revokeSessions(ctx context.Context, userID string, sessionIDs []string) error {
+	if len(sessionIDs) == 0 {
+		return nil
+	}
+	for _, sessionID := range sessionIDs {
+		if err := t.tokenRepo.RevokeFamily(ctx, sessionID); err != nil {
+			return err
+		}
+	}
+
+	if err := t.sessionEvents.Publish(userID, sessionIDs); err != nil {
+		t.logger.Errorf("[revokeSessions] Cannot publish revoked sessions of %s: %v", userID, err)
+	}
+	return nil
+}
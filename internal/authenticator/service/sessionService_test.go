@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"graduation-thesis/internal/authenticator/model"
+)
+
+func listSessions(t *testing.T, tokenService *TokenService, userID, currentSessionID string) []*model.Session {
+	t.Helper()
+	successResponse, errorResponse := tokenService.ListSessions(context.Background(), userID, currentSessionID)
+	if errorResponse != nil {
+		t.Fatalf("ListSessions: %d %s", errorResponse.Status, errorResponse.ErrorMessage)
+	}
+	return successResponse.Result.([]*model.Session)
+}
+
+func TestLoginRecordsSession(t *testing.T) {
+	tokenService, _ := newTestTokenService(t)
+	td := login(t, tokenService, "user-1")
+
+	claims, errorResponse := tokenService.Authenticate(context.Background(), td.AccessToken)
+	if errorResponse != nil {
+		t.Fatalf("Authenticate: %d %s", errorResponse.Status, errorResponse.ErrorMessage)
+	}
+	if claims.SessionID != td.FamilyID {
+		t.Errorf("session_id = %s, want %s", claims.SessionID, td.FamilyID)
+	}
+
+	sessions := listSessions(t, tokenService, "user-1", td.FamilyID)
+	if len(sessions) != 1 {
+		t.Fatalf("sessions = %d, want 1", len(sessions))
+	}
+	session := sessions[0]
+	if session.ID != td.FamilyID || session.DeviceName != "phone" || session.UserAgent != "test-agent" || session.IP != "10.0.0.1" {
+		t.Errorf("session = %+v", session)
+	}
+	if session.CreatedAt == 0 || session.LastUsedAt < session.CreatedAt {
+		t.Errorf("created_at = %d, last_used_at = %d", session.CreatedAt, session.LastUsedAt)
+	}
+	if !session.Current {
+		t.Error("the session of the request is not marked as current")
+	}
+
+	// Refreshing keeps the same session.
+	refresh(t, tokenService, td.RefreshToken)
+	if sessions := listSessions(t, tokenService, "user-1", ""); len(sessions) != 1 || sessions[0].Current {
+		t.Errorf("sessions after refresh = %+v", sessions)
+	}
+}
+
+func TestRevokeSession(t *testing.T) {
+	tokenService, _, events := newTestTokenServiceWithEvents(t)
+	phone := login(t, tokenService, "user-1")
+	laptop := login(t, tokenService, "user-1")
+
+	_, errorResponse := tokenService.RevokeSession(context.Background(), "user-1", phone.FamilyID)
+	if errorResponse != nil {
+		t.Fatalf("RevokeSession: %d %s", errorResponse.Status, errorResponse.ErrorMessage)
+	}
+
+	expectInvalid(t, tokenService, phone.AccessToken)
+	expectRefreshStatus(t, tokenService, phone.RefreshToken, http.StatusUnauthorized)
+	expectValid(t, tokenService, laptop.AccessToken, "user-1")
+	if sessions := listSessions(t, tokenService, "user-1", ""); len(sessions) != 1 || sessions[0].ID != laptop.FamilyID {
+		t.Errorf("sessions = %+v, want only %s", sessions, laptop.FamilyID)
+	}
+	if len(events.events) != 1 || events.events[0].userID != "user-1" || events.events[0].sessionIDs[0] != phone.FamilyID {
+		t.Errorf("revocation events = %+v", events.events)
+	}
+}
+
+func TestRevokeSessionOfAnotherUser(t *testing.T) {
+	tokenService, _ := newTestTokenService(t)
+	td := login(t, tokenService, "user-1")
+
+	for _, sessionID := range []string{td.FamilyID, "missing"} {
+		_, errorResponse := tokenService.RevokeSession(context.Background(), "user-2", sessionID)
+		if errorResponse == nil || errorResponse.Status != http.StatusNotFound {
+			t.Fatalf("RevokeSession(%s) = %+v, want status %d", sessionID, errorResponse, http.StatusNotFound)
+		}
+	}
+	expectValid(t, tokenService, td.AccessToken, "user-1")
+}
+
+func TestRevokeAllSessions(t *testing.T) {
+	tokenService, _, events := newTestTokenServiceWithEvents(t)
+	phone := login(t, tokenService, "user-1")
+	laptop := login(t, tokenService, "user-1")
+	other := login(t, tokenService, "user-2")
+
+	_, errorResponse := tokenService.RevokeAllSessions(context.Background(), "user-1")
+	if errorResponse != nil {
+		t.Fatalf("RevokeAllSessions: %d %s", errorResponse.Status, errorResponse.ErrorMessage)
+	}
+
+	expectInvalid(t, tokenService, phone.AccessToken)
+	expectInvalid(t, tokenService, laptop.AccessToken)
+	expectValid(t, tokenService, other.AccessToken, "user-2")
+	if sessions := listSessions(t, tokenService, "user-1", ""); len(sessions) != 0 {
+		t.Errorf("sessions left = %+v", sessions)
+	}
+	if len(events.events) != 1 || len(events.events[0].sessionIDs) != 2 {
+		t.Errorf("revocation events = %+v, want one for both sessions", events.events)
+	}
+}
@@ -1,15 +1,18 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
-	"strings"
 	"time"
 
 	"graduation-thesis/internal/authenticator/model"
 	"graduation-thesis/internal/authenticator/repository"
+	"graduation-thesis/pkg/auth"
 	"graduation-thesis/pkg/custom_error"
+	"graduation-thesis/pkg/jwks"
+	"graduation-thesis/pkg/logger"
 	responseModel "graduation-thesis/pkg/model"
 
 	"github.com/dgrijalva/jwt-go"
@@ -17,54 +20,98 @@ import (
 	"github.com/twinj/uuid"
 )
 
+// SessionEventPublisher tells the websocket handlers which sessions were
+// revoked so they can close the connections opened with them.
+type SessionEventPublisher interface {
+	Publish(userID string, sessionIDs []string) error
+}
+
 type TokenService struct {
 	tokenRepo     *repository.TokenRepo
-	accessSecret  string
+	keyService    *KeyService
+	sessionEvents SessionEventPublisher
 	refreshSecret string
 	atExpires     int64
 	rtExpires     int64
+	// familyExpires is how long a family lives after its login, however often
+	// it is refreshed.
+	familyExpires int64
 	mapError      map[error]int
+	logger        logger.Logger
+	now           func() time.Time
 }
 
 func NewTokenService(
 	tokenRepo *repository.TokenRepo,
-	atExpires, rtExpires int64,
-	accessSecret, refreshSecret string,
-	mapError map[error]int) *TokenService {
+	keyService *KeyService,
+	sessionEvents SessionEventPublisher,
+	atExpires, rtExpires, familyExpires int64,
+	refreshSecret string,
+	mapError map[error]int,
+	logger logger.Logger) *TokenService {
 	return &TokenService{
 		tokenRepo:     tokenRepo,
-		accessSecret:  accessSecret,
+		keyService:    keyService,
+		sessionEvents: sessionEvents,
 		refreshSecret: refreshSecret,
 		atExpires:     atExpires,
 		rtExpires:     rtExpires,
+		familyExpires: familyExpires,
 		mapError:      mapError,
+		logger:        logger,
+		now:           time.Now,
+	}
+}
+
+// CreateToken issues the tokens of a login, which start a new token family.
+// The family is the session of the login and its id is the session id.
+func (t *TokenService) CreateToken(ctx context.Context, userId string, metadata model.SessionMetadata) (*model.TokenDetails, error) {
+	createdAt := t.now()
+	td, err := t.newTokenDetails(userId, uuid.NewV4().String(), createdAt.Add(time.Duration(t.familyExpires)*time.Second))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := t.tokenRepo.StoreFamily(ctx, userId, td, metadata, createdAt); err != nil {
+		return nil, err
 	}
+
+	return td, nil
 }
 
-func (t *TokenService) CreateToken(userId string) (*model.TokenDetails, error) {
+// newTokenDetails issues a pair of tokens in the family, neither of which
+// outlives familyExpires.
+func (t *TokenService) newTokenDetails(userId, familyID string, familyExpires time.Time) (*model.TokenDetails, error) {
+	now := t.now()
 	td := model.TokenDetails{}
 	td.AccessUuid = uuid.NewV4().String()
-	td.AtExpires = t.atExpires
+	td.AtExpires = min(now.Add(time.Duration(t.atExpires)*time.Second).Unix(), familyExpires.Unix())
 	td.RefreshUuid = uuid.NewV4().String()
-	td.RtExpires = t.rtExpires
+	td.RtExpires = min(now.Add(time.Duration(t.rtExpires)*time.Second).Unix(), familyExpires.Unix())
+	td.FamilyID = familyID
+	td.FamilyExpires = familyExpires.Unix()
 
 	var err error
 
-	atClaims := jwt.MapClaims{}
-	atClaims["authorized"] = true
-	atClaims["user_id"] = userId
-	atClaims["access_uuid"] = td.AccessUuid
-	atClaims["exp"] = td.AtExpires
+	atClaims := (&auth.Claims{
+		UserID:     userId,
+		AccessUuid: td.AccessUuid,
+		SessionID:  td.FamilyID,
+	}).MapClaims(td.AtExpires)
 
-	at := jwt.NewWithClaims(jwt.SigningMethodHS256, atClaims)
-	td.AccessToken, err = at.SignedString([]byte(t.accessSecret))
+	signingKey, err := t.keyService.SigningKey()
+	if err != nil {
+		return nil, err
+	}
+	td.AccessToken, err = signingKey.Sign(atClaims)
 	if err != nil {
 		return nil, err
 	}
 
 	rtClaims := jwt.MapClaims{}
 	rtClaims["user_id"] = userId
-	rtClaims["refresh_uuid"] = td.AccessUuid
+	rtClaims["refresh_uuid"] = td.RefreshUuid
+	rtClaims["family_id"] = td.FamilyID
 	rtClaims["exp"] = td.RtExpires
 
 	rt := jwt.NewWithClaims(jwt.SigningMethodHS256, rtClaims)
@@ -73,18 +120,14 @@ func (t *TokenService) CreateToken(userId string) (*model.TokenDetails, error) {
 		return nil, err
 	}
 
-	if err := t.tokenRepo.StoreToken(userId, td.AccessUuid, time.Unix(td.AtExpires, 0)); err != nil {
-		return nil, err
-	}
-
-	if err := t.tokenRepo.StoreToken(userId, td.RefreshUuid, time.Unix(td.RtExpires, 0)); err != nil {
-		return nil, err
-	}
-
 	return &td, nil
 }
 
-func (t *TokenService) Refresh(refreshToken string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+// Refresh exchanges a refresh token for a new pair of tokens in the same
+// family. A refresh token can only be exchanged once; presenting it again
+// means it has leaked, so the whole family is revoked. Refreshing never takes
+// a family past familyExpires after its login.
+func (t *TokenService) Refresh(ctx context.Context, refreshToken string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
 	token, err := jwt.Parse(refreshToken, func(_token *jwt.Token) (interface{}, error) {
 		if _, ok := _token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", _token.Header["alg"])
@@ -94,13 +137,14 @@ func (t *TokenService) Refresh(refreshToken string) (*responseModel.SuccessRespo
 	})
 	if err != nil {
 		errorResponse := responseModel.ErrorResponse{
-			Status:       http.StatusBadRequest,
-			ErrorMessage: custom_error.ErrInvalidParameter.Error(),
+			Status:       http.StatusUnauthorized,
+			ErrorMessage: custom_error.ErrNoPermission.Error(),
 		}
 		return nil, &errorResponse
 	}
 
-	if _, ok := token.Claims.(jwt.MapClaims); !ok || !token.Valid {
+	rtClaims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
 		errorResponse := responseModel.ErrorResponse{
 			Status:       http.StatusUnauthorized,
 			ErrorMessage: custom_error.ErrNoPermission.Error(),
@@ -108,29 +152,42 @@ func (t *TokenService) Refresh(refreshToken string) (*responseModel.SuccessRespo
 		return nil, &errorResponse
 	}
 
-	rtClaims := token.Claims.(jwt.MapClaims)
-
-	userID := rtClaims["user_id"].(string)
-
-	refreshUuid, ok := rtClaims["refresh_uuid"].(string)
-	if !ok {
+	userID, uOk := rtClaims["user_id"].(string)
+	refreshUuid, rOk := rtClaims["refresh_uuid"].(string)
+	familyID, fOk := rtClaims["family_id"].(string)
+	if !uOk || !rOk || !fOk {
 		errorResponse := responseModel.ErrorResponse{
-			Status:       http.StatusConflict,
-			ErrorMessage: custom_error.ErrConflict.Error(),
+			Status:       http.StatusUnauthorized,
+			ErrorMessage: custom_error.ErrNoPermission.Error(),
 		}
 		return nil, &errorResponse
 	}
 
-	deleted, deleteErr := t.tokenRepo.DeleteToken(refreshUuid)
-	if deleted == 0 || deleteErr != nil {
+	session, err := t.tokenRepo.GetSession(ctx, familyID)
+	if err != nil {
+		if errors.Is(err, custom_error.ErrNotFound) {
+			err = custom_error.ErrNoPermission
+		}
+		status, ok := t.mapError[err]
+		if !ok {
+			status = http.StatusInternalServerError
+		}
 		errorResponse := responseModel.ErrorResponse{
-			Status:       http.StatusInternalServerError,
-			ErrorMessage: custom_error.ErrInternalServerError.Error(),
+			Status:       status,
+			ErrorMessage: err.Error(),
+		}
+		return nil, &errorResponse
+	}
+	familyExpires := time.Unix(session.CreatedAt, 0).Add(time.Duration(t.familyExpires) * time.Second)
+	if !t.now().Before(familyExpires) {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       http.StatusUnauthorized,
+			ErrorMessage: custom_error.ErrNoPermission.Error(),
 		}
 		return nil, &errorResponse
 	}
 
-	tokenDetails, tokenErr := t.CreateToken(userID)
+	tokenDetails, tokenErr := t.newTokenDetails(userID, familyID, familyExpires)
 	if tokenErr != nil {
 		errorResponse := responseModel.ErrorResponse{
 			Status:       http.StatusInternalServerError,
@@ -139,6 +196,28 @@ func (t *TokenService) Refresh(refreshToken string) (*responseModel.SuccessRespo
 		return nil, &errorResponse
 	}
 
+	err = t.tokenRepo.RotateFamily(ctx, userID, refreshUuid, tokenDetails)
+	if errors.Is(err, repository.ErrRefreshTokenReused) {
+		err = t.revokeSessions(ctx, userID, []string{familyID})
+		if err == nil {
+			err = custom_error.ErrNoPermission
+		}
+	}
+	if errors.Is(err, custom_error.ErrNotFound) {
+		err = custom_error.ErrNoPermission
+	}
+	if err != nil {
+		status, ok := t.mapError[err]
+		if !ok {
+			status = http.StatusInternalServerError
+		}
+		errorResponse := responseModel.ErrorResponse{
+			Status:       status,
+			ErrorMessage: err.Error(),
+		}
+		return nil, &errorResponse
+	}
+
 	successResponse := responseModel.SuccessResponse{
 		Status: http.StatusOK,
 		Result: tokenDetails,
@@ -146,13 +225,21 @@ func (t *TokenService) Refresh(refreshToken string) (*responseModel.SuccessRespo
 	return &successResponse, nil
 }
 
-func (t *TokenService) ValidateToken(tokenString string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
-	token, err := jwt.Parse(tokenString, func(_token *jwt.Token) (interface{}, error) {
-		if _, ok := _token.Method.(*jwt.SigningMethodHMAC); !ok {
+// Authenticate verifies an access token and checks that it has not been
+// revoked.
+func (t *TokenService) Authenticate(ctx context.Context, tokenString string) (*auth.Claims, *responseModel.ErrorResponse) {
+	parser := jwt.Parser{ValidMethods: []string{jwks.AlgorithmRS256, jwks.AlgorithmEdDSA}}
+	token, err := parser.Parse(tokenString, func(_token *jwt.Token) (interface{}, error) {
+		kid, _ := _token.Header["kid"].(string)
+		key, err := t.keyService.Key(kid)
+		if err != nil {
+			return nil, err
+		}
+		if key.Algorithm != _token.Method.Alg() {
 			return nil, fmt.Errorf("unexpected signing method: %v", _token.Header["alg"])
 		}
 
-		return []byte(t.accessSecret), nil
+		return key.PublicKey()
 	})
 	if err != nil {
 		errorResponse := responseModel.ErrorResponse{
@@ -162,7 +249,8 @@ func (t *TokenService) ValidateToken(tokenString string) (*responseModel.Success
 		return nil, &errorResponse
 	}
 
-	if _, ok := token.Claims.(jwt.MapClaims); !ok || !token.Valid {
+	atClaims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
 		errorResponse := responseModel.ErrorResponse{
 			Status:       http.StatusUnauthorized,
 			ErrorMessage: custom_error.ErrNoPermission.Error(),
@@ -170,8 +258,15 @@ func (t *TokenService) ValidateToken(tokenString string) (*responseModel.Success
 		return nil, &errorResponse
 	}
 
-	accessUuid := token.Claims.(jwt.MapClaims)["access_uuid"].(string)
-	userID, uErr := t.FetchUser(accessUuid)
+	claims, err := auth.ParseClaims(atClaims)
+	if err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       http.StatusUnauthorized,
+			ErrorMessage: custom_error.ErrNoPermission.Error(),
+		}
+		return nil, &errorResponse
+	}
+	userID, uErr := t.FetchUser(claims.AccessUuid)
 	if uErr != nil {
 		if errors.Is(uErr, redis.Nil) {
 			errorResponse := responseModel.ErrorResponse{
@@ -187,9 +282,26 @@ func (t *TokenService) ValidateToken(tokenString string) (*responseModel.Success
 		return nil, &errorResponse
 	}
 
+	// The token store, not the token, has the final say on whose it is.
+	claims.UserID = userID
+	if claims.SessionID != "" {
+		if err := t.tokenRepo.TouchSession(ctx, claims.SessionID, time.Now()); err != nil {
+			t.logger.Errorf("[Authenticate] Cannot touch session %s: %v", claims.SessionID, err)
+		}
+	}
+
+	return claims, nil
+}
+
+func (t *TokenService) ValidateToken(tokenString string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	claims, errorResponse := t.Authenticate(context.Background(), tokenString)
+	if errorResponse != nil {
+		return nil, errorResponse
+	}
+
 	successResponse := responseModel.SuccessResponse{
 		Status: http.StatusOK,
-		Result: userID,
+		Result: claims.UserID,
 	}
 	return &successResponse, nil
 }
@@ -199,16 +311,6 @@ func (t *TokenService) DeleteToken(tokenUuid string) (int64, error) {
 
 }
 
-func (t *TokenService) ExtractTokenFromRequest(r *http.Request) string {
-	bearToken := r.Header.Get("Authorization")
-	strArr := strings.Split(bearToken, " ")
-	if len(strArr) == 2 {
-		return strArr[1]
-	}
-
-	return ""
-}
-
 func (t *TokenService) FetchUser(tokenUuid string) (string, error) {
 	return t.tokenRepo.FetchUser(tokenUuid)
 }
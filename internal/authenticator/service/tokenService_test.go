@@ -0,0 +1,316 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"graduation-thesis/internal/authenticator/model"
+	"graduation-thesis/internal/authenticator/repository"
+	"graduation-thesis/pkg/custom_error"
+	"graduation-thesis/pkg/jwks"
+	"graduation-thesis/pkg/storage/redistest"
+
+	"github.com/dgrijalva/jwt-go"
+	"go.uber.org/zap"
+)
+
+const (
+	testAtExpires     = 15 * 60
+	testRtExpires     = 24 * 60 * 60
+	testFamilyExpires = 2 * testRtExpires
+)
+
+type recordedEvent struct {
+	userID     string
+	sessionIDs []string
+}
+
+type recordingPublisher struct {
+	mu     sync.Mutex
+	events []recordedEvent
+}
+
+func (r *recordingPublisher) Publish(userID string, sessionIDs []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, recordedEvent{userID: userID, sessionIDs: sessionIDs})
+	return nil
+}
+
+func newTestTokenService(t *testing.T) (*TokenService, *redistest.Server) {
+	tokenService, server, _ := newTestTokenServiceWithEvents(t)
+	return tokenService, server
+}
+
+func newTestTokenServiceWithEvents(t *testing.T) (*TokenService, *redistest.Server, *recordingPublisher) {
+	t.Helper()
+	client, server := redistest.NewClient(t)
+	keyService := NewKeyService(repository.NewKeyRepo(client), jwks.RotationPolicy{
+		Algorithm:   jwks.AlgorithmEdDSA,
+		Interval:    7 * 24 * time.Hour,
+		PublishLead: time.Hour,
+		Overlap:     time.Hour,
+	}, zap.NewNop().Sugar())
+	if err := keyService.Rotate(context.Background()); err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+
+	events := &recordingPublisher{}
+	tokenService := NewTokenService(
+		repository.NewTokenRepo(client),
+		keyService,
+		events,
+		testAtExpires,
+		testRtExpires,
+		testFamilyExpires,
+		"refresh-secret",
+		custom_error.MappingError(),
+		zap.NewNop().Sugar(),
+	)
+	return tokenService, server, events
+}
+
+func login(t *testing.T, tokenService *TokenService, userID string) *model.TokenDetails {
+	t.Helper()
+	td, err := tokenService.CreateToken(context.Background(), userID, model.SessionMetadata{
+		DeviceName: "phone",
+		UserAgent:  "test-agent",
+		IP:         "10.0.0.1",
+	})
+	if err != nil {
+		t.Fatalf("CreateToken: %v", err)
+	}
+	return td
+}
+
+func refresh(t *testing.T, tokenService *TokenService, refreshToken string) *model.TokenDetails {
+	t.Helper()
+	successResponse, errorResponse := tokenService.Refresh(context.Background(), refreshToken)
+	if errorResponse != nil {
+		t.Fatalf("Refresh: %d %s", errorResponse.Status, errorResponse.ErrorMessage)
+	}
+	return successResponse.Result.(*model.TokenDetails)
+}
+
+func expectRefreshStatus(t *testing.T, tokenService *TokenService, refreshToken string, status int) {
+	t.Helper()
+	_, errorResponse := tokenService.Refresh(context.Background(), refreshToken)
+	if errorResponse == nil {
+		t.Fatalf("Refresh succeeded, want status %d", status)
+	}
+	if errorResponse.Status != status {
+		t.Fatalf("Refresh status = %d, want %d", errorResponse.Status, status)
+	}
+}
+
+func expectValid(t *testing.T, tokenService *TokenService, accessToken, userID string) {
+	t.Helper()
+	successResponse, errorResponse := tokenService.ValidateToken(accessToken)
+	if errorResponse != nil {
+		t.Fatalf("ValidateToken: %d %s", errorResponse.Status, errorResponse.ErrorMessage)
+	}
+	if successResponse.Result != userID {
+		t.Fatalf("ValidateToken user = %v, want %s", successResponse.Result, userID)
+	}
+}
+
+func expectInvalid(t *testing.T, tokenService *TokenService, accessToken string) {
+	t.Helper()
+	_, errorResponse := tokenService.ValidateToken(accessToken)
+	if errorResponse == nil || errorResponse.Status != http.StatusUnauthorized {
+		t.Fatalf("ValidateToken = %+v, want status %d", errorResponse, http.StatusUnauthorized)
+	}
+}
+
+func TestCreateTokenUsesRelativeExpiry(t *testing.T) {
+	tokenService, _ := newTestTokenService(t)
+
+	before := time.Now().Unix()
+	td := login(t, tokenService, "user-1")
+	after := time.Now().Unix()
+
+	if td.AtExpires < before+testAtExpires || td.AtExpires > after+testAtExpires {
+		t.Errorf("AtExpires = %d, want within [%d, %d]", td.AtExpires, before+testAtExpires, after+testAtExpires)
+	}
+	if td.RtExpires < before+testRtExpires || td.RtExpires > after+testRtExpires {
+		t.Errorf("RtExpires = %d, want within [%d, %d]", td.RtExpires, before+testRtExpires, after+testRtExpires)
+	}
+	expectValid(t, tokenService, td.AccessToken, "user-1")
+}
+
+func TestRefreshTokenCarriesItsOwnUuid(t *testing.T) {
+	tokenService, _ := newTestTokenService(t)
+	td := login(t, tokenService, "user-1")
+
+	claims := jwt.MapClaims{}
+	if _, _, err := new(jwt.Parser).ParseUnverified(td.RefreshToken, claims); err != nil {
+		t.Fatalf("parse refresh token: %v", err)
+	}
+	if claims["refresh_uuid"] != td.RefreshUuid {
+		t.Errorf("refresh_uuid = %v, want %s", claims["refresh_uuid"], td.RefreshUuid)
+	}
+	if claims["refresh_uuid"] == td.AccessUuid {
+		t.Error("refresh_uuid is the access uuid")
+	}
+	if claims["family_id"] == "" || claims["family_id"] == nil {
+		t.Error("refresh token has no family_id")
+	}
+}
+
+func TestRefreshRotatesTokens(t *testing.T) {
+	tokenService, _ := newTestTokenService(t)
+	td := login(t, tokenService, "user-1")
+
+	next := refresh(t, tokenService, td.RefreshToken)
+	if next.RefreshToken == td.RefreshToken || next.RefreshUuid == td.RefreshUuid {
+		t.Fatal("refresh did not issue a new refresh token")
+	}
+	if next.FamilyID != td.FamilyID {
+		t.Errorf("family = %s, want %s", next.FamilyID, td.FamilyID)
+	}
+	expectValid(t, tokenService, next.AccessToken, "user-1")
+
+	// The chain keeps going as long as the latest token is used.
+	last := next
+	for i := 0; i < 3; i++ {
+		last = refresh(t, tokenService, last.RefreshToken)
+	}
+	expectValid(t, tokenService, last.AccessToken, "user-1")
+}
+
+func TestRefreshTokenReuseRevokesFamily(t *testing.T) {
+	tokenService, server, events := newTestTokenServiceWithEvents(t)
+	td := login(t, tokenService, "user-1")
+	next := refresh(t, tokenService, td.RefreshToken)
+	latest := refresh(t, tokenService, next.RefreshToken)
+
+	// Replaying a refresh token that was already exchanged is a theft signal.
+	expectRefreshStatus(t, tokenService, td.RefreshToken, http.StatusUnauthorized)
+
+	expectRefreshStatus(t, tokenService, latest.RefreshToken, http.StatusUnauthorized)
+	for _, accessToken := range []string{td.AccessToken, next.AccessToken, latest.AccessToken} {
+		expectInvalid(t, tokenService, accessToken)
+	}
+	if keys := server.Keys(); len(keys) != 1 || keys[0] != "jwks:keys" {
+		t.Errorf("keys left after revoking the family: %v", keys)
+	}
+	if len(events.events) != 1 || events.events[0].sessionIDs[0] != td.FamilyID {
+		t.Errorf("revocation events = %+v, want one for %s", events.events, td.FamilyID)
+	}
+}
+
+func TestRefreshTokenReuseLeavesOtherFamiliesAlone(t *testing.T) {
+	tokenService, _ := newTestTokenService(t)
+	phone := login(t, tokenService, "user-1")
+	laptop := login(t, tokenService, "user-1")
+
+	refresh(t, tokenService, phone.RefreshToken)
+	expectRefreshStatus(t, tokenService, phone.RefreshToken, http.StatusUnauthorized)
+
+	expectValid(t, tokenService, laptop.AccessToken, "user-1")
+	next := refresh(t, tokenService, laptop.RefreshToken)
+	expectValid(t, tokenService, next.AccessToken, "user-1")
+}
+
+func TestRefreshAfterFamilyExpires(t *testing.T) {
+	tokenService, server := newTestTokenService(t)
+	td := login(t, tokenService, "user-1")
+
+	server.FastForward((testRtExpires + 1) * time.Second)
+
+	expectRefreshStatus(t, tokenService, td.RefreshToken, http.StatusUnauthorized)
+	expectInvalid(t, tokenService, td.AccessToken)
+}
+
+func TestRefreshStopsAtFamilyLifetime(t *testing.T) {
+	tokenService, _ := newTestTokenService(t)
+	loggedInAt := time.Now()
+	td := login(t, tokenService, "user-1")
+	familyExpires := loggedInAt.Unix() + testFamilyExpires
+
+	// Refreshing late in the family only gets tokens up to its end.
+	tokenService.now = func() time.Time {
+		return loggedInAt.Add((testFamilyExpires - testRtExpires/2) * time.Second)
+	}
+	next := refresh(t, tokenService, td.RefreshToken)
+	if next.RtExpires < familyExpires-1 || next.RtExpires > familyExpires+1 {
+		t.Errorf("RtExpires = %d, want the end of the family %d", next.RtExpires, familyExpires)
+	}
+
+	tokenService.now = func() time.Time {
+		return loggedInAt.Add((testFamilyExpires + 1) * time.Second)
+	}
+	expectRefreshStatus(t, tokenService, next.RefreshToken, http.StatusUnauthorized)
+}
+
+func TestRefreshRejectsInvalidTokens(t *testing.T) {
+	tokenService, _ := newTestTokenService(t)
+	td := login(t, tokenService, "user-1")
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"garbage", "not-a-token"},
+		{"access token", td.AccessToken},
+		{"legacy token without family", signRefreshToken(t, jwt.MapClaims{
+			"user_id":      "user-1",
+			"refresh_uuid": td.RefreshUuid,
+			"exp":          td.RtExpires,
+		})},
+		{"expired", signRefreshToken(t, jwt.MapClaims{
+			"user_id":      "user-1",
+			"refresh_uuid": td.RefreshUuid,
+			"family_id":    td.FamilyID,
+			"exp":          time.Now().Add(-time.Minute).Unix(),
+		})},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expectRefreshStatus(t, tokenService, test.token, http.StatusUnauthorized)
+		})
+	}
+
+	// None of the rejected tokens counted as a reuse of the real one.
+	refresh(t, tokenService, td.RefreshToken)
+}
+
+func TestConcurrentRefreshIssuesOnePair(t *testing.T) {
+	tokenService, _ := newTestTokenService(t)
+	td := login(t, tokenService, "user-1")
+
+	const attempts = 8
+	results := make(chan int, attempts)
+	for i := 0; i < attempts; i++ {
+		go func() {
+			_, errorResponse := tokenService.Refresh(context.Background(), td.RefreshToken)
+			if errorResponse != nil {
+				results <- errorResponse.Status
+				return
+			}
+			results <- http.StatusOK
+		}()
+	}
+
+	succeeded := 0
+	for i := 0; i < attempts; i++ {
+		if <-results == http.StatusOK {
+			succeeded++
+		}
+	}
+	if succeeded > 1 {
+		t.Fatalf("%d refreshes succeeded with the same token, want at most 1", succeeded)
+	}
+}
+
+func signRefreshToken(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("refresh-secret"))
+	if err != nil {
+		t.Fatalf("sign refresh token: %v", err)
+	}
+	return token
+}
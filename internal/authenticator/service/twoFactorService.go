@@ -0,0 +1,319 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"graduation-thesis/internal/authenticator/model"
+	"graduation-thesis/internal/authenticator/repository"
+	"graduation-thesis/pkg/custom_error"
+	"graduation-thesis/pkg/encrypt/aes"
+	"graduation-thesis/pkg/encrypt/argon2"
+	responseModel "graduation-thesis/pkg/model"
+	"graduation-thesis/pkg/pb/userpb"
+	"graduation-thesis/pkg/totp"
+)
+
+const (
+	// totpSkew accepts codes of the step before and after the current one to
+	// allow for clock drift.
+	totpSkew             = 1
+	recoveryCodeBytes    = 5
+	maxChallengeAttempts = 5
+)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+type TwoFactorConfig struct {
+	Issuer            string
+	EnrollmentTTL     time.Duration
+	ChallengeTTL      time.Duration
+	RecoveryCodeCount int
+}
+
+type TwoFactorService struct {
+	twoFactorRepo *repository.TwoFactorRepo
+	userClient    userpb.UserServiceClient
+	config        TwoFactorConfig
+	mapError      map[error]int
+}
+
+func NewTwoFactorService(
+	twoFactorRepo *repository.TwoFactorRepo,
+	userClient userpb.UserServiceClient,
+	config TwoFactorConfig,
+	mapError map[error]int) *TwoFactorService {
+	return &TwoFactorService{
+		twoFactorRepo: twoFactorRepo,
+		userClient:    userClient,
+		config:        config,
+		mapError:      mapError,
+	}
+}
+
+func (s *TwoFactorService) Status(ctx context.Context, userID string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	enabled, err := s.twoFactorRepo.IsEnabled(ctx, userID)
+	if err != nil {
+		return nil, s.errorResponse(err)
+	}
+	hashes, err := s.twoFactorRepo.GetRecoveryCodeHashes(ctx, userID)
+	if err != nil {
+		return nil, s.errorResponse(err)
+	}
+
+	successResponse := responseModel.SuccessResponse{
+		Status: http.StatusOK,
+		Result: &model.TwoFactorStatus{
+			Enabled:           enabled,
+			RecoveryCodesLeft: len(hashes),
+		},
+	}
+	return &successResponse, nil
+}
+
+// Enroll starts enabling 2FA with a new secret. 2FA is only enabled once a
+// code generated from the secret is confirmed.
+func (s *TwoFactorService) Enroll(ctx context.Context, userID string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	enabled, err := s.twoFactorRepo.IsEnabled(ctx, userID)
+	if err != nil {
+		return nil, s.errorResponse(err)
+	}
+	if enabled {
+		return nil, s.errorResponse(custom_error.ErrConflict)
+	}
+
+	account, err := s.userClient.GetAccount(ctx, &userpb.GetAccountRequest{UserId: userID})
+	if err != nil {
+		return nil, s.errorResponse(custom_error.HandleGRPCError(err))
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, s.errorResponse(err)
+	}
+	encryptedSecret, err := aes.Encrypt(secret)
+	if err != nil {
+		return nil, s.errorResponse(err)
+	}
+	if err := s.twoFactorRepo.StorePendingSecret(ctx, userID, encryptedSecret, s.config.EnrollmentTTL); err != nil {
+		return nil, s.errorResponse(err)
+	}
+
+	successResponse := responseModel.SuccessResponse{
+		Status: http.StatusOK,
+		Result: &model.TwoFactorEnrollment{
+			Secret:          secret,
+			ProvisioningURI: totp.ProvisioningURI(s.config.Issuer, account.Username, secret),
+			ExpiresAt:       time.Now().Add(s.config.EnrollmentTTL).Unix(),
+		},
+	}
+	return &successResponse, nil
+}
+
+// Confirm enables 2FA once the user proves their app has the enrolled secret,
+// and returns the recovery codes. They are only ever shown here.
+func (s *TwoFactorService) Confirm(ctx context.Context, userID, code string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	encryptedSecret, err := s.twoFactorRepo.GetPendingSecret(ctx, userID)
+	if err != nil {
+		return nil, s.errorResponse(err)
+	}
+	secret, err := aes.Decrypt(encryptedSecret)
+	if err != nil {
+		return nil, s.errorResponse(err)
+	}
+	step, ok := totp.Validate(secret, code, time.Now(), totpSkew)
+	if !ok {
+		return nil, s.errorResponse(custom_error.ErrNoPermission)
+	}
+
+	recoveryCodes, hashes, err := s.newRecoveryCodes()
+	if err != nil {
+		return nil, s.errorResponse(err)
+	}
+	if err := s.twoFactorRepo.Enable(ctx, userID, encryptedSecret, step, hashes); err != nil {
+		return nil, s.errorResponse(err)
+	}
+
+	successResponse := responseModel.SuccessResponse{
+		Status: http.StatusOK,
+		Result: &model.RecoveryCodes{RecoveryCodes: recoveryCodes},
+	}
+	return &successResponse, nil
+}
+
+func (s *TwoFactorService) Disable(ctx context.Context, userID string, request *model.TwoFactorRequest) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	if err := s.verify(ctx, userID, request); err != nil {
+		return nil, s.errorResponse(err)
+	}
+	if err := s.twoFactorRepo.Disable(ctx, userID); err != nil {
+		return nil, s.errorResponse(err)
+	}
+
+	successResponse := responseModel.SuccessResponse{
+		Status: http.StatusNoContent,
+	}
+	return &successResponse, nil
+}
+
+// RegenerateRecoveryCodes replaces every recovery code of the user.
+func (s *TwoFactorService) RegenerateRecoveryCodes(ctx context.Context, userID string, request *model.TwoFactorRequest) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	if err := s.verify(ctx, userID, request); err != nil {
+		return nil, s.errorResponse(err)
+	}
+
+	recoveryCodes, hashes, err := s.newRecoveryCodes()
+	if err != nil {
+		return nil, s.errorResponse(err)
+	}
+	if err := s.twoFactorRepo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, s.errorResponse(err)
+	}
+
+	successResponse := responseModel.SuccessResponse{
+		Status: http.StatusOK,
+		Result: &model.RecoveryCodes{RecoveryCodes: recoveryCodes},
+	}
+	return &successResponse, nil
+}
+
+func (s *TwoFactorService) IsEnabled(ctx context.Context, userID string) (bool, error) {
+	return s.twoFactorRepo.IsEnabled(ctx, userID)
+}
+
+// NewChallenge is what a login of a user with 2FA gets instead of tokens.
+func (s *TwoFactorService) NewChallenge(ctx context.Context, userID string, metadata model.SessionMetadata) (*model.LoginChallenge, error) {
+	token, tokenHash, err := newOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.twoFactorRepo.StoreChallenge(ctx, tokenHash, userID, metadata, s.config.ChallengeTTL); err != nil {
+		return nil, err
+	}
+
+	return &model.LoginChallenge{
+		TwoFactorRequired: true,
+		ChallengeToken:    token,
+		ExpiresAt:         time.Now().Add(s.config.ChallengeTTL).Unix(),
+	}, nil
+}
+
+// CompleteChallenge checks the second factor of a login and returns the user
+// and metadata of the login. A challenge can be answered once and only a few
+// wrong answers are allowed.
+func (s *TwoFactorService) CompleteChallenge(ctx context.Context, challengeToken string, request *model.TwoFactorRequest) (string, model.SessionMetadata, error) {
+	tokenHash := hashOpaqueToken(challengeToken)
+	userID, metadata, attempts, err := s.twoFactorRepo.AttemptChallenge(ctx, tokenHash)
+	if errors.Is(err, custom_error.ErrNotFound) {
+		return "", metadata, custom_error.ErrNoPermission
+	}
+	if err != nil {
+		return "", metadata, err
+	}
+	if attempts > maxChallengeAttempts {
+		if _, err := s.twoFactorRepo.DeleteChallenge(ctx, tokenHash); err != nil {
+			return "", metadata, err
+		}
+		return "", metadata, custom_error.ErrTooManyRequests
+	}
+
+	if err := s.verify(ctx, userID, request); err != nil {
+		return "", metadata, err
+	}
+
+	deleted, err := s.twoFactorRepo.DeleteChallenge(ctx, tokenHash)
+	if err != nil {
+		return "", metadata, err
+	}
+	if !deleted {
+		return "", metadata, custom_error.ErrNoPermission
+	}
+	return userID, metadata, nil
+}
+
+// verify checks a code from the app, which is accepted once per time step, or
+// a recovery code, which is accepted once.
+func (s *TwoFactorService) verify(ctx context.Context, userID string, request *model.TwoFactorRequest) error {
+	switch {
+	case request.Code != "":
+		encryptedSecret, err := s.twoFactorRepo.GetSecret(ctx, userID)
+		if err != nil {
+			return err
+		}
+		secret, err := aes.Decrypt(encryptedSecret)
+		if err != nil {
+			return err
+		}
+		step, ok := totp.Validate(secret, request.Code, time.Now(), totpSkew)
+		if !ok {
+			return custom_error.ErrNoPermission
+		}
+		err = s.twoFactorRepo.UseStep(ctx, userID, step)
+		if errors.Is(err, repository.ErrCodeReused) {
+			return custom_error.ErrNoPermission
+		}
+		return err
+	case request.RecoveryCode != "":
+		hashes, err := s.twoFactorRepo.GetRecoveryCodeHashes(ctx, userID)
+		if err != nil {
+			return err
+		}
+		recoveryCode := []byte(normalizeRecoveryCode(request.RecoveryCode))
+		for _, hash := range hashes {
+			if ok, _ := argon2.Compare(hash, recoveryCode); !ok {
+				continue
+			}
+			consumed, err := s.twoFactorRepo.ConsumeRecoveryCode(ctx, userID, hash)
+			if err != nil {
+				return err
+			}
+			if consumed {
+				return nil
+			}
+		}
+		return custom_error.ErrNoPermission
+	default:
+		return custom_error.ErrInvalidParameter
+	}
+}
+
+// newRecoveryCodes returns codes formatted as xxxxx-xxxxx and their hashes.
+func (s *TwoFactorService) newRecoveryCodes() ([]string, []string, error) {
+	recoveryCodes := make([]string, 0, s.config.RecoveryCodeCount)
+	hashes := make([]string, 0, s.config.RecoveryCodeCount)
+	for i := 0; i < s.config.RecoveryCodeCount; i++ {
+		b := make([]byte, recoveryCodeBytes)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(recoveryCodeEncoding.EncodeToString(b))
+		hash, err := argon2.HashPassword([]byte(code))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		recoveryCodes = append(recoveryCodes, code[:5]+"-"+code[5:])
+		hashes = append(hashes, hash)
+	}
+	return recoveryCodes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+func (s *TwoFactorService) errorResponse(err error) *responseModel.ErrorResponse {
+	status, ok := s.mapError[err]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+	return &responseModel.ErrorResponse{
+		Status:       status,
+		ErrorMessage: err.Error(),
+	}
+}
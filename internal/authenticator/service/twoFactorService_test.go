@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"graduation-thesis/internal/authenticator/model"
+	"graduation-thesis/internal/authenticator/repository"
+	"graduation-thesis/pkg/custom_error"
+	"graduation-thesis/pkg/encrypt/aes"
+	"graduation-thesis/pkg/pb/userpb"
+	"graduation-thesis/pkg/totp"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestAuthService(t *testing.T) (*AuthService, *TwoFactorService, *redis.Client) {
+	t.Helper()
+	aes.InitAes("2fA_t3sT_eNcRyPt10n_k3y_32bYtEs!")
+	tokenService, server := newTestTokenService(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	// The user service accepts any password for alice.
+	users := &fakeUserClient{accounts: map[string]*userpb.Account{
+		"alice": {UserId: "user-1", Email: "alice@example.com", Username: "alice"},
+	}}
+	twoFactorService := NewTwoFactorService(
+		repository.NewTwoFactorRepo(client),
+		users,
+		TwoFactorConfig{
+			Issuer:            "Graduation Thesis",
+			EnrollmentTTL:     10 * time.Minute,
+			ChallengeTTL:      5 * time.Minute,
+			RecoveryCodeCount: 3,
+		},
+		custom_error.MappingError(),
+	)
+
+	authService := NewAuthService(tokenService, twoFactorService, custom_error.MappingError(), users)
+	return authService, twoFactorService, client
+}
+
+// enable enrolls user-1 and returns the secret and recovery codes.
+func enable(t *testing.T, twoFactorService *TwoFactorService) (string, []string) {
+	t.Helper()
+	successResponse, errorResponse := twoFactorService.Enroll(context.Background(), "user-1")
+	if errorResponse != nil {
+		t.Fatalf("Enroll: %d %s", errorResponse.Status, errorResponse.ErrorMessage)
+	}
+	enrollment := successResponse.Result.(*model.TwoFactorEnrollment)
+	uri, err := url.Parse(enrollment.ProvisioningURI)
+	if err != nil || uri.Query().Get("secret") != enrollment.Secret || uri.Path != "/Graduation Thesis:alice" {
+		t.Fatalf("provisioning uri = %s", enrollment.ProvisioningURI)
+	}
+
+	code, _ := totp.Code(enrollment.Secret, time.Now().Add(-totp.Period*time.Second))
+	successResponse, errorResponse = twoFactorService.Confirm(context.Background(), "user-1", code)
+	if errorResponse != nil {
+		t.Fatalf("Confirm: %d %s", errorResponse.Status, errorResponse.ErrorMessage)
+	}
+	return enrollment.Secret, successResponse.Result.(*model.RecoveryCodes).RecoveryCodes
+}
+
+func loginWithPassword(t *testing.T, authService *AuthService) interface{} {
+	t.Helper()
+	successResponse, errorResponse := authService.Login(context.Background(),
+		&model.LoginRequest{Username: "alice", Password: "p4ss", DeviceName: "phone"},
+		model.SessionMetadata{DeviceName: "phone"})
+	if errorResponse != nil {
+		t.Fatalf("Login: %d %s", errorResponse.Status, errorResponse.ErrorMessage)
+	}
+	return successResponse.Result
+}
+
+func loginTwoFactorStatus(authService *AuthService, challengeToken string, request model.TwoFactorRequest) (*model.TokenDetails, int) {
+	successResponse, errorResponse := authService.LoginTwoFactor(context.Background(), &model.TwoFactorLoginRequest{
+		ChallengeToken:   challengeToken,
+		TwoFactorRequest: request,
+	})
+	if errorResponse != nil {
+		return nil, errorResponse.Status
+	}
+	return successResponse.Result.(*model.TokenDetails), successResponse.Status
+}
+
+func TestLoginWithTwoFactor(t *testing.T) {
+	authService, twoFactorService, _ := newTestAuthService(t)
+	tokenService := authService.tokenService
+
+	if _, ok := loginWithPassword(t, authService).(*model.TokenDetails); !ok {
+		t.Fatal("login without 2FA did not issue tokens")
+	}
+
+	secret, _ := enable(t, twoFactorService)
+	challenge, ok := loginWithPassword(t, authService).(*model.LoginChallenge)
+	if !ok || !challenge.TwoFactorRequired || challenge.ChallengeToken == "" {
+		t.Fatalf("login with 2FA = %+v, want a challenge", challenge)
+	}
+
+	if _, status := loginTwoFactorStatus(authService, challenge.ChallengeToken, model.TwoFactorRequest{Code: "000000"}); status != http.StatusUnauthorized {
+		t.Fatalf("wrong code status = %d", status)
+	}
+	code, _ := totp.Code(secret, time.Now())
+	td, status := loginTwoFactorStatus(authService, challenge.ChallengeToken, model.TwoFactorRequest{Code: code})
+	if status != http.StatusOK {
+		t.Fatalf("LoginTwoFactor status = %d", status)
+	}
+	expectValid(t, tokenService, td.AccessToken, "user-1")
+	sessions := listSessions(t, tokenService, "user-1", "")
+	if len(sessions) != 2 || sessions[0].DeviceName != "phone" {
+		t.Errorf("sessions = %+v", sessions)
+	}
+
+	// The challenge is spent, and so is the code.
+	if _, status := loginTwoFactorStatus(authService, challenge.ChallengeToken, model.TwoFactorRequest{Code: code}); status != http.StatusUnauthorized {
+		t.Errorf("reused challenge status = %d", status)
+	}
+	challenge = loginWithPassword(t, authService).(*model.LoginChallenge)
+	if _, status := loginTwoFactorStatus(authService, challenge.ChallengeToken, model.TwoFactorRequest{Code: code}); status != http.StatusUnauthorized {
+		t.Errorf("replayed code status = %d", status)
+	}
+}
+
+func TestLoginWithRecoveryCode(t *testing.T) {
+	authService, twoFactorService, _ := newTestAuthService(t)
+	_, recoveryCodes := enable(t, twoFactorService)
+	if len(recoveryCodes) != 3 {
+		t.Fatalf("recovery codes = %v", recoveryCodes)
+	}
+
+	challenge := loginWithPassword(t, authService).(*model.LoginChallenge)
+	if _, status := loginTwoFactorStatus(authService, challenge.ChallengeToken, model.TwoFactorRequest{RecoveryCode: recoveryCodes[1]}); status != http.StatusOK {
+		t.Fatalf("recovery code status = %d", status)
+	}
+
+	challenge = loginWithPassword(t, authService).(*model.LoginChallenge)
+	if _, status := loginTwoFactorStatus(authService, challenge.ChallengeToken, model.TwoFactorRequest{RecoveryCode: recoveryCodes[1]}); status != http.StatusUnauthorized {
+		t.Errorf("reused recovery code status = %d", status)
+	}
+
+	successResponse, _ := twoFactorService.Status(context.Background(), "user-1")
+	if status := successResponse.Result.(*model.TwoFactorStatus); !status.Enabled || status.RecoveryCodesLeft != 2 {
+		t.Errorf("status = %+v", status)
+	}
+}
+
+func TestChallengeAttemptsAreLimited(t *testing.T) {
+	authService, twoFactorService, _ := newTestAuthService(t)
+	secret, _ := enable(t, twoFactorService)
+
+	challenge := loginWithPassword(t, authService).(*model.LoginChallenge)
+	for i := 0; i < maxChallengeAttempts; i++ {
+		loginTwoFactorStatus(authService, challenge.ChallengeToken, model.TwoFactorRequest{Code: "000000"})
+	}
+	code, _ := totp.Code(secret, time.Now())
+	if _, status := loginTwoFactorStatus(authService, challenge.ChallengeToken, model.TwoFactorRequest{Code: code}); status != http.StatusTooManyRequests {
+		t.Errorf("status after too many attempts = %d", status)
+	}
+}
+
+func TestDisableAndRegenerate(t *testing.T) {
+	authService, twoFactorService, _ := newTestAuthService(t)
+	_, recoveryCodes := enable(t, twoFactorService)
+
+	if _, errorResponse := twoFactorService.Enroll(context.Background(), "user-1"); errorResponse == nil || errorResponse.Status != http.StatusConflict {
+		t.Errorf("enrolling twice = %+v, want status %d", errorResponse, http.StatusConflict)
+	}
+
+	successResponse, errorResponse := twoFactorService.RegenerateRecoveryCodes(context.Background(), "user-1", &model.TwoFactorRequest{RecoveryCode: recoveryCodes[0]})
+	if errorResponse != nil {
+		t.Fatalf("RegenerateRecoveryCodes: %d %s", errorResponse.Status, errorResponse.ErrorMessage)
+	}
+	regenerated := successResponse.Result.(*model.RecoveryCodes).RecoveryCodes
+	if _, errorResponse := twoFactorService.Disable(context.Background(), "user-1", &model.TwoFactorRequest{RecoveryCode: recoveryCodes[2]}); errorResponse == nil {
+		t.Fatal("Disable accepted a replaced recovery code")
+	}
+	if _, errorResponse := twoFactorService.Disable(context.Background(), "user-1", &model.TwoFactorRequest{}); errorResponse == nil {
+		t.Fatal("Disable accepted no second factor")
+	}
+
+	if _, errorResponse := twoFactorService.Disable(context.Background(), "user-1", &model.TwoFactorRequest{RecoveryCode: regenerated[0]}); errorResponse != nil {
+		t.Fatalf("Disable: %d %s", errorResponse.Status, errorResponse.ErrorMessage)
+	}
+	if _, ok := loginWithPassword(t, authService).(*model.TokenDetails); !ok {
+		t.Error("login after disabling 2FA did not issue tokens")
+	}
+}
+
+func TestSecretIsStoredEncrypted(t *testing.T) {
+	_, twoFactorService, client := newTestAuthService(t)
+	secret, _ := enable(t, twoFactorService)
+
+	stored, err := client.HGet(context.Background(), "two_factor:user-1", "secret").Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored == secret {
+		t.Error("the secret is stored in plain text")
+	}
+}
@@ -3,11 +3,18 @@ package group
 import (
 	"crypto/tls"
 	"fmt"
+	"graduation-thesis/internal/group/grpc_handler"
 	"graduation-thesis/internal/group/handler"
 	"graduation-thesis/internal/group/repository"
 	"graduation-thesis/internal/group/service"
 	"graduation-thesis/pkg/custom_error"
+	"graduation-thesis/pkg/logger"
+	"graduation-thesis/pkg/pb/grouppb"
+	"graduation-thesis/pkg/pb/messagepb"
+	"graduation-thesis/pkg/pb/userpb"
+	"graduation-thesis/pkg/rpc"
 	"graduation-thesis/pkg/storage"
+	"net"
 	"net/http"
 	"sync"
 	"time"
@@ -30,18 +37,43 @@ func Run() {
 	defer postgre.Close()
 	redis := storage.GetRedisClient(viper.GetString("redis.url"))
 	defer redis.Close()
+	kafkaProducer := storage.GetKafkaProducer(viper.GetString("kafka.bootstrap_servers"), viper.GetInt("kafka.message_max_bytes"))
+	defer kafkaProducer.Close()
 	errorMap := custom_error.MappingError()
 
+	logger, err := logger.GetLogger(
+		viper.GetString("logger.level"),
+		viper.GetString("logger.path"),
+	)
+	if err != nil {
+		panic(err)
+	}
+
 	groupRepo := repository.NewGroupRepo(postgre, redis)
 	conversationRepo := repository.NewConversationRepo(postgre, redis)
+	inviteRepo := repository.NewInviteRepo(postgre)
+	membershipEventRepo := repository.NewMembershipEventRepo(kafkaProducer, viper.GetString("kafka.membership_topic"), logger)
+	systemEventRepo := repository.NewSystemEventRepo(
+		messagepb.NewMessageServiceClient(rpc.GetClientConn(viper.GetString("3rd_party.message_address"))),
+		viper.GetDuration("3rd_party.timeout"),
+	)
+	blockRepo := repository.NewBlockRepo(
+		userpb.NewUserServiceClient(rpc.GetClientConn(viper.GetString("3rd_party.user_address"))),
+		viper.GetDuration("3rd_party.timeout"),
+	)
 
-	groupService := service.NewGroupService(postgre, groupRepo, conversationRepo, errorMap)
-	conversationService := service.NewConversationService(postgre, conversationRepo, errorMap)
+	groupService := service.NewGroupService(postgre, groupRepo, conversationRepo, membershipEventRepo, systemEventRepo, blockRepo, errorMap, logger)
+	conversationService := service.NewConversationService(postgre, conversationRepo, membershipEventRepo, blockRepo, errorMap, logger)
+	inviteService := service.NewInviteService(postgre, inviteRepo, groupRepo, conversationRepo, membershipEventRepo, systemEventRepo, errorMap, logger)
 
-	groupHandler := handler.NewGroupHandler(groupService, viper.GetString("authenticator.url"))
-	conversationHandler := handler.NewConversationHandler(conversationService, viper.GetString("authenticator.url"))
+	groupHandler := handler.NewGroupHandler(groupService, viper.GetString("authenticator.address"))
+	conversationHandler := handler.NewConversationHandler(conversationService, viper.GetString("authenticator.address"))
+	inviteHandler := handler.NewInviteHandler(inviteService, viper.GetString("authenticator.address"))
 
-	router := handler.GetRouter(groupHandler, conversationHandler)
+	router := handler.GetRouter(groupHandler, conversationHandler, inviteHandler)
+
+	grpcSrv := rpc.NewServer()
+	grouppb.RegisterGroupServiceServer(grpcSrv, grpc_handler.NewGroupServer(conversationService, groupService))
 
 	TLSConfig := &tls.Config{
 		PreferServerCipherSuites: true,
@@ -74,7 +106,7 @@ func Run() {
 	}
 
 	var wg sync.WaitGroup
-	wg.Add(2)
+	wg.Add(3)
 
 	go func(wg *sync.WaitGroup) {
 		defer wg.Done()
@@ -88,6 +120,16 @@ func Run() {
 			panic(err)
 		}
 	}(&wg)
+	go func(wg *sync.WaitGroup) {
+		defer wg.Done()
+		lis, err := net.Listen("tcp", fmt.Sprintf(":%d", viper.GetInt("app.grpc_port")))
+		if err != nil {
+			panic(err)
+		}
+		if err := grpcSrv.Serve(lis); err != nil {
+			panic(err)
+		}
+	}(&wg)
 
 	wg.Wait()
 }
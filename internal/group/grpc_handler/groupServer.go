@@ -0,0 +1,80 @@
+package grpc_handler
+
+import (
+	"context"
+
+	"graduation-thesis/internal/group/model"
+	"graduation-thesis/internal/group/service"
+	"graduation-thesis/pkg/custom_error"
+	"graduation-thesis/pkg/pb/grouppb"
+)
+
+type GroupServer struct {
+	grouppb.UnimplementedGroupServiceServer
+	conversationService *service.ConversationService
+	groupService        *service.GroupService
+}
+
+func NewGroupServer(conversationService *service.ConversationService, groupService *service.GroupService) *GroupServer {
+	return &GroupServer{
+		conversationService: conversationService,
+		groupService:        groupService,
+	}
+}
+
+func (g *GroupServer) GetConversation(ctx context.Context, request *grouppb.GetConversationRequest) (*grouppb.Conversation, error) {
+	successResponse, errorResponse := g.conversationService.GetConversation(ctx, request.ConvId)
+	if errorResponse != nil {
+		return nil, custom_error.StatusToGRPCError(errorResponse.Status, errorResponse.ErrorMessage)
+	}
+
+	conversation := successResponse.Result.(*model.Conversation)
+	return &grouppb.Conversation{
+		Id:      request.ConvId,
+		Members: conversation.Members,
+	}, nil
+}
+
+func (g *GroupServer) GetConversationsContainUser(ctx context.Context, request *grouppb.GetConversationsContainUserRequest) (*grouppb.GetConversationsContainUserResponse, error) {
+	successResponse, errorResponse := g.conversationService.GetConversationsContainUser(ctx, request.UserId)
+	if errorResponse != nil {
+		return nil, custom_error.StatusToGRPCError(errorResponse.Status, errorResponse.ErrorMessage)
+	}
+
+	conversations := successResponse.Result.([]model.GetConversationsContainUserResponse)
+	response := grouppb.GetConversationsContainUserResponse{
+		Conversations: make([]*grouppb.ConversationSummary, len(conversations)),
+	}
+	for i, conversation := range conversations {
+		response.Conversations[i] = &grouppb.ConversationSummary{
+			ConvId:      conversation.ConversationID,
+			MemberCount: int32(conversation.MemberCount),
+			Archived:    conversation.Settings.Archived,
+			Pinned:      conversation.Settings.Pinned,
+			PinOrder:    int32(conversation.Settings.PinOrder),
+			Type:        conversation.Type,
+			GroupId:     conversation.GroupID,
+			Name:        conversation.Name,
+			Avatar:      conversation.Avatar,
+			PeerId:      conversation.PeerID,
+			PeerBlocked: conversation.PeerBlocked,
+		}
+		if mutedUntil := conversation.Settings.MutedUntil; mutedUntil != nil {
+			response.Conversations[i].MutedUntil = mutedUntil.Unix()
+		}
+	}
+	return &response, nil
+}
+
+func (g *GroupServer) CheckPost(ctx context.Context, request *grouppb.CheckPostRequest) (*grouppb.CheckPostResponse, error) {
+	successResponse, errorResponse := g.groupService.CheckPost(ctx, request.ConvId, request.UserId, request.Type)
+	if errorResponse != nil {
+		return nil, custom_error.StatusToGRPCError(errorResponse.Status, errorResponse.ErrorMessage)
+	}
+
+	policy := successResponse.Result.(*model.PostPolicy)
+	return &grouppb.CheckPostResponse{
+		ConvType:         policy.ConversationType,
+		MessageRetention: int32(policy.MessageRetention),
+	}, nil
+}
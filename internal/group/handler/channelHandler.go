@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"graduation-thesis/internal/group/model"
+	responseModel "graduation-thesis/pkg/model"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (g *GroupHandler) CreateChannel(c *gin.Context) {
+	userID := c.Request.Header.Get("X-User-ID")
+
+	var createChannelRequest model.CreateChannelRequest
+	if err := c.ShouldBindJSON(&createChannelRequest); err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       http.StatusBadRequest,
+			ErrorMessage: err.Error(),
+		}
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	successResponse, errorResponse := g.groupService.CreateChannel(c, &createChannelRequest, userID)
+	if errorResponse != nil {
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	c.JSON(successResponse.Status, successResponse)
+}
+
+func (g *GroupHandler) GetChannel(c *gin.Context) {
+	userID := c.Request.Header.Get("X-User-ID")
+	channelID := c.Param("channel_id")
+
+	successResponse, errorResponse := g.groupService.GetGroup(c, userID, channelID, "", "")
+	if errorResponse != nil {
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	c.JSON(successResponse.Status, successResponse)
+}
+
+func (g *GroupHandler) Subscribe(c *gin.Context) {
+	userID := c.Request.Header.Get("X-User-ID")
+	channelID := c.Param("channel_id")
+
+	successResponse, errorResponse := g.groupService.Subscribe(c, channelID, userID)
+	if errorResponse != nil {
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	c.JSON(successResponse.Status, successResponse)
+}
+
+func (g *GroupHandler) Unsubscribe(c *gin.Context) {
+	userID := c.Request.Header.Get("X-User-ID")
+	channelID := c.Param("channel_id")
+
+	successResponse, errorResponse := g.groupService.Unsubscribe(c, channelID, userID)
+	if errorResponse != nil {
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	c.JSON(successResponse.Status, successResponse)
+}
@@ -10,14 +10,14 @@ import (
 )
 
 type ConversationHandler struct {
-	conversationService *service.ConversationService
-	authenticatorURL    string
+	conversationService  *service.ConversationService
+	authenticatorAddress string
 }
 
-func NewConversationHandler(conversationService *service.ConversationService, authenticatorURL string) *ConversationHandler {
+func NewConversationHandler(conversationService *service.ConversationService, authenticatorAddress string) *ConversationHandler {
 	return &ConversationHandler{
-		conversationService: conversationService,
-		authenticatorURL:    authenticatorURL,
+		conversationService:  conversationService,
+		authenticatorAddress: authenticatorAddress,
 	}
 }
 
@@ -34,6 +34,7 @@ func (con *ConversationHandler) GetConversation(c *gin.Context) {
 }
 
 func (con *ConversationHandler) CreateConversation(c *gin.Context) {
+	userID := c.Request.Header.Get("X-User-ID")
 	var createConversationRequest model.CreateConversationRequest
 	if err := c.ShouldBindJSON(&createConversationRequest); err != nil {
 		errorResponse := responseModel.ErrorResponse{
@@ -44,7 +45,7 @@ func (con *ConversationHandler) CreateConversation(c *gin.Context) {
 		return
 	}
 
-	successResponse, errorResponse := con.conversationService.CreateConversation(c, &createConversationRequest)
+	successResponse, errorResponse := con.conversationService.CreateConversation(c, &createConversationRequest, userID)
 	if errorResponse != nil {
 		c.JSON(errorResponse.Status, errorResponse)
 		return
@@ -72,6 +73,27 @@ func (con *ConversationHandler) GetConversationsContainUser(c *gin.Context) {
 	c.JSON(successResponse.Status, successResponse)
 }
 
+func (con *ConversationHandler) GetOrCreateDirectedConversation(c *gin.Context) {
+	userID := c.Request.Header.Get("X-User-ID")
+	var directConversationRequest model.DirectConversationRequest
+	if err := c.ShouldBindJSON(&directConversationRequest); err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       http.StatusBadRequest,
+			ErrorMessage: err.Error(),
+		}
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	successResponse, errorResponse := con.conversationService.GetOrCreateDirectedConversation(c, userID, directConversationRequest.UserID)
+	if errorResponse != nil {
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	c.JSON(successResponse.Status, successResponse)
+}
+
 func (con *ConversationHandler) GetDirectedConversation(c *gin.Context) {
 	otherUser := c.Query("with")
 	userID := c.Request.Header.Get("X-User-ID")
@@ -84,3 +106,25 @@ func (con *ConversationHandler) GetDirectedConversation(c *gin.Context) {
 
 	c.JSON(successResponse.Status, successResponse)
 }
+
+func (con *ConversationHandler) UpdateSettings(c *gin.Context) {
+	conversationID := c.Param("conversation_id")
+	userID := c.Request.Header.Get("X-User-ID")
+	var updateSettingsRequest model.UpdateConversationSettingsRequest
+	if err := c.ShouldBindJSON(&updateSettingsRequest); err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       http.StatusBadRequest,
+			ErrorMessage: err.Error(),
+		}
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	successResponse, errorResponse := con.conversationService.UpdateSettings(c, &updateSettingsRequest, conversationID, userID)
+	if errorResponse != nil {
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	c.JSON(successResponse.Status, successResponse)
+}
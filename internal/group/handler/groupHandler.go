@@ -10,14 +10,14 @@ import (
 )
 
 type GroupHandler struct {
-	groupService     *service.GroupService
-	authenticatorURL string
+	groupService         *service.GroupService
+	authenticatorAddress string
 }
 
-func NewGroupHandler(groupService *service.GroupService, authenticatorURL string) *GroupHandler {
+func NewGroupHandler(groupService *service.GroupService, authenticatorAddress string) *GroupHandler {
 	return &GroupHandler{
-		groupService:     groupService,
-		authenticatorURL: authenticatorURL,
+		groupService:         groupService,
+		authenticatorAddress: authenticatorAddress,
 	}
 }
 
@@ -109,3 +109,91 @@ func (g *GroupHandler) DeleteGroup(c *gin.Context) {
 
 	c.JSON(successResponse.Status, successResponse)
 }
+
+func (g *GroupHandler) ChangeRole(c *gin.Context) {
+	groupID := c.Param("group_id")
+	userID := c.Request.Header.Get("X-User-ID")
+	var changeRoleRequest model.ChangeRoleRequest
+	if err := c.ShouldBindJSON(&changeRoleRequest); err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       http.StatusBadRequest,
+			ErrorMessage: err.Error(),
+		}
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	successResponse, errorResponse := g.groupService.ChangeRole(c, &changeRoleRequest, groupID, userID)
+	if errorResponse != nil {
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	c.JSON(successResponse.Status, successResponse)
+}
+
+func (g *GroupHandler) TransferOwnership(c *gin.Context) {
+	groupID := c.Param("group_id")
+	userID := c.Request.Header.Get("X-User-ID")
+	var transferOwnershipRequest model.TransferOwnershipRequest
+	if err := c.ShouldBindJSON(&transferOwnershipRequest); err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       http.StatusBadRequest,
+			ErrorMessage: err.Error(),
+		}
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	successResponse, errorResponse := g.groupService.TransferOwnership(c, &transferOwnershipRequest, groupID, userID)
+	if errorResponse != nil {
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	c.JSON(successResponse.Status, successResponse)
+}
+
+func (g *GroupHandler) UpdateSettings(c *gin.Context) {
+	groupID := c.Param("group_id")
+	userID := c.Request.Header.Get("X-User-ID")
+	var updateGroupSettingsRequest model.UpdateGroupSettingsRequest
+	if err := c.ShouldBindJSON(&updateGroupSettingsRequest); err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       http.StatusBadRequest,
+			ErrorMessage: err.Error(),
+		}
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	successResponse, errorResponse := g.groupService.UpdateSettings(c, &updateGroupSettingsRequest, groupID, userID)
+	if errorResponse != nil {
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	c.JSON(successResponse.Status, successResponse)
+}
+
+func (g *GroupHandler) ConvertToGroup(c *gin.Context) {
+	conversationID := c.Param("conversation_id")
+	userID := c.Request.Header.Get("X-User-ID")
+	var convertToGroupRequest model.ConvertToGroupRequest
+	if err := c.ShouldBindJSON(&convertToGroupRequest); err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       http.StatusBadRequest,
+			ErrorMessage: err.Error(),
+		}
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	successResponse, errorResponse := g.groupService.ConvertToGroup(c, &convertToGroupRequest, conversationID, userID)
+	if errorResponse != nil {
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	c.JSON(successResponse.Status, successResponse)
+}
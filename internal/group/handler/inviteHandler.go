@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"graduation-thesis/internal/group/model"
+	"graduation-thesis/internal/group/service"
+	responseModel "graduation-thesis/pkg/model"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type InviteHandler struct {
+	inviteService        *service.InviteService
+	authenticatorAddress string
+}
+
+func NewInviteHandler(inviteService *service.InviteService, authenticatorAddress string) *InviteHandler {
+	return &InviteHandler{
+		inviteService:        inviteService,
+		authenticatorAddress: authenticatorAddress,
+	}
+}
+
+func (i *InviteHandler) CreateInviteLink(c *gin.Context) {
+	groupID := c.Param("group_id")
+	userID := c.Request.Header.Get("X-User-ID")
+	var createInviteLinkRequest model.CreateInviteLinkRequest
+	if err := c.ShouldBindJSON(&createInviteLinkRequest); err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       http.StatusBadRequest,
+			ErrorMessage: err.Error(),
+		}
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	successResponse, errorResponse := i.inviteService.CreateInviteLink(c, &createInviteLinkRequest, groupID, userID)
+	if errorResponse != nil {
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	c.JSON(successResponse.Status, successResponse)
+}
+
+func (i *InviteHandler) GetInviteLinks(c *gin.Context) {
+	groupID := c.Param("group_id")
+	userID := c.Request.Header.Get("X-User-ID")
+
+	successResponse, errorResponse := i.inviteService.GetInviteLinks(c, groupID, userID)
+	if errorResponse != nil {
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	c.JSON(successResponse.Status, successResponse)
+}
+
+func (i *InviteHandler) RevokeInviteLink(c *gin.Context) {
+	groupID := c.Param("group_id")
+	linkID := c.Param("link_id")
+	userID := c.Request.Header.Get("X-User-ID")
+
+	successResponse, errorResponse := i.inviteService.RevokeInviteLink(c, groupID, linkID, userID)
+	if errorResponse != nil {
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	c.JSON(successResponse.Status, successResponse)
+}
+
+func (i *InviteHandler) JoinGroup(c *gin.Context) {
+	token := c.Param("token")
+	userID := c.Request.Header.Get("X-User-ID")
+
+	successResponse, errorResponse := i.inviteService.JoinGroup(c, token, userID)
+	if errorResponse != nil {
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	c.JSON(successResponse.Status, successResponse)
+}
+
+func (i *InviteHandler) GetJoinRequests(c *gin.Context) {
+	groupID := c.Param("group_id")
+	userID := c.Request.Header.Get("X-User-ID")
+
+	successResponse, errorResponse := i.inviteService.GetJoinRequests(c, groupID, userID)
+	if errorResponse != nil {
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	c.JSON(successResponse.Status, successResponse)
+}
+
+func (i *InviteHandler) HandleJoinRequest(c *gin.Context) {
+	groupID := c.Param("group_id")
+	requestID := c.Param("request_id")
+	userID := c.Request.Header.Get("X-User-ID")
+	var handleJoinRequestRequest model.HandleJoinRequestRequest
+	if err := c.ShouldBindJSON(&handleJoinRequestRequest); err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       http.StatusBadRequest,
+			ErrorMessage: err.Error(),
+		}
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	successResponse, errorResponse := i.inviteService.HandleJoinRequest(c, &handleJoinRequestRequest, groupID, requestID, userID)
+	if errorResponse != nil {
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	c.JSON(successResponse.Status, successResponse)
+}
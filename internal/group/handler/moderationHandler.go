@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"graduation-thesis/internal/group/model"
+	responseModel "graduation-thesis/pkg/model"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (g *GroupHandler) Kick(c *gin.Context) {
+	groupID := c.Param("group_id")
+	userID := c.Request.Header.Get("X-User-ID")
+	var kickRequest model.KickRequest
+	if err := c.ShouldBindJSON(&kickRequest); err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       http.StatusBadRequest,
+			ErrorMessage: err.Error(),
+		}
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	successResponse, errorResponse := g.groupService.Kick(c, &kickRequest, groupID, userID)
+	if errorResponse != nil {
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	c.JSON(successResponse.Status, successResponse)
+}
+
+func (g *GroupHandler) Ban(c *gin.Context) {
+	groupID := c.Param("group_id")
+	userID := c.Request.Header.Get("X-User-ID")
+	var banRequest model.RestrictRequest
+	if err := c.ShouldBindJSON(&banRequest); err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       http.StatusBadRequest,
+			ErrorMessage: err.Error(),
+		}
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	successResponse, errorResponse := g.groupService.Ban(c, &banRequest, groupID, userID)
+	if errorResponse != nil {
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	c.JSON(successResponse.Status, successResponse)
+}
+
+func (g *GroupHandler) Unban(c *gin.Context) {
+	groupID := c.Param("group_id")
+	target := c.Param("user_id")
+	userID := c.Request.Header.Get("X-User-ID")
+
+	successResponse, errorResponse := g.groupService.Unban(c, groupID, target, userID)
+	if errorResponse != nil {
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	c.JSON(successResponse.Status, successResponse)
+}
+
+func (g *GroupHandler) Mute(c *gin.Context) {
+	groupID := c.Param("group_id")
+	userID := c.Request.Header.Get("X-User-ID")
+	var muteRequest model.RestrictRequest
+	if err := c.ShouldBindJSON(&muteRequest); err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       http.StatusBadRequest,
+			ErrorMessage: err.Error(),
+		}
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	successResponse, errorResponse := g.groupService.Mute(c, &muteRequest, groupID, userID)
+	if errorResponse != nil {
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	c.JSON(successResponse.Status, successResponse)
+}
+
+func (g *GroupHandler) Unmute(c *gin.Context) {
+	groupID := c.Param("group_id")
+	target := c.Param("user_id")
+	userID := c.Request.Header.Get("X-User-ID")
+
+	successResponse, errorResponse := g.groupService.Unmute(c, groupID, target, userID)
+	if errorResponse != nil {
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	c.JSON(successResponse.Status, successResponse)
+}
+
+func (g *GroupHandler) GetModerationLog(c *gin.Context) {
+	groupID := c.Param("group_id")
+	userID := c.Request.Header.Get("X-User-ID")
+	limit, lErr := strconv.Atoi(c.DefaultQuery("limit", "25"))
+	before, bErr := strconv.ParseInt(c.DefaultQuery("before", "0"), 10, 64)
+	if lErr != nil || bErr != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       http.StatusBadRequest,
+			ErrorMessage: "invalid parameters",
+		}
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	successResponse, errorResponse := g.groupService.GetModerationLog(c, groupID, userID, before, limit)
+	if errorResponse != nil {
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	c.JSON(successResponse.Status, successResponse)
+}
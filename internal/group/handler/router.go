@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"graduation-thesis/pkg/auth"
 	"graduation-thesis/pkg/middleware"
 
 	"github.com/gin-gonic/gin"
@@ -8,34 +9,61 @@ import (
 
 var router *gin.Engine
 
-func NewRouter(groupHandler *GroupHandler, conversationHandler *ConversationHandler) *gin.Engine {
+func NewRouter(groupHandler *GroupHandler, conversationHandler *ConversationHandler, inviteHandler *InviteHandler) *gin.Engine {
 	r := gin.Default()
 	r.Use(middleware.Headers())
 	r.Use(middleware.SetupCors())
 
 	groupPath := r.Group("/v1/group")
 	{
-		groupPath.GET("", middleware.AuthMiddlewareV2(groupHandler.authenticatorURL), groupHandler.GetGroup)
-		groupPath.PUT("/:group_id", middleware.AuthMiddlewareV2(groupHandler.authenticatorURL), groupHandler.UpdateGroup)
-		groupPath.PUT("/:group_id/leave", middleware.AuthMiddlewareV2(groupHandler.authenticatorURL), groupHandler.LeaveGroup)
-		groupPath.POST("", middleware.AuthMiddlewareV2(groupHandler.authenticatorURL), groupHandler.CreateGroup)
-		groupPath.DELETE("/:group_id", middleware.AuthMiddlewareV2(groupHandler.authenticatorURL), groupHandler.DeleteGroup)
+		groupPath.GET("", middleware.AuthMiddlewareV2(groupHandler.authenticatorAddress, auth.ScopeGroupsRead), groupHandler.GetGroup)
+		groupPath.PUT("/:group_id", middleware.AuthMiddlewareV2(groupHandler.authenticatorAddress, auth.ScopeGroupsWrite), groupHandler.UpdateGroup)
+		groupPath.PUT("/:group_id/leave", middleware.AuthMiddlewareV2(groupHandler.authenticatorAddress, auth.ScopeGroupsWrite), groupHandler.LeaveGroup)
+		groupPath.PUT("/:group_id/role", middleware.AuthMiddlewareV2(groupHandler.authenticatorAddress, auth.ScopeGroupsWrite), groupHandler.ChangeRole)
+		groupPath.PUT("/:group_id/owner", middleware.AuthMiddlewareV2(groupHandler.authenticatorAddress), groupHandler.TransferOwnership)
+		groupPath.PUT("/:group_id/settings", middleware.AuthMiddlewareV2(groupHandler.authenticatorAddress, auth.ScopeGroupsWrite), groupHandler.UpdateSettings)
+		groupPath.POST("", middleware.AuthMiddlewareV2(groupHandler.authenticatorAddress, auth.ScopeGroupsWrite), groupHandler.CreateGroup)
+		groupPath.DELETE("/:group_id", middleware.AuthMiddlewareV2(groupHandler.authenticatorAddress), groupHandler.DeleteGroup)
+		groupPath.PUT("/:group_id/kick", middleware.AuthMiddlewareV2(groupHandler.authenticatorAddress, auth.ScopeGroupsWrite), groupHandler.Kick)
+		groupPath.PUT("/:group_id/ban", middleware.AuthMiddlewareV2(groupHandler.authenticatorAddress, auth.ScopeGroupsWrite), groupHandler.Ban)
+		groupPath.DELETE("/:group_id/ban/:user_id", middleware.AuthMiddlewareV2(groupHandler.authenticatorAddress, auth.ScopeGroupsWrite), groupHandler.Unban)
+		groupPath.PUT("/:group_id/mute", middleware.AuthMiddlewareV2(groupHandler.authenticatorAddress, auth.ScopeGroupsWrite), groupHandler.Mute)
+		groupPath.DELETE("/:group_id/mute/:user_id", middleware.AuthMiddlewareV2(groupHandler.authenticatorAddress, auth.ScopeGroupsWrite), groupHandler.Unmute)
+		groupPath.GET("/:group_id/audit_log", middleware.AuthMiddlewareV2(groupHandler.authenticatorAddress, auth.ScopeGroupsRead), groupHandler.GetModerationLog)
+
+		groupPath.POST("/:group_id/invite", middleware.AuthMiddlewareV2(inviteHandler.authenticatorAddress, auth.ScopeGroupsWrite), inviteHandler.CreateInviteLink)
+		groupPath.GET("/:group_id/invite", middleware.AuthMiddlewareV2(inviteHandler.authenticatorAddress, auth.ScopeGroupsRead), inviteHandler.GetInviteLinks)
+		groupPath.DELETE("/:group_id/invite/:link_id", middleware.AuthMiddlewareV2(inviteHandler.authenticatorAddress, auth.ScopeGroupsWrite), inviteHandler.RevokeInviteLink)
+		groupPath.POST("/join/:token", middleware.AuthMiddlewareV2(inviteHandler.authenticatorAddress), inviteHandler.JoinGroup)
+		groupPath.GET("/:group_id/join_request", middleware.AuthMiddlewareV2(inviteHandler.authenticatorAddress, auth.ScopeGroupsRead), inviteHandler.GetJoinRequests)
+		groupPath.PUT("/:group_id/join_request/:request_id", middleware.AuthMiddlewareV2(inviteHandler.authenticatorAddress, auth.ScopeGroupsWrite), inviteHandler.HandleJoinRequest)
+	}
+
+	channelPath := r.Group("/v1/channel")
+	{
+		channelPath.GET("/:channel_id", middleware.AuthMiddlewareV2(groupHandler.authenticatorAddress, auth.ScopeGroupsRead), groupHandler.GetChannel)
+		channelPath.POST("", middleware.AuthMiddlewareV2(groupHandler.authenticatorAddress, auth.ScopeGroupsWrite), groupHandler.CreateChannel)
+		channelPath.PUT("/:channel_id/subscribe", middleware.AuthMiddlewareV2(groupHandler.authenticatorAddress, auth.ScopeGroupsWrite), groupHandler.Subscribe)
+		channelPath.PUT("/:channel_id/unsubscribe", middleware.AuthMiddlewareV2(groupHandler.authenticatorAddress, auth.ScopeGroupsWrite), groupHandler.Unsubscribe)
 	}
 
 	conversationPath := r.Group("/v1/conversation")
 	{
 		conversationPath.GET("/:conversation_id", conversationHandler.GetConversation)
-		conversationPath.POST("", conversationHandler.CreateConversation)
-		conversationPath.GET("/user/:user_id", middleware.AuthMiddlewareV2(conversationHandler.authenticatorURL), conversationHandler.GetConversationsContainUser)
-		conversationPath.GET("/user", middleware.AuthMiddlewareV2(conversationHandler.authenticatorURL), conversationHandler.GetDirectedConversation)
+		conversationPath.POST("", middleware.AuthMiddlewareV2(conversationHandler.authenticatorAddress, auth.ScopeConversationsWrite), conversationHandler.CreateConversation)
+		conversationPath.GET("/user/:user_id", middleware.AuthMiddlewareV2(conversationHandler.authenticatorAddress, auth.ScopeConversationsRead), conversationHandler.GetConversationsContainUser)
+		conversationPath.GET("/user", middleware.AuthMiddlewareV2(conversationHandler.authenticatorAddress, auth.ScopeConversationsRead), conversationHandler.GetDirectedConversation)
+		conversationPath.POST("/direct", middleware.AuthMiddlewareV2(conversationHandler.authenticatorAddress, auth.ScopeConversationsWrite), conversationHandler.GetOrCreateDirectedConversation)
+		conversationPath.POST("/:conversation_id/group", middleware.AuthMiddlewareV2(groupHandler.authenticatorAddress, auth.ScopeConversationsWrite), groupHandler.ConvertToGroup)
+		conversationPath.PUT("/:conversation_id/settings", middleware.AuthMiddlewareV2(conversationHandler.authenticatorAddress, auth.ScopeConversationsWrite), conversationHandler.UpdateSettings)
 	}
 
 	return r
 }
 
-func GetRouter(groupHandler *GroupHandler, conversationHandler *ConversationHandler) *gin.Engine {
+func GetRouter(groupHandler *GroupHandler, conversationHandler *ConversationHandler, inviteHandler *InviteHandler) *gin.Engine {
 	if router == nil {
-		router = NewRouter(groupHandler, conversationHandler)
+		router = NewRouter(groupHandler, conversationHandler, inviteHandler)
 	}
 
 	return router
@@ -0,0 +1,29 @@
+package model
+
+const (
+	// EVENT_TYPE is the message type the system events are stored with
+	EVENT_TYPE = "event"
+
+	EVENT_ADD    = "add"
+	EVENT_REMOVE = "remove"
+	EVENT_JOIN   = "join"
+	EVENT_LEAVE  = "leave"
+	EVENT_RENAME = "rename"
+	EVENT_DELETE = "delete"
+	EVENT_KICK   = "kick"
+	EVENT_BAN    = "ban"
+
+	EVENT_OBJECT_USER  = "user"
+	EVENT_OBJECT_GROUP = "group"
+)
+
+// SystemEvent is stored in the conversation stream as the content of a message
+// of type event, e.g. "A added B" is {Actor: A, Action: add, Object: user, ObjectID: B}.
+type SystemEvent struct {
+	Actor          string `json:"actor"`
+	ConversationID string `json:"conversation_id"`
+	Action         string `json:"action"`
+	Object         string `json:"object"`
+	ObjectID       string `json:"object_id"`
+	Value          string `json:"value,omitempty"`
+}
@@ -2,15 +2,55 @@ package model
 
 import "time"
 
+const (
+	CONVERSATION_TYPE_DIRECT  = "direct"
+	CONVERSATION_TYPE_GROUP   = "group"
+	CONVERSATION_TYPE_CHANNEL = "channel"
+)
+
 type Group struct {
-	ID             string    `json:"id"`
-	GroupName      string    `json:"group_name"`
-	CreatedAt      time.Time `json:"created_at"`
-	LastUpdated    time.Time `json:"last_updated"`
-	Members        []string  `json:"members"`
-	Admins         []string  `json:"admins"`
-	ConversationID string    `json:"conv_id"`
-	Deleted        bool      `json:"deleted"`
+	ID          string `json:"id"`
+	GroupName   string `json:"group_name"`
+	Type        string `json:"type"`
+	Description string `json:"description"`
+	// Avatar is the fid of the image in the asset service
+	Avatar         string        `json:"avatar"`
+	CreatedAt      time.Time     `json:"created_at"`
+	LastUpdated    time.Time     `json:"last_updated"`
+	Members        []string      `json:"members"`
+	Admins         []string      `json:"admins"`
+	ConversationID string        `json:"conv_id"`
+	Deleted        bool          `json:"deleted"`
+	Settings       GroupSettings `json:"settings"`
+	// SubscriberCount is only filled for channels, whose members are not listed
+	SubscriberCount int `json:"subscriber_count,omitempty"`
+	// Roles lists the users holding a role other than member.
+	Roles []GroupRole `json:"roles,omitempty"`
+}
+
+// GroupSettings holds the lowest role allowed to post and to add members, the
+// minimum number of seconds between two messages of a member and how many
+// seconds messages are kept. 0 disables slow mode and keeps messages forever.
+type GroupSettings struct {
+	WhoCanPost       string `json:"who_can_post"`
+	WhoCanAddMembers string `json:"who_can_add_members"`
+	SlowModeInterval int    `json:"slow_mode_interval"`
+	MessageRetention int    `json:"message_retention"`
+}
+
+func DefaultGroupSettings() GroupSettings {
+	return GroupSettings{
+		WhoCanPost:       ROLE_MEMBER,
+		WhoCanAddMembers: ROLE_MODERATOR,
+	}
+}
+
+func (s GroupSettings) CanPost(role string) bool {
+	return roleRanks[role] >= roleRanks[s.WhoCanPost]
+}
+
+func (s GroupSettings) CanAddMembers(role string) bool {
+	return roleRanks[role] >= roleRanks[s.WhoCanAddMembers]
 }
 
 type Conversation struct {
@@ -19,8 +59,10 @@ type Conversation struct {
 }
 
 type CreateGroupRequest struct {
-	GroupName string   `json:"group_name" binding:"required"`
-	Members   []string `json:"members" binding:"required,gte=3"`
+	GroupName   string   `json:"group_name" binding:"required,max=200"`
+	Description string   `json:"description" binding:"max=1000"`
+	Avatar      string   `json:"avatar"`
+	Members     []string `json:"members" binding:"required,gte=3"`
 }
 
 type CreateGroupResponse struct {
@@ -28,10 +70,31 @@ type CreateGroupResponse struct {
 	ConversationID string `json:"conv_id"`
 }
 
+type CreateChannelRequest struct {
+	ChannelName string `json:"channel_name" binding:"required,max=200"`
+	Description string `json:"description" binding:"max=1000"`
+	Avatar      string `json:"avatar"`
+}
+
+type PostPolicy struct {
+	ConversationType string `json:"conv_type"`
+	MessageRetention int    `json:"message_retention"`
+}
+
 type UpdateGroupRequest struct {
-	GroupName string       `json:"group_name"`
-	Members   []ChangeUser `json:"members" binding:"lte=2"`
-	Admins    []ChangeUser `json:"admins" binding:"lte=2"`
+	GroupName string `json:"group_name" binding:"max=200"`
+	// Description and Avatar are left untouched when absent, an empty string clears them
+	Description *string      `json:"description" binding:"omitempty,max=1000"`
+	Avatar      *string      `json:"avatar"`
+	Members     []ChangeUser `json:"members" binding:"lte=2"`
+	Admins      []ChangeUser `json:"admins" binding:"lte=2"`
+}
+
+type UpdateGroupSettingsRequest struct {
+	WhoCanPost       *string `json:"who_can_post" binding:"omitempty,oneof=member moderator admin owner"`
+	WhoCanAddMembers *string `json:"who_can_add_members" binding:"omitempty,oneof=member moderator admin owner"`
+	SlowModeInterval *int    `json:"slow_mode_interval" binding:"omitempty,gte=0,lte=86400"`
+	MessageRetention *int    `json:"message_retention" binding:"omitempty,gte=0"`
 }
 
 type ChangeUser struct {
@@ -42,6 +105,8 @@ type ChangeUser struct {
 type UpdateGroupParams struct {
 	ID          string
 	GroupName   string
+	Description string
+	Avatar      string
 	LastUpdated time.Time
 	Deleted     bool
 	Admins      []string
@@ -53,10 +118,47 @@ type UpdateConversationParams struct {
 }
 
 type CreateConversationRequest struct {
-	Members []string `json:"members" binding:"required,gte=2"`
+	Members []string `json:"members" binding:"required,gte=1"`
+}
+
+type DirectConversationRequest struct {
+	UserID string `json:"user_id" binding:"required"`
+}
+
+// ConvertToGroupRequest turns a direct conversation into a group, Members are
+// added on top of the two users of the conversation.
+type ConvertToGroupRequest struct {
+	GroupName   string   `json:"group_name" binding:"required"`
+	Description string   `json:"description" binding:"max=1000"`
+	Avatar      string   `json:"avatar"`
+	Members     []string `json:"members"`
 }
 
 type GetConversationsContainUserResponse struct {
-	ConversationID string `json:"conv_id"`
-	MemberCount    int    `json:"member_count"`
+	ConversationID string               `json:"conv_id"`
+	MemberCount    int                  `json:"member_count"`
+	Type           string               `json:"type"`
+	GroupID        string               `json:"group_id,omitempty"`
+	Name           string               `json:"name,omitempty"`
+	Avatar         string               `json:"avatar,omitempty"`
+	PeerID         string               `json:"peer_id,omitempty"` // The other member of a direct conversation
+	PeerBlocked    bool                 `json:"peer_blocked,omitempty"`
+	Settings       ConversationSettings `json:"settings"`
+}
+
+// ConversationSettings is the state of a conversation for one of its members.
+// Pinned conversations are listed by ascending PinOrder.
+type ConversationSettings struct {
+	MutedUntil *time.Time `json:"muted_until,omitempty"`
+	Archived   bool       `json:"archived"`
+	Pinned     bool       `json:"pinned"`
+	PinOrder   int        `json:"pin_order"`
+}
+
+type UpdateConversationSettingsRequest struct {
+	// MutedUntil is a unix timestamp, 0 unmutes the conversation.
+	MutedUntil *int64 `json:"muted_until" binding:"omitempty,gte=0"`
+	Archived   *bool  `json:"archived"`
+	Pinned     *bool  `json:"pinned"`
+	PinOrder   *int   `json:"pin_order" binding:"omitempty,gte=0"`
 }
@@ -0,0 +1,62 @@
+package model
+
+import "time"
+
+const (
+	JOIN_REQUEST_PENDING  = "pending"
+	JOIN_REQUEST_APPROVED = "approved"
+	JOIN_REQUEST_REJECTED = "rejected"
+)
+
+type InviteLink struct {
+	ID              string     `json:"id"`
+	GroupID         string     `json:"group_id"`
+	Token           string     `json:"token"`
+	CreatedBy       string     `json:"created_by"`
+	CreatedAt       time.Time  `json:"created_at"`
+	ExpiresAt       *time.Time `json:"expires_at,omitempty"`
+	MaxUses         int        `json:"max_uses"`
+	Uses            int        `json:"uses"`
+	RequireApproval bool       `json:"require_approval"`
+	Revoked         bool       `json:"revoked"`
+}
+
+// IsUsable reports whether someone may still join through the link at now.
+func (i *InviteLink) IsUsable(now time.Time) bool {
+	if i.Revoked {
+		return false
+	}
+	if i.ExpiresAt != nil && !now.Before(*i.ExpiresAt) {
+		return false
+	}
+	return i.MaxUses == 0 || i.Uses < i.MaxUses
+}
+
+type CreateInviteLinkRequest struct {
+	// ExpiresIn is the lifetime of the link in seconds, 0 means it never expires.
+	ExpiresIn       int64 `json:"expires_in" binding:"gte=0"`
+	MaxUses         int   `json:"max_uses" binding:"gte=0"`
+	RequireApproval bool  `json:"require_approval"`
+}
+
+type JoinRequest struct {
+	ID          string    `json:"id"`
+	GroupID     string    `json:"group_id"`
+	UserID      string    `json:"user_id"`
+	LinkID      string    `json:"link_id"`
+	Status      string    `json:"status"`
+	CreatedAt   time.Time `json:"created_at"`
+	LastUpdated time.Time `json:"last_updated"`
+	HandledBy   string    `json:"handled_by,omitempty"`
+}
+
+type HandleJoinRequestRequest struct {
+	Action string `json:"action" binding:"required,oneof=approve reject"`
+}
+
+type JoinGroupResponse struct {
+	GroupID        string `json:"group_id"`
+	ConversationID string `json:"conv_id,omitempty"`
+	Status         string `json:"status"`
+	JoinRequestID  string `json:"join_request_id,omitempty"`
+}
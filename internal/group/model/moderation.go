@@ -0,0 +1,54 @@
+package model
+
+import "time"
+
+const (
+	RESTRICTION_BAN  = "ban"
+	RESTRICTION_MUTE = "mute"
+)
+
+const (
+	MODERATION_KICK   = "kick"
+	MODERATION_BAN    = "ban"
+	MODERATION_UNBAN  = "unban"
+	MODERATION_MUTE   = "mute"
+	MODERATION_UNMUTE = "unmute"
+)
+
+type Restriction struct {
+	GroupID   string     `json:"group_id"`
+	UserID    string     `json:"user_id"`
+	Type      string     `json:"type"`
+	Reason    string     `json:"reason,omitempty"`
+	CreatedBy string     `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// IsActive reports whether the restriction still applies at now.
+func (r *Restriction) IsActive(now time.Time) bool {
+	return r.ExpiresAt == nil || now.Before(*r.ExpiresAt)
+}
+
+type ModerationLogEntry struct {
+	ID        int64      `json:"id"`
+	GroupID   string     `json:"group_id"`
+	Actor     string     `json:"actor"`
+	Action    string     `json:"action"`
+	Target    string     `json:"target"`
+	Reason    string     `json:"reason,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type KickRequest struct {
+	UserID string `json:"user_id" binding:"required"`
+	Reason string `json:"reason" binding:"max=500"`
+}
+
+type RestrictRequest struct {
+	UserID string `json:"user_id" binding:"required"`
+	Reason string `json:"reason" binding:"max=500"`
+	// ExpiresIn is the duration of the restriction in seconds, 0 means until it is lifted.
+	ExpiresIn int64 `json:"expires_in" binding:"gte=0"`
+}
@@ -0,0 +1,85 @@
+package model
+
+import "time"
+
+const (
+	ROLE_OWNER     = "owner"
+	ROLE_ADMIN     = "admin"
+	ROLE_MODERATOR = "moderator"
+	ROLE_MEMBER    = "member"
+)
+
+const (
+	PERMISSION_RENAME          = "rename"
+	PERMISSION_ADD_MEMBERS     = "add_members"
+	PERMISSION_REMOVE_MEMBERS  = "remove_members"
+	PERMISSION_PROMOTE         = "promote"
+	PERMISSION_PIN             = "pin"
+	PERMISSION_DELETE_MESSAGES = "delete_messages"
+	PERMISSION_CHANGE_SETTINGS = "change_settings"
+	PERMISSION_DELETE_GROUP    = "delete_group"
+	PERMISSION_BAN             = "ban"
+	PERMISSION_MUTE            = "mute"
+	PERMISSION_VIEW_AUDIT_LOG  = "view_audit_log"
+)
+
+var rolePermissions = map[string][]string{
+	ROLE_OWNER: {
+		PERMISSION_RENAME, PERMISSION_ADD_MEMBERS, PERMISSION_REMOVE_MEMBERS, PERMISSION_PROMOTE,
+		PERMISSION_PIN, PERMISSION_DELETE_MESSAGES, PERMISSION_CHANGE_SETTINGS, PERMISSION_DELETE_GROUP,
+		PERMISSION_BAN, PERMISSION_MUTE, PERMISSION_VIEW_AUDIT_LOG,
+	},
+	ROLE_ADMIN: {
+		PERMISSION_RENAME, PERMISSION_ADD_MEMBERS, PERMISSION_REMOVE_MEMBERS, PERMISSION_PROMOTE,
+		PERMISSION_PIN, PERMISSION_DELETE_MESSAGES, PERMISSION_CHANGE_SETTINGS,
+		PERMISSION_BAN, PERMISSION_MUTE, PERMISSION_VIEW_AUDIT_LOG,
+	},
+	ROLE_MODERATOR: {
+		PERMISSION_ADD_MEMBERS, PERMISSION_REMOVE_MEMBERS, PERMISSION_PIN, PERMISSION_DELETE_MESSAGES,
+		PERMISSION_MUTE,
+	},
+	ROLE_MEMBER: {},
+}
+
+var roleRanks = map[string]int{
+	ROLE_OWNER:     3,
+	ROLE_ADMIN:     2,
+	ROLE_MODERATOR: 1,
+	ROLE_MEMBER:    0,
+}
+
+func IsValidRole(role string) bool {
+	_, ok := roleRanks[role]
+	return ok
+}
+
+func HasPermission(role, permission string) bool {
+	for _, p := range rolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Outranks reports whether a user holding role may act on a user holding
+// other, e.g. remove them or change their role.
+func Outranks(role, other string) bool {
+	return roleRanks[role] > roleRanks[other]
+}
+
+type GroupRole struct {
+	GroupID     string    `json:"group_id"`
+	UserID      string    `json:"user_id"`
+	Role        string    `json:"role"`
+	LastUpdated time.Time `json:"last_updated"`
+}
+
+type ChangeRoleRequest struct {
+	UserID string `json:"user_id" binding:"required"`
+	Role   string `json:"role" binding:"required,oneof=admin moderator member"`
+}
+
+type TransferOwnershipRequest struct {
+	UserID string `json:"user_id" binding:"required"`
+}
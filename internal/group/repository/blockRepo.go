@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"graduation-thesis/pkg/custom_error"
+	"graduation-thesis/pkg/pb/userpb"
+)
+
+// BlockRepo reads the block list owned by the user service.
+type BlockRepo struct {
+	userClient userpb.UserServiceClient
+	timeout    time.Duration
+}
+
+func NewBlockRepo(userClient userpb.UserServiceClient, timeout time.Duration) *BlockRepo {
+	return &BlockRepo{
+		userClient: userClient,
+		timeout:    timeout,
+	}
+}
+
+// IsBlocked reports whether userID blocked otherUser or was blocked by them.
+func (b *BlockRepo) IsBlocked(ctx context.Context, userID, otherUser string) (bool, error) {
+	relation, err := b.getRelation(ctx, userID, otherUser)
+	if err != nil {
+		return false, err
+	}
+	return relation.Blocked || relation.BlockedBy, nil
+}
+
+// IsBlockedBy reports whether otherUser blocked userID.
+func (b *BlockRepo) IsBlockedBy(ctx context.Context, userID, otherUser string) (bool, error) {
+	relation, err := b.getRelation(ctx, userID, otherUser)
+	if err != nil {
+		return false, err
+	}
+	return relation.BlockedBy, nil
+}
+
+// GetBlockedUsers returns the users userID blocked or was blocked by.
+func (b *BlockRepo) GetBlockedUsers(ctx context.Context, userID string) (map[string]bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	response, err := b.userClient.GetBlockedUsers(ctx, &userpb.GetBlockedUsersRequest{UserId: userID})
+	if err != nil {
+		return nil, custom_error.HandleGRPCError(err)
+	}
+
+	users := make(map[string]bool, len(response.Users))
+	for _, user := range response.Users {
+		users[user] = true
+	}
+	return users, nil
+}
+
+func (b *BlockRepo) getRelation(ctx context.Context, userID, otherUser string) (*userpb.BlockRelation, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	relation, err := b.userClient.GetBlockRelation(ctx, &userpb.GetBlockRelationRequest{
+		UserId:      userID,
+		OtherUserId: otherUser,
+	})
+	if err != nil {
+		return nil, custom_error.HandleGRPCError(err)
+	}
+	return relation, nil
+}
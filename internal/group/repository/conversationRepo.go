@@ -4,7 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"graduation-thesis/internal/group/model"
 	"graduation-thesis/pkg/custom_error"
 	"graduation-thesis/pkg/interfaces"
@@ -85,6 +85,26 @@ func (c *ConversationRepo) GetMembers(ctx context.Context, conversationID string
 	return &conversation, nil
 }
 
+func (c *ConversationRepo) CountMembers(ctx context.Context, conversationID string) (int, error) {
+	query := `SELECT count(*) FROM conv_map_user WHERE conv_id = $1`
+	var count int
+	if err := c.db.QueryRowContext(ctx, query, conversationID).Scan(&count); err != nil {
+		return 0, custom_error.HandlePostgreError(err)
+	}
+	return count, nil
+}
+
+// IsMember checks a single membership without loading every member, which
+// matters for channels with many subscribers.
+func (c *ConversationRepo) IsMember(ctx context.Context, conversationID, userID string) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM conv_map_user WHERE conv_id = $1 AND user_id = $2)`
+	var exists bool
+	if err := c.db.QueryRowContext(ctx, query, conversationID, userID).Scan(&exists); err != nil {
+		return false, custom_error.HandlePostgreError(err)
+	}
+	return exists, nil
+}
+
 func (c *ConversationRepo) Create(ctx context.Context, conversationID string) error {
 	query := `INSERT INTO conversations (id) VALUES ($1)`
 	_, err := c.db.ExecContext(ctx, query, conversationID)
@@ -136,20 +156,37 @@ func (c *ConversationRepo) Update(ctx context.Context, params model.UpdateConver
 }
 
 func (c *ConversationRepo) GetConversations(ctx context.Context, userID string) ([]model.GetConversationsContainUserResponse, error) {
-	query := `SELECT conv_id, count(user_id) FROM conv_map_user 
-		WHERE conv_id IN(SELECT conv_id FROM conv_map_user WHERE user_id = $1)
-		GROUP BY conv_id`
+	query := `SELECT m.conv_id, (SELECT count(*) FROM conv_map_user WHERE conv_id = m.conv_id),
+			m.muted_until, m.archived, m.pinned, m.pin_order,
+			COALESCE(g.type, ''), COALESCE(g.id, ''), COALESCE(g.group_name, ''), COALESCE(g.avatar, ''),
+			CASE WHEN g.id IS NULL THEN (SELECT user_id FROM conv_map_user WHERE conv_id = m.conv_id AND user_id <> $1 LIMIT 1) END
+		FROM conv_map_user m LEFT JOIN groups g ON g.conv_id = m.conv_id
+		WHERE m.user_id = $1 AND g.deleted IS NOT TRUE`
 	rows, err := c.db.QueryContext(ctx, query, userID)
 	if err != nil {
 		return nil, custom_error.HandlePostgreError(err)
 	}
+	defer rows.Close()
 
 	var conversations []model.GetConversationsContainUserResponse
 	for rows.Next() {
-		var conversation model.GetConversationsContainUserResponse
-		if err := rows.Scan(&conversation.ConversationID, &conversation.MemberCount); err != nil {
+		var (
+			conversation model.GetConversationsContainUserResponse
+			mutedUntil   sql.NullTime
+			peerID       sql.NullString
+		)
+		if err := rows.Scan(&conversation.ConversationID, &conversation.MemberCount, &mutedUntil,
+			&conversation.Settings.Archived, &conversation.Settings.Pinned, &conversation.Settings.PinOrder,
+			&conversation.Type, &conversation.GroupID, &conversation.Name, &conversation.Avatar, &peerID); err != nil {
 			return nil, custom_error.HandlePostgreError(err)
 		}
+		if mutedUntil.Valid {
+			conversation.Settings.MutedUntil = &mutedUntil.Time
+		}
+		if conversation.Type == "" { // Conversations without a group are direct ones
+			conversation.Type = model.CONVERSATION_TYPE_DIRECT
+			conversation.PeerID = peerID.String
+		}
 
 		conversations = append(conversations, conversation)
 	}
@@ -161,26 +198,90 @@ func (c *ConversationRepo) GetConversations(ctx context.Context, userID string)
 	return conversations, nil
 }
 
+// GetSettingsForUpdate returns custom_error.ErrNotFound if userID is not a
+// member of the conversation.
+func (c *ConversationRepo) GetSettingsForUpdate(ctx context.Context, conversationID, userID string) (*model.ConversationSettings, error) {
+	query := `SELECT muted_until, archived, pinned, pin_order FROM conv_map_user
+		WHERE conv_id = $1 AND user_id = $2 FOR UPDATE`
+	var (
+		settings   model.ConversationSettings
+		mutedUntil sql.NullTime
+	)
+	err := c.db.QueryRowContext(ctx, query, conversationID, userID).Scan(&mutedUntil, &settings.Archived, &settings.Pinned, &settings.PinOrder)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, custom_error.ErrNotFound
+		}
+		return nil, custom_error.HandlePostgreError(err)
+	}
+	if mutedUntil.Valid {
+		settings.MutedUntil = &mutedUntil.Time
+	}
+
+	return &settings, nil
+}
+
+func (c *ConversationRepo) UpdateSettings(ctx context.Context, conversationID, userID string, settings model.ConversationSettings) error {
+	query := `UPDATE conv_map_user SET muted_until = $3, archived = $4, pinned = $5, pin_order = $6
+		WHERE conv_id = $1 AND user_id = $2`
+	_, err := c.db.ExecContext(ctx, query, conversationID, userID, settings.MutedUntil, settings.Archived, settings.Pinned, settings.PinOrder)
+	return custom_error.HandlePostgreError(err)
+}
+
+// directPair orders the users of a direct conversation the way
+// direct_conversations stores them.
+func directPair(userID, otherUser string) (string, string) {
+	if userID < otherUser {
+		return userID, otherUser
+	}
+	return otherUser, userID
+}
+
 func (c *ConversationRepo) GetDirectedConversation(ctx context.Context, userID, otherUser string) (string, error) {
+	userLow, userHigh := directPair(userID, otherUser)
+	query := `SELECT conv_id FROM direct_conversations WHERE user_low = $1 AND user_high = $2`
+
 	var conversationID string
-	_ = c.getFromRedis(ctx, fmt.Sprintf("%s+%s", userID, otherUser), conversationID)
-	if conversationID != "" {
-		return conversationID, nil
+	if err := c.db.QueryRowContext(ctx, query, userLow, userHigh).Scan(&conversationID); err != nil {
+		return "", custom_error.HandlePostgreError(err)
 	}
-	query := `SELECT conv_id
-	FROM conv_map_user
-	GROUP BY conv_id
-	HAVING COUNT(*) = 2
-	   AND SUM(CASE WHEN user_id = $1 THEN 1 ELSE 0 END) = 1
-	   AND SUM(CASE WHEN user_id = $2 THEN 1 ELSE 0 END) = 1;`
-	row := c.db.QueryRowContext(ctx, query, userID, otherUser)
-	if err := row.Scan(&conversationID); err != nil {
-		return "", err
+	return conversationID, nil
+}
+
+// CreateDirectedConversation maps the pair of users to conversationID and
+// reports false if the pair already has a direct conversation. A concurrent
+// call for the same pair waits on the primary key until the other one ends.
+func (c *ConversationRepo) CreateDirectedConversation(ctx context.Context, conversationID, userID, otherUser string) (bool, error) {
+	userLow, userHigh := directPair(userID, otherUser)
+	query := `INSERT INTO direct_conversations (user_low, user_high, conv_id, created_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_low, user_high) DO NOTHING`
+	result, err := c.db.ExecContext(ctx, query, userLow, userHigh, conversationID, time.Now())
+	if err != nil {
+		return false, custom_error.HandlePostgreError(err)
 	}
 
-	if row.Err() != nil {
-		return "", row.Err()
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, custom_error.HandlePostgreError(err)
 	}
-	go c.setIntoRedis(ctx, fmt.Sprintf("%s+%s", userID, otherUser), conversationID)
-	return conversationID, nil
+	return affected == 1, nil
+}
+
+// DeleteDirectedConversation returns custom_error.ErrNotFound if
+// conversationID is not a direct conversation.
+func (c *ConversationRepo) DeleteDirectedConversation(ctx context.Context, conversationID string) error {
+	query := `DELETE FROM direct_conversations WHERE conv_id = $1`
+	result, err := c.db.ExecContext(ctx, query, conversationID)
+	if err != nil {
+		return custom_error.HandlePostgreError(err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return custom_error.HandlePostgreError(err)
+	}
+	if affected == 0 {
+		return custom_error.ErrNotFound
+	}
+	return nil
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"graduation-thesis/internal/group/model"
 	"graduation-thesis/pkg/custom_error"
 	"graduation-thesis/pkg/interfaces"
@@ -55,67 +56,174 @@ func (g *GroupRepo) deleteFromRedis(ctx context.Context, key string) error {
 	return err
 }
 
-func (g *GroupRepo) Get(ctx context.Context, groupID string) (*model.Group, error) {
+const groupColumns = `id, group_name, type, description, avatar, created_at, last_updated, conv_id, admins,
+	who_can_post, who_can_add_members, slow_mode_interval, message_retention`
+
+func scanGroup(row interface{ Scan(...interface{}) error }) (*model.Group, error) {
 	var group model.Group
-	if err := g.getFromRedis(ctx, groupID, group); err == nil {
-		return &group, nil
+	err := row.Scan(&group.ID, &group.GroupName, &group.Type, &group.Description, &group.Avatar, &group.CreatedAt, &group.LastUpdated,
+		&group.ConversationID, (*pq.StringArray)(&group.Admins), &group.Settings.WhoCanPost, &group.Settings.WhoCanAddMembers,
+		&group.Settings.SlowModeInterval, &group.Settings.MessageRetention)
+	if err != nil {
+		return nil, err
 	}
+	return &group, nil
+}
 
-	query := `SELECT id, group_name, created_at, last_updated, conv_id, admins FROM groups WHERE id = $1 AND deleted = false`
-	row := g.db.QueryRowContext(ctx, query, groupID)
+func (g *GroupRepo) Get(ctx context.Context, groupID string) (*model.Group, error) {
+	var cached model.Group
+	if err := g.getFromRedis(ctx, groupID, cached); err == nil {
+		return &cached, nil
+	}
 
-	if err := row.Scan(&group.ID, &group.GroupName, &group.CreatedAt, &group.LastUpdated, &group.ConversationID, (*pq.StringArray)(&group.Admins)); err != nil {
+	query := `SELECT ` + groupColumns + ` FROM groups WHERE id = $1 AND deleted = false`
+	group, err := scanGroup(g.db.QueryRowContext(ctx, query, groupID))
+	if err != nil {
 		return nil, custom_error.HandlePostgreError(err)
 	}
 
 	_ = g.setIntoRedis(ctx, groupID, group)
 
-	return &group, nil
+	return group, nil
 }
 
-func (g *GroupRepo) GetByName(ctx context.Context, groupName string) (*model.Group, error) {
-	query := `SELECT id, group_name, created_at, last_updated, conv_id, admins FROM groups WHERE group_name = $1 AND deleted = false LIMIT 1`
-	row := g.db.QueryRowContext(ctx, query, groupName)
-
-	var group model.Group
-	err := row.Scan(&group.ID, &group.GroupName, &group.CreatedAt, &group.LastUpdated, &group.ConversationID, (*pq.StringArray)(&group.Admins))
+// GetByName looks the group up among the groups of userID, names are only
+// unique from the point of view of a single user.
+func (g *GroupRepo) GetByName(ctx context.Context, groupName, userID string) (*model.Group, error) {
+	query := `SELECT ` + groupColumns + ` FROM groups WHERE group_name = $1 AND deleted = false
+		AND conv_id IN (SELECT conv_id FROM conv_map_user WHERE user_id = $2) ORDER BY created_at LIMIT 1`
+	group, err := scanGroup(g.db.QueryRowContext(ctx, query, groupName, userID))
 	if err != nil {
 		return nil, custom_error.HandlePostgreError(err)
 	}
 
-	return &group, nil
+	return group, nil
 }
 
 func (g *GroupRepo) GetByConversationID(ctx context.Context, conversationID string) (*model.Group, error) {
-	query := `SELECT id, group_name, created_at, last_updated, conv_id, admins FROM groups WHERE conv_id = $1 AND deleted = false LIMIT 1`
-	row := g.db.QueryRowContext(ctx, query, conversationID)
-
-	var group model.Group
-	err := row.Scan(&group.ID, &group.GroupName, &group.CreatedAt, &group.LastUpdated, &group.ConversationID, (*pq.StringArray)(&group.Admins))
+	query := `SELECT ` + groupColumns + ` FROM groups WHERE conv_id = $1 AND deleted = false LIMIT 1`
+	group, err := scanGroup(g.db.QueryRowContext(ctx, query, conversationID))
 	if err != nil {
 		return nil, custom_error.HandlePostgreError(err)
 	}
 
-	return &group, nil
+	return group, nil
 }
 
 func (g *GroupRepo) Create(ctx context.Context, group model.Group) error {
-	query := `INSERT INTO groups (id, group_name, created_at, last_updated, conv_id, deleted, admins) VALUES ($1, $2, $3, $4, $5, $6, $7)`
-	_, err := g.db.ExecContext(ctx, query, group.ID, group.GroupName, group.CreatedAt, group.LastUpdated,
-		group.ConversationID, group.Deleted, pq.StringArray(group.Admins))
+	query := `INSERT INTO groups (id, group_name, type, description, avatar, created_at, last_updated, conv_id, deleted, admins,
+		who_can_post, who_can_add_members, slow_mode_interval, message_retention) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`
+	_, err := g.db.ExecContext(ctx, query, group.ID, group.GroupName, group.Type, group.Description, group.Avatar, group.CreatedAt, group.LastUpdated,
+		group.ConversationID, group.Deleted, pq.StringArray(group.Admins), group.Settings.WhoCanPost, group.Settings.WhoCanAddMembers,
+		group.Settings.SlowModeInterval, group.Settings.MessageRetention)
 	return custom_error.HandlePostgreError(err)
 }
 
 func (g *GroupRepo) Update(ctx context.Context, params model.UpdateGroupParams) (*model.Group, error) {
-	query := `UPDATE groups SET group_name = $2, last_updated = $3, deleted = $4, admins = $5 WHERE id = $1 RETURNING id, group_name, created_at, last_updated, conv_id, admins`
-	row := g.db.QueryRowContext(ctx, query, params.ID, params.GroupName, params.LastUpdated, params.Deleted, pq.StringArray(params.Admins))
+	query := `UPDATE groups SET group_name = $2, description = $3, avatar = $4, last_updated = $5, deleted = $6, admins = $7 WHERE id = $1 RETURNING ` + groupColumns
+	row := g.db.QueryRowContext(ctx, query, params.ID, params.GroupName, params.Description, params.Avatar, params.LastUpdated,
+		params.Deleted, pq.StringArray(params.Admins))
 
-	var group model.Group
-	err := row.Scan(&group.ID, &group.GroupName, &group.CreatedAt, &group.LastUpdated, &group.ConversationID, (*pq.StringArray)(&group.Admins))
+	group, err := scanGroup(row)
 	if err != nil {
 		return nil, custom_error.HandlePostgreError(err)
 	}
 
 	_ = g.deleteFromRedis(ctx, group.ID)
-	return &group, nil
+	return group, nil
+}
+
+func (g *GroupRepo) UpdateSettings(ctx context.Context, groupID string, settings model.GroupSettings) (*model.Group, error) {
+	query := `UPDATE groups SET who_can_post = $2, who_can_add_members = $3, slow_mode_interval = $4, message_retention = $5,
+		last_updated = $6 WHERE id = $1 AND deleted = false RETURNING ` + groupColumns
+	row := g.db.QueryRowContext(ctx, query, groupID, settings.WhoCanPost, settings.WhoCanAddMembers, settings.SlowModeInterval,
+		settings.MessageRetention, time.Now())
+
+	group, err := scanGroup(row)
+	if err != nil {
+		return nil, custom_error.HandlePostgreError(err)
+	}
+
+	_ = g.deleteFromRedis(ctx, group.ID)
+	return group, nil
+}
+
+// GetForUpdate locks the group row until the transaction ends so that role
+// changes of the same group are serialized.
+func (g *GroupRepo) GetForUpdate(ctx context.Context, groupID string) (*model.Group, error) {
+	query := `SELECT ` + groupColumns + ` FROM groups WHERE id = $1 AND deleted = false FOR UPDATE`
+	group, err := scanGroup(g.db.QueryRowContext(ctx, query, groupID))
+	if err != nil {
+		return nil, custom_error.HandlePostgreError(err)
+	}
+
+	return group, nil
+}
+
+func (g *GroupRepo) GetRoles(ctx context.Context, groupID string) ([]model.GroupRole, error) {
+	query := `SELECT group_id, user_id, role, last_updated FROM group_roles WHERE group_id = $1 ORDER BY last_updated`
+	rows, err := g.db.QueryContext(ctx, query, groupID)
+	if err != nil {
+		return nil, custom_error.HandlePostgreError(err)
+	}
+	defer rows.Close()
+
+	var roles []model.GroupRole
+	for rows.Next() {
+		var role model.GroupRole
+		if err := rows.Scan(&role.GroupID, &role.UserID, &role.Role, &role.LastUpdated); err != nil {
+			return nil, custom_error.HandlePostgreError(err)
+		}
+		roles = append(roles, role)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, custom_error.HandlePostgreError(err)
+	}
+
+	return roles, nil
+}
+
+// GetRole returns ROLE_MEMBER for users without an explicit role.
+func (g *GroupRepo) GetRole(ctx context.Context, groupID, userID string) (string, error) {
+	query := `SELECT role FROM group_roles WHERE group_id = $1 AND user_id = $2`
+	row := g.db.QueryRowContext(ctx, query, groupID, userID)
+
+	var role string
+	if err := row.Scan(&role); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.ROLE_MEMBER, nil
+		}
+		return "", custom_error.HandlePostgreError(err)
+	}
+
+	return role, nil
+}
+
+func (g *GroupRepo) SetRole(ctx context.Context, groupID, userID, role string) error {
+	if role == model.ROLE_MEMBER {
+		return g.RemoveRoles(ctx, groupID, []string{userID})
+	}
+
+	query := `INSERT INTO group_roles (group_id, user_id, role, last_updated) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (group_id, user_id) DO UPDATE SET role = EXCLUDED.role, last_updated = EXCLUDED.last_updated`
+	_, err := g.db.ExecContext(ctx, query, groupID, userID, role, time.Now())
+	return custom_error.HandlePostgreError(err)
+}
+
+func (g *GroupRepo) RemoveRoles(ctx context.Context, groupID string, usersID []string) error {
+	if len(usersID) == 0 {
+		return nil
+	}
+
+	query := `DELETE FROM group_roles WHERE group_id = $1 AND user_id = ANY($2)`
+	_, err := g.db.ExecContext(ctx, query, groupID, pq.StringArray(usersID))
+	return custom_error.HandlePostgreError(err)
+}
+
+// AcquireSlowMode reports whether userID may post in conversationID, and if so
+// blocks them from posting again for interval.
+func (g *GroupRepo) AcquireSlowMode(ctx context.Context, conversationID, userID string, interval time.Duration) (bool, error) {
+	key := "slow_mode:" + conversationID + ":" + userID
+	return g.redis.SetNX(ctx, key, 1, interval).Result()
 }
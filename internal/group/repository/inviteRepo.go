@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"graduation-thesis/internal/group/model"
+	"graduation-thesis/pkg/custom_error"
+	"graduation-thesis/pkg/interfaces"
+)
+
+type InviteRepo struct {
+	db interfaces.DBTX
+}
+
+func NewInviteRepo(db interfaces.DBTX) *InviteRepo {
+	return &InviteRepo{
+		db: db,
+	}
+}
+
+func (i *InviteRepo) WithTx(tx *sql.Tx) *InviteRepo {
+	return &InviteRepo{
+		db: tx,
+	}
+}
+
+const inviteLinkColumns = `id, group_id, token, created_by, created_at, expires_at, max_uses, uses, require_approval, revoked`
+
+func scanInviteLink(row interface{ Scan(...interface{}) error }) (*model.InviteLink, error) {
+	var (
+		link      model.InviteLink
+		expiresAt sql.NullTime
+	)
+	err := row.Scan(&link.ID, &link.GroupID, &link.Token, &link.CreatedBy, &link.CreatedAt, &expiresAt,
+		&link.MaxUses, &link.Uses, &link.RequireApproval, &link.Revoked)
+	if err != nil {
+		return nil, err
+	}
+	if expiresAt.Valid {
+		link.ExpiresAt = &expiresAt.Time
+	}
+	return &link, nil
+}
+
+func (i *InviteRepo) CreateLink(ctx context.Context, link model.InviteLink) error {
+	query := `INSERT INTO group_invite_links (` + inviteLinkColumns + `) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+	_, err := i.db.ExecContext(ctx, query, link.ID, link.GroupID, link.Token, link.CreatedBy, link.CreatedAt, link.ExpiresAt,
+		link.MaxUses, link.Uses, link.RequireApproval, link.Revoked)
+	return custom_error.HandlePostgreError(err)
+}
+
+func (i *InviteRepo) GetLinks(ctx context.Context, groupID string) ([]model.InviteLink, error) {
+	query := `SELECT ` + inviteLinkColumns + ` FROM group_invite_links WHERE group_id = $1 AND revoked = false ORDER BY created_at DESC`
+	rows, err := i.db.QueryContext(ctx, query, groupID)
+	if err != nil {
+		return nil, custom_error.HandlePostgreError(err)
+	}
+	defer rows.Close()
+
+	var links []model.InviteLink
+	for rows.Next() {
+		link, err := scanInviteLink(rows)
+		if err != nil {
+			return nil, custom_error.HandlePostgreError(err)
+		}
+		links = append(links, *link)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, custom_error.HandlePostgreError(err)
+	}
+
+	return links, nil
+}
+
+// GetLinkByTokenForUpdate locks the link so that concurrent joins cannot use
+// it more than max_uses times.
+func (i *InviteRepo) GetLinkByTokenForUpdate(ctx context.Context, token string) (*model.InviteLink, error) {
+	query := `SELECT ` + inviteLinkColumns + ` FROM group_invite_links WHERE token = $1 FOR UPDATE`
+	link, err := scanInviteLink(i.db.QueryRowContext(ctx, query, token))
+	if err != nil {
+		return nil, custom_error.HandlePostgreError(err)
+	}
+	return link, nil
+}
+
+func (i *InviteRepo) IncreaseUses(ctx context.Context, linkID string) error {
+	query := `UPDATE group_invite_links SET uses = uses + 1 WHERE id = $1`
+	_, err := i.db.ExecContext(ctx, query, linkID)
+	return custom_error.HandlePostgreError(err)
+}
+
+func (i *InviteRepo) RevokeLink(ctx context.Context, groupID, linkID string) error {
+	query := `UPDATE group_invite_links SET revoked = true WHERE id = $1 AND group_id = $2`
+	result, err := i.db.ExecContext(ctx, query, linkID, groupID)
+	if err != nil {
+		return custom_error.HandlePostgreError(err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return custom_error.HandlePostgreError(err)
+	}
+	if affected == 0 {
+		return custom_error.ErrNotFound
+	}
+	return nil
+}
+
+const joinRequestColumns = `id, group_id, user_id, link_id, status, created_at, last_updated, handled_by`
+
+func scanJoinRequest(row interface{ Scan(...interface{}) error }) (*model.JoinRequest, error) {
+	var (
+		request   model.JoinRequest
+		handledBy sql.NullString
+	)
+	err := row.Scan(&request.ID, &request.GroupID, &request.UserID, &request.LinkID, &request.Status,
+		&request.CreatedAt, &request.LastUpdated, &handledBy)
+	if err != nil {
+		return nil, err
+	}
+	request.HandledBy = handledBy.String
+	return &request, nil
+}
+
+func (i *InviteRepo) CreateJoinRequest(ctx context.Context, request model.JoinRequest) error {
+	query := `INSERT INTO group_join_requests (id, group_id, user_id, link_id, status, created_at, last_updated) VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	_, err := i.db.ExecContext(ctx, query, request.ID, request.GroupID, request.UserID, request.LinkID, request.Status,
+		request.CreatedAt, request.LastUpdated)
+	return custom_error.HandlePostgreError(err)
+}
+
+func (i *InviteRepo) GetPendingJoinRequests(ctx context.Context, groupID string) ([]model.JoinRequest, error) {
+	query := `SELECT ` + joinRequestColumns + ` FROM group_join_requests WHERE group_id = $1 AND status = $2 ORDER BY created_at`
+	rows, err := i.db.QueryContext(ctx, query, groupID, model.JOIN_REQUEST_PENDING)
+	if err != nil {
+		return nil, custom_error.HandlePostgreError(err)
+	}
+	defer rows.Close()
+
+	var requests []model.JoinRequest
+	for rows.Next() {
+		request, err := scanJoinRequest(rows)
+		if err != nil {
+			return nil, custom_error.HandlePostgreError(err)
+		}
+		requests = append(requests, *request)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, custom_error.HandlePostgreError(err)
+	}
+
+	return requests, nil
+}
+
+func (i *InviteRepo) GetJoinRequestForUpdate(ctx context.Context, groupID, requestID string) (*model.JoinRequest, error) {
+	query := `SELECT ` + joinRequestColumns + ` FROM group_join_requests WHERE id = $1 AND group_id = $2 FOR UPDATE`
+	request, err := scanJoinRequest(i.db.QueryRowContext(ctx, query, requestID, groupID))
+	if err != nil {
+		return nil, custom_error.HandlePostgreError(err)
+	}
+	return request, nil
+}
+
+func (i *InviteRepo) UpdateJoinRequestStatus(ctx context.Context, requestID, status, handledBy string) error {
+	query := `UPDATE group_join_requests SET status = $2, handled_by = $3, last_updated = $4 WHERE id = $1`
+	_, err := i.db.ExecContext(ctx, query, requestID, status, handledBy, time.Now())
+	return custom_error.HandlePostgreError(err)
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"encoding/json"
+	"time"
+
+	"graduation-thesis/pkg/logger"
+	"graduation-thesis/pkg/membership"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+type MembershipEventRepo struct {
+	producer   *kafka.Producer
+	kafkaTopic string
+	deliveries chan kafka.Event
+	logger     logger.Logger
+}
+
+func NewMembershipEventRepo(producer *kafka.Producer, kafkaTopic string, logger logger.Logger) *MembershipEventRepo {
+	m := &MembershipEventRepo{
+		producer:   producer,
+		kafkaTopic: kafkaTopic,
+		deliveries: make(chan kafka.Event, 1000),
+		logger:     logger,
+	}
+	go m.watchDeliveries()
+	return m
+}
+
+// watchDeliveries logs the events Kafka failed to deliver after Publish
+// returned. Caches keep the members such an event would have dropped until
+// their ttl passes.
+func (m *MembershipEventRepo) watchDeliveries() {
+	for event := range m.deliveries {
+		message, ok := event.(*kafka.Message)
+		if !ok || message.TopicPartition.Error == nil {
+			continue
+		}
+		m.logger.Errorf("[MembershipEventRepo] Cannot deliver membership event of conversation %s: %v", message.Key, message.TopicPartition.Error)
+	}
+}
+
+func (m *MembershipEventRepo) Publish(conversationID, action string, users []string) error {
+	value, err := json.Marshal(membership.Event{
+		ConversationID: conversationID,
+		Action:         action,
+		Users:          users,
+		Timestamp:      time.Now().UnixMilli(),
+	})
+	if err != nil {
+		return err
+	}
+
+	return m.producer.Produce(&kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &m.kafkaTopic, Partition: int32(kafka.PartitionAny)},
+		Key:            []byte(conversationID),
+		Value:          value,
+	}, m.deliveries)
+}
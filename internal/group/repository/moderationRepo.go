@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"graduation-thesis/internal/group/model"
+	"graduation-thesis/pkg/custom_error"
+)
+
+// SetRestriction replaces any previous restriction of the same type, so a new
+// ban or mute overrides the reason and expiry of the old one.
+func (g *GroupRepo) SetRestriction(ctx context.Context, restriction model.Restriction) error {
+	query := `INSERT INTO group_restrictions (group_id, user_id, type, reason, created_by, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (group_id, user_id, type) DO UPDATE SET reason = EXCLUDED.reason, created_by = EXCLUDED.created_by,
+			created_at = EXCLUDED.created_at, expires_at = EXCLUDED.expires_at`
+	_, err := g.db.ExecContext(ctx, query, restriction.GroupID, restriction.UserID, restriction.Type, restriction.Reason,
+		restriction.CreatedBy, restriction.CreatedAt, restriction.ExpiresAt)
+	return custom_error.HandlePostgreError(err)
+}
+
+// RemoveRestriction returns custom_error.ErrNotFound if the user has no active
+// restriction of that type.
+func (g *GroupRepo) RemoveRestriction(ctx context.Context, groupID, userID, restrictionType string) error {
+	query := `DELETE FROM group_restrictions WHERE group_id = $1 AND user_id = $2 AND type = $3
+		AND (expires_at IS NULL OR expires_at > $4)`
+	result, err := g.db.ExecContext(ctx, query, groupID, userID, restrictionType, time.Now())
+	if err != nil {
+		return custom_error.HandlePostgreError(err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return custom_error.HandlePostgreError(err)
+	}
+	if affected == 0 {
+		return custom_error.ErrNotFound
+	}
+	return nil
+}
+
+// GetActiveRestriction returns nil if the user has no active restriction of
+// that type.
+func (g *GroupRepo) GetActiveRestriction(ctx context.Context, groupID, userID, restrictionType string) (*model.Restriction, error) {
+	query := `SELECT group_id, user_id, type, reason, created_by, created_at, expires_at FROM group_restrictions
+		WHERE group_id = $1 AND user_id = $2 AND type = $3`
+	row := g.db.QueryRowContext(ctx, query, groupID, userID, restrictionType)
+
+	var (
+		restriction model.Restriction
+		expiresAt   sql.NullTime
+	)
+	err := row.Scan(&restriction.GroupID, &restriction.UserID, &restriction.Type, &restriction.Reason,
+		&restriction.CreatedBy, &restriction.CreatedAt, &expiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, custom_error.HandlePostgreError(err)
+	}
+	if expiresAt.Valid {
+		restriction.ExpiresAt = &expiresAt.Time
+	}
+
+	if !restriction.IsActive(time.Now()) {
+		return nil, nil
+	}
+	return &restriction, nil
+}
+
+func (g *GroupRepo) AppendModerationLog(ctx context.Context, entry model.ModerationLogEntry) error {
+	query := `INSERT INTO group_moderation_log (group_id, actor, action, target, reason, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	_, err := g.db.ExecContext(ctx, query, entry.GroupID, entry.Actor, entry.Action, entry.Target, entry.Reason,
+		entry.ExpiresAt, entry.CreatedAt)
+	return custom_error.HandlePostgreError(err)
+}
+
+// GetModerationLog returns the newest entries first, starting below before
+// when it is not 0.
+func (g *GroupRepo) GetModerationLog(ctx context.Context, groupID string, before int64, limit int) ([]model.ModerationLogEntry, error) {
+	query := `SELECT id, group_id, actor, action, target, reason, expires_at, created_at FROM group_moderation_log
+		WHERE group_id = $1 AND ($2 = 0 OR id < $2) ORDER BY id DESC LIMIT $3`
+	rows, err := g.db.QueryContext(ctx, query, groupID, before, limit)
+	if err != nil {
+		return nil, custom_error.HandlePostgreError(err)
+	}
+	defer rows.Close()
+
+	var entries []model.ModerationLogEntry
+	for rows.Next() {
+		var (
+			entry     model.ModerationLogEntry
+			expiresAt sql.NullTime
+		)
+		err := rows.Scan(&entry.ID, &entry.GroupID, &entry.Actor, &entry.Action, &entry.Target, &entry.Reason,
+			&expiresAt, &entry.CreatedAt)
+		if err != nil {
+			return nil, custom_error.HandlePostgreError(err)
+		}
+		if expiresAt.Valid {
+			entry.ExpiresAt = &expiresAt.Time
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, custom_error.HandlePostgreError(err)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"graduation-thesis/internal/group/model"
+	"graduation-thesis/pkg/custom_error"
+	"graduation-thesis/pkg/pb/messagepb"
+)
+
+type SystemEventRepo struct {
+	messageClient messagepb.MessageServiceClient
+	timeout       time.Duration
+}
+
+func NewSystemEventRepo(messageClient messagepb.MessageServiceClient, timeout time.Duration) *SystemEventRepo {
+	return &SystemEventRepo{
+		messageClient: messageClient,
+		timeout:       timeout,
+	}
+}
+
+// Publish appends event to the conversation stream through the message service,
+// which hands it to the messages topic so members receive it like any message.
+func (s *SystemEventRepo) Publish(ctx context.Context, event model.SystemEvent) error {
+	content, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	_, err = s.messageClient.SendMessage(ctx, &messagepb.SendMessageRequest{
+		ConvId:  event.ConversationID,
+		Sender:  event.Actor,
+		Content: string(content),
+		MsgTime: time.Now().Unix(),
+		Type:    model.EVENT_TYPE,
+	})
+	return custom_error.HandleGRPCError(err)
+}
+
+func (s *SystemEventRepo) PublishAll(ctx context.Context, events []model.SystemEvent) error {
+	var lastErr error
+	for _, event := range events {
+		if err := s.Publish(ctx, event); err != nil {
+			lastErr = err
+		}
+	}
+	return lastErr
+}
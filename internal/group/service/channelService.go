@@ -0,0 +1,248 @@
+package service
+
+import (
+	"context"
+	"graduation-thesis/internal/group/model"
+	"graduation-thesis/internal/group/repository"
+	"graduation-thesis/pkg/custom_error"
+	"graduation-thesis/pkg/membership"
+	responseModel "graduation-thesis/pkg/model"
+	"net/http"
+	"time"
+
+	"github.com/twinj/uuid"
+)
+
+// Channels are groups of type channel: only admins post and anybody may
+// subscribe, so they start with the creator as their only member.
+func (g *GroupService) CreateChannel(ctx context.Context, request *model.CreateChannelRequest, userID string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	var createGroupResponse model.CreateGroupResponse
+	queryContext, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := g.execTx(ctx, func(gr *repository.GroupRepo, cr *repository.ConversationRepo) error {
+		conversationID := uuid.NewV4().String()
+		if err := cr.Create(queryContext, conversationID); err != nil {
+			return err
+		}
+
+		if err := cr.AddMembers(queryContext, conversationID, []string{userID}); err != nil {
+			return err
+		}
+
+		channel := model.Group{
+			ID:             uuid.NewV4().String(),
+			GroupName:      request.ChannelName,
+			Type:           model.CONVERSATION_TYPE_CHANNEL,
+			Description:    request.Description,
+			Avatar:         request.Avatar,
+			CreatedAt:      time.Now(),
+			LastUpdated:    time.Now(),
+			ConversationID: conversationID,
+			Admins:         []string{userID},
+			Settings: model.GroupSettings{
+				WhoCanPost:       model.ROLE_ADMIN,
+				WhoCanAddMembers: model.ROLE_ADMIN,
+			},
+		}
+		if err := gr.Create(queryContext, channel); err != nil {
+			return err
+		}
+
+		if err := gr.SetRole(queryContext, channel.ID, userID, model.ROLE_OWNER); err != nil {
+			return err
+		}
+
+		createGroupResponse.GroupID = channel.ID
+		createGroupResponse.ConversationID = conversationID
+		return nil
+	})
+
+	if err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       g.errorMap[err],
+			ErrorMessage: err.Error(),
+		}
+		return nil, &errorResponse
+	}
+	if err := g.membershipEventRepo.Publish(createGroupResponse.ConversationID, membership.ActionCreate, []string{userID}); err != nil {
+		g.logger.Errorf("[CreateChannel] Cannot publish membership event of conversation %s: %v", createGroupResponse.ConversationID, err)
+	}
+
+	successResponse := responseModel.SuccessResponse{
+		Status: http.StatusCreated,
+		Result: createGroupResponse,
+	}
+	return &successResponse, nil
+}
+
+func (g *GroupService) Subscribe(ctx context.Context, channelID, userID string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	var conversationID string
+	queryContext, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := g.execTx(ctx, func(gr *repository.GroupRepo, cr *repository.ConversationRepo) error {
+		channel, gErr := gr.GetForUpdate(queryContext, channelID)
+		if gErr != nil {
+			return gErr
+		}
+		if channel.Type != model.CONVERSATION_TYPE_CHANNEL {
+			return custom_error.ErrNotFound
+		}
+		conversationID = channel.ConversationID
+
+		subscribed, sErr := cr.IsMember(queryContext, channel.ConversationID, userID)
+		if sErr != nil {
+			return sErr
+		}
+		if subscribed {
+			return custom_error.ErrConflict
+		}
+
+		if err := checkNotBanned(queryContext, gr, channelID, []string{userID}); err != nil {
+			return err
+		}
+
+		return cr.AddMembers(queryContext, channel.ConversationID, []string{userID})
+	})
+
+	if err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       g.errorMap[err],
+			ErrorMessage: err.Error(),
+		}
+		return nil, &errorResponse
+	}
+	if err := g.membershipEventRepo.Publish(conversationID, membership.ActionAdd, []string{userID}); err != nil {
+		g.logger.Errorf("[Subscribe] Cannot publish membership event of conversation %s: %v", conversationID, err)
+	}
+
+	successResponse := responseModel.SuccessResponse{
+		Status: http.StatusNoContent,
+	}
+	return &successResponse, nil
+}
+
+func (g *GroupService) Unsubscribe(ctx context.Context, channelID, userID string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	queryContext, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	channel, err := g.groupRepo.Get(queryContext, channelID)
+	if err == nil && channel.Type != model.CONVERSATION_TYPE_CHANNEL {
+		err = custom_error.ErrNotFound
+	}
+	if err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       g.errorMap[err],
+			ErrorMessage: err.Error(),
+		}
+		return nil, &errorResponse
+	}
+
+	return g.LeaveGroup(ctx, channelID, userID)
+}
+
+// CheckPost decides whether userID may post a message of messageType in
+// conversationID. Conversations without a group are direct ones, which are
+// not restricted here.
+func (g *GroupService) CheckPost(ctx context.Context, conversationID, userID, messageType string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	queryContext, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	policy, err := g.checkPost(queryContext, conversationID, userID, messageType)
+	if err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       g.errorMap[err],
+			ErrorMessage: err.Error(),
+		}
+		return nil, &errorResponse
+	}
+
+	successResponse := responseModel.SuccessResponse{
+		Status: http.StatusOK,
+		Result: policy,
+	}
+	return &successResponse, nil
+}
+
+func (g *GroupService) checkPost(ctx context.Context, conversationID, userID, messageType string) (*model.PostPolicy, error) {
+	group, err := g.groupRepo.GetByConversationID(ctx, conversationID)
+	if err == custom_error.ErrNotFound {
+		if messageType != model.EVENT_TYPE {
+			if err := g.checkDirectPost(ctx, conversationID, userID); err != nil {
+				return nil, err
+			}
+		}
+		return &model.PostPolicy{ConversationType: model.CONVERSATION_TYPE_DIRECT}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	policy := model.PostPolicy{
+		ConversationType: group.Type,
+		MessageRetention: group.Settings.MessageRetention,
+	}
+	if messageType == model.EVENT_TYPE {
+		return &policy, nil
+	}
+
+	isMember, err := g.conversationRepo.IsMember(ctx, conversationID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, custom_error.ErrNoPermission
+	}
+
+	mute, err := g.groupRepo.GetActiveRestriction(ctx, group.ID, userID, model.RESTRICTION_MUTE)
+	if err != nil {
+		return nil, err
+	}
+	if mute != nil {
+		return nil, custom_error.ErrNoPermission
+	}
+
+	role, err := g.groupRepo.GetRole(ctx, group.ID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !group.Settings.CanPost(role) {
+		return nil, custom_error.ErrNoPermission
+	}
+	if group.Type == model.CONVERSATION_TYPE_CHANNEL && !model.Outranks(role, model.ROLE_MODERATOR) {
+		return nil, custom_error.ErrNoPermission
+	}
+
+	if group.Settings.SlowModeInterval > 0 && role == model.ROLE_MEMBER {
+		// Slow mode is best effort, a redis failure should not silence the group
+		acquired, err := g.groupRepo.AcquireSlowMode(ctx, conversationID, userID, time.Duration(group.Settings.SlowModeInterval)*time.Second)
+		if err == nil && !acquired {
+			return nil, custom_error.ErrTooManyRequests
+		}
+	}
+
+	return &policy, nil
+}
+
+// checkDirectPost refuses messages between users who blocked one another.
+func (g *GroupService) checkDirectPost(ctx context.Context, conversationID, userID string) error {
+	conversation, err := g.conversationRepo.GetMembers(ctx, conversationID)
+	if err != nil {
+		return err
+	}
+
+	for _, member := range conversation.Members {
+		if member == userID {
+			continue
+		}
+		blocked, err := g.blockRepo.IsBlocked(ctx, userID, member)
+		if err != nil {
+			return err
+		}
+		if blocked {
+			return custom_error.ErrNoPermission
+		}
+	}
+	return nil
+}
@@ -7,6 +7,8 @@ import (
 	"graduation-thesis/internal/group/model"
 	"graduation-thesis/internal/group/repository"
 	"graduation-thesis/pkg/custom_error"
+	"graduation-thesis/pkg/logger"
+	"graduation-thesis/pkg/membership"
 	responseModel "graduation-thesis/pkg/model"
 	"net/http"
 	"time"
@@ -14,17 +16,31 @@ import (
 	"github.com/twinj/uuid"
 )
 
+var errDirectedConversationExists = errors.New("directed conversation already exists")
+
 type ConversationService struct {
-	db               *sql.DB
-	conversationRepo *repository.ConversationRepo
-	errorMap         map[error]int
+	db                  *sql.DB
+	conversationRepo    *repository.ConversationRepo
+	membershipEventRepo *repository.MembershipEventRepo
+	blockRepo           *repository.BlockRepo
+	errorMap            map[error]int
+	logger              logger.Logger
 }
 
-func NewConversationService(db *sql.DB, conversationRepo *repository.ConversationRepo, errorMap map[error]int) *ConversationService {
+func NewConversationService(
+	db *sql.DB,
+	conversationRepo *repository.ConversationRepo,
+	membershipEventRepo *repository.MembershipEventRepo,
+	blockRepo *repository.BlockRepo,
+	errorMap map[error]int,
+	logger logger.Logger) *ConversationService {
 	return &ConversationService{
-		db:               db,
-		conversationRepo: conversationRepo,
-		errorMap:         errorMap,
+		db:                  db,
+		conversationRepo:    conversationRepo,
+		membershipEventRepo: membershipEventRepo,
+		blockRepo:           blockRepo,
+		errorMap:            errorMap,
+		logger:              logger,
 	}
 }
 
@@ -64,24 +80,41 @@ func (c *ConversationService) GetConversation(ctx context.Context, conversationI
 	return &successResponse, nil
 }
 
-func (c *ConversationService) CreateConversation(ctx context.Context, request *model.CreateConversationRequest) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
-	if len(request.Members) < 2 {
+// CreateConversation creates a conversation of userID and the requested
+// members, none of whom may have blocked userID or been blocked by them. It
+// returns the direct conversation of the two users when the members are a
+// pair, creating it only if it does not exist yet.
+func (c *ConversationService) CreateConversation(ctx context.Context, request *model.CreateConversationRequest, userID string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	members := uniqueMembers(append([]string{userID}, request.Members...))
+	if len(members) < 2 {
 		errorResponse := responseModel.ErrorResponse{
 			Status:       c.errorMap[custom_error.ErrInvalidParameter],
 			ErrorMessage: custom_error.ErrInvalidParameter.Error(),
 		}
 		return nil, &errorResponse
 	}
+	if len(members) == 2 {
+		return c.GetOrCreateDirectedConversation(ctx, userID, members[1])
+	}
+
 	queryContext, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
+	if err := c.checkNotBlocked(queryContext, userID, members); err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       c.errorMap[err],
+			ErrorMessage: err.Error(),
+		}
+		return nil, &errorResponse
+	}
+
 	conversationID := uuid.NewV4().String()
 	err := c.execTx(ctx, func(c *repository.ConversationRepo) error {
 		if err := c.Create(queryContext, conversationID); err != nil {
 			return err
 		}
 
-		addErr := c.AddMembers(queryContext, conversationID, request.Members)
+		addErr := c.AddMembers(queryContext, conversationID, members)
 		if addErr != nil {
 			return addErr
 		}
@@ -96,12 +129,93 @@ func (c *ConversationService) CreateConversation(ctx context.Context, request *m
 		}
 		return nil, &errorResponse
 	}
+	if err := c.membershipEventRepo.Publish(conversationID, membership.ActionCreate, members); err != nil {
+		c.logger.Errorf("[CreateConversation] Cannot publish membership event of conversation %s: %v", conversationID, err)
+	}
 
 	successResponse := responseModel.SuccessResponse{
 		Status: http.StatusCreated,
 		Result: model.Conversation{
 			ID:      conversationID,
-			Members: request.Members,
+			Members: members,
+		},
+	}
+	return &successResponse, nil
+}
+
+// GetOrCreateDirectedConversation is idempotent: every call for the same pair
+// of users, concurrent ones included, ends up with the same conversation.
+func (c *ConversationService) GetOrCreateDirectedConversation(ctx context.Context, userID, otherUser string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	if otherUser == "" || otherUser == userID {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       c.errorMap[custom_error.ErrInvalidParameter],
+			ErrorMessage: custom_error.ErrInvalidParameter.Error(),
+		}
+		return nil, &errorResponse
+	}
+
+	queryContext, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	members := []string{userID, otherUser}
+	conversationID, err := c.conversationRepo.GetDirectedConversation(queryContext, userID, otherUser)
+	created := false
+	if err == custom_error.ErrNotFound {
+		if bErr := c.checkNotBlocked(queryContext, userID, members); bErr != nil {
+			errorResponse := responseModel.ErrorResponse{
+				Status:       c.errorMap[bErr],
+				ErrorMessage: bErr.Error(),
+			}
+			return nil, &errorResponse
+		}
+
+		newConversationID := uuid.NewV4().String()
+		err = c.execTx(ctx, func(c *repository.ConversationRepo) error {
+			if err := c.Create(queryContext, newConversationID); err != nil {
+				return err
+			}
+
+			var dErr error
+			created, dErr = c.CreateDirectedConversation(queryContext, newConversationID, userID, otherUser)
+			if dErr != nil {
+				return dErr
+			}
+			if !created {
+				return errDirectedConversationExists
+			}
+
+			return c.AddMembers(queryContext, newConversationID, members)
+		})
+
+		switch err {
+		case nil:
+			conversationID = newConversationID
+		case errDirectedConversationExists: // Somebody else created it in the meantime
+			conversationID, err = c.conversationRepo.GetDirectedConversation(queryContext, userID, otherUser)
+		}
+	}
+
+	if err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       c.errorMap[err],
+			ErrorMessage: err.Error(),
+		}
+		return nil, &errorResponse
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+		if err := c.membershipEventRepo.Publish(conversationID, membership.ActionCreate, members); err != nil {
+			c.logger.Errorf("[GetOrCreateDirectedConversation] Cannot publish membership event of conversation %s: %v", conversationID, err)
+		}
+	}
+
+	successResponse := responseModel.SuccessResponse{
+		Status: status,
+		Result: model.Conversation{
+			ID:      conversationID,
+			Members: members,
 		},
 	}
 	return &successResponse, nil
@@ -109,6 +223,9 @@ func (c *ConversationService) CreateConversation(ctx context.Context, request *m
 
 func (c *ConversationService) GetConversationsContainUser(ctx context.Context, userID string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
 	conversations, err := c.conversationRepo.GetConversations(ctx, userID)
+	if err == nil {
+		err = c.markBlockedPeers(ctx, userID, conversations)
+	}
 	if err != nil {
 		errorResponse := responseModel.ErrorResponse{
 			Status:       c.errorMap[err],
@@ -124,19 +241,61 @@ func (c *ConversationService) GetConversationsContainUser(ctx context.Context, u
 	return &successResponse, nil
 }
 
-func (c *ConversationService) GetDirectedConversation(ctx context.Context, userID, otherUser string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
-	conversation, err := c.conversationRepo.GetDirectedConversation(ctx, userID, otherUser)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			errorResponse := responseModel.ErrorResponse{
-				Status:       http.StatusNotFound,
-				ErrorMessage: err.Error(),
+func (c *ConversationService) UpdateSettings(ctx context.Context, request *model.UpdateConversationSettingsRequest, conversationID, userID string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	var settings *model.ConversationSettings
+	queryContext, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := c.execTx(ctx, func(c *repository.ConversationRepo) error {
+		var sErr error
+		settings, sErr = c.GetSettingsForUpdate(queryContext, conversationID, userID)
+		if sErr != nil {
+			return sErr
+		}
+
+		if request.MutedUntil != nil {
+			settings.MutedUntil = nil
+			if *request.MutedUntil > 0 {
+				mutedUntil := time.Unix(*request.MutedUntil, 0)
+				settings.MutedUntil = &mutedUntil
 			}
-			return nil, &errorResponse
 		}
+		if request.Archived != nil {
+			settings.Archived = *request.Archived
+		}
+		if request.Pinned != nil {
+			settings.Pinned = *request.Pinned
+		}
+		if request.PinOrder != nil {
+			settings.PinOrder = *request.PinOrder
+		}
+
+		return c.UpdateSettings(queryContext, conversationID, userID, *settings)
+	})
+
+	if err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       c.errorMap[err],
+			ErrorMessage: err.Error(),
+		}
+		return nil, &errorResponse
+	}
+
+	successResponse := responseModel.SuccessResponse{
+		Status: http.StatusOK,
+		Result: settings,
+	}
+	return &successResponse, nil
+}
 
+func (c *ConversationService) GetDirectedConversation(ctx context.Context, userID, otherUser string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	queryContext, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	conversation, err := c.conversationRepo.GetDirectedConversation(queryContext, userID, otherUser)
+	if err != nil {
 		errorResponse := responseModel.ErrorResponse{
-			Status:       http.StatusInternalServerError,
+			Status:       c.errorMap[err],
 			ErrorMessage: err.Error(),
 		}
 		return nil, &errorResponse
@@ -148,3 +307,55 @@ func (c *ConversationService) GetDirectedConversation(ctx context.Context, userI
 	}
 	return &successResponse, nil
 }
+
+// markBlockedPeers flags the direct conversations whose peer blocked userID or
+// was blocked by them, so their read positions can be hidden.
+func (c *ConversationService) markBlockedPeers(ctx context.Context, userID string, conversations []model.GetConversationsContainUserResponse) error {
+	hasPeer := false
+	for _, conversation := range conversations {
+		if conversation.PeerID != "" {
+			hasPeer = true
+			break
+		}
+	}
+	if !hasPeer {
+		return nil
+	}
+
+	blockedUsers, err := c.blockRepo.GetBlockedUsers(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for i := range conversations {
+		conversations[i].PeerBlocked = blockedUsers[conversations[i].PeerID]
+	}
+	return nil
+}
+
+// checkNotBlocked refuses to put userID in a conversation with users they
+// blocked or were blocked by. Block lists that can't be read refuse too.
+func (c *ConversationService) checkNotBlocked(ctx context.Context, userID string, users []string) error {
+	blockedUsers, err := c.blockRepo.GetBlockedUsers(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, user := range users {
+		if blockedUsers[user] {
+			return custom_error.ErrNoPermission
+		}
+	}
+	return nil
+}
+
+func uniqueMembers(members []string) []string {
+	seen := make(map[string]bool, len(members))
+	var result []string
+	for _, member := range members {
+		if member == "" || seen[member] {
+			continue
+		}
+		seen[member] = true
+		result = append(result, member)
+	}
+	return result
+}
@@ -6,6 +6,8 @@ import (
 	"graduation-thesis/internal/group/model"
 	"graduation-thesis/internal/group/repository"
 	"graduation-thesis/pkg/custom_error"
+	"graduation-thesis/pkg/logger"
+	"graduation-thesis/pkg/membership"
 	responseModel "graduation-thesis/pkg/model"
 	"net/http"
 	"time"
@@ -14,18 +16,34 @@ import (
 )
 
 type GroupService struct {
-	db               *sql.DB
-	groupRepo        *repository.GroupRepo
-	conversationRepo *repository.ConversationRepo
-	errorMap         map[error]int
+	db                  *sql.DB
+	groupRepo           *repository.GroupRepo
+	conversationRepo    *repository.ConversationRepo
+	membershipEventRepo *repository.MembershipEventRepo
+	systemEventRepo     *repository.SystemEventRepo
+	blockRepo           *repository.BlockRepo
+	errorMap            map[error]int
+	logger              logger.Logger
 }
 
-func NewGroupService(db *sql.DB, groupRepo *repository.GroupRepo, conversationRepo *repository.ConversationRepo, errorMap map[error]int) *GroupService {
+func NewGroupService(
+	db *sql.DB,
+	groupRepo *repository.GroupRepo,
+	conversationRepo *repository.ConversationRepo,
+	membershipEventRepo *repository.MembershipEventRepo,
+	systemEventRepo *repository.SystemEventRepo,
+	blockRepo *repository.BlockRepo,
+	errorMap map[error]int,
+	logger logger.Logger) *GroupService {
 	return &GroupService{
-		db:               db,
-		groupRepo:        groupRepo,
-		conversationRepo: conversationRepo,
-		errorMap:         errorMap,
+		db:                  db,
+		groupRepo:           groupRepo,
+		conversationRepo:    conversationRepo,
+		membershipEventRepo: membershipEventRepo,
+		systemEventRepo:     systemEventRepo,
+		blockRepo:           blockRepo,
+		errorMap:            errorMap,
+		logger:              logger,
 	}
 }
 
@@ -72,11 +90,13 @@ func (g *GroupService) GetGroup(ctx context.Context, userID, groupID, groupName,
 	queryContext, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 	if groupName != "" {
-		group, err = g.groupRepo.GetByName(queryContext, groupName)
+		group, err = g.groupRepo.GetByName(queryContext, groupName, userID)
 	} else if groupID != "" {
 		group, err = g.groupRepo.Get(queryContext, groupID)
 	} else if conversationID != "" {
 		group, err = g.groupRepo.GetByConversationID(queryContext, conversationID)
+	} else {
+		err = custom_error.ErrInvalidParameter
 	}
 
 	if err != nil {
@@ -87,6 +107,26 @@ func (g *GroupService) GetGroup(ctx context.Context, userID, groupID, groupName,
 		return nil, &errorResponse
 	}
 
+	group.Roles, err = g.groupRepo.GetRoles(queryContext, group.ID)
+	if err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       g.errorMap[err],
+			ErrorMessage: err.Error(),
+		}
+		return nil, &errorResponse
+	}
+
+	if group.Type == model.CONVERSATION_TYPE_CHANNEL {
+		group.SubscriberCount, err = g.conversationRepo.CountMembers(queryContext, group.ConversationID)
+		if err != nil {
+			errorResponse := responseModel.ErrorResponse{
+				Status:       g.errorMap[err],
+				ErrorMessage: err.Error(),
+			}
+			return nil, &errorResponse
+		}
+	}
+
 	successResponse := responseModel.SuccessResponse{
 		Status: http.StatusOK,
 		Result: group,
@@ -116,6 +156,14 @@ func (g *GroupService) CreateGroup(ctx context.Context, request *model.CreateGro
 	queryContext, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
+	if err := g.checkNotBlockedBy(queryContext, userID, request.Members); err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       g.errorMap[err],
+			ErrorMessage: err.Error(),
+		}
+		return nil, &errorResponse
+	}
+
 	err := g.execTx(ctx, func(gr *repository.GroupRepo, cr *repository.ConversationRepo) error {
 		conversationID := uuid.NewV4().String()
 		cErr := cr.Create(queryContext, conversationID)
@@ -131,17 +179,25 @@ func (g *GroupService) CreateGroup(ctx context.Context, request *model.CreateGro
 		group := model.Group{
 			ID:             uuid.NewV4().String(),
 			GroupName:      request.GroupName,
+			Type:           model.CONVERSATION_TYPE_GROUP,
+			Description:    request.Description,
+			Avatar:         request.Avatar,
 			CreatedAt:      time.Now(),
 			LastUpdated:    time.Now(),
 			ConversationID: conversationID,
 			Deleted:        false,
 			Admins:         []string{userID},
+			Settings:       model.DefaultGroupSettings(),
 		}
 		gErr := gr.Create(queryContext, group)
 		if gErr != nil {
 			return gErr
 		}
 
+		if err := gr.SetRole(queryContext, group.ID, userID, model.ROLE_OWNER); err != nil {
+			return err
+		}
+
 		createGroupResponse.GroupID = group.ID
 		createGroupResponse.ConversationID = conversationID
 		return nil
@@ -154,6 +210,113 @@ func (g *GroupService) CreateGroup(ctx context.Context, request *model.CreateGro
 		}
 		return nil, &errorResponse
 	}
+	if err := g.membershipEventRepo.Publish(createGroupResponse.ConversationID, membership.ActionCreate, request.Members); err != nil {
+		g.logger.Errorf("[CreateGroup] Cannot publish membership event of conversation %s: %v", createGroupResponse.ConversationID, err)
+	}
+
+	successResponse := responseModel.SuccessResponse{
+		Status: http.StatusCreated,
+		Result: createGroupResponse,
+	}
+	return &successResponse, nil
+}
+
+// ConvertToGroup is the only way to add members to a direct conversation:
+// the conversation keeps its history and becomes the conversation of a new
+// group owned by userID. Like CreateGroup it needs at least three members, so
+// at least one user has to be added to the two of the conversation.
+func (g *GroupService) ConvertToGroup(ctx context.Context, request *model.ConvertToGroupRequest, conversationID, userID string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	if len(request.Members) == 0 {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       g.errorMap[custom_error.ErrInvalidParameter],
+			ErrorMessage: custom_error.ErrInvalidParameter.Error(),
+		}
+		return nil, &errorResponse
+	}
+
+	var (
+		createGroupResponse model.CreateGroupResponse
+		newMembers          []string
+	)
+	queryContext, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := g.execTx(ctx, func(gr *repository.GroupRepo, cr *repository.ConversationRepo) error {
+		conversation, cErr := cr.GetMembers(queryContext, conversationID)
+		if cErr != nil {
+			return cErr
+		}
+		if !g.isInGroup(userID, conversation.Members) {
+			return custom_error.ErrNoPermission
+		}
+
+		for _, member := range request.Members {
+			if !g.isInGroup(member, conversation.Members) && !g.isInGroup(member, newMembers) {
+				newMembers = append(newMembers, member)
+			}
+		}
+		if len(newMembers) == 0 { // Only members of the conversation were listed
+			return custom_error.ErrInvalidParameter
+		}
+
+		if err := cr.DeleteDirectedConversation(queryContext, conversationID); err != nil {
+			return err
+		}
+		if err := g.checkNotBlockedBy(queryContext, userID, newMembers); err != nil {
+			return err
+		}
+		if err := cr.AddMembers(queryContext, conversationID, newMembers); err != nil {
+			return err
+		}
+
+		group := model.Group{
+			ID:             uuid.NewV4().String(),
+			GroupName:      request.GroupName,
+			Type:           model.CONVERSATION_TYPE_GROUP,
+			Description:    request.Description,
+			Avatar:         request.Avatar,
+			CreatedAt:      time.Now(),
+			LastUpdated:    time.Now(),
+			ConversationID: conversationID,
+			Admins:         []string{userID},
+			Settings:       model.DefaultGroupSettings(),
+		}
+		if err := gr.Create(queryContext, group); err != nil {
+			return err
+		}
+
+		if err := gr.SetRole(queryContext, group.ID, userID, model.ROLE_OWNER); err != nil {
+			return err
+		}
+
+		createGroupResponse.GroupID = group.ID
+		createGroupResponse.ConversationID = conversationID
+		return nil
+	})
+
+	if err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       g.errorMap[err],
+			ErrorMessage: err.Error(),
+		}
+		return nil, &errorResponse
+	}
+	if err := g.membershipEventRepo.Publish(conversationID, membership.ActionAdd, newMembers); err != nil {
+		g.logger.Errorf("[ConvertToGroup] Cannot publish membership event of conversation %s: %v", conversationID, err)
+	}
+	if err := g.systemEventRepo.Publish(ctx, model.SystemEvent{
+		Actor:          userID,
+		ConversationID: conversationID,
+		Action:         model.EVENT_RENAME,
+		Object:         model.EVENT_OBJECT_GROUP,
+		ObjectID:       createGroupResponse.GroupID,
+		Value:          request.GroupName,
+	}); err != nil {
+		g.logger.Errorf("[ConvertToGroup] Cannot publish system event of conversation %s: %v", conversationID, err)
+	}
+	if err := g.systemEventRepo.PublishAll(ctx, g.userEvents(userID, conversationID, model.EVENT_ADD, newMembers)); err != nil {
+		g.logger.Errorf("[ConvertToGroup] Cannot publish system events of conversation %s: %v", conversationID, err)
+	}
 
 	successResponse := responseModel.SuccessResponse{
 		Status: http.StatusCreated,
@@ -163,7 +326,7 @@ func (g *GroupService) CreateGroup(ctx context.Context, request *model.CreateGro
 }
 
 func (g *GroupService) UpdateGroup(ctx context.Context, request *model.UpdateGroupRequest, groupID, userID string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
-	if request.GroupName == "" && len(request.Members) == 0 && len(request.Admins) == 0 {
+	if request.GroupName == "" && request.Description == nil && request.Avatar == nil && len(request.Members) == 0 && len(request.Admins) == 0 {
 		errorResponse := responseModel.ErrorResponse{
 			Status:       g.errorMap[custom_error.ErrInvalidParameter],
 			ErrorMessage: custom_error.ErrInvalidParameter.Error(),
@@ -171,50 +334,114 @@ func (g *GroupService) UpdateGroup(ctx context.Context, request *model.UpdateGro
 		return nil, &errorResponse
 	}
 
+	var (
+		conversationID string
+		events         []model.SystemEvent
+	)
 	queryContext, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 	err := g.execTx(ctx, func(gr *repository.GroupRepo, cr *repository.ConversationRepo) error {
-		group, gErr := gr.Get(queryContext, groupID)
+		group, gErr := gr.GetForUpdate(queryContext, groupID)
 		if gErr != nil {
 			return gErr
 		}
+		conversationID = group.ConversationID
 
-		if !g.isInGroup(userID, group.Admins) {
-			return custom_error.ErrNoPermission
+		role, rErr := gr.GetRole(queryContext, groupID, userID)
+		if rErr != nil {
+			return rErr
 		}
 
-		updateGroupParams := model.UpdateGroupParams{
-			ID:          groupID,
-			LastUpdated: time.Now(),
-			Deleted:     false,
-		}
+		groupName := group.GroupName
 		if request.GroupName != "" {
-			updateGroupParams.GroupName = request.GroupName
-		} else {
-			updateGroupParams.GroupName = group.GroupName
+			if !model.HasPermission(role, model.PERMISSION_RENAME) {
+				return custom_error.ErrNoPermission
+			}
+			if request.GroupName != group.GroupName {
+				events = append(events, model.SystemEvent{
+					Actor:          userID,
+					ConversationID: group.ConversationID,
+					Action:         model.EVENT_RENAME,
+					Object:         model.EVENT_OBJECT_GROUP,
+					ObjectID:       groupID,
+					Value:          request.GroupName,
+				})
+			}
+			groupName = request.GroupName
+		}
+		if request.Description != nil || request.Avatar != nil {
+			if !model.HasPermission(role, model.PERMISSION_RENAME) {
+				return custom_error.ErrNoPermission
+			}
+			if request.Description != nil {
+				group.Description = *request.Description
+			}
+			if request.Avatar != nil {
+				group.Avatar = *request.Avatar
+			}
 		}
 
-		if len(request.Members) > 0 {
-			for _, r := range request.Members {
-				if r.Action == "add" {
-					if err := cr.AddMembers(queryContext, group.ConversationID, r.Users); err != nil {
-						return err
+		for _, r := range request.Members {
+			if r.Action == "add" {
+				if !group.Settings.CanAddMembers(role) {
+					return custom_error.ErrNoPermission
+				}
+				if role == model.ROLE_MEMBER { // Outsiders have the member role as well
+					isMember, mErr := g.isMember(queryContext, cr, group.ConversationID, userID)
+					if mErr != nil {
+						return mErr
+					}
+					if !isMember {
+						return custom_error.ErrNoPermission
 					}
 				}
-				if r.Action == "remove" {
-					if err := cr.RemoveMembers(queryContext, group.ConversationID, r.Users); err != nil {
+				if err := checkNotBanned(queryContext, gr, groupID, r.Users); err != nil {
+					return err
+				}
+				if err := g.checkNotBlockedBy(queryContext, userID, r.Users); err != nil {
+					return err
+				}
+				if err := cr.AddMembers(queryContext, group.ConversationID, r.Users); err != nil {
+					return err
+				}
+				events = append(events, g.userEvents(userID, group.ConversationID, model.EVENT_ADD, r.Users)...)
+			}
+			if r.Action == "remove" {
+				if !model.HasPermission(role, model.PERMISSION_REMOVE_MEMBERS) {
+					return custom_error.ErrNoPermission
+				}
+				for _, user := range r.Users {
+					userRole, err := gr.GetRole(queryContext, groupID, user)
+					if err != nil {
 						return err
 					}
+					if !model.Outranks(role, userRole) {
+						return custom_error.ErrNoPermission
+					}
+				}
+				if err := cr.RemoveMembers(queryContext, group.ConversationID, r.Users); err != nil {
+					return err
 				}
+				if err := gr.RemoveRoles(queryContext, groupID, r.Users); err != nil {
+					return err
+				}
+				events = append(events, g.userEvents(userID, group.ConversationID, model.EVENT_REMOVE, r.Users)...)
 			}
 		}
 
-		updateGroupParams.Admins = group.Admins
-		if _, err := g.groupRepo.Update(queryContext, updateGroupParams); err != nil {
-			return err
+		for _, r := range request.Admins {
+			newRole := model.ROLE_ADMIN
+			if r.Action == "remove" {
+				newRole = model.ROLE_MEMBER
+			}
+			for _, user := range r.Users {
+				if err := g.changeRole(queryContext, gr, cr, group, role, user, newRole); err != nil {
+					return err
+				}
+			}
 		}
 
-		return nil
+		return g.updateAdmins(queryContext, gr, group, groupName, false)
 	})
 
 	if err != nil {
@@ -224,6 +451,14 @@ func (g *GroupService) UpdateGroup(ctx context.Context, request *model.UpdateGro
 		}
 		return nil, &errorResponse
 	}
+	for _, r := range request.Members {
+		if err := g.membershipEventRepo.Publish(conversationID, r.Action, r.Users); err != nil {
+			g.logger.Errorf("[UpdateGroup] Cannot publish membership event of conversation %s: %v", conversationID, err)
+		}
+	}
+	if err := g.systemEventRepo.PublishAll(ctx, events); err != nil {
+		g.logger.Errorf("[UpdateGroup] Cannot publish system events of conversation %s: %v", conversationID, err)
+	}
 
 	successResponse := responseModel.SuccessResponse{
 		Status: http.StatusNoContent,
@@ -231,17 +466,81 @@ func (g *GroupService) UpdateGroup(ctx context.Context, request *model.UpdateGro
 	return &successResponse, nil
 }
 
-func (g *GroupService) LeaveGroup(ctx context.Context, groupID, userID string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
-	group, gErr := g.groupRepo.Get(ctx, groupID)
-	if gErr != nil {
+func (g *GroupService) UpdateSettings(ctx context.Context, request *model.UpdateGroupSettingsRequest, groupID, userID string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	var group *model.Group
+	queryContext, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := g.execTx(ctx, func(gr *repository.GroupRepo, cr *repository.ConversationRepo) error {
+		current, gErr := gr.GetForUpdate(queryContext, groupID)
+		if gErr != nil {
+			return gErr
+		}
+
+		role, rErr := gr.GetRole(queryContext, groupID, userID)
+		if rErr != nil {
+			return rErr
+		}
+		if !model.HasPermission(role, model.PERMISSION_CHANGE_SETTINGS) {
+			return custom_error.ErrNoPermission
+		}
+
+		settings := current.Settings
+		if request.WhoCanPost != nil {
+			settings.WhoCanPost = *request.WhoCanPost
+		}
+		if request.WhoCanAddMembers != nil {
+			settings.WhoCanAddMembers = *request.WhoCanAddMembers
+		}
+		if request.SlowModeInterval != nil {
+			settings.SlowModeInterval = *request.SlowModeInterval
+		}
+		if request.MessageRetention != nil {
+			settings.MessageRetention = *request.MessageRetention
+		}
+
+		var uErr error
+		group, uErr = gr.UpdateSettings(queryContext, groupID, settings)
+		return uErr
+	})
+
+	if err != nil {
 		errorResponse := responseModel.ErrorResponse{
-			Status:       g.errorMap[gErr],
-			ErrorMessage: gErr.Error(),
+			Status:       g.errorMap[err],
+			ErrorMessage: err.Error(),
 		}
 		return nil, &errorResponse
 	}
 
-	if err := g.conversationRepo.RemoveMembers(ctx, group.ConversationID, []string{userID}); err != nil {
+	successResponse := responseModel.SuccessResponse{
+		Status: http.StatusOK,
+		Result: group.Settings,
+	}
+	return &successResponse, nil
+}
+
+func (g *GroupService) ChangeRole(ctx context.Context, request *model.ChangeRoleRequest, groupID, userID string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	queryContext, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := g.execTx(ctx, func(gr *repository.GroupRepo, cr *repository.ConversationRepo) error {
+		group, gErr := gr.GetForUpdate(queryContext, groupID)
+		if gErr != nil {
+			return gErr
+		}
+
+		role, rErr := gr.GetRole(queryContext, groupID, userID)
+		if rErr != nil {
+			return rErr
+		}
+
+		if err := g.changeRole(queryContext, gr, cr, group, role, request.UserID, request.Role); err != nil {
+			return err
+		}
+		return g.updateAdmins(queryContext, gr, group, group.GroupName, false)
+	})
+
+	if err != nil {
 		errorResponse := responseModel.ErrorResponse{
 			Status:       g.errorMap[err],
 			ErrorMessage: err.Error(),
@@ -255,11 +554,50 @@ func (g *GroupService) LeaveGroup(ctx context.Context, groupID, userID string) (
 	return &successResponse, nil
 }
 
-func (g *GroupService) DeleteGroup(ctx context.Context, groupID, userID string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+func (g *GroupService) TransferOwnership(ctx context.Context, request *model.TransferOwnershipRequest, groupID, userID string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	if request.UserID == userID {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       g.errorMap[custom_error.ErrInvalidParameter],
+			ErrorMessage: custom_error.ErrInvalidParameter.Error(),
+		}
+		return nil, &errorResponse
+	}
+
 	queryContext, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
-	group, err := g.groupRepo.Get(queryContext, groupID)
+	err := g.execTx(ctx, func(gr *repository.GroupRepo, cr *repository.ConversationRepo) error {
+		group, gErr := gr.GetForUpdate(queryContext, groupID)
+		if gErr != nil {
+			return gErr
+		}
+
+		role, rErr := gr.GetRole(queryContext, groupID, userID)
+		if rErr != nil {
+			return rErr
+		}
+		if role != model.ROLE_OWNER {
+			return custom_error.ErrNoPermission
+		}
+
+		isMember, mErr := g.isMember(queryContext, cr, group.ConversationID, request.UserID)
+		if mErr != nil {
+			return mErr
+		}
+		if !isMember {
+			return custom_error.ErrInvalidParameter
+		}
+
+		// The previous owner has to step down first, a group has only one owner
+		if err := gr.SetRole(queryContext, groupID, userID, model.ROLE_ADMIN); err != nil {
+			return err
+		}
+		if err := gr.SetRole(queryContext, groupID, request.UserID, model.ROLE_OWNER); err != nil {
+			return err
+		}
+		return g.updateAdmins(queryContext, gr, group, group.GroupName, false)
+	})
+
 	if err != nil {
 		errorResponse := responseModel.ErrorResponse{
 			Status:       g.errorMap[err],
@@ -268,23 +606,107 @@ func (g *GroupService) DeleteGroup(ctx context.Context, groupID, userID string)
 		return nil, &errorResponse
 	}
 
-	if !g.isInGroup(userID, group.Admins) {
+	successResponse := responseModel.SuccessResponse{
+		Status: http.StatusNoContent,
+	}
+	return &successResponse, nil
+}
+
+func (g *GroupService) LeaveGroup(ctx context.Context, groupID, userID string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	var (
+		conversationID   string
+		conversationType string
+		deleted          bool
+	)
+	queryContext, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := g.execTx(ctx, func(gr *repository.GroupRepo, cr *repository.ConversationRepo) error {
+		group, gErr := gr.GetForUpdate(queryContext, groupID)
+		if gErr != nil {
+			return gErr
+		}
+		conversationID = group.ConversationID
+		conversationType = group.Type
+
+		role, rErr := gr.GetRole(queryContext, groupID, userID)
+		if rErr != nil {
+			return rErr
+		}
+
+		if err := cr.RemoveMembers(queryContext, group.ConversationID, []string{userID}); err != nil {
+			return err
+		}
+		if err := gr.RemoveRoles(queryContext, groupID, []string{userID}); err != nil {
+			return err
+		}
+		if role != model.ROLE_OWNER {
+			return g.updateAdmins(queryContext, gr, group, group.GroupName, false)
+		}
+
+		successor, sErr := g.findSuccessor(queryContext, gr, cr, group)
+		if sErr != nil {
+			return sErr
+		}
+		if successor == "" { // Nobody is left, the group goes away with its last member
+			deleted = true
+			return g.updateAdmins(queryContext, gr, group, group.GroupName, true)
+		}
+		if err := gr.SetRole(queryContext, groupID, successor, model.ROLE_OWNER); err != nil {
+			return err
+		}
+		return g.updateAdmins(queryContext, gr, group, group.GroupName, false)
+	})
+
+	if err != nil {
 		errorResponse := responseModel.ErrorResponse{
-			Status:       g.errorMap[custom_error.ErrNoPermission],
-			ErrorMessage: custom_error.ErrNoPermission.Error(),
+			Status:       g.errorMap[err],
+			ErrorMessage: err.Error(),
 		}
 		return nil, &errorResponse
 	}
+	if err := g.membershipEventRepo.Publish(conversationID, membership.ActionRemove, []string{userID}); err != nil {
+		g.logger.Errorf("[LeaveGroup] Cannot publish membership event of conversation %s: %v", conversationID, err)
+	}
+	if deleted {
+		if err := g.membershipEventRepo.Publish(conversationID, membership.ActionDelete, nil); err != nil {
+			g.logger.Errorf("[LeaveGroup] Cannot publish membership event of conversation %s: %v", conversationID, err)
+		}
+	} else if conversationType != model.CONVERSATION_TYPE_CHANNEL { // Subscribers leaving a channel are not worth a message
+		if err := g.systemEventRepo.PublishAll(ctx, g.userEvents(userID, conversationID, model.EVENT_LEAVE, []string{userID})); err != nil {
+			g.logger.Errorf("[LeaveGroup] Cannot publish system events of conversation %s: %v", conversationID, err)
+		}
+	}
 
-	params := model.UpdateGroupParams{
-		ID:          groupID,
-		GroupName:   group.GroupName,
-		LastUpdated: time.Now(),
-		Deleted:     true,
-		Admins:      group.Admins,
+	successResponse := responseModel.SuccessResponse{
+		Status: http.StatusNoContent,
 	}
+	return &successResponse, nil
+}
+
+func (g *GroupService) DeleteGroup(ctx context.Context, groupID, userID string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	var conversationID string
+	queryContext, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := g.execTx(ctx, func(gr *repository.GroupRepo, cr *repository.ConversationRepo) error {
+		group, gErr := gr.GetForUpdate(queryContext, groupID)
+		if gErr != nil {
+			return gErr
+		}
+		conversationID = group.ConversationID
+
+		role, rErr := gr.GetRole(queryContext, groupID, userID)
+		if rErr != nil {
+			return rErr
+		}
+		if !model.HasPermission(role, model.PERMISSION_DELETE_GROUP) {
+			return custom_error.ErrNoPermission
+		}
+
+		return g.updateAdmins(queryContext, gr, group, group.GroupName, true)
+	})
 
-	_, err = g.groupRepo.Update(queryContext, params)
 	if err != nil {
 		errorResponse := responseModel.ErrorResponse{
 			Status:       g.errorMap[err],
@@ -292,6 +714,18 @@ func (g *GroupService) DeleteGroup(ctx context.Context, groupID, userID string)
 		}
 		return nil, &errorResponse
 	}
+	if err := g.systemEventRepo.Publish(ctx, model.SystemEvent{
+		Actor:          userID,
+		ConversationID: conversationID,
+		Action:         model.EVENT_DELETE,
+		Object:         model.EVENT_OBJECT_GROUP,
+		ObjectID:       groupID,
+	}); err != nil {
+		g.logger.Errorf("[DeleteGroup] Cannot publish system event of conversation %s: %v", conversationID, err)
+	}
+	if err := g.membershipEventRepo.Publish(conversationID, membership.ActionDelete, nil); err != nil {
+		g.logger.Errorf("[DeleteGroup] Cannot publish membership event of conversation %s: %v", conversationID, err)
+	}
 
 	successResponse := responseModel.SuccessResponse{
 		Status: http.StatusNoContent,
@@ -299,30 +733,122 @@ func (g *GroupService) DeleteGroup(ctx context.Context, groupID, userID string)
 	return &successResponse, nil
 }
 
-func (g *GroupService) createNewUserList(users []string, request []model.ChangeUser) []string {
-	currentAdminUsers := make(map[string]int, len(users))
-	for index, user := range users {
-		currentAdminUsers[user] = index
+// changeRole gives target newRole on behalf of a user holding actorRole. The
+// actor needs the promote permission and must outrank both the current and the
+// new role of the target, so nobody can grant more than they have.
+func (g *GroupService) changeRole(ctx context.Context, gr *repository.GroupRepo, cr *repository.ConversationRepo, group *model.Group, actorRole, target, newRole string) error {
+	if !model.IsValidRole(newRole) || newRole == model.ROLE_OWNER {
+		return custom_error.ErrInvalidParameter
+	}
+	if !model.HasPermission(actorRole, model.PERMISSION_PROMOTE) || !model.Outranks(actorRole, newRole) {
+		return custom_error.ErrNoPermission
 	}
-	var result []string
-	copy(result, users)
 
-	for _, r := range request {
-		if r.Action == "add" {
-			for _, user := range r.Users {
-				if _, ok := currentAdminUsers[user]; !ok {
-					result = append(result, user)
-				}
+	targetRole, err := gr.GetRole(ctx, group.ID, target)
+	if err != nil {
+		return err
+	}
+	if !model.Outranks(actorRole, targetRole) {
+		return custom_error.ErrNoPermission
+	}
+
+	isMember, err := g.isMember(ctx, cr, group.ConversationID, target)
+	if err != nil {
+		return err
+	}
+	if !isMember {
+		return custom_error.ErrInvalidParameter
+	}
+
+	return gr.SetRole(ctx, group.ID, target, newRole)
+}
+
+// findSuccessor picks the next owner: the longest serving admin, then
+// moderator, then any remaining member.
+func (g *GroupService) findSuccessor(ctx context.Context, gr *repository.GroupRepo, cr *repository.ConversationRepo, group *model.Group) (string, error) {
+	roles, err := gr.GetRoles(ctx, group.ID)
+	if err != nil {
+		return "", err
+	}
+	for _, candidate := range []string{model.ROLE_ADMIN, model.ROLE_MODERATOR} {
+		for _, role := range roles {
+			if role.Role == candidate {
+				return role.UserID, nil
 			}
 		}
+	}
 
-		if r.Action == "remove" {
-			for _, user := range r.Users {
-				if i, ok := currentAdminUsers[user]; ok {
-					result = append(result[:i], result[i+1:]...)
-				}
-			}
+	conversation, err := cr.GetMembers(ctx, group.ConversationID)
+	if err != nil {
+		return "", err
+	}
+	if len(conversation.Members) == 0 {
+		return "", nil
+	}
+	return conversation.Members[0], nil
+}
+
+// updateAdmins keeps the admins column in line with group_roles and saves the
+// other group fields at the same time.
+func (g *GroupService) updateAdmins(ctx context.Context, gr *repository.GroupRepo, group *model.Group, groupName string, deleted bool) error {
+	roles, err := gr.GetRoles(ctx, group.ID)
+	if err != nil {
+		return err
+	}
+
+	var admins []string
+	for _, role := range roles {
+		if role.Role == model.ROLE_OWNER || role.Role == model.ROLE_ADMIN {
+			admins = append(admins, role.UserID)
 		}
 	}
-	return result
+	if len(admins) == 0 {
+		admins = group.Admins
+	}
+
+	_, err = gr.Update(ctx, model.UpdateGroupParams{
+		ID:          group.ID,
+		GroupName:   groupName,
+		Description: group.Description,
+		Avatar:      group.Avatar,
+		LastUpdated: time.Now(),
+		Deleted:     deleted,
+		Admins:      admins,
+	})
+	return err
+}
+
+func (g *GroupService) userEvents(actor, conversationID, action string, users []string) []model.SystemEvent {
+	events := make([]model.SystemEvent, len(users))
+	for i, user := range users {
+		events[i] = model.SystemEvent{
+			Actor:          actor,
+			ConversationID: conversationID,
+			Action:         action,
+			Object:         model.EVENT_OBJECT_USER,
+			ObjectID:       user,
+		}
+	}
+	return events
+}
+
+// checkNotBlockedBy refuses to let actor add users who blocked them.
+func (g *GroupService) checkNotBlockedBy(ctx context.Context, actor string, users []string) error {
+	for _, user := range users {
+		if user == actor {
+			continue
+		}
+		blocked, err := g.blockRepo.IsBlockedBy(ctx, actor, user)
+		if err != nil {
+			return err
+		}
+		if blocked {
+			return custom_error.ErrNoPermission
+		}
+	}
+	return nil
+}
+
+func (g *GroupService) isMember(ctx context.Context, cr *repository.ConversationRepo, conversationID, userID string) (bool, error) {
+	return cr.IsMember(ctx, conversationID, userID)
 }
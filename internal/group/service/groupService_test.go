@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"graduation-thesis/internal/group/model"
+	"graduation-thesis/pkg/custom_error"
+)
+
+func groupMembers(n int) ([]string, map[string]string) {
+	members := make([]string, n)
+	roles := make(map[string]string, n)
+	ranked := []string{model.ROLE_OWNER, model.ROLE_ADMIN, model.ROLE_MODERATOR}
+	for i := range members {
+		members[i] = fmt.Sprintf("user-%d", i)
+		roles[members[i]] = model.ROLE_MEMBER
+		if i < len(ranked) {
+			roles[members[i]] = ranked[i]
+		}
+	}
+	return members, roles
+}
+
+func TestConvertToGroupNeedsNewMembers(t *testing.T) {
+	// The request is refused before the conversation is read.
+	g := &GroupService{errorMap: custom_error.MappingError()}
+
+	for _, members := range [][]string{nil, {}} {
+		_, errorResponse := g.ConvertToGroup(context.Background(), &model.ConvertToGroupRequest{GroupName: "pair", Members: members}, "conv-1", "user-1")
+		if errorResponse == nil || errorResponse.ErrorMessage != custom_error.ErrInvalidParameter.Error() {
+			t.Fatalf("converting with members %v = %+v, want an invalid parameter", members, errorResponse)
+		}
+	}
+}
+
+// BenchmarkIsInGroup checks the last of 500 members, the worst case.
+func BenchmarkIsInGroup(b *testing.B) {
+	g := &GroupService{}
+	members, _ := groupMembers(500)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if !g.isInGroup("user-499", members) {
+			b.Fatal("user-499 is in the group")
+		}
+	}
+}
+
+// BenchmarkPermissionChecks runs the checks made before posting and before
+// changing a role for every member of a 500 member group.
+func BenchmarkPermissionChecks(b *testing.B) {
+	members, roles := groupMembers(500)
+	settings := model.DefaultGroupSettings()
+	actorRole := roles["user-1"]
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, member := range members {
+			role := roles[member]
+			_ = settings.CanPost(role)
+			_ = model.HasPermission(actorRole, model.PERMISSION_PROMOTE) && model.Outranks(actorRole, role)
+		}
+	}
+}
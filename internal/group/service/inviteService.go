@@ -0,0 +1,416 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"graduation-thesis/internal/group/model"
+	"graduation-thesis/internal/group/repository"
+	"graduation-thesis/pkg/custom_error"
+	"graduation-thesis/pkg/logger"
+	"graduation-thesis/pkg/membership"
+	responseModel "graduation-thesis/pkg/model"
+	"net/http"
+	"time"
+
+	"github.com/twinj/uuid"
+)
+
+const inviteTokenLength = 16
+
+type InviteService struct {
+	db                  *sql.DB
+	inviteRepo          *repository.InviteRepo
+	groupRepo           *repository.GroupRepo
+	conversationRepo    *repository.ConversationRepo
+	membershipEventRepo *repository.MembershipEventRepo
+	systemEventRepo     *repository.SystemEventRepo
+	errorMap            map[error]int
+	logger              logger.Logger
+}
+
+func NewInviteService(
+	db *sql.DB,
+	inviteRepo *repository.InviteRepo,
+	groupRepo *repository.GroupRepo,
+	conversationRepo *repository.ConversationRepo,
+	membershipEventRepo *repository.MembershipEventRepo,
+	systemEventRepo *repository.SystemEventRepo,
+	errorMap map[error]int,
+	logger logger.Logger) *InviteService {
+	return &InviteService{
+		db:                  db,
+		inviteRepo:          inviteRepo,
+		groupRepo:           groupRepo,
+		conversationRepo:    conversationRepo,
+		membershipEventRepo: membershipEventRepo,
+		systemEventRepo:     systemEventRepo,
+		errorMap:            errorMap,
+		logger:              logger,
+	}
+}
+
+func (i *InviteService) execTx(ctx context.Context, fn func(*repository.InviteRepo, *repository.GroupRepo, *repository.ConversationRepo) error) error {
+	tx, err := i.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	err = fn(i.inviteRepo.WithTx(tx), i.groupRepo.WithTx(tx), i.conversationRepo.WithTx(tx))
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (i *InviteService) CreateInviteLink(ctx context.Context, request *model.CreateInviteLinkRequest, groupID, userID string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	queryContext, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	if err := i.checkPermission(queryContext, i.groupRepo, groupID, userID); err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       i.errorMap[err],
+			ErrorMessage: err.Error(),
+		}
+		return nil, &errorResponse
+	}
+
+	token, err := i.newToken()
+	if err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       http.StatusInternalServerError,
+			ErrorMessage: err.Error(),
+		}
+		return nil, &errorResponse
+	}
+
+	now := time.Now()
+	link := model.InviteLink{
+		ID:              uuid.NewV4().String(),
+		GroupID:         groupID,
+		Token:           token,
+		CreatedBy:       userID,
+		CreatedAt:       now,
+		MaxUses:         request.MaxUses,
+		RequireApproval: request.RequireApproval,
+	}
+	if request.ExpiresIn > 0 {
+		expiresAt := now.Add(time.Duration(request.ExpiresIn) * time.Second)
+		link.ExpiresAt = &expiresAt
+	}
+
+	if err := i.inviteRepo.CreateLink(queryContext, link); err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       i.errorMap[err],
+			ErrorMessage: err.Error(),
+		}
+		return nil, &errorResponse
+	}
+
+	successResponse := responseModel.SuccessResponse{
+		Status: http.StatusCreated,
+		Result: link,
+	}
+	return &successResponse, nil
+}
+
+func (i *InviteService) GetInviteLinks(ctx context.Context, groupID, userID string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	queryContext, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	if err := i.checkPermission(queryContext, i.groupRepo, groupID, userID); err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       i.errorMap[err],
+			ErrorMessage: err.Error(),
+		}
+		return nil, &errorResponse
+	}
+
+	links, err := i.inviteRepo.GetLinks(queryContext, groupID)
+	if err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       i.errorMap[err],
+			ErrorMessage: err.Error(),
+		}
+		return nil, &errorResponse
+	}
+
+	successResponse := responseModel.SuccessResponse{
+		Status: http.StatusOK,
+		Result: links,
+	}
+	return &successResponse, nil
+}
+
+func (i *InviteService) RevokeInviteLink(ctx context.Context, groupID, linkID, userID string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	queryContext, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	if err := i.checkPermission(queryContext, i.groupRepo, groupID, userID); err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       i.errorMap[err],
+			ErrorMessage: err.Error(),
+		}
+		return nil, &errorResponse
+	}
+
+	if err := i.inviteRepo.RevokeLink(queryContext, groupID, linkID); err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       i.errorMap[err],
+			ErrorMessage: err.Error(),
+		}
+		return nil, &errorResponse
+	}
+
+	successResponse := responseModel.SuccessResponse{
+		Status: http.StatusNoContent,
+	}
+	return &successResponse, nil
+}
+
+// JoinGroup adds the user to the group behind token, or files a join request
+// when the link requires an admin to approve it.
+func (i *InviteService) JoinGroup(ctx context.Context, token, userID string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	queryContext, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var response model.JoinGroupResponse
+	err := i.execTx(ctx, func(ir *repository.InviteRepo, gr *repository.GroupRepo, cr *repository.ConversationRepo) error {
+		link, lErr := ir.GetLinkByTokenForUpdate(queryContext, token)
+		if lErr != nil {
+			return lErr
+		}
+		if !link.IsUsable(time.Now()) {
+			return custom_error.ErrGone
+		}
+
+		group, gErr := gr.GetForUpdate(queryContext, link.GroupID)
+		if gErr == custom_error.ErrNotFound {
+			return custom_error.ErrGone
+		}
+		if gErr != nil {
+			return gErr
+		}
+
+		conversation, cErr := cr.GetMembers(queryContext, group.ConversationID)
+		if cErr != nil {
+			return cErr
+		}
+		for _, member := range conversation.Members {
+			if member == userID {
+				return custom_error.ErrConflict
+			}
+		}
+
+		if err := checkNotBanned(queryContext, gr, group.ID, []string{userID}); err != nil {
+			return err
+		}
+
+		// A pending request reserves a use as well, otherwise a limited link
+		// could collect more requests than it is allowed to admit
+		if err := ir.IncreaseUses(queryContext, link.ID); err != nil {
+			return err
+		}
+
+		response.GroupID = group.ID
+		if link.RequireApproval {
+			now := time.Now()
+			joinRequest := model.JoinRequest{
+				ID:          uuid.NewV4().String(),
+				GroupID:     group.ID,
+				UserID:      userID,
+				LinkID:      link.ID,
+				Status:      model.JOIN_REQUEST_PENDING,
+				CreatedAt:   now,
+				LastUpdated: now,
+			}
+			response.Status = model.JOIN_REQUEST_PENDING
+			response.JoinRequestID = joinRequest.ID
+			return ir.CreateJoinRequest(queryContext, joinRequest)
+		}
+
+		response.Status = model.JOIN_REQUEST_APPROVED
+		response.ConversationID = group.ConversationID
+		return cr.AddMembers(queryContext, group.ConversationID, []string{userID})
+	})
+
+	if err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       i.errorMap[err],
+			ErrorMessage: err.Error(),
+		}
+		return nil, &errorResponse
+	}
+
+	status := http.StatusAccepted
+	if response.Status == model.JOIN_REQUEST_APPROVED {
+		status = http.StatusOK
+		if err := i.membershipEventRepo.Publish(response.ConversationID, membership.ActionAdd, []string{userID}); err != nil {
+			i.logger.Errorf("[JoinGroup] Cannot publish membership event of conversation %s: %v", response.ConversationID, err)
+		}
+		if err := i.systemEventRepo.Publish(ctx, model.SystemEvent{
+			Actor:          userID,
+			ConversationID: response.ConversationID,
+			Action:         model.EVENT_JOIN,
+			Object:         model.EVENT_OBJECT_USER,
+			ObjectID:       userID,
+		}); err != nil {
+			i.logger.Errorf("[JoinGroup] Cannot publish system event of conversation %s: %v", response.ConversationID, err)
+		}
+	}
+
+	successResponse := responseModel.SuccessResponse{
+		Status: status,
+		Result: response,
+	}
+	return &successResponse, nil
+}
+
+func (i *InviteService) GetJoinRequests(ctx context.Context, groupID, userID string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	queryContext, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	if err := i.checkPermission(queryContext, i.groupRepo, groupID, userID); err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       i.errorMap[err],
+			ErrorMessage: err.Error(),
+		}
+		return nil, &errorResponse
+	}
+
+	requests, err := i.inviteRepo.GetPendingJoinRequests(queryContext, groupID)
+	if err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       i.errorMap[err],
+			ErrorMessage: err.Error(),
+		}
+		return nil, &errorResponse
+	}
+
+	successResponse := responseModel.SuccessResponse{
+		Status: http.StatusOK,
+		Result: requests,
+	}
+	return &successResponse, nil
+}
+
+func (i *InviteService) HandleJoinRequest(ctx context.Context, request *model.HandleJoinRequestRequest, groupID, requestID, userID string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	queryContext, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var (
+		conversationID string
+		joinedUser     string
+	)
+	err := i.execTx(ctx, func(ir *repository.InviteRepo, gr *repository.GroupRepo, cr *repository.ConversationRepo) error {
+		group, gErr := gr.GetForUpdate(queryContext, groupID)
+		if gErr != nil {
+			return gErr
+		}
+		if err := i.checkPermission(queryContext, gr, groupID, userID); err != nil {
+			return err
+		}
+
+		joinRequest, jErr := ir.GetJoinRequestForUpdate(queryContext, groupID, requestID)
+		if jErr != nil {
+			return jErr
+		}
+		if joinRequest.Status != model.JOIN_REQUEST_PENDING {
+			return custom_error.ErrConflict
+		}
+
+		if request.Action == "reject" {
+			return ir.UpdateJoinRequestStatus(queryContext, requestID, model.JOIN_REQUEST_REJECTED, userID)
+		}
+
+		conversation, cErr := cr.GetMembers(queryContext, group.ConversationID)
+		if cErr != nil {
+			return cErr
+		}
+		// The user may have been added by someone else in the meantime
+		for _, member := range conversation.Members {
+			if member == joinRequest.UserID {
+				return ir.UpdateJoinRequestStatus(queryContext, requestID, model.JOIN_REQUEST_APPROVED, userID)
+			}
+		}
+
+		// The user may have been banned while the request was pending
+		if err := checkNotBanned(queryContext, gr, groupID, []string{joinRequest.UserID}); err != nil {
+			return err
+		}
+		if err := cr.AddMembers(queryContext, group.ConversationID, []string{joinRequest.UserID}); err != nil {
+			return err
+		}
+		conversationID = group.ConversationID
+		joinedUser = joinRequest.UserID
+		return ir.UpdateJoinRequestStatus(queryContext, requestID, model.JOIN_REQUEST_APPROVED, userID)
+	})
+
+	if err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       i.errorMap[err],
+			ErrorMessage: err.Error(),
+		}
+		return nil, &errorResponse
+	}
+	if joinedUser != "" {
+		if err := i.membershipEventRepo.Publish(conversationID, membership.ActionAdd, []string{joinedUser}); err != nil {
+			i.logger.Errorf("[HandleJoinRequest] Cannot publish membership event of conversation %s: %v", conversationID, err)
+		}
+		if err := i.systemEventRepo.Publish(ctx, model.SystemEvent{
+			Actor:          userID,
+			ConversationID: conversationID,
+			Action:         model.EVENT_ADD,
+			Object:         model.EVENT_OBJECT_USER,
+			ObjectID:       joinedUser,
+		}); err != nil {
+			i.logger.Errorf("[HandleJoinRequest] Cannot publish system event of conversation %s: %v", conversationID, err)
+		}
+	}
+
+	successResponse := responseModel.SuccessResponse{
+		Status: http.StatusNoContent,
+	}
+	return &successResponse, nil
+}
+
+func (i *InviteService) checkPermission(ctx context.Context, gr *repository.GroupRepo, groupID, userID string) error {
+	group, err := gr.Get(ctx, groupID)
+	if err != nil {
+		return err
+	}
+
+	role, err := gr.GetRole(ctx, groupID, userID)
+	if err != nil {
+		return err
+	}
+	if !group.Settings.CanAddMembers(role) {
+		return custom_error.ErrNoPermission
+	}
+	if role != model.ROLE_MEMBER {
+		return nil
+	}
+
+	// Outsiders have the member role as well
+	conversation, err := i.conversationRepo.GetMembers(ctx, group.ConversationID)
+	if err != nil {
+		return err
+	}
+	for _, member := range conversation.Members {
+		if member == userID {
+			return nil
+		}
+	}
+	return custom_error.ErrNoPermission
+}
+
+func (i *InviteService) newToken() (string, error) {
+	buf := make([]byte, inviteTokenLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,313 @@
+package service
+
+import (
+	"context"
+	"graduation-thesis/internal/group/model"
+	"graduation-thesis/internal/group/repository"
+	"graduation-thesis/pkg/custom_error"
+	"graduation-thesis/pkg/membership"
+	responseModel "graduation-thesis/pkg/model"
+	"net/http"
+	"time"
+)
+
+const maxModerationLogLimit = 100
+
+func (g *GroupService) Kick(ctx context.Context, request *model.KickRequest, groupID, userID string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	var conversationID string
+	queryContext, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := g.execTx(ctx, func(gr *repository.GroupRepo, cr *repository.ConversationRepo) error {
+		group, gErr := g.moderate(queryContext, gr, groupID, userID, request.UserID, model.PERMISSION_REMOVE_MEMBERS)
+		if gErr != nil {
+			return gErr
+		}
+		conversationID = group.ConversationID
+
+		isMember, mErr := g.isMember(queryContext, cr, group.ConversationID, request.UserID)
+		if mErr != nil {
+			return mErr
+		}
+		if !isMember {
+			return custom_error.ErrInvalidParameter
+		}
+
+		if err := g.removeMember(queryContext, gr, cr, group, request.UserID); err != nil {
+			return err
+		}
+		return g.appendModerationLog(queryContext, gr, groupID, userID, model.MODERATION_KICK, request.UserID, request.Reason, nil)
+	})
+
+	if err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       g.errorMap[err],
+			ErrorMessage: err.Error(),
+		}
+		return nil, &errorResponse
+	}
+	if err := g.membershipEventRepo.Publish(conversationID, membership.ActionRemove, []string{request.UserID}); err != nil {
+		g.logger.Errorf("[Kick] Cannot publish membership event of conversation %s: %v", conversationID, err)
+	}
+	if err := g.systemEventRepo.PublishAll(ctx, g.userEvents(userID, conversationID, model.EVENT_KICK, []string{request.UserID})); err != nil {
+		g.logger.Errorf("[Kick] Cannot publish system events of conversation %s: %v", conversationID, err)
+	}
+
+	successResponse := responseModel.SuccessResponse{
+		Status: http.StatusNoContent,
+	}
+	return &successResponse, nil
+}
+
+// Ban removes the user from the group if they are in it and keeps them from
+// joining again until the ban expires or is lifted. Users who are not in the
+// group can be banned as well.
+func (g *GroupService) Ban(ctx context.Context, request *model.RestrictRequest, groupID, userID string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	var (
+		conversationID string
+		removed        bool
+	)
+	queryContext, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := g.execTx(ctx, func(gr *repository.GroupRepo, cr *repository.ConversationRepo) error {
+		group, gErr := g.moderate(queryContext, gr, groupID, userID, request.UserID, model.PERMISSION_BAN)
+		if gErr != nil {
+			return gErr
+		}
+		conversationID = group.ConversationID
+
+		isMember, mErr := g.isMember(queryContext, cr, group.ConversationID, request.UserID)
+		if mErr != nil {
+			return mErr
+		}
+		if isMember {
+			if err := g.removeMember(queryContext, gr, cr, group, request.UserID); err != nil {
+				return err
+			}
+			removed = true
+		}
+
+		return g.restrict(queryContext, gr, groupID, userID, model.RESTRICTION_BAN, model.MODERATION_BAN, request)
+	})
+
+	if err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       g.errorMap[err],
+			ErrorMessage: err.Error(),
+		}
+		return nil, &errorResponse
+	}
+	if removed {
+		if err := g.membershipEventRepo.Publish(conversationID, membership.ActionRemove, []string{request.UserID}); err != nil {
+			g.logger.Errorf("[Ban] Cannot publish membership event of conversation %s: %v", conversationID, err)
+		}
+		if err := g.systemEventRepo.PublishAll(ctx, g.userEvents(userID, conversationID, model.EVENT_BAN, []string{request.UserID})); err != nil {
+			g.logger.Errorf("[Ban] Cannot publish system events of conversation %s: %v", conversationID, err)
+		}
+	}
+
+	successResponse := responseModel.SuccessResponse{
+		Status: http.StatusNoContent,
+	}
+	return &successResponse, nil
+}
+
+func (g *GroupService) Unban(ctx context.Context, groupID, target, userID string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	return g.lift(ctx, groupID, target, userID, model.PERMISSION_BAN, model.RESTRICTION_BAN, model.MODERATION_UNBAN)
+}
+
+// Mute keeps a member from posting until the mute expires or is lifted.
+func (g *GroupService) Mute(ctx context.Context, request *model.RestrictRequest, groupID, userID string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	queryContext, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := g.execTx(ctx, func(gr *repository.GroupRepo, cr *repository.ConversationRepo) error {
+		group, gErr := g.moderate(queryContext, gr, groupID, userID, request.UserID, model.PERMISSION_MUTE)
+		if gErr != nil {
+			return gErr
+		}
+
+		isMember, mErr := g.isMember(queryContext, cr, group.ConversationID, request.UserID)
+		if mErr != nil {
+			return mErr
+		}
+		if !isMember {
+			return custom_error.ErrInvalidParameter
+		}
+
+		return g.restrict(queryContext, gr, groupID, userID, model.RESTRICTION_MUTE, model.MODERATION_MUTE, request)
+	})
+
+	if err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       g.errorMap[err],
+			ErrorMessage: err.Error(),
+		}
+		return nil, &errorResponse
+	}
+
+	successResponse := responseModel.SuccessResponse{
+		Status: http.StatusNoContent,
+	}
+	return &successResponse, nil
+}
+
+func (g *GroupService) Unmute(ctx context.Context, groupID, target, userID string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	return g.lift(ctx, groupID, target, userID, model.PERMISSION_MUTE, model.RESTRICTION_MUTE, model.MODERATION_UNMUTE)
+}
+
+func (g *GroupService) GetModerationLog(ctx context.Context, groupID, userID string, before int64, limit int) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	if limit <= 0 || limit > maxModerationLogLimit || before < 0 {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       g.errorMap[custom_error.ErrInvalidParameter],
+			ErrorMessage: custom_error.ErrInvalidParameter.Error(),
+		}
+		return nil, &errorResponse
+	}
+
+	queryContext, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var entries []model.ModerationLogEntry
+	_, err := g.groupRepo.Get(queryContext, groupID)
+	if err == nil {
+		var role string
+		role, err = g.groupRepo.GetRole(queryContext, groupID, userID)
+		if err == nil && !model.HasPermission(role, model.PERMISSION_VIEW_AUDIT_LOG) {
+			err = custom_error.ErrNoPermission
+		}
+	}
+	if err == nil {
+		entries, err = g.groupRepo.GetModerationLog(queryContext, groupID, before, limit)
+	}
+
+	if err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       g.errorMap[err],
+			ErrorMessage: err.Error(),
+		}
+		return nil, &errorResponse
+	}
+
+	successResponse := responseModel.SuccessResponse{
+		Status: http.StatusOK,
+		Result: entries,
+	}
+	return &successResponse, nil
+}
+
+// moderate locks the group and checks that actor holds permission and
+// outranks target.
+func (g *GroupService) moderate(ctx context.Context, gr *repository.GroupRepo, groupID, actor, target, permission string) (*model.Group, error) {
+	group, err := gr.GetForUpdate(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	role, err := gr.GetRole(ctx, groupID, actor)
+	if err != nil {
+		return nil, err
+	}
+	if !model.HasPermission(role, permission) {
+		return nil, custom_error.ErrNoPermission
+	}
+
+	targetRole, err := gr.GetRole(ctx, groupID, target)
+	if err != nil {
+		return nil, err
+	}
+	if !model.Outranks(role, targetRole) {
+		return nil, custom_error.ErrNoPermission
+	}
+
+	return group, nil
+}
+
+func (g *GroupService) removeMember(ctx context.Context, gr *repository.GroupRepo, cr *repository.ConversationRepo, group *model.Group, userID string) error {
+	if err := cr.RemoveMembers(ctx, group.ConversationID, []string{userID}); err != nil {
+		return err
+	}
+	if err := gr.RemoveRoles(ctx, group.ID, []string{userID}); err != nil {
+		return err
+	}
+	return g.updateAdmins(ctx, gr, group, group.GroupName, false)
+}
+
+func (g *GroupService) restrict(ctx context.Context, gr *repository.GroupRepo, groupID, actor, restrictionType, action string, request *model.RestrictRequest) error {
+	now := time.Now()
+	var expiresAt *time.Time
+	if request.ExpiresIn > 0 {
+		expires := now.Add(time.Duration(request.ExpiresIn) * time.Second)
+		expiresAt = &expires
+	}
+
+	err := gr.SetRestriction(ctx, model.Restriction{
+		GroupID:   groupID,
+		UserID:    request.UserID,
+		Type:      restrictionType,
+		Reason:    request.Reason,
+		CreatedBy: actor,
+		CreatedAt: now,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return err
+	}
+	return g.appendModerationLog(ctx, gr, groupID, actor, action, request.UserID, request.Reason, expiresAt)
+}
+
+func (g *GroupService) lift(ctx context.Context, groupID, target, userID, permission, restrictionType, action string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	queryContext, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	err := g.execTx(ctx, func(gr *repository.GroupRepo, cr *repository.ConversationRepo) error {
+		if _, err := g.moderate(queryContext, gr, groupID, userID, target, permission); err != nil {
+			return err
+		}
+		if err := gr.RemoveRestriction(queryContext, groupID, target, restrictionType); err != nil {
+			return err
+		}
+		return g.appendModerationLog(queryContext, gr, groupID, userID, action, target, "", nil)
+	})
+
+	if err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       g.errorMap[err],
+			ErrorMessage: err.Error(),
+		}
+		return nil, &errorResponse
+	}
+
+	successResponse := responseModel.SuccessResponse{
+		Status: http.StatusNoContent,
+	}
+	return &successResponse, nil
+}
+
+func (g *GroupService) appendModerationLog(ctx context.Context, gr *repository.GroupRepo, groupID, actor, action, target, reason string, expiresAt *time.Time) error {
+	return gr.AppendModerationLog(ctx, model.ModerationLogEntry{
+		GroupID:   groupID,
+		Actor:     actor,
+		Action:    action,
+		Target:    target,
+		Reason:    reason,
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	})
+}
+
+// checkNotBanned returns custom_error.ErrNoPermission if any of users is
+// banned from the group.
+func checkNotBanned(ctx context.Context, gr *repository.GroupRepo, groupID string, users []string) error {
+	for _, user := range users {
+		ban, err := gr.GetActiveRestriction(ctx, groupID, user, model.RESTRICTION_BAN)
+		if err != nil {
+			return err
+		}
+		if ban != nil {
+			return custom_error.ErrNoPermission
+		}
+	}
+	return nil
+}
@@ -5,31 +5,46 @@ import (
 	"sync"
 )
 
+// Frame is a message waiting to be written to a websocket handler. The writer
+// reports the outcome on Result so the sender knows the frame left the process.
+type Frame struct {
+	Message Message
+	Result  chan error
+}
+
 type ChanMessage struct {
 	mu       sync.RWMutex
 	isClosed bool
-	Channel  chan Message
+	Channel  chan Frame
 }
 
 func NewChanMessage(size int) *ChanMessage {
 	return &ChanMessage{
-		Channel: make(chan Message, size),
+		Channel: make(chan Frame, size),
 	}
 }
 
-func (c *ChanMessage) Send(message Message) error {
+func (c *ChanMessage) Send(message Message) (<-chan error, error) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	if !c.isClosed {
-		c.Channel <- message
-		return nil
+	if c.isClosed {
+		return nil, custom_error.ErrChannelHasClosed
+	}
+
+	// Never block while holding the read lock, otherwise Close could wait
+	// forever on a connection whose writer has already gone.
+	result := make(chan error, 1)
+	select {
+	case c.Channel <- Frame{Message: message, Result: result}:
+		return result, nil
+	default:
+		return nil, custom_error.ErrConnectionErr
 	}
-	return custom_error.ErrChannelHasClosed
 }
 
 func (c *ChanMessage) Close() {
 	c.mu.Lock()
-	c.mu.Unlock()
+	defer c.mu.Unlock()
 	if !c.isClosed {
 		c.isClosed = true
 		close(c.Channel)
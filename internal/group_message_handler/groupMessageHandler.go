@@ -3,12 +3,18 @@ package group_message_handler
 import (
 	"fmt"
 	"graduation-thesis/pkg/logger"
+	"graduation-thesis/pkg/membership"
+	"graduation-thesis/pkg/pb/grouppb"
+	"graduation-thesis/pkg/pb/messagepb"
+	"graduation-thesis/pkg/pb/websocketmanagerpb"
+	"graduation-thesis/pkg/rpc"
 	"graduation-thesis/pkg/storage"
 	"os"
 	"os/signal"
 	"sync"
 
 	"github.com/spf13/viper"
+	"github.com/twinj/uuid"
 )
 
 func Run() {
@@ -22,8 +28,10 @@ func Run() {
 		}
 	}
 
-	consumer := storage.NewKafkaConsumer(viper.GetString("kafka.bootstrap_servers"), viper.GetString("kafka.group_id"))
+	consumer := storage.NewKafkaManualCommitConsumer(viper.GetString("kafka.bootstrap_servers"), viper.GetString("kafka.group_id"))
 	defer consumer.Close()
+	producer := storage.NewKafkaProducer(viper.GetString("kafka.bootstrap_servers"), viper.GetInt("kafka.message_max_bytes"))
+	defer producer.Close()
 	logger, err := logger.GetLogger(
 		viper.GetString("logger.level"),
 		viper.GetString("logger.path"),
@@ -32,14 +40,27 @@ func Run() {
 		panic(err)
 	}
 
+	membershipConsumer := storage.NewKafkaBroadcastConsumer(
+		viper.GetString("kafka.bootstrap_servers"),
+		fmt.Sprintf("%s-%s", viper.GetString("kafka.membership_group_id"), uuid.NewV4().String()),
+	)
+	defer membershipConsumer.Close()
+	groupClient := grouppb.NewGroupServiceClient(rpc.GetClientConn(viper.GetString("3rd_party.group_address")))
+	membershipCache := membership.NewCache(membership.GroupLoader(groupClient, viper.GetDuration("timeout")), viper.GetDuration("membership_cache_ttl"))
+
 	worker := NewWorker(
 		consumer,
+		producer,
 		viper.GetStringSlice("kafka.topics"),
-		viper.GetString("3rd_party.group_url"),
-		viper.GetString("3rd_party.websocket_manager_url"),
+		viper.GetString("kafka.retry_topic"),
+		viper.GetString("kafka.dead_letter_topic"),
+		membershipCache,
+		messagepb.NewMessageServiceClient(rpc.GetClientConn(viper.GetString("3rd_party.message_address"))),
+		websocketmanagerpb.NewWebsocketManagerServiceClient(rpc.GetClientConn(viper.GetString("3rd_party.websocket_manager_address"))),
 		viper.GetDuration("timeout"),
 		viper.GetInt("max_retries"),
 		viper.GetDuration("retry_interval"),
+		viper.GetDuration("max_retry_interval"),
 		viper.GetDuration("ping_interval"),
 		logger,
 	)
@@ -47,7 +68,7 @@ func Run() {
 	interrupt := make(chan os.Signal, 1)
 	signal.Notify(interrupt, os.Interrupt)
 	var wg sync.WaitGroup
-	wg.Add(1)
+	wg.Add(2)
 
 	go func(wg *sync.WaitGroup) {
 		defer wg.Done()
@@ -55,6 +76,12 @@ func Run() {
 			panic(err)
 		}
 	}(&wg)
+	go func(wg *sync.WaitGroup) {
+		defer wg.Done()
+		if err := membershipCache.Listen(membershipConsumer, viper.GetString("kafka.membership_topic"), logger, worker.Done); err != nil {
+			panic(err)
+		}
+	}(&wg)
 	_ = <-interrupt
 	close(worker.Done)
 	wg.Wait()
@@ -47,3 +47,12 @@ func (m *MapMu) Del(key string) {
 	defer m.mu.Unlock()
 	delete(m.data, key)
 }
+
+func (m *MapMu) GetOrSet(key string) *sync.Mutex {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.data[key]; !ok {
+		m.data[key] = &sync.Mutex{}
+	}
+	return m.data[key]
+}
@@ -1,10 +1,14 @@
 package group_message_handler
 
+import "encoding/json"
+
 const (
 	MESSAGE_TYPE = "message"
 	EVENT_TYPE   = "event"
 )
 
+const CONVERSATION_TYPE_CHANNEL = "channel"
+
 type ConversationMessage struct {
 	ConversationID        string `json:"conv_id"`
 	ConversationMessageID int64  `json:"conv_msg_id"`
@@ -19,25 +23,29 @@ type Event struct {
 	ConversationID string `json:"conversation_id"`
 	Action         string `json:"action"`
 	Object         string `json:"object"`
-	ObjectID       string `json:"objectID"`
+	ObjectID       string `json:"object_id"`
+	Value          string `json:"value,omitempty"`
 }
 
 type KafkaMessage struct {
-	UserID         string      `json:"user_id"`
-	ConversationID string      `json:"conversation_id"`
-	Type           string      `json:"type"`
-	Timestamp      int64       `json:"timestamp"`
-	Data           interface{} `json:"data"`
+	UserID           string          `json:"user_id"`
+	ConversationID   string          `json:"conversation_id"`
+	ConversationType string          `json:"conv_type,omitempty"`
+	Type             string          `json:"type"`
+	Timestamp        int64           `json:"timestamp"`
+	Data             json.RawMessage `json:"data"`
 }
 
 type Message struct {
-	ConversationID        string `json:"conv_id" `
-	ConversationMessageID int64  `json:"conv_msg_id"`
-	MessageTime           int64  `json:"msg_time"`
-	Sender                string `json:"sender"`
-	Content               string `json:"content"`
-	IV                    string `json:"iv"`
-	Receiver              string `json:"receiver"`
+	ConversationID        string   `json:"conv_id" `
+	ConversationMessageID int64    `json:"conv_msg_id"`
+	MessageTime           int64    `json:"msg_time"`
+	Sender                string   `json:"sender"`
+	Content               string   `json:"content"`
+	IV                    string   `json:"iv"`
+	Receiver              string   `json:"receiver"`
+	Receivers             []string `json:"receivers,omitempty"`
+	Type                  string   `json:"type,omitempty"`
 }
 
 type Conversation struct {
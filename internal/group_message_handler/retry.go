@@ -0,0 +1,143 @@
+package group_message_handler
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+const (
+	ATTEMPT_HEADER    = "attempt"
+	NOT_BEFORE_HEADER = "not_before"
+	RECEIVERS_HEADER  = "receivers"
+	ERROR_HEADER      = "error"
+)
+
+// errMalformedMessage is returned for messages that no retry can deliver.
+var errMalformedMessage = errors.New("malformed message")
+
+// handleMessage processes a message until it is delivered, handed to the retry
+// topic or dead-lettered. It returns false when stop is closed before the
+// message reached one of those states.
+func (w *Worker) handleMessage(message *kafka.Message, stop <-chan struct{}) bool {
+	attempt, notBefore, receivers := w.readHeaders(message)
+	if !w.waitUntil(notBefore, stop) {
+		return false
+	}
+
+	var kafkaMessage KafkaMessage
+	if err := json.Unmarshal(message.Value, &kafkaMessage); err != nil {
+		w.logger.Errorf("[MAIN] Cannot unmarshal message at %d[%d]: %v\n", message.TopicPartition.Partition, message.TopicPartition.Offset, err)
+		return w.produce(w.deadLetterTopic, message, attempt, 0, nil, err, stop)
+	}
+	if kafkaMessage.Type != MESSAGE_TYPE && kafkaMessage.Type != EVENT_TYPE {
+		return true
+	}
+
+	failed, err := w.processMessage(&kafkaMessage, receivers)
+	if err == nil {
+		return true
+	}
+
+	if errors.Is(err, errMalformedMessage) {
+		w.logger.Errorf("[MAIN][message_%v] Cannot unmarshal data: %v", kafkaMessage.ConversationID, err)
+		return w.produce(w.deadLetterTopic, message, attempt, 0, nil, err, stop)
+	}
+	if attempt >= w.maxRetries {
+		w.logger.Errorf("[MAIN][message_%v] Giving up after %d attempts: %v", kafkaMessage.ConversationID, attempt+1, err)
+		return w.produce(w.deadLetterTopic, message, attempt, 0, failed, err, stop)
+	}
+	delay := w.backoff(attempt)
+	w.logger.Infof("[MAIN][message_%v] Retrying %d receivers in %v: %v", kafkaMessage.ConversationID, len(failed), delay, err)
+	return w.produce(w.retryTopic, message, attempt+1, time.Now().Add(delay).UnixMilli(), failed, err, stop)
+}
+
+func (w *Worker) readHeaders(message *kafka.Message) (int, int64, []string) {
+	var (
+		attempt   int
+		notBefore int64
+		receivers []string
+	)
+	for _, header := range message.Headers {
+		switch header.Key {
+		case ATTEMPT_HEADER:
+			attempt, _ = strconv.Atoi(string(header.Value))
+		case NOT_BEFORE_HEADER:
+			notBefore, _ = strconv.ParseInt(string(header.Value), 10, 64)
+		case RECEIVERS_HEADER:
+			_ = json.Unmarshal(header.Value, &receivers)
+		}
+	}
+	return attempt, notBefore, receivers
+}
+
+func (w *Worker) waitUntil(notBefore int64, stop <-chan struct{}) bool {
+	delay := time.Until(time.UnixMilli(notBefore))
+	if delay <= 0 {
+		return true
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-stop:
+		return false
+	}
+}
+
+func (w *Worker) backoff(attempt int) time.Duration {
+	delay := w.retryInterval
+	for i := 0; i < attempt && delay < w.maxRetryInterval; i++ {
+		delay *= 2
+	}
+	if delay > w.maxRetryInterval {
+		delay = w.maxRetryInterval
+	}
+	return delay
+}
+
+// produce copies message to topic and waits for the broker to acknowledge it,
+// so the original offset is never committed before its copy is stored.
+func (w *Worker) produce(topic string, message *kafka.Message, attempt int, notBefore int64, receivers []string, cause error, stop <-chan struct{}) bool {
+	headers := []kafka.Header{
+		{Key: ATTEMPT_HEADER, Value: []byte(strconv.Itoa(attempt))},
+		{Key: NOT_BEFORE_HEADER, Value: []byte(strconv.FormatInt(notBefore, 10))},
+	}
+	if len(receivers) > 0 {
+		value, _ := json.Marshal(receivers)
+		headers = append(headers, kafka.Header{Key: RECEIVERS_HEADER, Value: value})
+	}
+	if cause != nil {
+		headers = append(headers, kafka.Header{Key: ERROR_HEADER, Value: []byte(cause.Error())})
+	}
+
+	for i := 0; ; i++ {
+		deliveryChan := make(chan kafka.Event, 1)
+		err := w.producer.Produce(&kafka.Message{
+			TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+			Key:            message.Key,
+			Value:          message.Value,
+			Headers:        headers,
+		}, deliveryChan)
+		if err == nil {
+			if m, ok := (<-deliveryChan).(*kafka.Message); ok {
+				err = m.TopicPartition.Error
+			}
+		}
+		if err == nil {
+			return true
+		}
+
+		w.logger.Errorf("[MAIN] Cannot produce message at %d[%d] to %s: %v", message.TopicPartition.Partition, message.TopicPartition.Offset, topic, err)
+		select {
+		case <-time.After(w.backoff(i)):
+		case <-stop:
+			return false
+		}
+	}
+}
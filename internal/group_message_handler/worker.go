@@ -1,72 +1,115 @@
 package group_message_handler
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net"
-	"net/http"
 	"net/url"
 	"sync"
 	"time"
 
+	"graduation-thesis/pkg/custom_error"
 	"graduation-thesis/pkg/logger"
-	request "graduation-thesis/pkg/requests"
+	"graduation-thesis/pkg/membership"
+	"graduation-thesis/pkg/pb/messagepb"
+	"graduation-thesis/pkg/pb/websocketmanagerpb"
 
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
 	"github.com/gorilla/websocket"
 )
 
 type Worker struct {
-	consumer            *kafka.Consumer
-	topics              []string
-	groupURL            string
-	websocketManagerURL string
-	maxRetries          int
-	timeout             time.Duration
-	retryInterval       time.Duration
-	pingInterval        time.Duration
-	logger              logger.Logger
-	mapConnection       *MapConnection
-	mapMu               *MapMu
-	wg                  *sync.WaitGroup
-	Done                chan struct{}
+	consumer               *kafka.Consumer
+	producer               *kafka.Producer
+	topics                 []string
+	retryTopic             string
+	deadLetterTopic        string
+	membershipCache        *membership.Cache
+	messageClient          messagepb.MessageServiceClient
+	websocketManagerClient websocketmanagerpb.WebsocketManagerServiceClient
+	maxRetries             int
+	timeout                time.Duration
+	retryInterval          time.Duration
+	maxRetryInterval       time.Duration
+	pingInterval           time.Duration
+	logger                 logger.Logger
+	mapConnection          *MapConnection
+	mapMu                  *MapMu
+	partitions             map[string]*partition
+	wg                     *sync.WaitGroup
+	Done                   chan struct{}
 }
 
 func NewWorker(
 	consumer *kafka.Consumer,
-	topics []string, groupURL,
-	websocketManagerURL string,
+	producer *kafka.Producer,
+	topics []string,
+	retryTopic string,
+	deadLetterTopic string,
+	membershipCache *membership.Cache,
+	messageClient messagepb.MessageServiceClient,
+	websocketManagerClient websocketmanagerpb.WebsocketManagerServiceClient,
 	timeout time.Duration,
 	maxRetries int,
 	retryInterval time.Duration,
+	maxRetryInterval time.Duration,
 	pingInterval time.Duration,
 	logger logger.Logger) *Worker {
 	return &Worker{
-		consumer:            consumer,
-		topics:              topics,
-		groupURL:            groupURL,
-		websocketManagerURL: websocketManagerURL,
-		maxRetries:          maxRetries,
-		timeout:             timeout,
-		retryInterval:       retryInterval,
-		pingInterval:        pingInterval,
-		logger:              logger,
+		consumer:               consumer,
+		producer:               producer,
+		topics:                 topics,
+		retryTopic:             retryTopic,
+		deadLetterTopic:        deadLetterTopic,
+		membershipCache:        membershipCache,
+		messageClient:          messageClient,
+		websocketManagerClient: websocketManagerClient,
+		maxRetries:             maxRetries,
+		timeout:                timeout,
+		retryInterval:          retryInterval,
+		maxRetryInterval:       maxRetryInterval,
+		pingInterval:           pingInterval,
+		logger:                 logger,
 		mapConnection: &MapConnection{
 			data: make(map[string]*ChanMessage),
 		},
 		mapMu: &MapMu{
 			data: make(map[string]*sync.Mutex),
 		},
-		wg:   &sync.WaitGroup{},
-		Done: make(chan struct{}),
+		partitions: make(map[string]*partition),
+		wg:         &sync.WaitGroup{},
+		Done:       make(chan struct{}),
 	}
 }
 
-// rebalanceCallback is only useful when we commit manually ?
+// PARTITION_BUFFER is how many messages of a partition may wait for its
+// goroutine before the partition is paused.
+const PARTITION_BUFFER = 100
 
+// partition feeds the messages of a topic partition to the goroutine handling
+// them in order. Only the poll loop touches it, except for the channels.
+type partition struct {
+	messages chan *kafka.Message
+	stop     chan struct{}
+	done     chan struct{}
+	// paused is set when messages is full. The messages of the partition that
+	// are still polled are dropped until it is resumed from resumeFrom.
+	paused     bool
+	resumeFrom kafka.TopicPartition
+}
+
+func partitionKey(topicPartition kafka.TopicPartition) string {
+	return fmt.Sprintf("%s[%d]", *topicPartition.Topic, topicPartition.Partition)
+}
+
+// Do polls the subscribed topics and the retry topic. Each partition is handled
+// by its own goroutine so messages of a partition are processed in order, and
+// an offset is only committed once its message has been fully fanned out,
+// retried or dead-lettered.
 func (w *Worker) Do() error {
 	w.logger.Info("[MAIN] Starting Group Message Handler")
-	err := w.consumer.SubscribeTopics(w.topics, nil)
+	err := w.consumer.SubscribeTopics(append(w.topics, w.retryTopic), w.rebalance)
 	if err != nil {
 		return err
 	}
@@ -75,15 +118,18 @@ func (w *Worker) Do() error {
 		select {
 		case <-w.Done:
 			w.logger.Info("[MAIN] Shutting down Group Message Handler")
+			for key := range w.partitions {
+				w.stopPartition(key)
+			}
 			w.wg.Wait()
 			return nil
 		default:
+			w.resumePartitions()
 			event := w.consumer.Poll(100)
 			switch e := event.(type) {
 			case *kafka.Message:
 				w.logger.Infof("[MAIN] Message at %d[%d]: %v\n", e.TopicPartition.Partition, e.TopicPartition.Offset, e.Value)
-				w.processMessage(e)
-				w.logger.Infof("[MAIN] Done in processing message at %d[%d]\n", e.TopicPartition.Partition, e.TopicPartition.Offset)
+				w.dispatch(e)
 			case kafka.PartitionEOF:
 				// TODO: Study of PartitionEOF events'affect --> Normal events, just correnponded for notification
 				w.logger.Infof("[MAIN] Reached %v\n", e)
@@ -96,150 +142,298 @@ func (w *Worker) Do() error {
 	}
 }
 
-func (w *Worker) processMessage(message *kafka.Message) {
-	var kafkaMessage KafkaMessage
-	if err := json.Unmarshal(message.Value, &kafkaMessage); err != nil {
-		w.logger.Errorf("[MAIN] Cannot unmarshal message at %d[%d]: %v\n", message.TopicPartition.Partition, message.TopicPartition.Offset, err)
-		return
+// rebalance runs inside Poll. Revoked partitions are stopped before they are
+// handed to another consumer, so none of their offsets is committed by this
+// one; messages they had not committed are read again by the new owner.
+func (w *Worker) rebalance(consumer *kafka.Consumer, event kafka.Event) error {
+	revoked, ok := event.(kafka.RevokedPartitions)
+	if !ok {
+		return nil
 	}
+	w.logger.Infof("[MAIN] Partitions revoked: %v", revoked.Partitions)
+	for _, topicPartition := range revoked.Partitions {
+		w.stopPartition(partitionKey(topicPartition))
+	}
+	return nil
+}
 
-	users, err := w.getConversationUsers(kafkaMessage.ConversationID)
-	if err != nil {
-		w.logger.Errorf("[MAIN] Failed at get user in coversation %v: %v\n", kafkaMessage.ConversationID, err)
+func (w *Worker) stopPartition(key string) {
+	p, ok := w.partitions[key]
+	if !ok {
 		return
 	}
+	close(p.stop)
+	<-p.done
+	delete(w.partitions, key)
+	if p.paused {
+		// The partition may be assigned to this consumer again.
+		_ = w.consumer.Resume([]kafka.TopicPartition{p.resumeFrom})
+	}
+}
 
-	if len(users) <= 2 {
-		w.logger.Info("[MAIN] Ignored: conversation has only two members\n")
+// dispatch never blocks the poll loop, which serves every partition. When the
+// goroutine of a partition falls behind, the partition is paused and read
+// again from the first message that did not fit once it caught up.
+func (w *Worker) dispatch(message *kafka.Message) {
+	key := partitionKey(message.TopicPartition)
+	p, ok := w.partitions[key]
+	if !ok {
+		p = &partition{
+			messages: make(chan *kafka.Message, PARTITION_BUFFER),
+			stop:     make(chan struct{}),
+			done:     make(chan struct{}),
+		}
+		w.partitions[key] = p
+		go w.consumePartition(p)
+	}
+	if p.paused {
 		return
 	}
 
-	data := kafkaMessage.Data.(map[string]interface{})
+	select {
+	case p.messages <- message:
+	default:
+		w.logger.Infof("[MAIN] Pausing %s at offset %d", key, message.TopicPartition.Offset)
+		p.paused = true
+		p.resumeFrom = kafka.TopicPartition{
+			Topic:     message.TopicPartition.Topic,
+			Partition: message.TopicPartition.Partition,
+			Offset:    message.TopicPartition.Offset,
+		}
+		if err := w.consumer.Pause([]kafka.TopicPartition{p.resumeFrom}); err != nil {
+			w.logger.Errorf("[MAIN] Cannot pause %s: %v", key, err)
+		}
+	}
+}
 
-	for _, user := range users {
-		if user == kafkaMessage.UserID {
+// resumePartitions resumes the paused partitions whose goroutine has worked
+// through half of its backlog.
+func (w *Worker) resumePartitions() {
+	for key, p := range w.partitions {
+		if !p.paused || len(p.messages) > PARTITION_BUFFER/2 {
 			continue
 		}
-		message := Message{
-			ConversationID:        data["conv_id"].(string),
-			ConversationMessageID: int64(data["conv_msg_id"].(float64)),
-			MessageTime:           int64(data["msg_time"].(float64)),
-			Sender:                data["sender"].(string),
-			Content:               data["content"].(string),
-			IV:                    data["iv"].(string),
-			Receiver:              user,
+		if err := w.consumer.Seek(p.resumeFrom, 0); err != nil {
+			w.logger.Errorf("[MAIN] Cannot seek %s to offset %d: %v", key, p.resumeFrom.Offset, err)
+			continue
+		}
+		if err := w.consumer.Resume([]kafka.TopicPartition{p.resumeFrom}); err != nil {
+			w.logger.Errorf("[MAIN] Cannot resume %s: %v", key, err)
+			continue
 		}
-		go w.sendMessage(user, message)
+		p.paused = false
 	}
 }
 
-func (w *Worker) getConversationUsers(conversationID string) ([]string, error) {
-	var (
-		result interface{}
-		err    error
-	)
-	for i := 1; i <= w.maxRetries; i++ {
-		result, err = request.HTTPRequestCall(
-			fmt.Sprintf("%s/conversation/%s", w.groupURL, conversationID),
-			http.MethodGet,
-			"",
-			nil,
-			w.timeout,
-		)
-		if err != nil {
-			w.logger.Errorf("[MAIN] Failed to get users of conversation %s for %dth time: %v", conversationID, i, err.Error())
-			time.Sleep(w.retryInterval)
-			continue
+func (w *Worker) consumePartition(p *partition) {
+	defer close(p.done)
+	for {
+		select {
+		case <-p.stop:
+			return
+		case message := <-p.messages:
+			if !w.handleMessage(message, p.stop) {
+				return // Stopped, leave the offset uncommitted
+			}
+			select {
+			case <-p.stop:
+				return // Revoked while handling the message
+			default:
+			}
+			if _, err := w.consumer.CommitMessage(message); err != nil {
+				w.logger.Errorf("[MAIN] Cannot commit message at %d[%d]: %v", message.TopicPartition.Partition, message.TopicPartition.Offset, err)
+				continue
+			}
+			w.logger.Infof("[MAIN] Done in processing message at %d[%d]\n", message.TopicPartition.Partition, message.TopicPartition.Offset)
 		}
-		break
+	}
+}
+
+// processMessage fans message out to the members of its conversation. Only the
+// receivers listed in onlyReceivers are handled when it is not empty. It returns
+// the receivers whose delivery failed and should be retried. Offline subscribers
+// of a channel are skipped, they read the channel timeline when they come back.
+func (w *Worker) processMessage(kafkaMessage *KafkaMessage, onlyReceivers []string) ([]string, error) {
+	var message Message
+	if err := json.Unmarshal(kafkaMessage.Data, &message); err != nil {
+		return nil, fmt.Errorf("%w: %v", errMalformedMessage, err)
 	}
 
+	users, err := w.getConversationUsers(kafkaMessage.ConversationID)
 	if err != nil {
-		return nil, err
+		w.logger.Errorf("[MAIN] Failed at get user in coversation %v: %v\n", kafkaMessage.ConversationID, err)
+		return onlyReceivers, err
 	}
 
-	membersInterface := result.(map[string]interface{})["members"].([]interface{})
-	members := make([]string, len(membersInterface))
-	for i, value := range membersInterface {
-		members[i] = fmt.Sprintf("%v", value)
+	isChannel := kafkaMessage.ConversationType == CONVERSATION_TYPE_CHANNEL
+	if len(users) <= 2 && !isChannel {
+		w.logger.Info("[MAIN] Ignored: conversation has only two members\n")
+		return nil, nil
+	}
+
+	receivers := make([]string, 0, len(users))
+	for _, user := range users {
+		if user != kafkaMessage.UserID && (len(onlyReceivers) == 0 || contains(onlyReceivers, user)) {
+			receivers = append(receivers, user)
+		}
+	}
+	if len(receivers) == 0 {
+		return nil, nil
+	}
+
+	result, err := w.getWebsocketHandlersOfUsers(receivers)
+	if err != nil {
+		w.logger.Errorf("[MAIN] Failed at get websocket handlers of conversation %v: %v\n", kafkaMessage.ConversationID, err)
+		return receivers, err
 	}
-	return members, nil
-}
 
-func (w *Worker) getWebsocketHandlerConnectedUser(userID string) (*WebsocketHandler, error) {
 	var (
-		result           interface{}
-		err              error
-		websocketHandler WebsocketHandler
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		failed  []string
+		lastErr error
 	)
-	for i := 1; i <= w.maxRetries; i++ {
-		result, err = request.HTTPRequestCall(
-			fmt.Sprintf("%s/user/%s", w.websocketManagerURL, userID),
-			http.MethodGet,
-			"",
-			nil,
-			w.timeout,
-		)
-		if err != nil {
-			w.logger.Errorf("[MAIN] Failed to get websocket connecting to user %s for %dth time: %v", userID, i, err.Error())
-			time.Sleep(w.retryInterval)
-			continue
+	fail := func(users []string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		failed = append(failed, users...)
+		lastErr = err
+	}
+
+	if len(result.OfflineUserIds) > 0 && !isChannel {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := w.confirmUserInboxes(result.OfflineUserIds, message); err != nil {
+				fail(result.OfflineUserIds, err)
+			}
+		}()
+	}
+
+	for _, r := range result.Recipients {
+		websocketHandler := WebsocketHandler{
+			ID:        r.WebsocketHandler.Id,
+			IPAddress: r.WebsocketHandler.IpAddress,
 		}
-		break
+		frame := message
+		frame.Receiver = ""
+		frame.Receivers = r.UserIds
+		wg.Add(1)
+		go func(websocketHandler *WebsocketHandler, frame Message) {
+			defer wg.Done()
+			if err := w.sendMessage(websocketHandler, frame); err != nil {
+				fail(frame.Receivers, err)
+			}
+		}(&websocketHandler, frame)
 	}
+	wg.Wait()
+
+	return failed, lastErr
+}
 
+func (w *Worker) getConversationUsers(conversationID string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), w.timeout)
+	defer cancel()
+
+	members, err := w.membershipCache.Get(ctx, conversationID)
 	if err != nil {
+		w.logger.Errorf("[MAIN] Failed to get users of conversation %s: %v", conversationID, err)
 		return nil, err
 	}
-	websocketHandlerJSON, _ := json.Marshal(result)
-	if err := json.Unmarshal(websocketHandlerJSON, &websocketHandler); err != nil {
-		w.logger.Errorf("[getWebsocketHandlerConnectedUser] Cannot unmarshal result from websocket manager: %v", err.Error())
-		return nil, err
-	}
-	w.logger.Infof("websocket Handler is: %v", websocketHandler)
-	return &websocketHandler, nil
+
+	return members, nil
 }
 
-func (w *Worker) sendMessage(userID string, message Message) {
-	websocketHandler, err := w.getWebsocketHandlerConnectedUser(userID)
+// getWebsocketHandlersOfUsers resolves every receiver in a single call and
+// groups the online ones by the websocket handler they are connected to.
+func (w *Worker) getWebsocketHandlersOfUsers(usersID []string) (*websocketmanagerpb.GetWebsocketHandlersOfUsersResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), w.timeout)
+	defer cancel()
+
+	result, err := w.websocketManagerClient.GetWebsocketHandlersOfUsers(ctx, &websocketmanagerpb.GetWebsocketHandlersOfUsersRequest{UserIds: usersID})
 	if err != nil {
-		w.logger.Errorf(
-			"[MAIN][message_%v_%v] Failed to get websocket connecting to user %s: %v",
-			message.ConversationMessageID,
-			message.ConversationID,
-			userID,
-			err.Error(),
-		)
-		return
+		w.logger.Errorf("[MAIN] Failed to get websocket handlers connecting to %d users: %v", len(usersID), err)
+		return nil, custom_error.HandleGRPCError(err)
 	}
-	if websocketHandler.ID == "" {
-		w.logger.Infof("[MAIN][message_%v_%v] User %v is not online",
-			message.ConversationMessageID,
-			message.ConversationID,
-			userID,
-		)
-		return
+
+	return result, nil
+}
+
+// confirmUserInboxes makes sure offline users find the message in their inbox
+// instead of relying on the best-effort write done by the message service.
+func (w *Worker) confirmUserInboxes(usersID []string, message Message) error {
+	ctx, cancel := context.WithTimeout(context.Background(), w.timeout)
+	defer cancel()
+
+	_, err := w.messageClient.ConfirmUserInboxes(ctx, &messagepb.ConfirmUserInboxesRequest{
+		UserIds: usersID,
+		Message: &messagepb.ConversationMessage{
+			ConvId:    message.ConversationID,
+			ConvMsgId: message.ConversationMessageID,
+			MsgTime:   message.MessageTime,
+			Sender:    message.Sender,
+			Content:   message.Content,
+			Iv:        message.IV,
+			Type:      message.Type,
+		},
+	})
+	if err != nil {
+		w.logger.Errorf("[MAIN][message_%v_%v] Failed to confirm inbox of %d offline users: %v",
+			message.ConversationMessageID, message.ConversationID, len(usersID), err)
+		return custom_error.HandleGRPCError(err)
 	}
+	return nil
+}
 
+// sendMessage delivers one frame to a websocket handler, which expands
+// message.Receivers to its local connections, and waits until it is written.
+func (w *Worker) sendMessage(websocketHandler *WebsocketHandler, message Message) error {
 	websocketConnection := w.mapConnection.Get(websocketHandler.ID)
-	if websocketConnection != nil && websocketConnection.Send(message) == nil {
-		return
+	if websocketConnection == nil {
+		mu := w.mapMu.GetOrSet(websocketHandler.ID)
+		mu.Lock()
+		websocketConnection = w.mapConnection.Get(websocketHandler.ID)
+		if websocketConnection == nil { // Nobody has connected to the handler while we were waiting
+			if _, err := w.establishWebsocketConnection(websocketHandler); err != nil {
+				mu.Unlock()
+				w.logger.Errorf(
+					"[MAIN][message_%v_%v] Cannot establish a websocket connection to %s at %s: %v",
+					message.ConversationMessageID,
+					message.ConversationID,
+					websocketHandler.ID,
+					websocketHandler.IPAddress,
+					err,
+				)
+				return custom_error.ErrConnectionErr
+			}
+			websocketConnection = w.mapConnection.Get(websocketHandler.ID)
+		}
+		mu.Unlock()
+		if websocketConnection == nil {
+			return custom_error.ErrConnectionErr
+		}
 	}
 
-	_, eErr := w.establishWebsocketConnection(websocketHandler)
-	if eErr != nil {
-		w.logger.Errorf(
-			"[MAIN][message_%v_%v] Cannot establish a websocket connection to %s at %s: %v",
-			message.ConversationMessageID,
-			message.ConversationID,
-			websocketHandler.ID,
-			websocketHandler.IPAddress,
-			eErr,
-		)
-		return
+	result, err := websocketConnection.Send(message)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(w.timeout):
+		return custom_error.ErrTimeout
+	}
+}
+
+func contains(users []string, userID string) bool {
+	for _, user := range users {
+		if user == userID {
+			return true
+		}
 	}
-	_ = w.mapConnection.Get(websocketHandler.ID).Send(message)
-	return
+	return false
 }
 
 func (w *Worker) establishWebsocketConnection(websocketHandler *WebsocketHandler) (*websocket.Conn, error) {
@@ -279,18 +473,21 @@ func (w *Worker) keepWebsocketConnection(conn *websocket.Conn, websocketHandlerI
 	defer conn.Close()
 	timer := time.NewTimer(w.pingInterval)
 	defer timer.Stop()
+	channel := w.mapConnection.Get(websocketHandlerID).Channel
 	for {
 		select {
 		case <-timer.C:
 			conn.WriteMessage(websocket.PingMessage, []byte("ping"))
 			timer.Reset(w.pingInterval)
 
-		case message, ok := <-w.mapConnection.Get(websocketHandlerID).Channel:
+		case frame, ok := <-channel:
 			if !ok { // Channel has been closed
 				w.logger.Infof("[%s][Write] Connection to websocket handler %s has already closed\n", websocketHandlerID, websocketHandlerID)
 				return
 			}
+			message := frame.Message
 			err := conn.WriteJSON(message)
+			frame.Result <- err
 			if err != nil {
 				w.logger.Errorf(
 					"[%s][Write] Error while delivering message %v in conversation %v: %v",
@@ -0,0 +1,100 @@
+package group_message_handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"graduation-thesis/pkg/membership"
+	"graduation-thesis/pkg/pb/messagepb"
+	"graduation-thesis/pkg/pb/websocketmanagerpb"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+// fakeWebsocketManagerClient spreads users over handlers websocket handlers
+// and reports every offline-th user as offline.
+type fakeWebsocketManagerClient struct {
+	websocketmanagerpb.WebsocketManagerServiceClient
+	handlers int
+	offline  int
+}
+
+func (f *fakeWebsocketManagerClient) GetWebsocketHandlersOfUsers(ctx context.Context, in *websocketmanagerpb.GetWebsocketHandlersOfUsersRequest, opts ...grpc.CallOption) (*websocketmanagerpb.GetWebsocketHandlersOfUsersResponse, error) {
+	recipients := make([]*websocketmanagerpb.Recipients, f.handlers)
+	for i := range recipients {
+		recipients[i] = &websocketmanagerpb.Recipients{
+			WebsocketHandler: &websocketmanagerpb.WebsocketHandler{Id: fmt.Sprintf("handler-%d", i), IpAddress: fmt.Sprintf("10.0.0.%d:8080", i)},
+		}
+	}
+	response := &websocketmanagerpb.GetWebsocketHandlersOfUsersResponse{}
+	for i, user := range in.UserIds {
+		if i%f.offline == 0 {
+			response.OfflineUserIds = append(response.OfflineUserIds, user)
+			continue
+		}
+		recipients[i%f.handlers].UserIds = append(recipients[i%f.handlers].UserIds, user)
+	}
+	for _, r := range recipients {
+		if len(r.UserIds) > 0 {
+			response.Recipients = append(response.Recipients, r)
+		}
+	}
+	return response, nil
+}
+
+type fakeMessageClient struct {
+	messagepb.MessageServiceClient
+}
+
+func (fakeMessageClient) ConfirmUserInboxes(ctx context.Context, in *messagepb.ConfirmUserInboxesRequest, opts ...grpc.CallOption) (*messagepb.ConfirmUserInboxesResponse, error) {
+	return &messagepb.ConfirmUserInboxesResponse{}, nil
+}
+
+// newBenchmarkWorker returns a worker fanning out to a conversation of size
+// members over connections to handlers websocket handlers, which accept every
+// frame at once.
+func newBenchmarkWorker(b *testing.B, size, handlers int) *Worker {
+	members := make([]string, size)
+	for i := range members {
+		members[i] = fmt.Sprintf("user-%d", i)
+	}
+	cache := membership.NewCache(func(ctx context.Context, conversationID string) ([]string, error) {
+		return members, nil
+	}, time.Hour)
+
+	w := NewWorker(nil, nil, nil, "", "", cache, fakeMessageClient{}, &fakeWebsocketManagerClient{handlers: handlers, offline: 5},
+		time.Second, 0, time.Second, time.Second, time.Minute, zap.NewNop().Sugar())
+	for i := 0; i < handlers; i++ {
+		id := fmt.Sprintf("handler-%d", i)
+		w.mapConnection.Set(id, 100)
+		connection := w.mapConnection.Get(id)
+		go func() {
+			for frame := range connection.Channel {
+				frame.Result <- nil
+			}
+		}()
+		b.Cleanup(connection.Close)
+	}
+	return w
+}
+
+// BenchmarkProcessMessage fans a message out to a 500 member group whose
+// online members are spread over 10 websocket handlers.
+func BenchmarkProcessMessage(b *testing.B) {
+	w := newBenchmarkWorker(b, 500, 10)
+	data, _ := json.Marshal(Message{ConversationID: "group-1", ConversationMessageID: 1, Sender: "user-1", Content: "hi"})
+	kafkaMessage := &KafkaMessage{UserID: "user-1", ConversationID: "group-1", Type: "message", Data: data}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		failed, err := w.processMessage(kafkaMessage, nil)
+		if err != nil || len(failed) > 0 {
+			b.Fatalf("processMessage failed for %d receivers: %v", len(failed), err)
+		}
+	}
+}
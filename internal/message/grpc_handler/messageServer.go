@@ -0,0 +1,139 @@
+package grpc_handler
+
+import (
+	"context"
+	"net/http"
+
+	"graduation-thesis/internal/message/model"
+	"graduation-thesis/internal/message/service"
+	"graduation-thesis/pkg/custom_error"
+	"graduation-thesis/pkg/pb/messagepb"
+)
+
+type MessageServer struct {
+	messagepb.UnimplementedMessageServiceServer
+	messageService *service.MessageService
+}
+
+func NewMessageServer(messageService *service.MessageService) *MessageServer {
+	return &MessageServer{
+		messageService: messageService,
+	}
+}
+
+func (m *MessageServer) SendMessage(ctx context.Context, request *messagepb.SendMessageRequest) (*messagepb.SendMessageResponse, error) {
+	sendMessageRequest := model.SendMessageRequest{
+		ConversationID: request.ConvId,
+		Sender:         request.Sender,
+		Content:        request.Content,
+		IV:             request.Iv,
+		MessageTime:    request.MsgTime,
+		Type:           request.Type,
+	}
+	successResponse, errorResponse := m.messageService.SendMessage(ctx, &sendMessageRequest)
+	if errorResponse != nil {
+		return nil, custom_error.StatusToGRPCError(errorResponse.Status, errorResponse.ErrorMessage)
+	}
+
+	return &messagepb.SendMessageResponse{
+		ConvMsgId: successResponse.Result.(int64),
+	}, nil
+}
+
+func (m *MessageServer) GetUserInbox(ctx context.Context, request *messagepb.GetUserInboxRequest) (*messagepb.GetUserInboxResponse, error) {
+	limit := int(request.Limit)
+	if limit <= 0 {
+		limit = 1000
+	}
+	successResponse, errorResponse := m.messageService.UserInbox(ctx, request.UserId, limit, 0, 0)
+	if errorResponse != nil {
+		return nil, custom_error.StatusToGRPCError(errorResponse.Status, errorResponse.ErrorMessage)
+	}
+
+	userInboxes := successResponse.Result.([]*model.UserInbox)
+	response := messagepb.GetUserInboxResponse{
+		Inboxes: make([]*messagepb.UserInbox, len(userInboxes)),
+	}
+	for i, userInbox := range userInboxes {
+		response.Inboxes[i] = &messagepb.UserInbox{
+			UserId:     userInbox.UserID,
+			InboxMsgId: userInbox.InboxMessageID,
+			ConvId:     userInbox.ConversationID,
+			ConvMsgId:  userInbox.ConversationMessageID,
+			MsgTime:    userInbox.MessageTime,
+			Sender:     userInbox.Sender,
+			Content:    userInbox.Content,
+			Iv:         userInbox.IV,
+			Type:       userInbox.Type,
+		}
+	}
+	return &response, nil
+}
+
+func (m *MessageServer) GetConversationMessages(ctx context.Context, request *messagepb.GetConversationMessagesRequest) (*messagepb.GetConversationMessagesResponse, error) {
+	limit := int(request.Limit)
+	if limit <= 0 {
+		limit = 20
+	}
+	successResponse, errorResponse := m.messageService.GetConversationMessages(ctx, request.UserId, request.ConvId, limit, request.BeforeMsg)
+	if errorResponse != nil {
+		return nil, custom_error.StatusToGRPCError(errorResponse.Status, errorResponse.ErrorMessage)
+	}
+
+	conversationMessages := successResponse.Result.([]*model.ConversationMessage)
+	response := messagepb.GetConversationMessagesResponse{
+		Messages: make([]*messagepb.ConversationMessage, len(conversationMessages)),
+	}
+	for i, conversationMessage := range conversationMessages {
+		response.Messages[i] = &messagepb.ConversationMessage{
+			ConvId:    conversationMessage.ConversationID,
+			ConvMsgId: conversationMessage.ConversationMessageID,
+			MsgTime:   conversationMessage.MessageTime,
+			Sender:    conversationMessage.Sender,
+			Content:   conversationMessage.Content,
+			Iv:        conversationMessage.IV,
+			Type:      conversationMessage.Type,
+		}
+	}
+	return &response, nil
+}
+
+func (m *MessageServer) GetReadReceipt(ctx context.Context, request *messagepb.GetReadReceiptRequest) (*messagepb.ReadReceipt, error) {
+	readReceiptRequest := model.ReadReceiptRequest{
+		ConversationID: request.ConvId,
+		UserID:         request.UserId,
+	}
+	successResponse, errorResponse := m.messageService.GetReadReceipts(ctx, request.RequesterId, &readReceiptRequest)
+	if errorResponse != nil {
+		return nil, custom_error.StatusToGRPCError(errorResponse.Status, errorResponse.ErrorMessage)
+	}
+
+	readReceipt := successResponse.Result.(*model.ReadReceipt)
+	return &messagepb.ReadReceipt{
+		ConvId: readReceipt.ConversationID,
+		UserId: readReceipt.UserID,
+		MsgId:  readReceipt.MessageID,
+	}, nil
+}
+
+func (m *MessageServer) ConfirmUserInboxes(ctx context.Context, request *messagepb.ConfirmUserInboxesRequest) (*messagepb.ConfirmUserInboxesResponse, error) {
+	if request.Message == nil {
+		return nil, custom_error.StatusToGRPCError(http.StatusBadRequest, custom_error.ErrInvalidParameter.Error())
+	}
+
+	conversationMessage := model.ConversationMessage{
+		ConversationID:        request.Message.ConvId,
+		ConversationMessageID: request.Message.ConvMsgId,
+		MessageTime:           request.Message.MsgTime,
+		Sender:                request.Message.Sender,
+		Content:               request.Message.Content,
+		IV:                    request.Message.Iv,
+		Type:                  request.Message.Type,
+	}
+	_, errorResponse := m.messageService.ConfirmUserInboxes(ctx, request.UserIds, &conversationMessage)
+	if errorResponse != nil {
+		return nil, custom_error.StatusToGRPCError(errorResponse.Status, errorResponse.ErrorMessage)
+	}
+
+	return &messagepb.ConfirmUserInboxesResponse{}, nil
+}
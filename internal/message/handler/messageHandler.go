@@ -3,6 +3,7 @@ package handler
 import (
 	"graduation-thesis/internal/message/model"
 	"graduation-thesis/internal/message/service"
+	"graduation-thesis/pkg/auth"
 	responseModel "graduation-thesis/pkg/model"
 	"net/http"
 	"strconv"
@@ -11,14 +12,14 @@ import (
 )
 
 type MessageHandler struct {
-	messageService   *service.MessageService
-	authenticatorURL string
+	messageService       *service.MessageService
+	authenticatorAddress string
 }
 
-func NewMessageHandler(messageService *service.MessageService, authenticatorURL string) *MessageHandler {
+func NewMessageHandler(messageService *service.MessageService, authenticatorAddress string) *MessageHandler {
 	return &MessageHandler{
-		messageService:   messageService,
-		authenticatorURL: authenticatorURL,
+		messageService:       messageService,
+		authenticatorAddress: authenticatorAddress,
 	}
 }
 
@@ -153,7 +154,7 @@ func (m *MessageHandler) ReadReceipts(c *gin.Context) {
 		return
 	}
 
-	successResponse, errorResponse := m.messageService.GetReadReceipts(c, &readReceipt)
+	successResponse, errorResponse := m.messageService.GetReadReceipts(c, c.Request.Header.Get("X-User-ID"), &readReceipt)
 	if errorResponse != nil {
 		c.JSON(errorResponse.Status, errorResponse)
 		return
@@ -194,6 +195,15 @@ func (m *MessageHandler) SendMessage(c *gin.Context) {
 		c.JSON(errorMessage.Status, errorMessage)
 		return
 	}
+	// Internal services send on behalf of users, anyone else as themselves.
+	if c.Request.Header.Get("X-Auth-Kind") != auth.KindService && sendMessageRequest.Sender != c.Request.Header.Get("X-User-ID") {
+		errorMessage := responseModel.ErrorResponse{
+			Status:       http.StatusForbidden,
+			ErrorMessage: "cannot send as another user",
+		}
+		c.JSON(errorMessage.Status, errorMessage)
+		return
+	}
 
 	successResponse, errorResponse := m.messageService.SendMessage(c, &sendMessageRequest)
 	if errorResponse != nil {
@@ -203,3 +213,25 @@ func (m *MessageHandler) SendMessage(c *gin.Context) {
 
 	c.JSON(successResponse.Status, successResponse)
 }
+
+func (m *MessageHandler) Chats(c *gin.Context) {
+	userID := c.Request.Header.Get("X-User-ID")
+	archived, aErr := strconv.ParseBool(c.DefaultQuery("archived", "false"))
+	limit, lErr := strconv.Atoi(c.DefaultQuery("limit", "25"))
+	if aErr != nil || lErr != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       http.StatusBadRequest,
+			ErrorMessage: "invalid parameters",
+		}
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	successResponse, errorResponse := m.messageService.GetChats(c, userID, archived, limit, c.Query("cursor"))
+	if errorResponse != nil {
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	c.JSON(successResponse.Status, successResponse)
+}
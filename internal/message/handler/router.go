@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"graduation-thesis/pkg/auth"
 	"graduation-thesis/pkg/middleware"
 
 	"github.com/gin-gonic/gin"
@@ -23,14 +24,16 @@ func initRouter(messageHandler *MessageHandler) *gin.Engine {
 		messagePath.POST("/_search/conversation", messageHandler.SearchConversation)
 
 		// New version
-		messagePath.GET("/inbox/:user_id", middleware.AuthMiddlewareV2(messageHandler.authenticatorURL), messageHandler.Inboxes)
-		messagePath.GET("/conversation/:conv_id", middleware.AuthMiddlewareV2(messageHandler.authenticatorURL), messageHandler.ConversationMessages)
-		messagePath.POST("/read_receipt", messageHandler.ReadReceipts)
+		messagePath.GET("/inbox/:user_id", middleware.AuthMiddlewareV2(messageHandler.authenticatorAddress, auth.ScopeMessagesRead), messageHandler.Inboxes)
+		messagePath.GET("/conversation/:conv_id", middleware.AuthMiddlewareV2(messageHandler.authenticatorAddress, auth.ScopeMessagesRead), messageHandler.ConversationMessages)
+		messagePath.POST("/read_receipt", middleware.AuthMiddlewareV2(messageHandler.authenticatorAddress, auth.ScopeMessagesRead), messageHandler.ReadReceipts)
 		messagePath.PUT("/read_receipt", messageHandler.UpdateReadReceipts)
-		messagePath.POST("/message", messageHandler.SendMessage)
+		messagePath.POST("/message", middleware.AuthMiddlewareV2(messageHandler.authenticatorAddress, auth.ScopeMessagesSend), messageHandler.SendMessage)
 
 	}
 
+	router.GET("/v1/chats", middleware.AuthMiddlewareV2(messageHandler.authenticatorAddress, auth.ScopeConversationsRead), messageHandler.Chats)
+
 	return router
 }
 
@@ -3,16 +3,25 @@ package message
 import (
 	"crypto/tls"
 	"fmt"
+	"graduation-thesis/internal/message/grpc_handler"
 	"graduation-thesis/internal/message/handler"
 	"graduation-thesis/internal/message/repository"
 	"graduation-thesis/internal/message/service"
+	"graduation-thesis/pkg/custom_error"
 	"graduation-thesis/pkg/logger"
+	"graduation-thesis/pkg/membership"
+	"graduation-thesis/pkg/pb/grouppb"
+	"graduation-thesis/pkg/pb/messagepb"
+	"graduation-thesis/pkg/pb/userpb"
+	"graduation-thesis/pkg/rpc"
 	"graduation-thesis/pkg/storage"
+	"net"
 	"net/http"
 	"sync"
 	"time"
 
 	"github.com/spf13/viper"
+	"github.com/twinj/uuid"
 )
 
 func Run() {
@@ -41,11 +50,25 @@ func Run() {
 	}
 
 	messageRepo := repository.NewMessageRepo(session, kafkaProducer, viper.GetString("kafka.topic"))
-	messageService := service.NewMessageService(messageRepo, viper.GetString("group_service_url"), logger)
-	messageHandler := handler.NewMessageHandler(messageService, viper.GetString("authenticator_url"))
+	groupClient := grouppb.NewGroupServiceClient(rpc.GetClientConn(viper.GetString("group_service_address")))
+	membershipCache := membership.NewCache(membership.GroupLoader(groupClient, 5*time.Second), viper.GetDuration("membership_cache_ttl"))
+	membershipConsumer := storage.NewKafkaBroadcastConsumer(
+		viper.GetString("kafka.bootstrap_servers"),
+		fmt.Sprintf("%s-%s", viper.GetString("kafka.membership_group_id"), uuid.NewV4().String()),
+	)
+	defer membershipConsumer.Close()
+	blockRepo := repository.NewBlockRepo(
+		userpb.NewUserServiceClient(rpc.GetClientConn(viper.GetString("user_service_address"))),
+		5*time.Second,
+	)
+	messageService := service.NewMessageService(messageRepo, membershipCache, groupClient, blockRepo, custom_error.MappingError(), logger)
+	messageHandler := handler.NewMessageHandler(messageService, viper.GetString("authenticator_address"))
 
 	router := handler.GetRouter(messageHandler)
 
+	grpcServer := rpc.NewServer()
+	messagepb.RegisterMessageServiceServer(grpcServer, grpc_handler.NewMessageServer(messageService))
+
 	TLSConfig := &tls.Config{
 		PreferServerCipherSuites: true,
 		CipherSuites: []uint16{
@@ -77,7 +100,7 @@ func Run() {
 	}
 
 	var wg sync.WaitGroup
-	wg.Add(2)
+	wg.Add(4)
 
 	go func(wg *sync.WaitGroup) {
 		defer wg.Done()
@@ -95,5 +118,23 @@ func Run() {
 		}
 	}(&wg)
 
+	go func(wg *sync.WaitGroup) {
+		defer wg.Done()
+		lis, err := net.Listen("tcp", fmt.Sprintf(":%d", viper.GetInt("app.grpc_port")))
+		if err != nil {
+			panic(err.Error())
+		}
+		if err := grpcServer.Serve(lis); err != nil {
+			panic(err.Error())
+		}
+	}(&wg)
+
+	go func(wg *sync.WaitGroup) {
+		defer wg.Done()
+		if err := membershipCache.Listen(membershipConsumer, viper.GetString("kafka.membership_topic"), logger, nil); err != nil {
+			panic(err.Error())
+		}
+	}(&wg)
+
 	wg.Wait()
 }
@@ -0,0 +1,41 @@
+package model
+
+const CONVERSATION_TYPE_DIRECT = "direct"
+
+// Chat is an entry of a user's chat list.
+type Chat struct {
+	ConversationID string `json:"conv_id"`
+	MemberCount    int    `json:"member_count"`
+	Type           string `json:"type"`
+	GroupID        string `json:"group_id,omitempty"`
+	Name           string `json:"name,omitempty"`
+	Avatar         string `json:"avatar,omitempty"`
+	PeerID         string `json:"peer_id,omitempty"`
+	// MutedUntil is a unix timestamp, 0 when notifications are not muted.
+	MutedUntil int64 `json:"muted_until,omitempty"`
+	Archived   bool  `json:"archived"`
+	Pinned     bool  `json:"pinned"`
+	PinOrder   int   `json:"pin_order"`
+	// LastActivity is the time of the last message, 0 for conversations without
+	// one or whose last message expired.
+	LastActivity int64                `json:"last_activity"`
+	LastMessage  *ConversationMessage `json:"last_msg,omitempty"`
+	UnreadCount  int64                `json:"unread_count"`
+	// PeerLastSeen is the last message the peer of a direct conversation has
+	// read, hidden when one of them blocked the other.
+	PeerLastSeen int64 `json:"peer_last_seen_msg,omitempty"`
+	PeerBlocked  bool  `json:"-"`
+}
+
+// ChatPage is a page of a user's chat list. NextCursor asks for the next page
+// and is empty on the last one.
+type ChatPage struct {
+	Chats      []*Chat `json:"chats"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
+
+// ChatActivity tells when the last message of a conversation was sent.
+type ChatActivity struct {
+	ConversationID string
+	LastActivity   int64
+}
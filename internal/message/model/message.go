@@ -7,6 +7,8 @@ const (
 	EVENT_TYPE   = "event"
 )
 
+const CONVERSATION_TYPE_CHANNEL = "channel"
+
 type Message struct {
 	ID          string    `cql:"id" json:"id"`
 	From        string    `cql:"from" json:"from"`
@@ -41,11 +43,12 @@ type EvictMessageRequest struct {
 }
 
 type KafkaMessage struct {
-	UserID         string      `json:"user_id"`
-	ConversationID string      `json:"conversation_id"`
-	Type           string      `json:"type"`
-	Timestamp      int64       `json:"timestamp"`
-	Data           interface{} `json:"data"`
+	UserID           string      `json:"user_id"`
+	ConversationID   string      `json:"conversation_id"`
+	ConversationType string      `json:"conv_type,omitempty"`
+	Type             string      `json:"type"`
+	Timestamp        int64       `json:"timestamp"`
+	Data             interface{} `json:"data"`
 }
 
 type ConversationMessage struct {
@@ -55,6 +58,7 @@ type ConversationMessage struct {
 	Sender                string `json:"sender" cql:"sender"`
 	Content               string `json:"content" cql:"content"`
 	IV                    string `json:"iv" cql:"iv"`
+	Type                  string `json:"type,omitempty" cql:"type"`
 }
 
 type UserInbox struct {
@@ -66,6 +70,7 @@ type UserInbox struct {
 	Sender                string `json:"sender" cql:"sender"`
 	Content               string `json:"content" cql:"content"`
 	IV                    string `json:"iv" cql:"iv"`
+	Type                  string `json:"type,omitempty" cql:"type"`
 }
 
 type ReadReceipt struct {
@@ -108,6 +113,8 @@ type SendMessageRequest struct {
 	Content        string `json:"content" binding:"required,max=10000"`
 	IV             string `json:"iv"`
 	MessageTime    int64  `json:"msg_time"`
+	// Type is only set by internal callers, clients can not send events
+	Type string `json:"-"`
 }
 
 type UserInboxResponse struct {
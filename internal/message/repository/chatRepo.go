@@ -0,0 +1,181 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"graduation-thesis/internal/message/model"
+
+	"github.com/gocql/gocql"
+)
+
+// The chat list of a user is kept in user_chats, which holds the last message
+// of each of their conversations, and user_chats_by_activity, which orders
+// them by the time of that message. Moving a conversation up deletes its old
+// row from user_chats_by_activity; rows left behind by concurrent updates
+// are told apart by their time no longer matching user_chats.
+
+// UpdateUserChat makes message the last one of its conversation in the chat
+// list of userID, unless a later message is there already. The rows expire
+// with the message after ttl seconds unless ttl is 0.
+func (m *MessageRepo) UpdateUserChat(ctx context.Context, userID string, message *model.ConversationMessage, ttl int) error {
+	var lastActivity, lastConvMsgID int64
+	query := `SELECT last_msg_time, conv_msg_id FROM user_chats WHERE user_id = ? AND conv_id = ?`
+	err := m.session.Query(query, userID, message.ConversationID).WithContext(ctx).Scan(&lastActivity, &lastConvMsgID)
+	found := err == nil
+	if err != nil && !errors.Is(err, gocql.ErrNotFound) {
+		return err
+	}
+	if found && (lastActivity > message.MessageTime || (lastActivity == message.MessageTime && lastConvMsgID > message.ConversationMessageID)) {
+		return nil
+	}
+
+	// Every statement is on the partition of userID.
+	batch := m.session.NewBatch(gocql.LoggedBatch).WithContext(ctx)
+	if found && lastActivity != message.MessageTime {
+		batch.Query(`DELETE FROM user_chats_by_activity WHERE user_id = ? AND last_msg_time = ? AND conv_id = ?`,
+			userID, lastActivity, message.ConversationID)
+	}
+	batch.Query(`INSERT INTO user_chats_by_activity (user_id, last_msg_time, conv_id) VALUES (?, ?, ?) USING TTL ?`,
+		userID, message.MessageTime, message.ConversationID, ttl)
+	batch.Query(`INSERT INTO user_chats (user_id, conv_id, last_msg_time, conv_msg_id, sender, content, iv, type) VALUES (?, ?, ?, ?, ?, ?, ?, ?) USING TTL ?`,
+		userID, message.ConversationID, message.MessageTime, message.ConversationMessageID, message.Sender, message.Content, message.IV, message.Type, ttl)
+	return m.session.ExecuteBatch(batch)
+}
+
+// GetUserChatsByActivity returns up to limit conversations of userID, the
+// latest active first, that come after the conversation conversationID active
+// at lastActivity. An empty conversationID starts from the top.
+func (m *MessageRepo) GetUserChatsByActivity(ctx context.Context, userID string, lastActivity int64, conversationID string, limit int) ([]*model.ChatActivity, error) {
+	var query *gocql.Query
+	if conversationID == "" {
+		query = m.session.Query(`SELECT conv_id, last_msg_time FROM user_chats_by_activity WHERE user_id = ? LIMIT ?`, userID, limit)
+	} else {
+		query = m.session.Query(`SELECT conv_id, last_msg_time FROM user_chats_by_activity WHERE user_id = ? AND (last_msg_time, conv_id) < (?, ?) LIMIT ?`,
+			userID, lastActivity, conversationID, limit)
+	}
+	scanner := query.WithContext(ctx).Iter().Scanner()
+
+	var activities []*model.ChatActivity
+	for scanner.Next() {
+		var activity model.ChatActivity
+		if err := scanner.Scan(&activity.ConversationID, &activity.LastActivity); err != nil {
+			return nil, err
+		}
+		activities = append(activities, &activity)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return activities, nil
+}
+
+// GetUserChats returns the last message of each of conversationIDs in the
+// chat list of userID, by conversation. Conversations without one are left
+// out.
+func (m *MessageRepo) GetUserChats(ctx context.Context, userID string, conversationIDs []string) (map[string]*model.ConversationMessage, error) {
+	lastMessages := make(map[string]*model.ConversationMessage, len(conversationIDs))
+	if len(conversationIDs) == 0 {
+		return lastMessages, nil
+	}
+
+	query := `SELECT conv_id, last_msg_time, conv_msg_id, sender, content, iv, type FROM user_chats WHERE user_id = ? AND conv_id IN ?`
+	scanner := m.session.Query(query, userID, conversationIDs).WithContext(ctx).Iter().Scanner()
+	for scanner.Next() {
+		var message model.ConversationMessage
+		if err := scanner.Scan(&message.ConversationID,
+			&message.MessageTime,
+			&message.ConversationMessageID,
+			&message.Sender,
+			&message.Content,
+			&message.IV,
+			&message.Type); err != nil {
+			return nil, err
+		}
+		lastMessages[message.ConversationID] = &message
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return lastMessages, nil
+}
+
+// GetUserChatIDs returns every conversation in the chat list of userID.
+func (m *MessageRepo) GetUserChatIDs(ctx context.Context, userID string) (map[string]bool, error) {
+	scanner := m.session.Query(`SELECT conv_id FROM user_chats WHERE user_id = ?`, userID).WithContext(ctx).Iter().Scanner()
+
+	conversationIDs := make(map[string]bool)
+	for scanner.Next() {
+		var conversationID string
+		if err := scanner.Scan(&conversationID); err != nil {
+			return nil, err
+		}
+		conversationIDs[conversationID] = true
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return conversationIDs, nil
+}
+
+// DeleteUserChatActivity deletes a row of user_chats_by_activity that was
+// left behind.
+func (m *MessageRepo) DeleteUserChatActivity(ctx context.Context, userID string, activity *model.ChatActivity) error {
+	query := `DELETE FROM user_chats_by_activity WHERE user_id = ? AND last_msg_time = ? AND conv_id = ?`
+	return m.session.Query(query, userID, activity.LastActivity, activity.ConversationID).WithContext(ctx).Exec()
+}
+
+// GetSkippedMessageIDs returns the ids of conversationIDs given up with
+// SkipMessageID, by conversation.
+func (m *MessageRepo) GetSkippedMessageIDs(ctx context.Context, conversationIDs []string) (map[string][]int64, error) {
+	if len(conversationIDs) == 0 {
+		return nil, nil
+	}
+
+	query := `SELECT conv_id, conv_msg_id FROM conv_msg_gap WHERE conv_id IN ?`
+	scanner := m.session.Query(query, conversationIDs).WithContext(ctx).Iter().Scanner()
+
+	skipped := make(map[string][]int64)
+	for scanner.Next() {
+		var (
+			conversationID string
+			convMsgID      int64
+		)
+		if err := scanner.Scan(&conversationID, &convMsgID); err != nil {
+			return nil, err
+		}
+		skipped[conversationID] = append(skipped[conversationID], convMsgID)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return skipped, nil
+}
+
+// GetReadReceiptsOf returns the read receipts of userIDs in conversationIDs.
+func (m *MessageRepo) GetReadReceiptsOf(ctx context.Context, conversationIDs, userIDs []string) ([]*model.ReadReceipt, error) {
+	if len(conversationIDs) == 0 || len(userIDs) == 0 {
+		return nil, nil
+	}
+
+	query := `SELECT conv_id, user_id, last_seen_msg FROM read_receipt WHERE conv_id IN ? AND user_id IN ?`
+	scanner := m.session.Query(query, conversationIDs, userIDs).WithContext(ctx).Iter().Scanner()
+
+	var readReceipts []*model.ReadReceipt
+	for scanner.Next() {
+		var readReceipt model.ReadReceipt
+		if err := scanner.Scan(&readReceipt.ConversationID, &readReceipt.UserID, &readReceipt.MessageID); err != nil {
+			return nil, err
+		}
+		readReceipts = append(readReceipts, &readReceipt)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return readReceipts, nil
+}
@@ -94,7 +94,7 @@ func (m *MessageRepo) GetConversation(ctx context.Context, users []string, timeF
 }
 
 func (m *MessageRepo) GetUserInbox(ctx context.Context, userID string, limit, lastInbox int) ([]*model.UserInbox, error) {
-	query := `SELECT user_id, inbox_msg_id, conv_id, conv_msg_id, msg_time, sender, content, iv FROM user_inbox WHERE user_id = ? LIMIT ?`
+	query := `SELECT user_id, inbox_msg_id, conv_id, conv_msg_id, msg_time, sender, content, iv, type FROM user_inbox WHERE user_id = ? LIMIT ?`
 	scanner := m.session.Query(query, userID, limit).WithContext(ctx).Iter().Scanner()
 
 	var userInboxes []*model.UserInbox
@@ -107,7 +107,8 @@ func (m *MessageRepo) GetUserInbox(ctx context.Context, userID string, limit, la
 			&userInbox.MessageTime,
 			&userInbox.Sender,
 			&userInbox.Content,
-			&userInbox.IV); err != nil {
+			&userInbox.IV,
+			&userInbox.Type); err != nil {
 			return nil, err
 		}
 
@@ -122,7 +123,7 @@ func (m *MessageRepo) GetUserInbox(ctx context.Context, userID string, limit, la
 }
 
 func (m *MessageRepo) GetConversationMessages(ctx context.Context, conversationID string, limit int, beforeMsg int64) ([]*model.ConversationMessage, error) {
-	query := `SELECT conv_id, conv_msg_id, msg_time, sender, content, iv FROM conv_msg WHERE conv_id = ? AND conv_msg_id < ? LIMIT ?`
+	query := `SELECT conv_id, conv_msg_id, msg_time, sender, content, iv, type FROM conv_msg WHERE conv_id = ? AND conv_msg_id < ? LIMIT ?`
 	scanner := m.session.Query(query, conversationID, beforeMsg, limit).WithContext(ctx).Iter().Scanner()
 
 	var conversationMessages []*model.ConversationMessage
@@ -133,7 +134,8 @@ func (m *MessageRepo) GetConversationMessages(ctx context.Context, conversationI
 			&conversationMessage.MessageTime,
 			&conversationMessage.Sender,
 			&conversationMessage.Content,
-			&conversationMessage.IV); err != nil {
+			&conversationMessage.IV,
+			&conversationMessage.Type); err != nil {
 			return nil, err
 		}
 
@@ -188,56 +190,128 @@ func (m *MessageRepo) UpdateReadReceipts(ctx context.Context, conversationID str
 	return err
 }
 
-func (m *MessageRepo) CreateConversationMessage(ctx context.Context, conversationID, sender, content, iv string, messageTime int64) (int64, error) {
-	getQuery := `SELECT conv_msg_id FROM conv_msg WHERE conv_id = ? LIMIT 1`
+// ErrSequenceContention is returned when the id of a message could not be
+// allocated because other messages of the conversation kept taking it.
+var ErrSequenceContention = errors.New("too many concurrent messages in conversation")
+
+const maxSequenceAttempts = 10
+
+// NextMessageID allocates the id of the next message of the conversation. Ids
+// come from conv_msg_seq, which never expires, and are taken with lightweight
+// transactions, so they stay dense and unique even after the messages holding
+// the latest ids expired and when members send at the same time.
+//
+// Unread counts are the distance between ids, so every id handed out must end
+// up holding a message: callers store it with CreateConversationMessage,
+// retrying with the same id rather than allocating another one, and give it up
+// with SkipMessageID if it cannot be stored.
+func (m *MessageRepo) NextMessageID(ctx context.Context, conversationID string) (int64, error) {
 	var lastConvMsgID int64
-	getErr := m.session.Query(getQuery, conversationID).WithContext(ctx).Scan(&lastConvMsgID)
-	if getErr != nil && !errors.Is(getErr, gocql.ErrNotFound) {
-		return int64(0), getErr
+	err := m.session.Query(`SELECT last_msg_id FROM conv_msg_seq WHERE conv_id = ?`, conversationID).WithContext(ctx).Scan(&lastConvMsgID)
+	if errors.Is(err, gocql.ErrNotFound) {
+		// Conversations older than the sequence go on from their last message.
+		err = m.session.Query(`SELECT conv_msg_id FROM conv_msg WHERE conv_id = ? LIMIT 1`, conversationID).WithContext(ctx).Scan(&lastConvMsgID)
+		if err != nil && !errors.Is(err, gocql.ErrNotFound) {
+			return 0, err
+		}
+
+		next := lastConvMsgID + 1
+		var existingConversationID string
+		applied, err := m.session.Query(`INSERT INTO conv_msg_seq (conv_id, last_msg_id) VALUES (?, ?) IF NOT EXISTS`, conversationID, next).
+			WithContext(ctx).ScanCAS(&existingConversationID, &lastConvMsgID)
+		if err != nil {
+			return 0, err
+		}
+		if applied {
+			return next, nil
+		}
+	} else if err != nil {
+		return 0, err
+	}
+
+	for i := 0; i < maxSequenceAttempts; i++ {
+		next := lastConvMsgID + 1
+		applied, err := m.session.Query(`UPDATE conv_msg_seq SET last_msg_id = ? WHERE conv_id = ? IF last_msg_id = ?`, next, conversationID, lastConvMsgID).
+			WithContext(ctx).ScanCAS(&lastConvMsgID)
+		if err != nil {
+			return 0, err
+		}
+		if applied {
+			return next, nil
+		}
 	}
+	return 0, ErrSequenceContention
+}
 
-	createQuery := `INSERT INTO conv_msg(conv_id, conv_msg_id, msg_time, sender, content, iv) VALUES (?, ?, ?, ?, ?, ?)`
-	createErr := m.session.Query(createQuery, conversationID, lastConvMsgID+1, messageTime, sender, content, iv).WithContext(ctx).Exec()
+// SkipMessageID gives up on convMsgID, allocated by NextMessageID but never
+// stored: whatever a failed attempt left under it is removed and the id is
+// recorded in conv_msg_gap, so it is not counted as unread.
+func (m *MessageRepo) SkipMessageID(ctx context.Context, conversationID string, convMsgID int64) error {
+	batch := m.session.NewBatch(gocql.LoggedBatch).WithContext(ctx)
+	batch.Entries = append(batch.Entries,
+		gocql.BatchEntry{
+			Stmt:       `DELETE FROM conv_msg WHERE conv_id = ? AND conv_msg_id = ?`,
+			Args:       []interface{}{conversationID, convMsgID},
+			Idempotent: true,
+		},
+		gocql.BatchEntry{
+			Stmt:       `INSERT INTO conv_msg_gap (conv_id, conv_msg_id) VALUES (?, ?)`,
+			Args:       []interface{}{conversationID, convMsgID},
+			Idempotent: true,
+		},
+	)
+	return m.session.ExecuteBatch(batch)
+}
+
+// CreateConversationMessage stores the message under convMsgID, allocated by
+// NextMessageID, and hands it to the messages topic. Storing it again under
+// the same id overwrites it. Messages expire after ttl seconds unless ttl is 0.
+func (m *MessageRepo) CreateConversationMessage(ctx context.Context, conversationID string, convMsgID int64, conversationType, sender, content, iv, messageType string, messageTime int64, ttl int) error {
+	createQuery := `INSERT INTO conv_msg(conv_id, conv_msg_id, msg_time, sender, content, iv, type) VALUES (?, ?, ?, ?, ?, ?, ?) USING TTL ?`
+	createErr := m.session.Query(createQuery, conversationID, convMsgID, messageTime, sender, content, iv, messageType, ttl).WithContext(ctx).Exec()
 	if createErr != nil {
-		return int64(0), createErr
+		return createErr
 	}
 
 	conversationMessage := model.ConversationMessage{
 		ConversationID:        conversationID,
-		ConversationMessageID: lastConvMsgID + 1,
+		ConversationMessageID: convMsgID,
 		MessageTime:           messageTime,
 		Sender:                sender,
 		Content:               content,
 		IV:                    iv,
+		Type:                  messageType,
 	}
 
 	kafkaMessage := model.KafkaMessage{
-		UserID:         sender,
-		ConversationID: conversationID,
-		Type:           model.MESSAGE_TYPE,
-		Timestamp:      messageTime,
-		Data:           conversationMessage,
+		UserID:           sender,
+		ConversationID:   conversationID,
+		ConversationType: conversationType,
+		Type:             messageType,
+		Timestamp:        messageTime,
+		Data:             conversationMessage,
 	}
 	value, err := json.Marshal(kafkaMessage)
 	if err != nil {
-		return 0, err
+		return err
 	}
 
 	if err := m.producer.Produce(&kafka.Message{
 		TopicPartition: kafka.TopicPartition{Topic: &m.kafkaTopic, Partition: int32(kafka.PartitionAny)},
+		Key:            []byte(conversationID),
 		Value:          value,
 	}, nil); err != nil {
-		return 0, err
+		return err
 	}
 
-	return lastConvMsgID + 1, nil
+	return nil
 }
 
-func (m *MessageRepo) InsertUserInbox(ctx context.Context, userID, conversationID, sender, content, iv string, convMsgID, messageTime int64) error {
+func (m *MessageRepo) InsertUserInbox(ctx context.Context, userID, conversationID, sender, content, iv, messageType string, convMsgID, messageTime int64) error {
 	var lastInboxMsgID int64
 
-	query := `INSERT INTO user_inbox (user_id, inbox_msg_id, conv_id, conv_msg_id, msg_time, sender, content, iv) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
-	err := m.session.Query(query, userID, lastInboxMsgID+1, conversationID, convMsgID, messageTime, sender, content, iv).WithContext(ctx).Exec()
+	query := `INSERT INTO user_inbox (user_id, inbox_msg_id, conv_id, conv_msg_id, msg_time, sender, content, iv, type) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	err := m.session.Query(query, userID, lastInboxMsgID+1, conversationID, convMsgID, messageTime, sender, content, iv, messageType).WithContext(ctx).Exec()
 	return err
 }
 
@@ -0,0 +1,375 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"graduation-thesis/internal/message/model"
+	"graduation-thesis/pkg/custom_error"
+	responseModel "graduation-thesis/pkg/model"
+	"graduation-thesis/pkg/pb/grouppb"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	maxChatsLimit = 100
+	// maxChatWriters bounds how many chat lists are updated at once for a message.
+	maxChatWriters = 16
+)
+
+// chatCursor is where a page of the chat list ends. Conversations with a last
+// message come first, the latest active first, then the quiet ones by id.
+type chatCursor struct {
+	quiet          bool
+	lastActivity   int64
+	conversationID string
+}
+
+func (c chatCursor) String() string {
+	value := fmt.Sprintf("a:%d:%s", c.lastActivity, c.conversationID)
+	if c.quiet {
+		value = "q:" + c.conversationID
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(value))
+}
+
+func parseChatCursor(cursor string) (chatCursor, error) {
+	if cursor == "" {
+		return chatCursor{}, nil
+	}
+	value, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return chatCursor{}, custom_error.ErrInvalidParameter
+	}
+
+	if conversationID, ok := strings.CutPrefix(string(value), "q:"); ok {
+		return chatCursor{quiet: true, conversationID: conversationID}, nil
+	}
+	var c chatCursor
+	if _, err := fmt.Sscanf(string(value), "a:%d:%s", &c.lastActivity, &c.conversationID); err != nil {
+		return chatCursor{}, custom_error.ErrInvalidParameter
+	}
+	return c, nil
+}
+
+// GetChats lists a page of the conversations of userID with their profile,
+// per-user settings, last message and unread count. The first page starts
+// with every pinned conversation in pin order, on top of limit others ordered
+// by last activity.
+func (m *MessageService) GetChats(ctx context.Context, userID string, archived bool, limit int, rawCursor string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	cursor, err := parseChatCursor(rawCursor)
+	if limit <= 0 || err != nil {
+		errorMessage := responseModel.ErrorResponse{
+			Status:       http.StatusBadRequest,
+			ErrorMessage: "invalid parameters",
+		}
+		return nil, &errorMessage
+	}
+	if limit > maxChatsLimit {
+		limit = maxChatsLimit
+	}
+
+	groupContext, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	response, err := m.groupClient.GetConversationsContainUser(groupContext, &grouppb.GetConversationsContainUserRequest{UserId: userID})
+	if err != nil {
+		m.logger.Errorf("[GetChats] Cannot get conversations of user %v: %v", userID, err)
+		err = custom_error.HandleGRPCError(err)
+		status, ok := m.errorMap[err]
+		if !ok {
+			status = http.StatusInternalServerError
+		}
+		errorMessage := responseModel.ErrorResponse{
+			Status:       status,
+			ErrorMessage: err.Error(),
+		}
+		return nil, &errorMessage
+	}
+
+	chats := make(map[string]*model.Chat, len(response.Conversations))
+	var pinned []*model.Chat
+	for _, conversation := range response.Conversations {
+		if conversation.Archived != archived {
+			continue
+		}
+		chat := &model.Chat{
+			ConversationID: conversation.ConvId,
+			MemberCount:    int(conversation.MemberCount),
+			Type:           conversation.Type,
+			GroupID:        conversation.GroupId,
+			Name:           conversation.Name,
+			Avatar:         conversation.Avatar,
+			PeerID:         conversation.PeerId,
+			PeerBlocked:    conversation.PeerBlocked,
+			MutedUntil:     conversation.MutedUntil,
+			Archived:       conversation.Archived,
+			Pinned:         conversation.Pinned,
+			PinOrder:       int(conversation.PinOrder),
+		}
+		chats[chat.ConversationID] = chat
+		if chat.Pinned {
+			pinned = append(pinned, chat)
+		}
+	}
+
+	queryContext, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var page model.ChatPage
+	if rawCursor == "" && len(pinned) > 0 {
+		sort.Slice(pinned, func(i, j int) bool {
+			return pinned[i].PinOrder < pinned[j].PinOrder
+		})
+		err = m.fillLastMessages(queryContext, userID, pinned)
+		page.Chats = pinned
+	}
+	if err == nil && !cursor.quiet {
+		err = m.listActiveChats(queryContext, userID, chats, &cursor, limit, &page)
+	}
+	if err == nil && cursor.quiet {
+		err = m.listQuietChats(queryContext, userID, chats, cursor, limit, &page)
+	}
+	if err == nil {
+		err = m.fillReadPositions(queryContext, userID, page.Chats)
+	}
+	if err != nil {
+		m.logger.Errorf("[GetChats] Cannot list the chats of user %v: %v", userID, err)
+		errorMessage := responseModel.ErrorResponse{
+			Status:       http.StatusInternalServerError,
+			ErrorMessage: err.Error(),
+		}
+		return nil, &errorMessage
+	}
+	if page.Chats == nil {
+		page.Chats = []*model.Chat{}
+	}
+
+	successResponse := responseModel.SuccessResponse{
+		Status: http.StatusOK,
+		Result: page,
+	}
+	return &successResponse, nil
+}
+
+// listActiveChats adds up to limit unpinned chats with a last message after
+// cursor to page. The cursor moves on to the quiet chats once they ran out.
+func (m *MessageService) listActiveChats(ctx context.Context, userID string, chats map[string]*model.Chat, cursor *chatCursor, limit int, page *model.ChatPage) error {
+	listed := 0
+	for {
+		activities, err := m.messageRepo.GetUserChatsByActivity(ctx, userID, cursor.lastActivity, cursor.conversationID, limit)
+		if err != nil {
+			return err
+		}
+
+		conversationIDs := make([]string, 0, len(activities))
+		for _, activity := range activities {
+			if chat, ok := chats[activity.ConversationID]; ok && !chat.Pinned {
+				conversationIDs = append(conversationIDs, activity.ConversationID)
+			}
+		}
+		lastMessages, err := m.messageRepo.GetUserChats(ctx, userID, conversationIDs)
+		if err != nil {
+			return err
+		}
+
+		for _, activity := range activities {
+			cursor.lastActivity, cursor.conversationID = activity.LastActivity, activity.ConversationID
+			// Conversations the user left, archived or pinned are skipped
+			chat, ok := chats[activity.ConversationID]
+			if !ok || chat.Pinned {
+				continue
+			}
+			lastMessage := lastMessages[activity.ConversationID]
+			if lastMessage == nil || lastMessage.MessageTime != activity.LastActivity {
+				go m.deleteUserChatActivity(context.WithoutCancel(ctx), userID, activity)
+				continue
+			}
+
+			chat.LastActivity = lastMessage.MessageTime
+			chat.LastMessage = lastMessage
+			page.Chats = append(page.Chats, chat)
+			if listed++; listed == limit {
+				page.NextCursor = cursor.String()
+				return nil
+			}
+		}
+
+		if len(activities) < limit {
+			*cursor = chatCursor{quiet: true}
+			return nil
+		}
+	}
+}
+
+// deleteUserChatActivity deletes a row of the activity index that a concurrent
+// update of the chat list left behind.
+func (m *MessageService) deleteUserChatActivity(ctx context.Context, userID string, activity *model.ChatActivity) {
+	if err := m.messageRepo.DeleteUserChatActivity(ctx, userID, activity); err != nil {
+		m.logger.Errorf("[GetChats] Cannot delete stale activity of conversation %s of user %s: %v", activity.ConversationID, userID, err)
+	}
+}
+
+// listQuietChats fills page with the unpinned chats without a last message
+// after cursor, by conversation id.
+func (m *MessageService) listQuietChats(ctx context.Context, userID string, chats map[string]*model.Chat, cursor chatCursor, limit int, page *model.ChatPage) error {
+	remaining := limit - (len(page.Chats) - countPinned(page.Chats))
+	active, err := m.messageRepo.GetUserChatIDs(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	var quiet []*model.Chat
+	for conversationID, chat := range chats {
+		if !chat.Pinned && !active[conversationID] && conversationID > cursor.conversationID {
+			quiet = append(quiet, chat)
+		}
+	}
+	sort.Slice(quiet, func(i, j int) bool {
+		return quiet[i].ConversationID < quiet[j].ConversationID
+	})
+
+	if len(quiet) > remaining {
+		quiet = quiet[:remaining]
+		page.NextCursor = chatCursor{quiet: true, conversationID: quiet[remaining-1].ConversationID}.String()
+	}
+	page.Chats = append(page.Chats, quiet...)
+	return nil
+}
+
+func countPinned(chats []*model.Chat) int {
+	count := 0
+	for _, chat := range chats {
+		if chat.Pinned {
+			count++
+		}
+	}
+	return count
+}
+
+func (m *MessageService) fillLastMessages(ctx context.Context, userID string, chats []*model.Chat) error {
+	conversationIDs := make([]string, len(chats))
+	for i, chat := range chats {
+		conversationIDs[i] = chat.ConversationID
+	}
+	lastMessages, err := m.messageRepo.GetUserChats(ctx, userID, conversationIDs)
+	if err != nil {
+		return err
+	}
+
+	for _, chat := range chats {
+		if lastMessage := lastMessages[chat.ConversationID]; lastMessage != nil {
+			chat.LastActivity = lastMessage.MessageTime
+			chat.LastMessage = lastMessage
+		}
+	}
+	return nil
+}
+
+// fillReadPositions counts the messages after userID's read receipt, and sets
+// the read position of the peer of direct conversations. Both take a single
+// query for the whole page.
+func (m *MessageService) fillReadPositions(ctx context.Context, userID string, chats []*model.Chat) error {
+	conversationIDs := make([]string, 0, len(chats))
+	var peerConversationIDs, peers []string
+	byID := make(map[string]*model.Chat, len(chats))
+	for _, chat := range chats {
+		if chat.LastMessage == nil {
+			continue
+		}
+		conversationIDs = append(conversationIDs, chat.ConversationID)
+		byID[chat.ConversationID] = chat
+		if chat.Type == model.CONVERSATION_TYPE_DIRECT && chat.PeerID != "" && !chat.PeerBlocked {
+			peerConversationIDs = append(peerConversationIDs, chat.ConversationID)
+			peers = append(peers, chat.PeerID)
+		}
+	}
+
+	lastSeen := make(map[string]int64, len(conversationIDs))
+	readReceipts, err := m.messageRepo.GetReadReceiptsOf(ctx, conversationIDs, []string{userID})
+	if err != nil {
+		return err
+	}
+	for _, readReceipt := range readReceipts {
+		lastSeen[readReceipt.ConversationID] = readReceipt.MessageID
+	}
+	skipped, err := m.messageRepo.GetSkippedMessageIDs(ctx, conversationIDs)
+	if err != nil {
+		return err
+	}
+	for _, conversationID := range conversationIDs {
+		chat := byID[conversationID]
+		chat.UnreadCount = unreadCount(lastSeen[conversationID], chat.LastMessage.ConversationMessageID, skipped[conversationID])
+	}
+
+	peerReadReceipts, err := m.messageRepo.GetReadReceiptsOf(ctx, peerConversationIDs, peers)
+	if err != nil {
+		return err
+	}
+	for _, readReceipt := range peerReadReceipts {
+		// The query returns every peer in every conversation asked for
+		if chat := byID[readReceipt.ConversationID]; chat.PeerID == readReceipt.UserID {
+			chat.PeerLastSeen = readReceipt.MessageID
+		}
+	}
+	return nil
+}
+
+// unreadCount counts the messages after seen up to last. Message ids are dense
+// (see MessageRepo.NextMessageID), so it is the distance between ids less the
+// skipped ids in between. Ids of expired messages still count, as they were
+// never read.
+func unreadCount(seen, last int64, skipped []int64) int64 {
+	if seen >= last {
+		return 0
+	}
+	count := last - seen
+	for _, id := range skipped {
+		if seen < id && id <= last {
+			count--
+		}
+	}
+	return count
+}
+
+// UpdateUserChats moves the conversation of message to the top of the chat
+// list of every member, a few members at a time.
+func (m *MessageService) UpdateUserChats(ctx context.Context, message *model.ConversationMessage, ttl int) error {
+	var (
+		members []string
+		err     error
+	)
+	for i := 0; i < MAXRETRY; i++ {
+		members, err = m.getConversationMembers(ctx, message.ConversationID)
+		if err == nil {
+			break
+		}
+		m.logger.Errorf("[UpdateUserChats] Cannot get conversation %s 'members: %v", message.ConversationID, err)
+		time.Sleep(time.Second)
+	}
+	if err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	writers := make(chan struct{}, maxChatWriters)
+	for _, member := range members {
+		writers <- struct{}{}
+		wg.Add(1)
+		go func(member string) {
+			defer func() {
+				<-writers
+				wg.Done()
+			}()
+			if err := m.messageRepo.UpdateUserChat(ctx, member, message, ttl); err != nil {
+				m.logger.Errorf("[UpdateUserChats] Failed to move conversation %s up the chat list of %s: %v", message.ConversationID, member, err)
+			}
+		}(member)
+	}
+	wg.Wait()
+	return nil
+}
@@ -0,0 +1,22 @@
+package service
+
+import "testing"
+
+func TestUnreadCountLeavesOutSkippedIDs(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		seen, last int64
+		skipped    []int64
+		want       int64
+	}{
+		{name: "all read", seen: 7, last: 7, want: 0},
+		{name: "dense", seen: 3, last: 7, want: 4},
+		{name: "gap after the receipt", seen: 3, last: 7, skipped: []int64{5}, want: 3},
+		// Gaps already read or after the last message don't count.
+		{name: "gaps outside", seen: 3, last: 7, skipped: []int64{2, 3, 8}, want: 4},
+	} {
+		if got := unreadCount(test.seen, test.last, test.skipped); got != test.want {
+			t.Errorf("%s: unreadCount = %d, want %d", test.name, got, test.want)
+		}
+	}
+}
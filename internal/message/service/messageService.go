@@ -2,12 +2,13 @@ package service
 
 import (
 	"context"
-	"fmt"
 	"graduation-thesis/internal/message/model"
 	"graduation-thesis/internal/message/repository"
+	"graduation-thesis/pkg/custom_error"
 	"graduation-thesis/pkg/logger"
+	"graduation-thesis/pkg/membership"
 	responseModel "graduation-thesis/pkg/model"
-	request "graduation-thesis/pkg/requests"
+	"graduation-thesis/pkg/pb/grouppb"
 	"math"
 	"net/http"
 	"time"
@@ -15,16 +16,33 @@ import (
 
 const MAXRETRY = 5
 
+// BlockLister returns the users userID blocked or was blocked by.
+type BlockLister interface {
+	GetBlockedUsers(ctx context.Context, userID string) (map[string]bool, error)
+}
+
 type MessageService struct {
 	messageRepo     *repository.MessageRepo
-	groupServiceUrl string
+	membershipCache *membership.Cache
+	groupClient     grouppb.GroupServiceClient
+	blockRepo       BlockLister
+	errorMap        map[error]int
 	logger          logger.Logger
 }
 
-func NewMessageService(messageRepo *repository.MessageRepo, groupServiceUrl string, logger logger.Logger) *MessageService {
+func NewMessageService(
+	messageRepo *repository.MessageRepo,
+	membershipCache *membership.Cache,
+	groupClient grouppb.GroupServiceClient,
+	blockRepo BlockLister,
+	errorMap map[error]int,
+	logger logger.Logger) *MessageService {
 	return &MessageService{
 		messageRepo:     messageRepo,
-		groupServiceUrl: groupServiceUrl,
+		membershipCache: membershipCache,
+		groupClient:     groupClient,
+		blockRepo:       blockRepo,
+		errorMap:        errorMap,
 		logger:          logger,
 	}
 }
@@ -123,43 +141,12 @@ func (m *MessageService) UserInbox(ctx context.Context, userID string, limit, of
 	return &successResponse, nil
 }
 
-func (m *MessageService) getConversationMembers(ctx context.Context, userID, conversationID string) ([]string, error) {
-	var (
-		result interface{}
-		err    error
-	)
-	for i := 1; i <= 5; i++ {
-		result, err = request.HTTPRequestCall(
-			fmt.Sprintf("%s/conversation/%s", m.groupServiceUrl, conversationID),
-			http.MethodGet,
-			userID,
-			nil,
-			5*time.Second,
-		)
-		if err != nil {
-			time.Sleep(time.Second)
-			continue
-		}
-	}
-	if err != nil {
-		return nil, err
-	}
-
-	type conversation struct {
-		ID      string   `json:"id"`
-		Members []string `json:"members"`
-	}
-
-	membersInterface := result.(map[string]interface{})["members"].([]interface{})
-	members := make([]string, len(membersInterface))
-	for i, value := range membersInterface {
-		members[i] = fmt.Sprintf("%v", value)
-	}
-	return members, nil
+func (m *MessageService) getConversationMembers(ctx context.Context, conversationID string) ([]string, error) {
+	return m.membershipCache.Get(ctx, conversationID)
 }
 
 func (m *MessageService) GetConversationMessages(ctx context.Context, userID, conversationID string, limit int, beforeMsg int64) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
-	conversationMembers, cErr := m.getConversationMembers(ctx, userID, conversationID)
+	conversationMembers, cErr := m.getConversationMembers(ctx, conversationID)
 	if cErr != nil {
 		errorMessage := responseModel.ErrorResponse{
 			Status:       http.StatusInternalServerError,
@@ -202,8 +189,38 @@ func (m *MessageService) GetConversationMessages(ctx context.Context, userID, co
 	return &successResponse, nil
 }
 
-func (m *MessageService) GetReadReceipts(ctx context.Context, readReceiptRequest *model.ReadReceiptRequest) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+// GetReadReceipts returns the read receipts of a conversation, or the one of
+// readReceiptRequest.UserID, as requesterID may see them. Receipts of users who
+// blocked requesterID or were blocked by them read as if nothing was read, like
+// the peer read position of the chat list. Internal callers pass no requester.
+func (m *MessageService) GetReadReceipts(ctx context.Context, requesterID string, readReceiptRequest *model.ReadReceiptRequest) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	var blockedUsers map[string]bool
+	if requesterID != "" {
+		var err error
+		blockedUsers, err = m.blockRepo.GetBlockedUsers(ctx, requesterID)
+		if err != nil {
+			status, ok := m.errorMap[err]
+			if !ok {
+				status = http.StatusInternalServerError
+			}
+			errorResponse := responseModel.ErrorResponse{
+				Status:       status,
+				ErrorMessage: err.Error(),
+			}
+
+			return nil, &errorResponse
+		}
+	}
+
 	if userID := readReceiptRequest.UserID; userID != "" {
+		if blockedUsers[userID] {
+			successResponse := responseModel.SuccessResponse{
+				Status: http.StatusOK,
+				Result: &model.ReadReceipt{ConversationID: readReceiptRequest.ConversationID, UserID: userID},
+			}
+			return &successResponse, nil
+		}
+
 		readReceipt, err := m.messageRepo.GetReadReceipt(ctx, readReceiptRequest.ConversationID, userID)
 		if err != nil {
 			errorResponse := responseModel.ErrorResponse{
@@ -233,11 +250,25 @@ func (m *MessageService) GetReadReceipts(ctx context.Context, readReceiptRequest
 
 	successResponse := responseModel.SuccessResponse{
 		Status: http.StatusOK,
-		Result: readReceipts,
+		Result: withoutBlockedUsers(readReceipts, blockedUsers),
 	}
 	return &successResponse, nil
 }
 
+// withoutBlockedUsers drops the read receipts of blockedUsers.
+func withoutBlockedUsers(readReceipts []*model.ReadReceipt, blockedUsers map[string]bool) []*model.ReadReceipt {
+	if len(blockedUsers) == 0 {
+		return readReceipts
+	}
+	visible := readReceipts[:0]
+	for _, readReceipt := range readReceipts {
+		if !blockedUsers[readReceipt.UserID] {
+			visible = append(visible, readReceipt)
+		}
+	}
+	return visible
+}
+
 func (m *MessageService) UpdateReadReceipts(ctx context.Context, updateReadReceiptRequest *model.UpdateReadReceiptRequest) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
 	err := m.messageRepo.UpdateReadReceipts(ctx, updateReadReceiptRequest.ConversationID, updateReadReceiptRequest.ReadReceiptUpdate)
 	if err != nil {
@@ -271,13 +302,44 @@ func (m *MessageService) SendMessage(ctx context.Context, request *model.SendMes
 	if request.MessageTime == 0 {
 		request.MessageTime = time.Now().Unix()
 	}
+	if request.Type == "" {
+		request.Type = model.MESSAGE_TYPE
+	}
+
+	policy, err := m.checkPost(ctx, request)
+	if err != nil {
+		status, ok := m.errorMap[err]
+		if !ok {
+			status = http.StatusInternalServerError
+		}
+		errorMessage := responseModel.ErrorResponse{
+			Status:       status,
+			ErrorMessage: err.Error(),
+		}
+		return nil, &errorMessage
+	}
 
 	for i := 0; i < MAXRETRY; i++ {
-		convMsgID, createErr = m.messageRepo.CreateConversationMessage(ctx, request.ConversationID, request.Sender, request.Content, request.IV, request.MessageTime)
+		convMsgID, createErr = m.messageRepo.NextMessageID(ctx, request.ConversationID)
 		if createErr == nil {
 			break
 		}
 	}
+	// A failed write is retried under the id already allocated, as an id left
+	// without a message would count as unread in GetChats. An id that cannot be
+	// stored is given up so it is not counted either.
+	if createErr == nil {
+		for i := 0; i < MAXRETRY; i++ {
+			createErr = m.messageRepo.CreateConversationMessage(ctx, request.ConversationID, convMsgID, policy.ConvType, request.Sender,
+				request.Content, request.IV, request.Type, request.MessageTime, int(policy.MessageRetention))
+			if createErr == nil {
+				break
+			}
+		}
+		if createErr != nil {
+			m.skipMessageID(context.WithoutCancel(ctx), request.ConversationID, convMsgID)
+		}
+	}
 
 	if createErr != nil {
 		errorMessage := responseModel.ErrorResponse{
@@ -286,13 +348,42 @@ func (m *MessageService) SendMessage(ctx context.Context, request *model.SendMes
 		}
 		return nil, &errorMessage
 	}
-	go m.InsertUserInboxes(ctx, request.ConversationID, request.Sender, request.Content, request.IV, convMsgID, request.MessageTime)
-	go m.messageRepo.UpdateReadReceipts(ctx, request.ConversationID, []model.ReadReceiptUpdate{
-		{
-			UserID:    request.Sender,
-			MessageID: convMsgID,
-		},
-	})
+	// The writes below outlive the request, whose context is cancelled as soon
+	// as the response is sent.
+	background := context.WithoutCancel(ctx)
+	if policy.ConvType != model.CONVERSATION_TYPE_CHANNEL { // Subscribers read the channel timeline instead of an inbox copy
+		go func() {
+			err := m.InsertUserInboxes(background, request.ConversationID, request.Sender, request.Content, request.IV, request.Type, convMsgID, request.MessageTime)
+			if err != nil {
+				m.logger.Errorf("[SendMessage] Failed to insert inboxes of message %d of conversation %s: %v", convMsgID, request.ConversationID, err)
+			}
+		}()
+	}
+	go func() {
+		err := m.UpdateUserChats(background, &model.ConversationMessage{
+			ConversationID:        request.ConversationID,
+			ConversationMessageID: convMsgID,
+			MessageTime:           request.MessageTime,
+			Sender:                request.Sender,
+			Content:               request.Content,
+			IV:                    request.IV,
+			Type:                  request.Type,
+		}, int(policy.MessageRetention))
+		if err != nil {
+			m.logger.Errorf("[SendMessage] Failed to update chat lists for message %d of conversation %s: %v", convMsgID, request.ConversationID, err)
+		}
+	}()
+	go func() {
+		err := m.messageRepo.UpdateReadReceipts(background, request.ConversationID, []model.ReadReceiptUpdate{
+			{
+				UserID:    request.Sender,
+				MessageID: convMsgID,
+			},
+		})
+		if err != nil {
+			m.logger.Errorf("[SendMessage] Failed to update read receipt of %s in conversation %s: %v", request.Sender, request.ConversationID, err)
+		}
+	}()
 
 	successMessage := responseModel.SuccessResponse{
 		Status: http.StatusCreated,
@@ -301,13 +392,41 @@ func (m *MessageService) SendMessage(ctx context.Context, request *model.SendMes
 	return &successMessage, nil
 }
 
-func (m *MessageService) InsertUserInboxes(ctx context.Context, conversationID, sender, content, iv string, convMsgID, messageTime int64) error {
+func (m *MessageService) skipMessageID(ctx context.Context, conversationID string, convMsgID int64) {
+	var err error
+	for i := 0; i < MAXRETRY; i++ {
+		if err = m.messageRepo.SkipMessageID(ctx, conversationID, convMsgID); err == nil {
+			return
+		}
+	}
+	m.logger.Errorf("[SendMessage] Cannot give up id %d of conversation %s, it counts as unread: %v", convMsgID, conversationID, err)
+}
+
+// checkPost asks the group service whether the sender may post in the conversation
+// and how long the message has to be kept.
+func (m *MessageService) checkPost(ctx context.Context, request *model.SendMessageRequest) (*grouppb.CheckPostResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	policy, err := m.groupClient.CheckPost(ctx, &grouppb.CheckPostRequest{
+		ConvId: request.ConversationID,
+		UserId: request.Sender,
+		Type:   request.Type,
+	})
+	if err != nil {
+		m.logger.Errorf("[SendMessage] User %v cannot post in conversation %v: %v", request.Sender, request.ConversationID, err)
+		return nil, custom_error.HandleGRPCError(err)
+	}
+	return policy, nil
+}
+
+func (m *MessageService) InsertUserInboxes(ctx context.Context, conversationID, sender, content, iv, messageType string, convMsgID, messageTime int64) error {
 	var (
 		members []string
 		err     error
 	)
 	for i := 0; i < MAXRETRY; i++ {
-		members, err = m.getConversationMembers(ctx, sender, conversationID)
+		members, err = m.getConversationMembers(ctx, conversationID)
 		if err == nil {
 			break
 		}
@@ -323,12 +442,37 @@ func (m *MessageService) InsertUserInboxes(ctx context.Context, conversationID,
 			continue
 		}
 
-		go m.messageRepo.InsertUserInbox(ctx, member, conversationID, sender, content, iv, convMsgID, messageTime)
+		go func(member string) {
+			err := m.messageRepo.InsertUserInbox(ctx, member, conversationID, sender, content, iv, messageType, convMsgID, messageTime)
+			if err != nil {
+				m.logger.Errorf("[InsertUserInboxes] Failed to insert message %d of conversation %s into the inbox of %s: %v", convMsgID, conversationID, member, err)
+			}
+		}(member)
 	}
 
 	return nil
 }
 
+func (m *MessageService) ConfirmUserInboxes(ctx context.Context, usersID []string, message *model.ConversationMessage) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	for _, userID := range usersID {
+		err := m.messageRepo.InsertUserInbox(ctx, userID, message.ConversationID, message.Sender, message.Content, message.IV, message.Type, message.ConversationMessageID, message.MessageTime)
+		if err != nil {
+			m.logger.Errorf("[ConfirmUserInboxes] Cannot store message %v of conversation %v in inbox of user %v: %v",
+				message.ConversationMessageID, message.ConversationID, userID, err)
+			errorMessage := responseModel.ErrorResponse{
+				Status:       http.StatusInternalServerError,
+				ErrorMessage: err.Error(),
+			}
+			return nil, &errorMessage
+		}
+	}
+
+	successMessage := responseModel.SuccessResponse{
+		Status: http.StatusNoContent,
+	}
+	return &successMessage, nil
+}
+
 func (m *MessageService) DeleteUserInbox(ctx context.Context, userID, conversationID string) error {
 	if err := m.messageRepo.DeleteUserInbox(ctx, userID, conversationID); err != nil {
 		return err
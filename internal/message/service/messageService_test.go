@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"graduation-thesis/internal/message/model"
+	"graduation-thesis/pkg/custom_error"
+
+	"go.uber.org/zap"
+)
+
+// fakeBlockLister holds who blocked whom, blocks go both ways.
+type fakeBlockLister map[string][]string
+
+func (f fakeBlockLister) GetBlockedUsers(ctx context.Context, userID string) (map[string]bool, error) {
+	users := make(map[string]bool)
+	for blocker, blocked := range f {
+		for _, user := range blocked {
+			if blocker == userID {
+				users[user] = true
+			}
+			if user == userID {
+				users[blocker] = true
+			}
+		}
+	}
+	return users, nil
+}
+
+func TestBlockedUserCannotReadReceipt(t *testing.T) {
+	// The receipt is answered before the store is read.
+	m := NewMessageService(nil, nil, nil, fakeBlockLister{"bob": {"mallory"}}, custom_error.MappingError(), zap.NewNop().Sugar())
+
+	successResponse, errorResponse := m.GetReadReceipts(context.Background(), "mallory", &model.ReadReceiptRequest{ConversationID: "conv-1", UserID: "bob"})
+	if errorResponse != nil || successResponse.Status != http.StatusOK {
+		t.Fatalf("GetReadReceipts = %+v, %+v", successResponse, errorResponse)
+	}
+	if readReceipt := successResponse.Result.(*model.ReadReceipt); readReceipt.UserID != "bob" || readReceipt.MessageID != 0 {
+		t.Fatalf("mallory read %+v, want nothing read", readReceipt)
+	}
+}
+
+func TestReadReceiptsLeaveOutBlockedUsers(t *testing.T) {
+	readReceipts := []*model.ReadReceipt{
+		{ConversationID: "group-1", UserID: "alice", MessageID: 3},
+		{ConversationID: "group-1", UserID: "bob", MessageID: 5},
+		{ConversationID: "group-1", UserID: "carol", MessageID: 4},
+	}
+	blockedUsers, _ := fakeBlockLister{"bob": {"mallory"}}.GetBlockedUsers(context.Background(), "mallory")
+
+	visible := withoutBlockedUsers(readReceipts, blockedUsers)
+	if len(visible) != 2 || visible[0].UserID != "alice" || visible[1].UserID != "carol" {
+		t.Fatalf("mallory sees the receipts of %+v", visible)
+	}
+	if visible := withoutBlockedUsers(readReceipts[:2], nil); len(visible) != 2 {
+		t.Fatalf("without blocks %d receipts are visible, want 2", len(visible))
+	}
+}
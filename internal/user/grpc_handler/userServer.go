@@ -0,0 +1,116 @@
+package grpc_handler
+
+import (
+	"context"
+
+	"graduation-thesis/internal/user/model"
+	"graduation-thesis/internal/user/service"
+	"graduation-thesis/pkg/custom_error"
+	"graduation-thesis/pkg/pb/userpb"
+)
+
+type UserServer struct {
+	userpb.UnimplementedUserServiceServer
+	blockService    *service.BlockService
+	userService     *service.UserService
+	identityService *service.IdentityService
+}
+
+func NewUserServer(blockService *service.BlockService, userService *service.UserService, identityService *service.IdentityService) *UserServer {
+	return &UserServer{
+		blockService:    blockService,
+		userService:     userService,
+		identityService: identityService,
+	}
+}
+
+func (u *UserServer) GetBlockRelation(ctx context.Context, request *userpb.GetBlockRelationRequest) (*userpb.BlockRelation, error) {
+	successResponse, errorResponse := u.blockService.GetBlockRelation(ctx, request.UserId, request.OtherUserId)
+	if errorResponse != nil {
+		return nil, custom_error.StatusToGRPCError(errorResponse.Status, errorResponse.ErrorMessage)
+	}
+
+	relation := successResponse.Result.(*model.BlockRelation)
+	return &userpb.BlockRelation{
+		Blocked:   relation.Blocked,
+		BlockedBy: relation.BlockedBy,
+	}, nil
+}
+
+func (u *UserServer) GetBlockedUsers(ctx context.Context, request *userpb.GetBlockedUsersRequest) (*userpb.GetBlockedUsersResponse, error) {
+	successResponse, errorResponse := u.blockService.GetRelatedUsers(ctx, request.UserId)
+	if errorResponse != nil {
+		return nil, custom_error.StatusToGRPCError(errorResponse.Status, errorResponse.ErrorMessage)
+	}
+
+	return &userpb.GetBlockedUsersResponse{
+		Users: successResponse.Result.([]string),
+	}, nil
+}
+
+func (u *UserServer) GetAccount(ctx context.Context, request *userpb.GetAccountRequest) (*userpb.Account, error) {
+	successResponse, errorResponse := u.userService.GetAccount(ctx, request.UserId, request.Username)
+	if errorResponse != nil {
+		return nil, custom_error.StatusToGRPCError(errorResponse.Status, errorResponse.ErrorMessage)
+	}
+
+	user := successResponse.Result.(*model.User)
+	return &userpb.Account{
+		UserId:   user.ID,
+		Email:    user.Email,
+		Username: user.Username,
+	}, nil
+}
+
+func (u *UserServer) SetPassword(ctx context.Context, request *userpb.SetPasswordRequest) (*userpb.SetPasswordResponse, error) {
+	_, errorResponse := u.userService.SetPassword(ctx, request.UserId, request.Password)
+	if errorResponse != nil {
+		return nil, custom_error.StatusToGRPCError(errorResponse.Status, errorResponse.ErrorMessage)
+	}
+
+	return &userpb.SetPasswordResponse{}, nil
+}
+
+func (u *UserServer) LinkExternalIdentity(ctx context.Context, request *userpb.LinkExternalIdentityRequest) (*userpb.LinkExternalIdentityResponse, error) {
+	profile := request.GetProfile()
+	externalProfile := model.ExternalProfile{
+		Username:      profile.GetUsername(),
+		Email:         profile.GetEmail(),
+		EmailVerified: profile.GetEmailVerified(),
+		FirstName:     profile.GetFirstName(),
+		LastName:      profile.GetLastName(),
+		PhoneNumber:   profile.GetPhoneNumber(),
+	}
+	if profile.GetAvatar() != "" {
+		avatar := model.Avatar(profile.GetAvatar())
+		externalProfile.Avatar = &avatar
+	}
+
+	successResponse, errorResponse := u.identityService.LinkExternalIdentity(ctx, request.Provider, request.Subject, request.LinkByEmail, externalProfile)
+	if errorResponse != nil {
+		return nil, custom_error.StatusToGRPCError(errorResponse.Status, errorResponse.ErrorMessage)
+	}
+
+	result := successResponse.Result.(*service.LinkResult)
+	return &userpb.LinkExternalIdentityResponse{
+		UserId:  result.UserID,
+		Created: result.Created,
+	}, nil
+}
+
+// VerifyCredential checks a login for the authenticator, counting failures
+// against the client address it saw.
+func (u *UserServer) VerifyCredential(ctx context.Context, request *userpb.VerifyCredentialRequest) (*userpb.VerifyCredentialResponse, error) {
+	loginRequest := model.LoginRequest{
+		Username: request.Username,
+		Password: request.Password,
+	}
+	successResponse, errorResponse := u.userService.VerifyCredential(ctx, &loginRequest, request.Ip)
+	if errorResponse != nil {
+		return nil, custom_error.StatusToGRPCErrorWithRetry(errorResponse.Status, errorResponse.ErrorMessage, errorResponse.RetryAfter)
+	}
+
+	return &userpb.VerifyCredentialResponse{
+		UserId: successResponse.Result.(string),
+	}, nil
+}
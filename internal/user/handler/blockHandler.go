@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"graduation-thesis/internal/user/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type BlockHandler struct {
+	blockService         *service.BlockService
+	authenticatorAddress string
+}
+
+func NewBlockHandler(blockService *service.BlockService, authenticatorAddress string) *BlockHandler {
+	return &BlockHandler{
+		blockService:         blockService,
+		authenticatorAddress: authenticatorAddress,
+	}
+}
+
+func (b *BlockHandler) GetBlocks(c *gin.Context) {
+	userID := c.Request.Header.Get("X-User-ID")
+	successResponse, errorResponse := b.blockService.GetBlocks(c, userID)
+	if errorResponse != nil {
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	c.JSON(successResponse.Status, successResponse)
+}
+
+func (b *BlockHandler) Block(c *gin.Context) {
+	userID := c.Request.Header.Get("X-User-ID")
+	blockedUserID := c.Param("user_id")
+	successResponse, errorResponse := b.blockService.Block(c, userID, blockedUserID)
+	if errorResponse != nil {
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	c.JSON(successResponse.Status, successResponse)
+}
+
+func (b *BlockHandler) Unblock(c *gin.Context) {
+	userID := c.Request.Header.Get("X-User-ID")
+	blockedUserID := c.Param("user_id")
+	successResponse, errorResponse := b.blockService.Unblock(c, userID, blockedUserID)
+	if errorResponse != nil {
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	c.JSON(successResponse.Status, successResponse)
+}
@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"graduation-thesis/internal/user/model"
+	"graduation-thesis/internal/user/service"
+	responseModel "graduation-thesis/pkg/model"
+)
+
+type LoginGuardHandler struct {
+	loginGuardService    *service.LoginGuardService
+	authenticatorAddress string
+}
+
+func NewLoginGuardHandler(loginGuardService *service.LoginGuardService, authenticatorAddress string) *LoginGuardHandler {
+	return &LoginGuardHandler{
+		loginGuardService:    loginGuardService,
+		authenticatorAddress: authenticatorAddress,
+	}
+}
+
+func (l *LoginGuardHandler) Unlock(c *gin.Context) {
+	var unlockRequest model.UnlockLoginRequest
+	if err := c.ShouldBindQuery(&unlockRequest); err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       http.StatusBadRequest,
+			ErrorMessage: model.ErrInvalidParameter.Error(),
+		}
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	userID := c.Request.Header.Get("X-User-ID")
+	successResponse, errorResponse := l.loginGuardService.Unlock(c, userID, &unlockRequest)
+	if errorResponse != nil {
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	c.JSON(successResponse.Status, successResponse)
+}
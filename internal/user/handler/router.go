@@ -8,7 +8,7 @@ import (
 
 var router *gin.Engine
 
-func InitRouter(authHandler *AuthHandler, userHandler *UserHandler) {
+func InitRouter(userHandler *UserHandler, blockHandler *BlockHandler, loginGuardHandler *LoginGuardHandler, verificationHandler *VerificationHandler) {
 	router = gin.Default()
 
 	router.GET("/health", func(ctx *gin.Context) {
@@ -17,27 +17,38 @@ func InitRouter(authHandler *AuthHandler, userHandler *UserHandler) {
 	router.Use(middleware.Headers())
 	router.Use(middleware.SetupCors())
 
-	authPath := router.Group("/v1/auth")
-	{
-		authPath.POST("/login", authHandler.Login)
-		authPath.POST("/refresh", authHandler.Refresh)
-		authPath.GET("/logout", authHandler.AuthMiddleware(), authHandler.Logout)
-	}
-
 	userPath := router.Group("/v1/user")
 	{
-		userPath.POST("/verify", userHandler.Verify)
 		userPath.GET("/:id", userHandler.GetUser)
 		userPath.GET("", userHandler.GetUserByUsername)
 		userPath.GET("/all", userHandler.GetAllUser)
 		userPath.POST("", userHandler.Register)
-		userPath.PUT("/:id", middleware.AuthMiddlewareV2(userHandler.authenticatorURL), userHandler.UpdateUser)
+		userPath.PUT("/:id", middleware.AuthMiddlewareV2(userHandler.authenticatorAddress), userHandler.UpdateUser)
+	}
+
+	verificationPath := router.Group("/v1/user/verification")
+	{
+		verificationPath.GET("/email", verificationHandler.VerifyEmail)
+		verificationPath.POST("/phone", middleware.AuthMiddlewareV2(verificationHandler.authenticatorAddress), verificationHandler.VerifyPhone)
+		verificationPath.POST("/resend", middleware.AuthMiddlewareV2(verificationHandler.authenticatorAddress), verificationHandler.Resend)
+	}
+
+	blockPath := router.Group("/v1/block")
+	{
+		blockPath.GET("", middleware.AuthMiddlewareV2(blockHandler.authenticatorAddress), blockHandler.GetBlocks)
+		blockPath.PUT("/:user_id", middleware.AuthMiddlewareV2(blockHandler.authenticatorAddress), blockHandler.Block)
+		blockPath.DELETE("/:user_id", middleware.AuthMiddlewareV2(blockHandler.authenticatorAddress), blockHandler.Unblock)
+	}
+
+	adminPath := router.Group("/v1/admin")
+	{
+		adminPath.DELETE("/login-locks", middleware.AuthMiddlewareV2(loginGuardHandler.authenticatorAddress), loginGuardHandler.Unlock)
 	}
 }
 
-func GetRouter(authHandler *AuthHandler, userHandler *UserHandler) *gin.Engine {
+func GetRouter(userHandler *UserHandler, blockHandler *BlockHandler, loginGuardHandler *LoginGuardHandler, verificationHandler *VerificationHandler) *gin.Engine {
 	if router == nil {
-		InitRouter(authHandler, userHandler)
+		InitRouter(userHandler, blockHandler, loginGuardHandler, verificationHandler)
 	}
 	return router
 }
@@ -11,14 +11,14 @@ import (
 )
 
 type UserHandler struct {
-	userService      *service.UserService
-	authenticatorURL string
+	userService          *service.UserService
+	authenticatorAddress string
 }
 
-func NewUserHandler(userService *service.UserService, authenticatorURL string) *UserHandler {
+func NewUserHandler(userService *service.UserService, authenticatorAddress string) *UserHandler {
 	return &UserHandler{
-		userService:      userService,
-		authenticatorURL: authenticatorURL,
+		userService:          userService,
+		authenticatorAddress: authenticatorAddress,
 	}
 }
 
@@ -116,23 +116,3 @@ func (u *UserHandler) UpdateUser(c *gin.Context) {
 
 	c.JSON(successResponse.Status, successResponse)
 }
-
-func (u *UserHandler) Verify(c *gin.Context) {
-	var loginRequest model.LoginRequest
-	if err := c.ShouldBindJSON(&loginRequest); err != nil {
-		errorResponse := responseModel.ErrorResponse{
-			Status:       http.StatusBadRequest,
-			ErrorMessage: model.ErrInvalidParameter.Error(),
-		}
-		c.JSON(errorResponse.Status, errorResponse)
-		return
-	}
-
-	successResponse, errorResposne := u.userService.VerifyCredential(c, &loginRequest)
-	if errorResposne != nil {
-		c.JSON(errorResposne.Status, errorResposne)
-		return
-	}
-
-	c.JSON(successResponse.Status, successResponse)
-}
@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"graduation-thesis/internal/user/model"
+	"graduation-thesis/internal/user/service"
+	responseModel "graduation-thesis/pkg/model"
+)
+
+type VerificationHandler struct {
+	verificationService  *service.VerificationService
+	authenticatorAddress string
+}
+
+func NewVerificationHandler(verificationService *service.VerificationService, authenticatorAddress string) *VerificationHandler {
+	return &VerificationHandler{
+		verificationService:  verificationService,
+		authenticatorAddress: authenticatorAddress,
+	}
+}
+
+// VerifyEmail is where the emailed link points, so it takes the token from the
+// query.
+func (v *VerificationHandler) VerifyEmail(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       http.StatusBadRequest,
+			ErrorMessage: model.ErrInvalidParameter.Error(),
+		}
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	successResponse, errorResponse := v.verificationService.VerifyEmail(c, token)
+	if errorResponse != nil {
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	c.JSON(successResponse.Status, successResponse)
+}
+
+func (v *VerificationHandler) VerifyPhone(c *gin.Context) {
+	var verifyPhoneRequest model.VerifyPhoneRequest
+	if err := c.ShouldBindJSON(&verifyPhoneRequest); err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       http.StatusBadRequest,
+			ErrorMessage: model.ErrInvalidParameter.Error(),
+		}
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	userID := c.Request.Header.Get("X-User-ID")
+	successResponse, errorResponse := v.verificationService.VerifyPhone(c, userID, verifyPhoneRequest.Code)
+	if errorResponse != nil {
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	c.JSON(successResponse.Status, successResponse)
+}
+
+func (v *VerificationHandler) Resend(c *gin.Context) {
+	var resendRequest model.ResendVerificationRequest
+	if err := c.ShouldBindJSON(&resendRequest); err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       http.StatusBadRequest,
+			ErrorMessage: model.ErrInvalidParameter.Error(),
+		}
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	userID := c.Request.Header.Get("X-User-ID")
+	successResponse, errorResponse := v.verificationService.Resend(c, userID, resendRequest.Channel)
+	if errorResponse != nil {
+		if errorResponse.RetryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(errorResponse.RetryAfter))
+		}
+		c.JSON(errorResponse.Status, errorResponse)
+		return
+	}
+
+	c.JSON(successResponse.Status, successResponse)
+}
@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+type Block struct {
+	UserID        string    `json:"user_id"`
+	BlockedUserID string    `json:"blocked_user_id"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// BlockRelation tells whether a user blocked another one and whether they
+// were blocked by them.
+type BlockRelation struct {
+	Blocked   bool `json:"blocked"`
+	BlockedBy bool `json:"blocked_by"`
+}
@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// ExternalIdentity links the subject of an identity provider to a user.
+type ExternalIdentity struct {
+	Provider  string
+	Subject   string
+	UserID    string
+	CreatedAt time.Time
+}
+
+// ExternalProfile is what the identity provider says about its subject, used
+// to fill in the profile of users provisioned on their first login.
+type ExternalProfile struct {
+	Username      string
+	Email         string
+	EmailVerified bool
+	FirstName     string
+	LastName      string
+	PhoneNumber   string
+	Avatar        *Avatar
+}
@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+const (
+	LoginReasonBadCredentials = "bad_credentials"
+	LoginReasonLocked         = "locked"
+)
+
+// LoginAuditEvent records one login attempt. UserID is empty when the username
+// does not belong to anybody.
+type LoginAuditEvent struct {
+	ID        string    `json:"id"`
+	Username  string    `json:"username"`
+	UserID    string    `json:"user_id,omitempty"`
+	IP        string    `json:"ip"`
+	Success   bool      `json:"success"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type UnlockLoginRequest struct {
+	Username string `form:"username"`
+	IP       string `form:"ip"`
+}
@@ -14,6 +14,15 @@ type User struct {
 	LastUpdated time.Time
 	Avatar      *Avatar
 	PublicKey   string
+	// VerifiedAt is set once the first email of the account is verified;
+	// until then the account can't be found by other users.
+	VerifiedAt      *time.Time
+	EmailVerifiedAt *time.Time
+	PhoneVerifiedAt *time.Time
+}
+
+func (u *User) Verified() bool {
+	return u.VerifiedAt != nil
 }
 
 type Avatar string
@@ -0,0 +1,14 @@
+package model
+
+const (
+	VerificationChannelEmail = "email"
+	VerificationChannelPhone = "phone"
+)
+
+type VerifyPhoneRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type ResendVerificationRequest struct {
+	Channel string `json:"channel" binding:"required,oneof=email phone"`
+}
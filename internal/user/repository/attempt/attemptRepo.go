@@ -0,0 +1,127 @@
+package attempt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// AttemptRepo keeps failed login counters and login locks. Counters live in
+// fixed buckets one window long; Failures weighs the previous bucket by how
+// much of it still overlaps the sliding window ending now.
+type AttemptRepo struct {
+	redis *redis.Client
+}
+
+func NewAttemptRepo(redisClient *redis.Client) *AttemptRepo {
+	return &AttemptRepo{
+		redis: redisClient,
+	}
+}
+
+func failuresKey(subject string, bucket int64) string {
+	return fmt.Sprintf("login_failures:%s:%d", subject, bucket)
+}
+
+func lockKey(subject string) string {
+	return fmt.Sprintf("login_lock:%s", subject)
+}
+
+func bucketOf(now time.Time, window time.Duration) int64 {
+	return now.UnixNano() / int64(window)
+}
+
+// AddFailure counts a failed login of subject and returns the failures within
+// the window, this one included.
+func (a *AttemptRepo) AddFailure(ctx context.Context, subject string, window time.Duration, now time.Time) (float64, error) {
+	key := failuresKey(subject, bucketOf(now, window))
+	_, err := a.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Incr(ctx, key)
+		// The bucket is still read as the previous one during the next window.
+		pipe.Expire(ctx, key, 2*window)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return a.Failures(ctx, subject, window, now)
+}
+
+func (a *AttemptRepo) Failures(ctx context.Context, subject string, window time.Duration, now time.Time) (float64, error) {
+	bucket := bucketOf(now, window)
+	values, err := a.redis.MGet(ctx, failuresKey(subject, bucket-1), failuresKey(subject, bucket)).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	previous, err := parseCount(values[0])
+	if err != nil {
+		return 0, err
+	}
+	current, err := parseCount(values[1])
+	if err != nil {
+		return 0, err
+	}
+
+	elapsed := float64(now.UnixNano()-bucket*int64(window)) / float64(window)
+	return previous*(1-elapsed) + current, nil
+}
+
+func (a *AttemptRepo) ResetFailures(ctx context.Context, subject string, window time.Duration, now time.Time) error {
+	bucket := bucketOf(now, window)
+	return a.redis.Del(ctx, failuresKey(subject, bucket-1), failuresKey(subject, bucket)).Err()
+}
+
+// Lock refuses logins of subject until the given time. An existing lock that
+// lasts longer is kept.
+func (a *AttemptRepo) Lock(ctx context.Context, subject string, until time.Time, now time.Time) error {
+	lockedUntil, err := a.LockedUntil(ctx, subject)
+	if err != nil {
+		return err
+	}
+	if !until.After(lockedUntil) || !until.After(now) {
+		return nil
+	}
+
+	return a.redis.Set(ctx, lockKey(subject), until.UnixMilli(), until.Sub(now)).Err()
+}
+
+// LockedUntil returns the zero time if subject is not locked.
+func (a *AttemptRepo) LockedUntil(ctx context.Context, subject string) (time.Time, error) {
+	value, err := a.redis.Get(ctx, lockKey(subject)).Int64()
+	if errors.Is(err, redis.Nil) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.UnixMilli(value), nil
+}
+
+// Unlock lifts the lock of subject and forgets its failures, so that the next
+// failed login does not lock it again straight away.
+func (a *AttemptRepo) Unlock(ctx context.Context, subject string, window time.Duration, now time.Time) error {
+	bucket := bucketOf(now, window)
+	return a.redis.Del(ctx, lockKey(subject), failuresKey(subject, bucket-1), failuresKey(subject, bucket)).Err()
+}
+
+func parseCount(value interface{}) (float64, error) {
+	if value == nil {
+		return 0, nil
+	}
+	s, ok := value.(string)
+	if !ok {
+		return 0, fmt.Errorf("unexpected counter value %v", value)
+	}
+	count, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return float64(count), nil
+}
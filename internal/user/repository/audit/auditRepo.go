@@ -0,0 +1,25 @@
+package audit
+
+import (
+	"context"
+
+	"graduation-thesis/internal/user/model"
+	"graduation-thesis/pkg/custom_error"
+	"graduation-thesis/pkg/interfaces"
+)
+
+type AuditRepo struct {
+	db interfaces.DBTX
+}
+
+func NewAuditRepo(db interfaces.DBTX) *AuditRepo {
+	return &AuditRepo{
+		db: db,
+	}
+}
+
+func (a *AuditRepo) RecordLogin(ctx context.Context, event *model.LoginAuditEvent) error {
+	query := `INSERT INTO login_audit_events (id, username, user_id, ip, success, reason, created_at) VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6, $7)`
+	_, err := a.db.ExecContext(ctx, query, event.ID, event.Username, event.UserID, event.IP, event.Success, event.Reason, event.CreatedAt)
+	return custom_error.HandlePostgreError(err)
+}
@@ -0,0 +1,101 @@
+package block
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"graduation-thesis/internal/user/model"
+	"graduation-thesis/pkg/custom_error"
+	"graduation-thesis/pkg/interfaces"
+)
+
+type BlockRepo struct {
+	db interfaces.DBTX
+}
+
+func NewBlockRepo(db interfaces.DBTX) *BlockRepo {
+	return &BlockRepo{
+		db: db,
+	}
+}
+
+func (b *BlockRepo) WithTx(tx *sql.Tx) *BlockRepo {
+	return &BlockRepo{
+		db: tx,
+	}
+}
+
+// Block is idempotent, blocking somebody twice keeps the first block.
+func (b *BlockRepo) Block(ctx context.Context, userID, blockedUserID string) error {
+	query := `INSERT INTO user_blocks (user_id, blocked_user_id, created_at) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`
+	_, err := b.db.ExecContext(ctx, query, userID, blockedUserID, time.Now())
+	return custom_error.HandlePostgreError(err)
+}
+
+func (b *BlockRepo) Unblock(ctx context.Context, userID, blockedUserID string) error {
+	query := `DELETE FROM user_blocks WHERE user_id = $1 AND blocked_user_id = $2`
+	_, err := b.db.ExecContext(ctx, query, userID, blockedUserID)
+	return custom_error.HandlePostgreError(err)
+}
+
+func (b *BlockRepo) GetBlocks(ctx context.Context, userID string) ([]model.Block, error) {
+	query := `SELECT user_id, blocked_user_id, created_at FROM user_blocks WHERE user_id = $1 ORDER BY created_at DESC`
+	rows, err := b.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, custom_error.HandlePostgreError(err)
+	}
+	defer rows.Close()
+
+	var blocks []model.Block
+	for rows.Next() {
+		var block model.Block
+		if err := rows.Scan(&block.UserID, &block.BlockedUserID, &block.CreatedAt); err != nil {
+			return nil, custom_error.HandlePostgreError(err)
+		}
+		blocks = append(blocks, block)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, custom_error.HandlePostgreError(err)
+	}
+
+	return blocks, nil
+}
+
+func (b *BlockRepo) GetRelation(ctx context.Context, userID, otherUserID string) (*model.BlockRelation, error) {
+	query := `SELECT
+			EXISTS (SELECT 1 FROM user_blocks WHERE user_id = $1 AND blocked_user_id = $2),
+			EXISTS (SELECT 1 FROM user_blocks WHERE user_id = $2 AND blocked_user_id = $1)`
+	var relation model.BlockRelation
+	if err := b.db.QueryRowContext(ctx, query, userID, otherUserID).Scan(&relation.Blocked, &relation.BlockedBy); err != nil {
+		return nil, custom_error.HandlePostgreError(err)
+	}
+	return &relation, nil
+}
+
+// GetRelatedUsers returns the users userID blocked or was blocked by.
+func (b *BlockRepo) GetRelatedUsers(ctx context.Context, userID string) ([]string, error) {
+	query := `SELECT blocked_user_id FROM user_blocks WHERE user_id = $1
+		UNION SELECT user_id FROM user_blocks WHERE blocked_user_id = $1`
+	rows, err := b.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, custom_error.HandlePostgreError(err)
+	}
+	defer rows.Close()
+
+	var users []string
+	for rows.Next() {
+		var user string
+		if err := rows.Scan(&user); err != nil {
+			return nil, custom_error.HandlePostgreError(err)
+		}
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, custom_error.HandlePostgreError(err)
+	}
+
+	return users, nil
+}
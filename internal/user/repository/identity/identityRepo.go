@@ -0,0 +1,69 @@
+package identity
+
+import (
+	"context"
+	"time"
+
+	"graduation-thesis/internal/user/model"
+	"graduation-thesis/pkg/custom_error"
+	"graduation-thesis/pkg/interfaces"
+)
+
+type IdentityRepo struct {
+	db interfaces.DBTX
+}
+
+func NewIdentityRepo(db interfaces.DBTX) *IdentityRepo {
+	return &IdentityRepo{
+		db: db,
+	}
+}
+
+// GetUserID returns the user the subject is linked to, or
+// custom_error.ErrNotFound.
+func (i *IdentityRepo) GetUserID(ctx context.Context, provider, subject string) (string, error) {
+	query := `SELECT user_id FROM external_identities WHERE provider = $1 AND subject = $2`
+	var userID string
+	err := i.db.QueryRowContext(ctx, query, provider, subject).Scan(&userID)
+	return userID, custom_error.HandlePostgreError(err)
+}
+
+// Link returns custom_error.ErrConflict if the subject is already linked.
+func (i *IdentityRepo) Link(ctx context.Context, identity *model.ExternalIdentity) error {
+	query := `INSERT INTO external_identities (provider, subject, user_id, created_at) VALUES ($1, $2, $3, $4)`
+	_, err := i.db.ExecContext(ctx, query, identity.Provider, identity.Subject, identity.UserID, time.Now())
+	return custom_error.HandlePostgreError(err)
+}
+
+// Provision creates the user and links the identity to it in one statement,
+// so a failed link leaves no user behind. An email verified by the provider
+// verifies the account. It returns custom_error.ErrConflict if the username,
+// the email or the subject is taken.
+func (i *IdentityRepo) Provision(ctx context.Context, params *model.CreateUserParams, identity *model.ExternalIdentity, emailVerified bool) (*model.User, error) {
+	var verifiedAt *time.Time
+	now := time.Now()
+	if emailVerified {
+		verifiedAt = &now
+	}
+
+	query := `WITH new_user AS (
+				INSERT INTO users (id, username, password, first_name, last_name, email, phone_number, created_at, last_updated, avatar, public_key, verified_at, email_verified_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8, $9, '', $10, $10)
+				RETURNING id, username, password, first_name, last_name, email, phone_number, created_at, last_updated, avatar, public_key, verified_at, email_verified_at, phone_verified_at
+			), link AS (
+				INSERT INTO external_identities (provider, subject, user_id, created_at)
+				SELECT $11, $12, id, $8 FROM new_user
+			)
+			SELECT id, username, password, first_name, last_name, email, phone_number, created_at, last_updated, avatar, public_key, verified_at, email_verified_at, phone_verified_at FROM new_user`
+	row := i.db.QueryRowContext(ctx, query, params.ID, params.Username, params.HashPassword,
+		params.FirstName, params.LastName, params.Email, params.PhoneNumber,
+		now, params.Avatar, verifiedAt, identity.Provider, identity.Subject)
+
+	var result model.User
+	err := row.Scan(&result.ID, &result.Username, &result.Password, &result.FirstName, &result.LastName,
+		&result.Email, &result.PhoneNumber, &result.CreatedAt, &result.LastUpdated, &result.Avatar, &result.PublicKey, &result.VerifiedAt, &result.EmailVerifiedAt, &result.PhoneVerifiedAt)
+	if err != nil {
+		return nil, custom_error.HandlePostgreError(err)
+	}
+	return &result, nil
+}
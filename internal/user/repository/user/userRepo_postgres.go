@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"graduation-thesis/internal/user/model"
+	"graduation-thesis/pkg/custom_error"
 	"graduation-thesis/pkg/interfaces"
 	"time"
 )
@@ -26,19 +27,22 @@ func (u *UserRepoPostgres) WithTx(tx *sql.Tx) *UserRepoPostgres {
 
 func (u *UserRepoPostgres) Create(ctx context.Context, params *model.CreateUserParams) (*model.User, error) {
 	query := `INSERT INTO users (id, username, password, first_name, last_name, email, phone_number, created_at, last_updated, avatar, public_key)
-			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id, username, password, first_name, last_name, email, phone_number, created_at, last_updated, avatar, public_key`
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id, username, password, first_name, last_name, email, phone_number, created_at, last_updated, avatar, public_key, verified_at, email_verified_at, phone_verified_at`
 	row := u.db.QueryRowContext(ctx, query, params.ID, params.Username, params.HashPassword,
 		params.FirstName, params.LastName, params.Email, params.PhoneNumber,
 		time.Now(), time.Now(), params.Avatar, "")
 
 	var result model.User
 	err := row.Scan(&result.ID, &result.Username, &result.Password, &result.FirstName, &result.LastName,
-		&result.Email, &result.PhoneNumber, &result.CreatedAt, &result.LastUpdated, &result.Avatar, &result.PublicKey)
+		&result.Email, &result.PhoneNumber, &result.CreatedAt, &result.LastUpdated, &result.Avatar, &result.PublicKey, &result.VerifiedAt, &result.EmailVerifiedAt, &result.PhoneVerifiedAt)
 	return &result, err
 }
 
 func (u *UserRepoPostgres) Update(ctx context.Context, userId string, params model.UpdateUserParams) error {
-	query := `UPDATE users SET first_name = $2, last_name = $3, email = $4, phone_number = $5, last_updated = $6, avatar = $7, password = $8, public_key = $9
+	// A changed email or phone number has to be verified again.
+	query := `UPDATE users SET first_name = $2, last_name = $3, email = $4, phone_number = $5, last_updated = $6, avatar = $7, password = $8, public_key = $9,
+			email_verified_at = CASE WHEN email = $4 THEN email_verified_at END,
+			phone_verified_at = CASE WHEN phone_number = $5 THEN phone_verified_at END
 			WHERE id = $1`
 	_, err := u.db.ExecContext(ctx, query, userId, params.FirstName, params.LastName, params.Email,
 		params.PhoneNumber, time.Now(), params.Avatar, params.Password, params.PublicKey)
@@ -49,53 +53,70 @@ func (u *UserRepoPostgres) Update(ctx context.Context, userId string, params mod
 	return nil
 }
 
+func (u *UserRepoPostgres) UpdatePassword(ctx context.Context, userId string, hashPassword string) error {
+	query := `UPDATE users SET password = $2, last_updated = $3 WHERE id = $1`
+	result, err := u.db.ExecContext(ctx, query, userId, hashPassword, time.Now())
+	if err != nil {
+		return custom_error.HandlePostgreError(err)
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return custom_error.ErrNotFound
+	}
+	return nil
+}
+
 func (u *UserRepoPostgres) Get(ctx context.Context, userId string) (*model.User, error) {
-	query := `SELECT id, username, password, first_name, last_name, email, phone_number, created_at, last_updated, avatar, public_key 
+	query := `SELECT id, username, password, first_name, last_name, email, phone_number, created_at, last_updated, avatar, public_key, verified_at, email_verified_at, phone_verified_at
 				FROM users WHERE id = $1`
 	row := u.db.QueryRowContext(ctx, query, userId)
 
 	var user model.User
 	err := row.Scan(&user.ID, &user.Username, &user.Password, &user.FirstName, &user.LastName, &user.Email,
-		&user.PhoneNumber, &user.CreatedAt, &user.LastUpdated, &user.Avatar, &user.PublicKey)
+		&user.PhoneNumber, &user.CreatedAt, &user.LastUpdated, &user.Avatar, &user.PublicKey, &user.VerifiedAt, &user.EmailVerifiedAt, &user.PhoneVerifiedAt)
 	return &user, err
 }
 
 func (u *UserRepoPostgres) GetForUpdate(ctx context.Context, userId string) (*model.User, error) {
-	query := `SELECT id, username, password, first_name, last_name, email, phone_number, created_at, last_updated, avatar, public_key 
+	query := `SELECT id, username, password, first_name, last_name, email, phone_number, created_at, last_updated, avatar, public_key, verified_at, email_verified_at, phone_verified_at
 				FROM users WHERE id = $1 FOR UPDATE`
 	row := u.db.QueryRowContext(ctx, query, userId)
 
 	var user model.User
 	err := row.Scan(&user.ID, &user.Username, &user.Password, &user.FirstName, &user.LastName, &user.Email,
-		&user.PhoneNumber, &user.CreatedAt, &user.LastUpdated, &user.Avatar, &user.PublicKey)
+		&user.PhoneNumber, &user.CreatedAt, &user.LastUpdated, &user.Avatar, &user.PublicKey, &user.VerifiedAt, &user.EmailVerifiedAt, &user.PhoneVerifiedAt)
 	return &user, err
 }
 
 func (u *UserRepoPostgres) GetByUsername(ctx context.Context, username string) (*model.User, error) {
-	query := `SELECT id, username, password, first_name, last_name, email, phone_number, created_at, last_updated, avatar, public_key 
+	query := `SELECT id, username, password, first_name, last_name, email, phone_number, created_at, last_updated, avatar, public_key, verified_at, email_verified_at, phone_verified_at
 			FROM users WHERE username = $1`
 	row := u.db.QueryRowContext(ctx, query, username)
 
 	var user model.User
 	err := row.Scan(&user.ID, &user.Username, &user.Password, &user.FirstName, &user.LastName, &user.Email,
-		&user.PhoneNumber, &user.CreatedAt, &user.LastUpdated, &user.Avatar, &user.PublicKey)
+		&user.PhoneNumber, &user.CreatedAt, &user.LastUpdated, &user.Avatar, &user.PublicKey, &user.VerifiedAt, &user.EmailVerifiedAt, &user.PhoneVerifiedAt)
 	return &user, err
 }
 
 func (u *UserRepoPostgres) GetByEmail(ctx context.Context, email string) (*model.User, error) {
-	query := `SELECT id, username, password, first_name, last_name, email, phone_number, created_at, last_updated, avatar, public_key 
+	query := `SELECT id, username, password, first_name, last_name, email, phone_number, created_at, last_updated, avatar, public_key, verified_at, email_verified_at, phone_verified_at
 			FROM users WHERE email = $1`
 	row := u.db.QueryRowContext(ctx, query, email)
 
 	var user model.User
 	err := row.Scan(&user.ID, &user.Username, &user.Password, &user.FirstName, &user.LastName, &user.Email,
-		&user.PhoneNumber, &user.CreatedAt, &user.LastUpdated, &user.Avatar, &user.PublicKey)
+		&user.PhoneNumber, &user.CreatedAt, &user.LastUpdated, &user.Avatar, &user.PublicKey, &user.VerifiedAt, &user.EmailVerifiedAt, &user.PhoneVerifiedAt)
 	return &user, err
 }
 
 func (u *UserRepoPostgres) GetAll(ctx context.Context, contain string, limit, offset int) ([]*model.User, error) {
-	query := `SELECT id, username, password, first_name, last_name, email, phone_number, created_at, last_updated, avatar, public_key
-			FROM users WHERE username LIKE '%' || $1 || '%' AND public_key <> '' LIMIT $2 OFFSET $3`
+	query := `SELECT id, username, password, first_name, last_name, email, phone_number, created_at, last_updated, avatar, public_key, verified_at, email_verified_at, phone_verified_at
+			FROM users WHERE username LIKE '%' || $1 || '%' AND public_key <> '' AND verified_at IS NOT NULL LIMIT $2 OFFSET $3`
 	rows, err := u.db.QueryContext(ctx, query, contain, limit, offset)
 	if err != nil {
 		return nil, err
@@ -106,7 +127,7 @@ func (u *UserRepoPostgres) GetAll(ctx context.Context, contain string, limit, of
 		var user model.User
 		if err := rows.Scan(&user.ID, &user.Username, &user.Password, &user.FirstName, &user.LastName,
 			&user.Email, &user.PhoneNumber, &user.CreatedAt, &user.LastUpdated,
-			&user.Avatar, &user.PublicKey); err != nil {
+			&user.Avatar, &user.PublicKey, &user.VerifiedAt, &user.EmailVerifiedAt, &user.PhoneVerifiedAt); err != nil {
 			return nil, err
 		}
 
@@ -119,6 +140,36 @@ func (u *UserRepoPostgres) GetAll(ctx context.Context, contain string, limit, of
 	return users, nil
 }
 
+// MarkEmailVerified verifies the email of the user, and with it the account,
+// if it is still the one the verification was sent to.
+func (u *UserRepoPostgres) MarkEmailVerified(ctx context.Context, userId, email string) error {
+	query := `UPDATE users SET email_verified_at = $3, verified_at = COALESCE(verified_at, $3) WHERE id = $1 AND email = $2`
+	return u.markVerified(ctx, query, userId, email)
+}
+
+// MarkPhoneVerified verifies the phone number of the user if it is still the
+// one the code was sent to.
+func (u *UserRepoPostgres) MarkPhoneVerified(ctx context.Context, userId, phoneNumber string) error {
+	query := `UPDATE users SET phone_verified_at = $3 WHERE id = $1 AND phone_number = $2`
+	return u.markVerified(ctx, query, userId, phoneNumber)
+}
+
+func (u *UserRepoPostgres) markVerified(ctx context.Context, query, userId, value string) error {
+	result, err := u.db.ExecContext(ctx, query, userId, value, time.Now())
+	if err != nil {
+		return custom_error.HandlePostgreError(err)
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return custom_error.ErrNotFound
+	}
+	return nil
+}
+
 func (u *UserRepoPostgres) Delete(ctx context.Context, userId string) error {
 	return nil
 }
@@ -0,0 +1,180 @@
+package verification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"graduation-thesis/pkg/custom_error"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var ErrTooManyAttempts = errors.New("too many attempts")
+
+// VerificationRepo keeps the pending email and phone verifications. Email
+// tokens and phone codes are stored hashed; a user has at most one of each
+// pending, issuing a new one replaces the previous.
+type VerificationRepo struct {
+	redis *redis.Client
+}
+
+func NewVerificationRepo(redisClient *redis.Client) *VerificationRepo {
+	return &VerificationRepo{
+		redis: redisClient,
+	}
+}
+
+func emailTokenKey(tokenHash string) string {
+	return "email_verification:" + tokenHash
+}
+
+func userEmailTokenKey(userID string) string {
+	return "email_verification_user:" + userID
+}
+
+func phoneCodeKey(userID string) string {
+	return "phone_verification:" + userID
+}
+
+func cooldownKey(channel, userID string) string {
+	return fmt.Sprintf("verification_cooldown:%s:%s", channel, userID)
+}
+
+func sendsKey(channel, userID string) string {
+	return fmt.Sprintf("verification_sends:%s:%s", channel, userID)
+}
+
+func (v *VerificationRepo) StoreEmailToken(ctx context.Context, userID, email, tokenHash string, ttl time.Duration) error {
+	previous, err := v.redis.SetArgs(ctx, userEmailTokenKey(userID), tokenHash, redis.SetArgs{TTL: ttl, Get: true}).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return err
+	}
+
+	_, err = v.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if previous != "" {
+			pipe.Del(ctx, emailTokenKey(previous))
+		}
+		pipe.HSet(ctx, emailTokenKey(tokenHash), "user_id", userID, "email", email)
+		pipe.Expire(ctx, emailTokenKey(tokenHash), ttl)
+		return nil
+	})
+	return err
+}
+
+// ConsumeEmailToken returns the user and email the token was sent for and
+// deletes it. It returns custom_error.ErrNotFound if the token has expired,
+// been used or been replaced.
+func (v *VerificationRepo) ConsumeEmailToken(ctx context.Context, tokenHash string) (string, string, error) {
+	var fields *redis.MapStringStringCmd
+	_, err := v.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		fields = pipe.HGetAll(ctx, emailTokenKey(tokenHash))
+		pipe.Del(ctx, emailTokenKey(tokenHash))
+		return nil
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	token := fields.Val()
+	if token["user_id"] == "" {
+		return "", "", custom_error.ErrNotFound
+	}
+	if err := v.redis.Del(ctx, userEmailTokenKey(token["user_id"])).Err(); err != nil {
+		return "", "", err
+	}
+	return token["user_id"], token["email"], nil
+}
+
+func (v *VerificationRepo) StorePhoneCode(ctx context.Context, userID, phoneNumber, codeHash string, ttl time.Duration) error {
+	key := phoneCodeKey(userID)
+	_, err := v.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, key)
+		pipe.HSet(ctx, key, "code_hash", codeHash, "phone_number", phoneNumber, "attempts", 0)
+		pipe.Expire(ctx, key, ttl)
+		return nil
+	})
+	return err
+}
+
+// AttemptPhoneCode counts an attempt at the pending code of the user and
+// returns its hash and the phone number it was sent to. The code is dropped
+// once maxAttempts have been used, and ErrTooManyAttempts returned.
+func (v *VerificationRepo) AttemptPhoneCode(ctx context.Context, userID string, maxAttempts int) (string, string, error) {
+	key := phoneCodeKey(userID)
+	var fields *redis.MapStringStringCmd
+	_, err := v.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HIncrBy(ctx, key, "attempts", 1)
+		fields = pipe.HGetAll(ctx, key)
+		return nil
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	code := fields.Val()
+	if code["code_hash"] == "" {
+		// HINCRBY created the key, the code had expired or was never sent.
+		return "", "", v.deleteAnd(ctx, key, custom_error.ErrNotFound)
+	}
+	attempts, err := strconv.Atoi(code["attempts"])
+	if err != nil {
+		return "", "", err
+	}
+	if attempts > maxAttempts {
+		return "", "", v.deleteAnd(ctx, key, ErrTooManyAttempts)
+	}
+	return code["code_hash"], code["phone_number"], nil
+}
+
+func (v *VerificationRepo) DeletePhoneCode(ctx context.Context, userID string) error {
+	return v.redis.Del(ctx, phoneCodeKey(userID)).Err()
+}
+
+func (v *VerificationRepo) deleteAnd(ctx context.Context, key string, err error) error {
+	if delErr := v.redis.Del(ctx, key).Err(); delErr != nil {
+		return delErr
+	}
+	return err
+}
+
+// ReserveSend takes one send on channel for the user. Sends have to be
+// cooldown apart and at most maxSends may go out within window; if the send
+// is not allowed it returns how long to wait.
+func (v *VerificationRepo) ReserveSend(ctx context.Context, channel, userID string, cooldown, window time.Duration, maxSends int) (time.Duration, error) {
+	ok, err := v.redis.SetNX(ctx, cooldownKey(channel, userID), 1, cooldown).Result()
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return v.ttl(ctx, cooldownKey(channel, userID))
+	}
+
+	sends, err := v.redis.Incr(ctx, sendsKey(channel, userID)).Result()
+	if err != nil {
+		return 0, err
+	}
+	if sends == 1 {
+		if err := v.redis.Expire(ctx, sendsKey(channel, userID), window).Err(); err != nil {
+			return 0, err
+		}
+	}
+	if sends > int64(maxSends) {
+		return v.ttl(ctx, sendsKey(channel, userID))
+	}
+	return 0, nil
+}
+
+func (v *VerificationRepo) ttl(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := v.redis.TTL(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	// The key expired in between, waiting a second is enough.
+	if ttl <= 0 {
+		return time.Second, nil
+	}
+	return ttl, nil
+}
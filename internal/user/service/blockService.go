@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"graduation-thesis/internal/user/repository/block"
+	"graduation-thesis/pkg/custom_error"
+	responseModel "graduation-thesis/pkg/model"
+	"net/http"
+	"time"
+)
+
+type BlockService struct {
+	blockRepo *block.BlockRepo
+	mapError  map[error]int
+}
+
+func NewBlockService(blockRepo *block.BlockRepo, mapError map[error]int) *BlockService {
+	return &BlockService{
+		blockRepo: blockRepo,
+		mapError:  mapError,
+	}
+}
+
+func (b *BlockService) Block(ctx context.Context, userID, blockedUserID string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	if blockedUserID == "" || blockedUserID == userID {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       b.mapError[custom_error.ErrInvalidParameter],
+			ErrorMessage: custom_error.ErrInvalidParameter.Error(),
+		}
+		return nil, &errorResponse
+	}
+
+	queryContext, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	if err := b.blockRepo.Block(queryContext, userID, blockedUserID); err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       b.mapError[err],
+			ErrorMessage: err.Error(),
+		}
+		return nil, &errorResponse
+	}
+
+	successResponse := responseModel.SuccessResponse{
+		Status: http.StatusNoContent,
+	}
+	return &successResponse, nil
+}
+
+func (b *BlockService) Unblock(ctx context.Context, userID, blockedUserID string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	queryContext, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	if err := b.blockRepo.Unblock(queryContext, userID, blockedUserID); err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       b.mapError[err],
+			ErrorMessage: err.Error(),
+		}
+		return nil, &errorResponse
+	}
+
+	successResponse := responseModel.SuccessResponse{
+		Status: http.StatusNoContent,
+	}
+	return &successResponse, nil
+}
+
+func (b *BlockService) GetBlocks(ctx context.Context, userID string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	queryContext, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	blocks, err := b.blockRepo.GetBlocks(queryContext, userID)
+	if err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       b.mapError[err],
+			ErrorMessage: err.Error(),
+		}
+		return nil, &errorResponse
+	}
+
+	successResponse := responseModel.SuccessResponse{
+		Status: http.StatusOK,
+		Result: blocks,
+	}
+	return &successResponse, nil
+}
+
+func (b *BlockService) GetBlockRelation(ctx context.Context, userID, otherUserID string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	queryContext, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	relation, err := b.blockRepo.GetRelation(queryContext, userID, otherUserID)
+	if err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       b.mapError[err],
+			ErrorMessage: err.Error(),
+		}
+		return nil, &errorResponse
+	}
+
+	successResponse := responseModel.SuccessResponse{
+		Status: http.StatusOK,
+		Result: relation,
+	}
+	return &successResponse, nil
+}
+
+func (b *BlockService) GetRelatedUsers(ctx context.Context, userID string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	queryContext, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	users, err := b.blockRepo.GetRelatedUsers(queryContext, userID)
+	if err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       b.mapError[err],
+			ErrorMessage: err.Error(),
+		}
+		return nil, &errorResponse
+	}
+
+	successResponse := responseModel.SuccessResponse{
+		Status: http.StatusOK,
+		Result: users,
+	}
+	return &successResponse, nil
+}
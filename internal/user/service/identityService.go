@@ -0,0 +1,241 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"graduation-thesis/internal/user/model"
+	"graduation-thesis/pkg/custom_error"
+	"graduation-thesis/pkg/encrypt/argon2"
+	responseModel "graduation-thesis/pkg/model"
+
+	"github.com/twinj/uuid"
+)
+
+const (
+	maxUsernameLength   = 32
+	provisionAttempts   = 5
+	unusablePasswordLen = 32
+)
+
+var usernameInvalidChars = regexp.MustCompile(`[^a-z0-9._-]+`)
+
+type IdentityLinks interface {
+	GetUserID(ctx context.Context, provider, subject string) (string, error)
+	Link(ctx context.Context, identity *model.ExternalIdentity) error
+	Provision(ctx context.Context, params *model.CreateUserParams, identity *model.ExternalIdentity, emailVerified bool) (*model.User, error)
+}
+
+type IdentityAccounts interface {
+	GetByEmail(ctx context.Context, email string) (*model.User, error)
+	GetByUsername(ctx context.Context, username string) (*model.User, error)
+}
+
+// LinkResult is the user an external identity logs in as.
+type LinkResult struct {
+	UserID  string
+	Created bool
+}
+
+type IdentityService struct {
+	links        IdentityLinks
+	accounts     IdentityAccounts
+	verification *VerificationService
+	mapError     map[error]int
+}
+
+func NewIdentityService(links IdentityLinks, accounts IdentityAccounts, verification *VerificationService, mapError map[error]int) *IdentityService {
+	return &IdentityService{
+		links:        links,
+		accounts:     accounts,
+		verification: verification,
+		mapError:     mapError,
+	}
+}
+
+// LinkExternalIdentity returns the user the subject of provider logs in as.
+// A subject seen for the first time is linked to the account with its email
+// if linkByEmail is set and both the provider and the account verified that
+// email; otherwise a user is provisioned from profile. An email that belongs
+// to an account the subject can't be linked to is a conflict: linking it
+// anyway would hand the account to whoever controls the provider's records.
+func (i *IdentityService) LinkExternalIdentity(ctx context.Context, provider, subject string, linkByEmail bool, profile model.ExternalProfile) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	profile.Email = strings.TrimSpace(profile.Email)
+	if provider == "" || subject == "" || profile.Email == "" {
+		return nil, i.errorResponse(custom_error.ErrInvalidParameter)
+	}
+
+	result, err := i.link(ctx, provider, subject, linkByEmail, profile)
+	if err != nil {
+		return nil, i.errorResponse(err)
+	}
+
+	successResponse := responseModel.SuccessResponse{
+		Status: http.StatusOK,
+		Result: result,
+	}
+	if result.Created {
+		successResponse.Status = http.StatusCreated
+	}
+	return &successResponse, nil
+}
+
+func (i *IdentityService) link(ctx context.Context, provider, subject string, linkByEmail bool, profile model.ExternalProfile) (*LinkResult, error) {
+	userID, err := i.links.GetUserID(ctx, provider, subject)
+	if err == nil {
+		return &LinkResult{UserID: userID}, nil
+	}
+	if !errors.Is(err, custom_error.ErrNotFound) {
+		return nil, err
+	}
+
+	identity := &model.ExternalIdentity{Provider: provider, Subject: subject}
+	existing, err := i.accounts.GetByEmail(ctx, profile.Email)
+	switch {
+	case err == nil:
+		if !linkByEmail || !profile.EmailVerified || existing.EmailVerifiedAt == nil {
+			return nil, custom_error.ErrConflict
+		}
+		identity.UserID = existing.ID
+		if err := i.links.Link(ctx, identity); err != nil {
+			return i.linkedMeanwhile(ctx, provider, subject, err)
+		}
+		return &LinkResult{UserID: existing.ID}, nil
+	case !errors.Is(err, sql.ErrNoRows):
+		return nil, err
+	}
+
+	return i.provision(ctx, identity, profile)
+}
+
+func (i *IdentityService) provision(ctx context.Context, identity *model.ExternalIdentity, profile model.ExternalProfile) (*LinkResult, error) {
+	password, err := unusablePassword()
+	if err != nil {
+		return nil, err
+	}
+	params := model.CreateUserParams{
+		HashPassword: password,
+		FirstName:    profile.FirstName,
+		LastName:     profile.LastName,
+		Email:        profile.Email,
+		PhoneNumber:  profile.PhoneNumber,
+		Avatar:       profile.Avatar,
+	}
+
+	base := usernameFor(profile)
+	for attempt := 0; attempt < provisionAttempts; attempt++ {
+		username, err := i.freeUsername(ctx, base, attempt)
+		if err != nil {
+			return nil, err
+		}
+		if username == "" {
+			continue
+		}
+		params.ID = uuid.NewV4().String()
+		params.Username = username
+
+		user, err := i.links.Provision(ctx, &params, identity, profile.EmailVerified)
+		if errors.Is(err, custom_error.ErrConflict) {
+			// Another login of the subject, or a registration, won the race.
+			if result, linkErr := i.linkedMeanwhile(ctx, identity.Provider, identity.Subject, err); linkErr == nil {
+				return result, nil
+			}
+			if _, emailErr := i.accounts.GetByEmail(ctx, profile.Email); emailErr == nil {
+				return nil, custom_error.ErrConflict
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// Whatever the provider did not verify has to be verified here.
+		i.verification.SendVerifications(ctx, user, !profile.EmailVerified, true)
+		return &LinkResult{UserID: user.ID, Created: true}, nil
+	}
+	return nil, custom_error.ErrConflict
+}
+
+// freeUsername returns base on the first attempt and base with a random
+// suffix after, or "" if the candidate is taken.
+func (i *IdentityService) freeUsername(ctx context.Context, base string, attempt int) (string, error) {
+	username := base
+	if attempt > 0 {
+		suffix, err := randomHex(3)
+		if err != nil {
+			return "", err
+		}
+		username = base + "-" + suffix
+	}
+
+	_, err := i.accounts.GetByUsername(ctx, username)
+	if err == nil {
+		return "", nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return "", err
+	}
+	return username, nil
+}
+
+// linkedMeanwhile returns the user the subject was linked to by a concurrent
+// login, or err if it is still not linked.
+func (i *IdentityService) linkedMeanwhile(ctx context.Context, provider, subject string, err error) (*LinkResult, error) {
+	userID, getErr := i.links.GetUserID(ctx, provider, subject)
+	if getErr != nil {
+		return nil, err
+	}
+	return &LinkResult{UserID: userID}, nil
+}
+
+// usernameFor picks the preferred username of the provider, or the local part
+// of the email, reduced to the characters usernames are made of.
+func usernameFor(profile model.ExternalProfile) string {
+	username := profile.Username
+	if username == "" {
+		username, _, _ = strings.Cut(profile.Email, "@")
+	}
+	username = usernameInvalidChars.ReplaceAllString(strings.ToLower(username), "")
+	if len(username) > maxUsernameLength {
+		username = username[:maxUsernameLength]
+	}
+	if username == "" {
+		username = "user"
+	}
+	return username
+}
+
+// unusablePassword hashes a random password nobody knows, so provisioned
+// users log in through their provider until they reset it.
+func unusablePassword() (string, error) {
+	password, err := randomHex(unusablePasswordLen)
+	if err != nil {
+		return "", err
+	}
+	return argon2.HashPassword([]byte(password))
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func (i *IdentityService) errorResponse(err error) *responseModel.ErrorResponse {
+	status, ok := i.mapError[err]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+	return &responseModel.ErrorResponse{
+		Status:       status,
+		ErrorMessage: err.Error(),
+	}
+}
@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"graduation-thesis/internal/user/model"
+	"graduation-thesis/pkg/custom_error"
+)
+
+// fakeIdentityStore keeps users and their external identities in memory.
+type fakeIdentityStore struct {
+	mu    sync.Mutex
+	users map[string]*model.User
+	links map[string]string
+}
+
+func newFakeIdentityStore(users ...*model.User) *fakeIdentityStore {
+	store := &fakeIdentityStore{users: make(map[string]*model.User), links: make(map[string]string)}
+	for _, user := range users {
+		store.users[user.ID] = user
+	}
+	return store
+}
+
+func (f *fakeIdentityStore) GetUserID(ctx context.Context, provider, subject string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	userID, ok := f.links[provider+"/"+subject]
+	if !ok {
+		return "", custom_error.ErrNotFound
+	}
+	return userID, nil
+}
+
+func (f *fakeIdentityStore) Link(ctx context.Context, identity *model.ExternalIdentity) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := identity.Provider + "/" + identity.Subject
+	if _, ok := f.links[key]; ok {
+		return custom_error.ErrConflict
+	}
+	f.links[key] = identity.UserID
+	return nil
+}
+
+func (f *fakeIdentityStore) Provision(ctx context.Context, params *model.CreateUserParams, identity *model.ExternalIdentity, emailVerified bool) (*model.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := identity.Provider + "/" + identity.Subject
+	if _, ok := f.links[key]; ok {
+		return nil, custom_error.ErrConflict
+	}
+	for _, user := range f.users {
+		if user.Username == params.Username || user.Email == params.Email {
+			return nil, custom_error.ErrConflict
+		}
+	}
+
+	user := &model.User{
+		ID:          params.ID,
+		Username:    params.Username,
+		Password:    params.HashPassword,
+		FirstName:   params.FirstName,
+		LastName:    params.LastName,
+		Email:       params.Email,
+		PhoneNumber: params.PhoneNumber,
+		Avatar:      params.Avatar,
+	}
+	if emailVerified {
+		now := time.Now()
+		user.VerifiedAt, user.EmailVerifiedAt = &now, &now
+	}
+	f.users[user.ID] = user
+	f.links[key] = user.ID
+	copied := *user
+	return &copied, nil
+}
+
+func (f *fakeIdentityStore) find(match func(*model.User) bool) (*model.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, user := range f.users {
+		if match(user) {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeIdentityStore) GetByEmail(ctx context.Context, email string) (*model.User, error) {
+	return f.find(func(user *model.User) bool { return user.Email == email })
+}
+
+func (f *fakeIdentityStore) GetByUsername(ctx context.Context, username string) (*model.User, error) {
+	return f.find(func(user *model.User) bool { return user.Username == username })
+}
+
+func newTestIdentityService(t *testing.T, users ...*model.User) (*IdentityService, *fakeIdentityStore, *recordingNotifier) {
+	t.Helper()
+	verificationService, _, notifier, _ := newTestVerificationService(t)
+	store := newFakeIdentityStore(users...)
+	return NewIdentityService(store, store, verificationService, custom_error.MappingError()), store, notifier
+}
+
+func linkIdentity(t *testing.T, identityService *IdentityService, subject string, linkByEmail bool, profile model.ExternalProfile) (*LinkResult, int) {
+	t.Helper()
+	successResponse, errorResponse := identityService.LinkExternalIdentity(context.Background(), "corp", subject, linkByEmail, profile)
+	if errorResponse != nil {
+		return nil, errorResponse.Status
+	}
+	return successResponse.Result.(*LinkResult), successResponse.Status
+}
+
+func TestLinkExternalIdentityProvisions(t *testing.T) {
+	identityService, store, notifier := newTestIdentityService(t)
+	avatar := model.Avatar("https://idp.example.com/alice.png")
+	profile := model.ExternalProfile{
+		Username:      "Alice.Nguyen ",
+		Email:         "alice@corp.example.com",
+		EmailVerified: true,
+		FirstName:     "Alice",
+		LastName:      "Nguyen",
+		Avatar:        &avatar,
+	}
+
+	result, status := linkIdentity(t, identityService, "sub-1", false, profile)
+	if status != http.StatusCreated || !result.Created {
+		t.Fatalf("first login = %+v %d", result, status)
+	}
+	user := store.users[result.UserID]
+	if user.Username != "alice.nguyen" || user.FirstName != "Alice" || user.LastName != "Nguyen" || user.Email != profile.Email || *user.Avatar != avatar {
+		t.Fatalf("provisioned %+v", user)
+	}
+	if !user.Verified() {
+		t.Fatal("email verified by the provider does not verify the account")
+	}
+	if len(notifier.sent) != 0 {
+		t.Fatalf("sent %+v for a verified email", notifier.sent)
+	}
+
+	again, status := linkIdentity(t, identityService, "sub-1", false, profile)
+	if status != http.StatusOK || again.Created || again.UserID != result.UserID {
+		t.Fatalf("second login = %+v %d", again, status)
+	}
+}
+
+func TestLinkExternalIdentityUnverifiedEmail(t *testing.T) {
+	identityService, store, notifier := newTestIdentityService(t)
+
+	result, status := linkIdentity(t, identityService, "sub-1", false, model.ExternalProfile{Email: "bob@corp.example.com"})
+	if status != http.StatusCreated {
+		t.Fatalf("login = %d", status)
+	}
+	user := store.users[result.UserID]
+	if user.Username != "bob" || user.Verified() {
+		t.Fatalf("provisioned %+v", user)
+	}
+	if message := notifier.last(t, model.VerificationChannelEmail); message.to != "bob@corp.example.com" {
+		t.Fatalf("verification sent to %s", message.to)
+	}
+}
+
+func TestLinkExternalIdentityTakenUsername(t *testing.T) {
+	identityService, store, _ := newTestIdentityService(t, &model.User{ID: "user-1", Username: "alice", Email: "alice@example.com"})
+
+	result, status := linkIdentity(t, identityService, "sub-1", false, model.ExternalProfile{Username: "alice", Email: "alice@corp.example.com"})
+	if status != http.StatusCreated {
+		t.Fatalf("login = %d", status)
+	}
+	if username := store.users[result.UserID].Username; !strings.HasPrefix(username, "alice-") {
+		t.Fatalf("provisioned username %q", username)
+	}
+}
+
+func TestLinkExternalIdentityByEmail(t *testing.T) {
+	now := time.Now()
+	verified := &model.User{ID: "user-1", Username: "alice", Email: "alice@corp.example.com", VerifiedAt: &now, EmailVerifiedAt: &now}
+	unverified := &model.User{ID: "user-2", Username: "bob", Email: "bob@corp.example.com"}
+
+	for _, test := range []struct {
+		name        string
+		email       string
+		linkByEmail bool
+		verified    bool
+		want        int
+	}{
+		{"linked", verified.Email, true, true, http.StatusOK},
+		{"linking disabled", verified.Email, false, true, http.StatusConflict},
+		{"not verified by the provider", verified.Email, true, false, http.StatusConflict},
+		{"account not verified", unverified.Email, true, true, http.StatusConflict},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			identityService, store, _ := newTestIdentityService(t, verified, unverified)
+			result, status := linkIdentity(t, identityService, "sub-1", test.linkByEmail, model.ExternalProfile{Email: test.email, EmailVerified: test.verified})
+			if status != test.want {
+				t.Fatalf("login = %d, want %d", status, test.want)
+			}
+			if status == http.StatusOK && (result.UserID != verified.ID || result.Created) {
+				t.Fatalf("linked to %+v", result)
+			}
+			if status != http.StatusOK && len(store.links) != 0 {
+				t.Fatalf("linked %v after a conflict", store.links)
+			}
+		})
+	}
+}
+
+func TestLinkExternalIdentityNeedsEmail(t *testing.T) {
+	identityService, _, _ := newTestIdentityService(t)
+	if _, status := linkIdentity(t, identityService, "sub-1", false, model.ExternalProfile{Username: "alice"}); status != http.StatusUnprocessableEntity {
+		t.Fatalf("login without an email = %d", status)
+	}
+}
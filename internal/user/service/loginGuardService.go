@@ -0,0 +1,222 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"graduation-thesis/internal/user/model"
+	"graduation-thesis/internal/user/repository/attempt"
+	"graduation-thesis/pkg/custom_error"
+	"graduation-thesis/pkg/logger"
+	responseModel "graduation-thesis/pkg/model"
+
+	"github.com/twinj/uuid"
+)
+
+// LoginProtectionConfig counts failed logins over a sliding Window. From
+// DelayAfter failures on a username each further one locks it for twice as
+// long as the previous, starting at BaseDelay and capped at MaxDelay; at
+// LockoutAfter failures it is locked for LockoutDuration. An IP is locked for
+// LockoutDuration at IPLockoutAfter failures, whichever usernames they were on.
+type LoginProtectionConfig struct {
+	Window          time.Duration
+	DelayAfter      int
+	BaseDelay       time.Duration
+	MaxDelay        time.Duration
+	LockoutAfter    int
+	LockoutDuration time.Duration
+	IPLockoutAfter  int
+}
+
+type LoginAuditRecorder interface {
+	RecordLogin(ctx context.Context, event *model.LoginAuditEvent) error
+}
+
+type LoginGuardService struct {
+	attemptRepo *attempt.AttemptRepo
+	auditRepo   LoginAuditRecorder
+	config      LoginProtectionConfig
+	admins      map[string]struct{}
+	mapError    map[error]int
+	logger      logger.Logger
+	now         func() time.Time
+}
+
+func NewLoginGuardService(attemptRepo *attempt.AttemptRepo, auditRepo LoginAuditRecorder, config LoginProtectionConfig, admins []string, mapError map[error]int, logger logger.Logger) *LoginGuardService {
+	adminSet := make(map[string]struct{}, len(admins))
+	for _, admin := range admins {
+		adminSet[admin] = struct{}{}
+	}
+
+	return &LoginGuardService{
+		attemptRepo: attemptRepo,
+		auditRepo:   auditRepo,
+		config:      config,
+		admins:      adminSet,
+		mapError:    mapError,
+		logger:      logger,
+		now:         time.Now,
+	}
+}
+
+func usernameSubject(username string) string {
+	return fmt.Sprintf("user:%s", username)
+}
+
+func ipSubject(ip string) string {
+	return fmt.Sprintf("ip:%s", ip)
+}
+
+// Check returns how long logins of username from ip are still refused, zero if
+// they are allowed.
+func (l *LoginGuardService) Check(ctx context.Context, username, ip string) (time.Duration, error) {
+	now := l.now()
+	subjects := []string{usernameSubject(username)}
+	if ip != "" {
+		subjects = append(subjects, ipSubject(ip))
+	}
+
+	var wait time.Duration
+	for _, subject := range subjects {
+		lockedUntil, err := l.attemptRepo.LockedUntil(ctx, subject)
+		if err != nil {
+			return 0, err
+		}
+		if remaining := lockedUntil.Sub(now); remaining > wait {
+			wait = remaining
+		}
+	}
+	return wait, nil
+}
+
+// Failed counts a failed login and locks the username or the IP once they run
+// over their limits.
+func (l *LoginGuardService) Failed(ctx context.Context, username, userID, ip, reason string) {
+	l.audit(ctx, username, userID, ip, false, reason)
+	// Refused attempts were never checked against the password, counting them
+	// would only keep a lock going.
+	if reason == model.LoginReasonLocked {
+		return
+	}
+
+	now := l.now()
+	subject := usernameSubject(username)
+	failures, err := l.attemptRepo.AddFailure(ctx, subject, l.config.Window, now)
+	if err != nil {
+		l.logger.Errorf("[Failed] Cannot count failed login of %s: %v", username, err)
+	} else if delay := l.delay(int(failures)); delay > 0 {
+		if err := l.attemptRepo.Lock(ctx, subject, now.Add(delay), now); err != nil {
+			l.logger.Errorf("[Failed] Cannot lock logins of %s: %v", username, err)
+		}
+	}
+
+	if ip == "" {
+		return
+	}
+	subject = ipSubject(ip)
+	failures, err = l.attemptRepo.AddFailure(ctx, subject, l.config.Window, now)
+	if err != nil {
+		l.logger.Errorf("[Failed] Cannot count failed login from %s: %v", ip, err)
+	} else if int(failures) >= l.config.IPLockoutAfter {
+		if err := l.attemptRepo.Lock(ctx, subject, now.Add(l.config.LockoutDuration), now); err != nil {
+			l.logger.Errorf("[Failed] Cannot lock logins from %s: %v", ip, err)
+		}
+	}
+}
+
+// Succeeded forgets the failures of username. Those of the IP are kept, a
+// login to the attacker's own account must not hide the guessing on others.
+func (l *LoginGuardService) Succeeded(ctx context.Context, username, userID, ip string) {
+	l.audit(ctx, username, userID, ip, true, "")
+	if err := l.attemptRepo.ResetFailures(ctx, usernameSubject(username), l.config.Window, l.now()); err != nil {
+		l.logger.Errorf("[Succeeded] Cannot reset failed logins of %s: %v", username, err)
+	}
+}
+
+func (l *LoginGuardService) delay(failures int) time.Duration {
+	switch {
+	case failures >= l.config.LockoutAfter:
+		return l.config.LockoutDuration
+	case failures < l.config.DelayAfter:
+		return 0
+	}
+
+	shift := failures - l.config.DelayAfter
+	if shift >= 32 {
+		return l.config.MaxDelay
+	}
+	delay := l.config.BaseDelay << shift
+	if delay > l.config.MaxDelay || delay <= 0 {
+		return l.config.MaxDelay
+	}
+	return delay
+}
+
+func (l *LoginGuardService) audit(ctx context.Context, username, userID, ip string, success bool, reason string) {
+	event := model.LoginAuditEvent{
+		ID:        uuid.NewV4().String(),
+		Username:  username,
+		UserID:    userID,
+		IP:        ip,
+		Success:   success,
+		Reason:    reason,
+		CreatedAt: l.now(),
+	}
+	if err := l.auditRepo.RecordLogin(ctx, &event); err != nil {
+		l.logger.Errorf("[audit] Cannot record login audit event of %s: %v", username, err)
+	}
+}
+
+func (l *LoginGuardService) IsAdmin(userID string) bool {
+	_, ok := l.admins[userID]
+	return ok
+}
+
+// Unlock lets an admin lift the lock of a username, an IP or both.
+func (l *LoginGuardService) Unlock(ctx context.Context, adminID string, request *model.UnlockLoginRequest) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	var errorResponse responseModel.ErrorResponse
+
+	if !l.IsAdmin(adminID) {
+		errorResponse.Status = l.mapError[custom_error.ErrNoPermission]
+		errorResponse.ErrorMessage = custom_error.ErrNoPermission.Error()
+		return nil, &errorResponse
+	}
+	if request.Username == "" && request.IP == "" {
+		errorResponse.Status = l.mapError[custom_error.ErrInvalidParameter]
+		errorResponse.ErrorMessage = custom_error.ErrInvalidParameter.Error()
+		return nil, &errorResponse
+	}
+
+	now := l.now()
+	var subjects []string
+	if request.Username != "" {
+		subjects = append(subjects, usernameSubject(request.Username))
+	}
+	if request.IP != "" {
+		subjects = append(subjects, ipSubject(request.IP))
+	}
+	for _, subject := range subjects {
+		if err := l.attemptRepo.Unlock(ctx, subject, l.config.Window, now); err != nil {
+			errorResponse.Status = http.StatusInternalServerError
+			errorResponse.ErrorMessage = err.Error()
+			return nil, &errorResponse
+		}
+	}
+
+	successResponse := responseModel.SuccessResponse{
+		Status: http.StatusNoContent,
+	}
+	return &successResponse, nil
+}
+
+// tooManyAttempts is the response to a login refused for wait.
+func tooManyAttempts(wait time.Duration) *responseModel.ErrorResponse {
+	return &responseModel.ErrorResponse{
+		Status:       http.StatusTooManyRequests,
+		ErrorMessage: custom_error.ErrTooManyRequests.Error(),
+		RetryAfter:   int(math.Ceil(wait.Seconds())),
+	}
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"graduation-thesis/internal/user/model"
+	"graduation-thesis/internal/user/repository/attempt"
+	"graduation-thesis/pkg/custom_error"
+	"graduation-thesis/pkg/storage/redistest"
+
+	"go.uber.org/zap"
+)
+
+type recordingAuditor struct {
+	mu     sync.Mutex
+	events []model.LoginAuditEvent
+}
+
+func (r *recordingAuditor) RecordLogin(ctx context.Context, event *model.LoginAuditEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, *event)
+	return nil
+}
+
+var testLoginProtection = LoginProtectionConfig{
+	Window:          15 * time.Minute,
+	DelayAfter:      3,
+	BaseDelay:       time.Second,
+	MaxDelay:        time.Minute,
+	LockoutAfter:    10,
+	LockoutDuration: 15 * time.Minute,
+	IPLockoutAfter:  20,
+}
+
+// newTestLoginGuard returns a guard whose clock only moves with advance.
+func newTestLoginGuard(t *testing.T) (*LoginGuardService, *recordingAuditor, func(time.Duration)) {
+	t.Helper()
+	client, server := redistest.NewClient(t)
+	auditor := &recordingAuditor{}
+	guard := NewLoginGuardService(attempt.NewAttemptRepo(client), auditor, testLoginProtection, []string{"admin-1"}, custom_error.MappingError(), zap.NewNop().Sugar())
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	guard.now = func() time.Time { return now }
+	advance := func(d time.Duration) {
+		now = now.Add(d)
+		server.FastForward(d)
+	}
+	return guard, auditor, advance
+}
+
+func fail(t *testing.T, guard *LoginGuardService, username, ip string, times int) {
+	t.Helper()
+	for i := 0; i < times; i++ {
+		guard.Failed(context.Background(), username, "", ip, model.LoginReasonBadCredentials)
+	}
+}
+
+func wait(t *testing.T, guard *LoginGuardService, username, ip string) time.Duration {
+	t.Helper()
+	d, err := guard.Check(context.Background(), username, ip)
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	return d
+}
+
+func TestLoginDelaysGrow(t *testing.T) {
+	for failures, want := range map[int]time.Duration{
+		2: 0,
+		3: time.Second,
+		4: 2 * time.Second,
+		5: 4 * time.Second,
+		9: time.Minute,
+	} {
+		guard, _, _ := newTestLoginGuard(t)
+		fail(t, guard, "alice", "198.51.100.1", failures)
+		if d := wait(t, guard, "alice", "198.51.100.1"); d != want {
+			t.Fatalf("locked for %s after %d failures, want %s", d, failures, want)
+		}
+	}
+}
+
+func TestLoginLockout(t *testing.T) {
+	guard, _, advance := newTestLoginGuard(t)
+
+	fail(t, guard, "alice", "198.51.100.1", 10)
+	if d := wait(t, guard, "alice", "198.51.100.2"); d != 15*time.Minute {
+		t.Fatalf("locked for %s, want the lockout from any ip", d)
+	}
+	if d := wait(t, guard, "bob", "198.51.100.2"); d != 0 {
+		t.Fatalf("other user locked for %s", d)
+	}
+
+	// Refused attempts do not extend the lock.
+	advance(10 * time.Minute)
+	guard.Failed(context.Background(), "alice", "", "198.51.100.1", model.LoginReasonLocked)
+	if d := wait(t, guard, "alice", "198.51.100.2"); d != 5*time.Minute {
+		t.Fatalf("locked for %s after a refused attempt", d)
+	}
+
+	advance(5 * time.Minute)
+	if d := wait(t, guard, "alice", "198.51.100.2"); d != 0 {
+		t.Fatalf("still locked for %s after the lockout", d)
+	}
+}
+
+func TestLoginFailuresSlideOut(t *testing.T) {
+	guard, _, advance := newTestLoginGuard(t)
+
+	fail(t, guard, "alice", "198.51.100.1", 2)
+	advance(30 * time.Minute)
+	fail(t, guard, "alice", "198.51.100.1", 2)
+	if d := wait(t, guard, "alice", "198.51.100.1"); d != 0 {
+		t.Fatalf("locked for %s by failures from past windows", d)
+	}
+}
+
+func TestLoginSuccessResetsUsernameOnly(t *testing.T) {
+	guard, auditor, _ := newTestLoginGuard(t)
+
+	fail(t, guard, "alice", "198.51.100.1", 2)
+	guard.Succeeded(context.Background(), "alice", "user-1", "198.51.100.1")
+	fail(t, guard, "alice", "198.51.100.1", 2)
+	if d := wait(t, guard, "alice", "198.51.100.1"); d != 0 {
+		t.Fatalf("locked for %s although the failures were reset", d)
+	}
+
+	if len(auditor.events) != 5 {
+		t.Fatalf("%d audit events, want 5", len(auditor.events))
+	}
+	success := auditor.events[2]
+	if !success.Success || success.UserID != "user-1" || success.IP != "198.51.100.1" || success.ID == "" {
+		t.Fatalf("success event = %+v", success)
+	}
+	if failure := auditor.events[0]; failure.Success || failure.Reason != model.LoginReasonBadCredentials {
+		t.Fatalf("failure event = %+v", failure)
+	}
+
+	// Guessing on many usernames from one ip is still caught.
+	for _, username := range []string{"u1", "u2", "u3", "u4", "u5", "u6", "u7", "u8"} {
+		fail(t, guard, username, "198.51.100.1", 2)
+	}
+	if d := wait(t, guard, "carol", "198.51.100.1"); d != 15*time.Minute {
+		t.Fatalf("ip locked for %s after 20 failures", d)
+	}
+	if d := wait(t, guard, "carol", "198.51.100.9"); d != 0 {
+		t.Fatalf("other ip locked for %s", d)
+	}
+}
+
+func TestUnlock(t *testing.T) {
+	guard, _, _ := newTestLoginGuard(t)
+	ctx := context.Background()
+	fail(t, guard, "alice", "198.51.100.1", 10)
+
+	_, errorResponse := guard.Unlock(ctx, "user-1", &model.UnlockLoginRequest{Username: "alice"})
+	if errorResponse == nil || errorResponse.Status != http.StatusUnauthorized {
+		t.Fatalf("Unlock by a non admin = %+v", errorResponse)
+	}
+	_, errorResponse = guard.Unlock(ctx, "admin-1", &model.UnlockLoginRequest{})
+	if errorResponse == nil || errorResponse.Status != http.StatusUnprocessableEntity {
+		t.Fatalf("Unlock of nothing = %+v", errorResponse)
+	}
+
+	successResponse, errorResponse := guard.Unlock(ctx, "admin-1", &model.UnlockLoginRequest{Username: "alice"})
+	if errorResponse != nil || successResponse.Status != http.StatusNoContent {
+		t.Fatalf("Unlock = %+v %+v", successResponse, errorResponse)
+	}
+	if d := wait(t, guard, "alice", "198.51.100.1"); d != 0 {
+		t.Fatalf("locked for %s after unlock", d)
+	}
+
+	// The failures are forgotten too, so the next one does not lock again.
+	fail(t, guard, "alice", "198.51.100.1", 1)
+	if d := wait(t, guard, "alice", "198.51.100.1"); d != 0 {
+		t.Fatalf("locked for %s right after unlock", d)
+	}
+}
+
+func TestTooManyAttemptsRoundsUp(t *testing.T) {
+	errorResponse := tooManyAttempts(1500 * time.Millisecond)
+	if errorResponse.Status != http.StatusTooManyRequests || errorResponse.RetryAfter != 2 {
+		t.Fatalf("tooManyAttempts = %+v", errorResponse)
+	}
+}
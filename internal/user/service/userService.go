@@ -5,10 +5,10 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"graduation-thesis/internal/user/helper/argon2"
 	"graduation-thesis/internal/user/model"
 	user "graduation-thesis/internal/user/repository/user"
 	"graduation-thesis/pkg/custom_error"
+	"graduation-thesis/pkg/encrypt/argon2"
 	responseModel "graduation-thesis/pkg/model"
 	"net/http"
 
@@ -19,14 +19,18 @@ type UserService struct {
 	db               *sql.DB
 	userRepoPostgres *user.UserRepoPostgres
 	userRepoRedis    *user.UserRepoRedis
+	loginGuard       *LoginGuardService
+	verification     *VerificationService
 	mapError         map[error]int
 }
 
-func NewUserService(db *sql.DB, userRepoPostgres *user.UserRepoPostgres, userRepoRedis *user.UserRepoRedis, mapError map[error]int) *UserService {
+func NewUserService(db *sql.DB, userRepoPostgres *user.UserRepoPostgres, userRepoRedis *user.UserRepoRedis, loginGuard *LoginGuardService, verification *VerificationService, mapError map[error]int) *UserService {
 	return &UserService{
 		db:               db,
 		userRepoPostgres: userRepoPostgres,
 		userRepoRedis:    userRepoRedis,
+		loginGuard:       loginGuard,
+		verification:     verification,
 		mapError:         mapError,
 	}
 }
@@ -82,7 +86,8 @@ func (u *UserService) GetUser(ctx context.Context, id string) (*responseModel.Su
 		errorResponse.ErrorMessage = err.Error()
 		return nil, &errorResponse
 	}
-	if user == nil {
+	// Unverified accounts can't be found until they are verified.
+	if user == nil || !user.Verified() {
 		errorResponse.Status = http.StatusNotFound
 		errorResponse.ErrorMessage = model.ErrNoUser.Error()
 		return nil, &errorResponse
@@ -140,7 +145,7 @@ func (u *UserService) GetUserByUsername(ctx context.Context, username string) (*
 		errorResponse.ErrorMessage = err.Error()
 		return nil, &errorResponse
 	}
-	if user == nil {
+	if user == nil || !user.Verified() {
 		errorResponse.Status = http.StatusNotFound
 		errorResponse.ErrorMessage = model.ErrNoUser.Error()
 		return nil, &errorResponse
@@ -239,6 +244,9 @@ func (u *UserService) CreateUser(ctx context.Context, createUserRequest model.Cr
 			return nil, &errorResponse
 		}
 
+		// The account stays unverified until the emailed link is followed.
+		u.verification.SendVerifications(ctx, newUser, true, true)
+
 		successResponse.Result = newUser
 		successResponse.Status = http.StatusCreated
 		return &successResponse, nil
@@ -314,32 +322,131 @@ func (u *UserService) UpdateUser(ctx context.Context, id string, updateUserReque
 	}
 
 	go u.userRepoRedis.Delete(ctx, id)
+
+	emailChanged := updateUserParams.Email != user.Email
+	phoneChanged := updateUserParams.PhoneNumber != user.PhoneNumber
+	if emailChanged || phoneChanged {
+		user.Email = updateUserParams.Email
+		user.PhoneNumber = updateUserParams.PhoneNumber
+		u.verification.SendVerifications(ctx, user, emailChanged, phoneChanged)
+	}
+
 	successResponse.Status = http.StatusOK
 	return &successResponse, nil
 }
 
-func (u *UserService) VerifyCredential(ctx context.Context, loginRequest *model.LoginRequest) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
-	user, err := u.userRepoPostgres.GetByUsername(ctx, loginRequest.Username)
+// VerifyCredential is rate limited per username and per ip, see
+// LoginGuardService.
+func (u *UserService) VerifyCredential(ctx context.Context, loginRequest *model.LoginRequest, ip string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	wait, err := u.loginGuard.Check(ctx, loginRequest.Username, ip)
 	if err != nil {
 		errorResponse := responseModel.ErrorResponse{
-			Status:       u.mapError[err],
+			Status:       http.StatusInternalServerError,
+			ErrorMessage: err.Error(),
+		}
+		return nil, &errorResponse
+	}
+	if wait > 0 {
+		u.loginGuard.Failed(ctx, loginRequest.Username, "", ip, model.LoginReasonLocked)
+		return nil, tooManyAttempts(wait)
+	}
+
+	user, err := u.userRepoPostgres.GetByUsername(ctx, loginRequest.Username)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       http.StatusInternalServerError,
 			ErrorMessage: err.Error(),
 		}
 		return nil, &errorResponse
 	}
 
+	// Unknown usernames fail like wrong passwords so they cannot be told apart.
+	if err != nil {
+		u.loginGuard.Failed(ctx, loginRequest.Username, "", ip, model.LoginReasonBadCredentials)
+		errorResponse := responseModel.ErrorResponse{
+			Status:       u.mapError[custom_error.ErrNoPermission],
+			ErrorMessage: custom_error.ErrNoPermission.Error(),
+		}
+		return nil, &errorResponse
+	}
+
 	check, checkErr := argon2.Compare(user.Password, []byte(loginRequest.Password))
 	if !check || checkErr != nil {
+		u.loginGuard.Failed(ctx, loginRequest.Username, user.ID, ip, model.LoginReasonBadCredentials)
 		errorResponse := responseModel.ErrorResponse{
-			Status:       http.StatusBadRequest,
+			Status:       u.mapError[custom_error.ErrNoPermission],
 			ErrorMessage: custom_error.ErrNoPermission.Error(),
 		}
 		return nil, &errorResponse
 	}
 
+	u.loginGuard.Succeeded(ctx, loginRequest.Username, user.ID, ip)
 	successResponse := responseModel.SuccessResponse{
 		Status: http.StatusOK,
 		Result: user.ID,
 	}
 	return &successResponse, nil
 }
+
+// GetAccount looks a user up by id, or by username if userID is empty, for
+// the auth flows of the authenticator.
+func (u *UserService) GetAccount(ctx context.Context, userID, username string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	var user *model.User
+	var err error
+	if userID != "" {
+		user, err = u.userRepoPostgres.Get(ctx, userID)
+	} else {
+		user, err = u.userRepoPostgres.GetByUsername(ctx, username)
+	}
+	if err != nil {
+		err = custom_error.HandlePostgreError(err)
+		errorResponse := responseModel.ErrorResponse{
+			Status:       u.mapError[err],
+			ErrorMessage: err.Error(),
+		}
+		return nil, &errorResponse
+	}
+
+	successResponse := responseModel.SuccessResponse{
+		Status: http.StatusOK,
+		Result: user,
+	}
+	return &successResponse, nil
+}
+
+func (u *UserService) SetPassword(ctx context.Context, userID, password string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	if password == "" {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       u.mapError[custom_error.ErrInvalidParameter],
+			ErrorMessage: custom_error.ErrInvalidParameter.Error(),
+		}
+		return nil, &errorResponse
+	}
+
+	hashPassword, hashErr := argon2.HashPassword([]byte(password))
+	if hashErr != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       http.StatusInternalServerError,
+			ErrorMessage: hashErr.Error(),
+		}
+		return nil, &errorResponse
+	}
+
+	if err := u.userRepoPostgres.UpdatePassword(ctx, userID, hashPassword); err != nil {
+		status, ok := u.mapError[err]
+		if !ok {
+			status = http.StatusInternalServerError
+		}
+		errorResponse := responseModel.ErrorResponse{
+			Status:       status,
+			ErrorMessage: err.Error(),
+		}
+		return nil, &errorResponse
+	}
+
+	go u.userRepoRedis.Delete(context.Background(), userID)
+	successResponse := responseModel.SuccessResponse{
+		Status: http.StatusNoContent,
+	}
+	return &successResponse, nil
+}
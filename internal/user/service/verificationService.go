@@ -0,0 +1,232 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"net/http"
+	"net/url"
+	"time"
+
+	"graduation-thesis/internal/user/model"
+	"graduation-thesis/internal/user/repository/verification"
+	"graduation-thesis/pkg/custom_error"
+	"graduation-thesis/pkg/logger"
+	responseModel "graduation-thesis/pkg/model"
+	"graduation-thesis/pkg/notify"
+)
+
+const (
+	verificationTokenBytes = 32
+	phoneCodeDigits        = 6
+)
+
+// VerificationConfig limits resends per channel to one every ResendCooldown
+// and MaxSends per ResendWindow, the message sent on registration included.
+type VerificationConfig struct {
+	EmailTokenTTL   time.Duration
+	PhoneCodeTTL    time.Duration
+	MaxCodeAttempts int
+	ResendCooldown  time.Duration
+	ResendWindow    time.Duration
+	MaxSends        int
+	VerifyURL       string
+}
+
+type VerificationAccounts interface {
+	Get(ctx context.Context, userId string) (*model.User, error)
+	MarkEmailVerified(ctx context.Context, userId, email string) error
+	MarkPhoneVerified(ctx context.Context, userId, phoneNumber string) error
+}
+
+type VerificationService struct {
+	verificationRepo *verification.VerificationRepo
+	accounts         VerificationAccounts
+	notifier         notify.Notifier
+	config           VerificationConfig
+	mapError         map[error]int
+	logger           logger.Logger
+}
+
+func NewVerificationService(verificationRepo *verification.VerificationRepo, accounts VerificationAccounts, notifier notify.Notifier, config VerificationConfig, mapError map[error]int, logger logger.Logger) *VerificationService {
+	return &VerificationService{
+		verificationRepo: verificationRepo,
+		accounts:         accounts,
+		notifier:         notifier,
+		config:           config,
+		mapError:         mapError,
+		logger:           logger,
+	}
+}
+
+// SendVerifications sends a link to the email of a new or changed account and
+// a code to its phone number if it has one. Failures are only logged, the user
+// can ask for another message.
+func (v *VerificationService) SendVerifications(ctx context.Context, user *model.User, email, phone bool) {
+	if email {
+		if _, err := v.send(ctx, model.VerificationChannelEmail, user); err != nil {
+			v.logger.Errorf("[SendVerifications] Cannot send email verification to %s: %v", user.ID, err)
+		}
+	}
+	if phone && user.PhoneNumber != "" {
+		if _, err := v.send(ctx, model.VerificationChannelPhone, user); err != nil {
+			v.logger.Errorf("[SendVerifications] Cannot send phone verification to %s: %v", user.ID, err)
+		}
+	}
+}
+
+// Resend sends another verification on channel to the user.
+func (v *VerificationService) Resend(ctx context.Context, userID, channel string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	user, err := v.accounts.Get(ctx, userID)
+	if err != nil {
+		return nil, v.errorResponse(custom_error.HandlePostgreError(err))
+	}
+
+	switch channel {
+	case model.VerificationChannelEmail:
+		if user.EmailVerifiedAt != nil {
+			return nil, v.errorResponse(custom_error.ErrConflict)
+		}
+	case model.VerificationChannelPhone:
+		if user.PhoneNumber == "" {
+			return nil, v.errorResponse(custom_error.ErrInvalidParameter)
+		}
+		if user.PhoneVerifiedAt != nil {
+			return nil, v.errorResponse(custom_error.ErrConflict)
+		}
+	default:
+		return nil, v.errorResponse(custom_error.ErrInvalidParameter)
+	}
+
+	wait, err := v.send(ctx, channel, user)
+	if err != nil {
+		return nil, v.errorResponse(err)
+	}
+	if wait > 0 {
+		return nil, tooManyAttempts(wait)
+	}
+
+	successResponse := responseModel.SuccessResponse{
+		Status: http.StatusAccepted,
+	}
+	return &successResponse, nil
+}
+
+// send returns how long to wait if the user has asked for too many messages.
+func (v *VerificationService) send(ctx context.Context, channel string, user *model.User) (time.Duration, error) {
+	wait, err := v.verificationRepo.ReserveSend(ctx, channel, user.ID, v.config.ResendCooldown, v.config.ResendWindow, v.config.MaxSends)
+	if err != nil || wait > 0 {
+		return wait, err
+	}
+
+	if channel == model.VerificationChannelPhone {
+		return 0, v.sendPhoneCode(ctx, user)
+	}
+	return 0, v.sendEmailLink(ctx, user)
+}
+
+func (v *VerificationService) sendEmailLink(ctx context.Context, user *model.User) error {
+	b := make([]byte, verificationTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	if err := v.verificationRepo.StoreEmailToken(ctx, user.ID, user.Email, hashVerificationSecret(token), v.config.EmailTokenTTL); err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s?token=%s", v.config.VerifyURL, url.QueryEscape(token))
+	return v.notifier.SendEmail(ctx, user.Email, "Verify your email",
+		fmt.Sprintf("Welcome %s,\n\n"+
+			"Follow this link within %s to verify your email:\n%s\n\n"+
+			"If you didn't sign up, you can ignore this email.\n", user.Username, v.config.EmailTokenTTL, link))
+}
+
+func (v *VerificationService) sendPhoneCode(ctx context.Context, user *model.User) error {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(math.Pow10(phoneCodeDigits))))
+	if err != nil {
+		return err
+	}
+	code := fmt.Sprintf("%0*d", phoneCodeDigits, n.Int64())
+	if err := v.verificationRepo.StorePhoneCode(ctx, user.ID, user.PhoneNumber, hashVerificationSecret(code), v.config.PhoneCodeTTL); err != nil {
+		return err
+	}
+
+	return v.notifier.SendSMS(ctx, user.PhoneNumber,
+		fmt.Sprintf("Your verification code is %s. It expires in %s.", code, v.config.PhoneCodeTTL))
+}
+
+// VerifyEmail verifies the email a link was sent to, and with the first one
+// the account. Links to an email that has since been changed are refused.
+func (v *VerificationService) VerifyEmail(ctx context.Context, token string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	userID, email, err := v.verificationRepo.ConsumeEmailToken(ctx, hashVerificationSecret(token))
+	if err == nil {
+		err = v.accounts.MarkEmailVerified(ctx, userID, email)
+	}
+	if errors.Is(err, custom_error.ErrNotFound) {
+		err = custom_error.ErrNoPermission
+	}
+	if err != nil {
+		return nil, v.errorResponse(err)
+	}
+
+	successResponse := responseModel.SuccessResponse{
+		Status: http.StatusNoContent,
+	}
+	return &successResponse, nil
+}
+
+func (v *VerificationService) VerifyPhone(ctx context.Context, userID, code string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	codeHash, phoneNumber, err := v.verificationRepo.AttemptPhoneCode(ctx, userID, v.config.MaxCodeAttempts)
+	switch {
+	case errors.Is(err, verification.ErrTooManyAttempts):
+		return nil, v.errorResponse(custom_error.ErrTooManyRequests)
+	case errors.Is(err, custom_error.ErrNotFound):
+		return nil, v.errorResponse(custom_error.ErrNoPermission)
+	case err != nil:
+		return nil, v.errorResponse(err)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(codeHash), []byte(hashVerificationSecret(code))) != 1 {
+		return nil, v.errorResponse(custom_error.ErrNoPermission)
+	}
+	if err := v.verificationRepo.DeletePhoneCode(ctx, userID); err != nil {
+		return nil, v.errorResponse(err)
+	}
+
+	err = v.accounts.MarkPhoneVerified(ctx, userID, phoneNumber)
+	if errors.Is(err, custom_error.ErrNotFound) {
+		err = custom_error.ErrNoPermission
+	}
+	if err != nil {
+		return nil, v.errorResponse(err)
+	}
+
+	successResponse := responseModel.SuccessResponse{
+		Status: http.StatusNoContent,
+	}
+	return &successResponse, nil
+}
+
+func (v *VerificationService) errorResponse(err error) *responseModel.ErrorResponse {
+	status, ok := v.mapError[err]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+	return &responseModel.ErrorResponse{
+		Status:       status,
+		ErrorMessage: err.Error(),
+	}
+}
+
+func hashVerificationSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,323 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"graduation-thesis/internal/user/model"
+	"graduation-thesis/internal/user/repository/verification"
+	"graduation-thesis/pkg/custom_error"
+	"graduation-thesis/pkg/storage/redistest"
+
+	"go.uber.org/zap"
+)
+
+type fakeAccounts struct {
+	mu    sync.Mutex
+	users map[string]*model.User
+}
+
+func (f *fakeAccounts) Get(ctx context.Context, userId string) (*model.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	user, ok := f.users[userId]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (f *fakeAccounts) MarkEmailVerified(ctx context.Context, userId, email string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	user, ok := f.users[userId]
+	if !ok || user.Email != email {
+		return custom_error.ErrNotFound
+	}
+	now := time.Now()
+	user.EmailVerifiedAt = &now
+	if user.VerifiedAt == nil {
+		user.VerifiedAt = &now
+	}
+	return nil
+}
+
+func (f *fakeAccounts) MarkPhoneVerified(ctx context.Context, userId, phoneNumber string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	user, ok := f.users[userId]
+	if !ok || user.PhoneNumber != phoneNumber {
+		return custom_error.ErrNotFound
+	}
+	now := time.Now()
+	user.PhoneVerifiedAt = &now
+	return nil
+}
+
+type sentMessage struct {
+	channel string
+	to      string
+	body    string
+}
+
+type recordingNotifier struct {
+	mu   sync.Mutex
+	sent []sentMessage
+}
+
+func (r *recordingNotifier) SendEmail(ctx context.Context, to, subject, body string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sent = append(r.sent, sentMessage{channel: model.VerificationChannelEmail, to: to, body: body})
+	return nil
+}
+
+func (r *recordingNotifier) SendSMS(ctx context.Context, to, body string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sent = append(r.sent, sentMessage{channel: model.VerificationChannelPhone, to: to, body: body})
+	return nil
+}
+
+// last returns the latest message sent on channel.
+func (r *recordingNotifier) last(t *testing.T, channel string) sentMessage {
+	t.Helper()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := len(r.sent) - 1; i >= 0; i-- {
+		if r.sent[i].channel == channel {
+			return r.sent[i]
+		}
+	}
+	t.Fatalf("nothing sent by %s", channel)
+	return sentMessage{}
+}
+
+var (
+	linkPattern = regexp.MustCompile(`https?://\S+`)
+	codePattern = regexp.MustCompile(`\b\d{6}\b`)
+)
+
+func emailToken(t *testing.T, message sentMessage) string {
+	t.Helper()
+	link, err := url.Parse(linkPattern.FindString(message.body))
+	if err != nil || link.Query().Get("token") == "" {
+		t.Fatalf("no verification link in %q", message.body)
+	}
+	return link.Query().Get("token")
+}
+
+func phoneCode(t *testing.T, message sentMessage) string {
+	t.Helper()
+	code := codePattern.FindString(message.body)
+	if code == "" {
+		t.Fatalf("no code in %q", message.body)
+	}
+	return code
+}
+
+var testVerification = VerificationConfig{
+	EmailTokenTTL:   24 * time.Hour,
+	PhoneCodeTTL:    10 * time.Minute,
+	MaxCodeAttempts: 3,
+	ResendCooldown:  time.Minute,
+	ResendWindow:    time.Hour,
+	MaxSends:        3,
+	VerifyURL:       "http://localhost/v1/user/verification/email",
+}
+
+func newTestVerificationService(t *testing.T) (*VerificationService, *fakeAccounts, *recordingNotifier, *redistest.Server) {
+	t.Helper()
+	client, server := redistest.NewClient(t)
+	accounts := &fakeAccounts{users: map[string]*model.User{
+		"user-1": {ID: "user-1", Username: "alice", Email: "alice@example.com", PhoneNumber: "+84900000001"},
+	}}
+	notifier := &recordingNotifier{}
+	verificationService := NewVerificationService(verification.NewVerificationRepo(client), accounts, notifier, testVerification, custom_error.MappingError(), zap.NewNop().Sugar())
+	return verificationService, accounts, notifier, server
+}
+
+func register(t *testing.T, verificationService *VerificationService, accounts *fakeAccounts) {
+	t.Helper()
+	user, _ := accounts.Get(context.Background(), "user-1")
+	verificationService.SendVerifications(context.Background(), user, true, true)
+}
+
+func TestVerifyEmail(t *testing.T) {
+	verificationService, accounts, notifier, _ := newTestVerificationService(t)
+	ctx := context.Background()
+	register(t, verificationService, accounts)
+
+	message := notifier.last(t, model.VerificationChannelEmail)
+	if message.to != "alice@example.com" {
+		t.Fatalf("link sent to %s", message.to)
+	}
+	token := emailToken(t, message)
+
+	successResponse, errorResponse := verificationService.VerifyEmail(ctx, token)
+	if errorResponse != nil || successResponse.Status != http.StatusNoContent {
+		t.Fatalf("VerifyEmail = %+v %+v", successResponse, errorResponse)
+	}
+	user, _ := accounts.Get(ctx, "user-1")
+	if !user.Verified() || user.EmailVerifiedAt == nil {
+		t.Fatalf("account not verified: %+v", user)
+	}
+
+	_, errorResponse = verificationService.VerifyEmail(ctx, token)
+	if errorResponse == nil || errorResponse.Status != http.StatusUnauthorized {
+		t.Fatalf("second VerifyEmail = %+v, want 401", errorResponse)
+	}
+}
+
+func TestVerifyEmailAfterEmailChange(t *testing.T) {
+	verificationService, accounts, notifier, _ := newTestVerificationService(t)
+	register(t, verificationService, accounts)
+	token := emailToken(t, notifier.last(t, model.VerificationChannelEmail))
+
+	accounts.users["user-1"].Email = "mallory@example.com"
+	_, errorResponse := verificationService.VerifyEmail(context.Background(), token)
+	if errorResponse == nil || errorResponse.Status != http.StatusUnauthorized {
+		t.Fatalf("VerifyEmail = %+v, want 401", errorResponse)
+	}
+	if accounts.users["user-1"].Verified() {
+		t.Fatal("account verified with a link to the old email")
+	}
+}
+
+func TestVerifyEmailTokenExpires(t *testing.T) {
+	verificationService, accounts, notifier, server := newTestVerificationService(t)
+	register(t, verificationService, accounts)
+	token := emailToken(t, notifier.last(t, model.VerificationChannelEmail))
+
+	server.FastForward(25 * time.Hour)
+	_, errorResponse := verificationService.VerifyEmail(context.Background(), token)
+	if errorResponse == nil || errorResponse.Status != http.StatusUnauthorized {
+		t.Fatalf("VerifyEmail = %+v, want 401", errorResponse)
+	}
+}
+
+func TestVerifyPhone(t *testing.T) {
+	verificationService, accounts, notifier, _ := newTestVerificationService(t)
+	ctx := context.Background()
+	register(t, verificationService, accounts)
+
+	message := notifier.last(t, model.VerificationChannelPhone)
+	if message.to != "+84900000001" {
+		t.Fatalf("code sent to %s", message.to)
+	}
+	code := phoneCode(t, message)
+
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+	_, errorResponse := verificationService.VerifyPhone(ctx, "user-1", wrong)
+	if errorResponse == nil || errorResponse.Status != http.StatusUnauthorized {
+		t.Fatalf("VerifyPhone with a wrong code = %+v", errorResponse)
+	}
+
+	successResponse, errorResponse := verificationService.VerifyPhone(ctx, "user-1", code)
+	if errorResponse != nil || successResponse.Status != http.StatusNoContent {
+		t.Fatalf("VerifyPhone = %+v %+v", successResponse, errorResponse)
+	}
+	user, _ := accounts.Get(ctx, "user-1")
+	if user.PhoneVerifiedAt == nil {
+		t.Fatal("phone not verified")
+	}
+	// A verified phone does not verify the account.
+	if user.Verified() {
+		t.Fatal("account verified by its phone")
+	}
+
+	_, errorResponse = verificationService.VerifyPhone(ctx, "user-1", code)
+	if errorResponse == nil || errorResponse.Status != http.StatusUnauthorized {
+		t.Fatalf("second VerifyPhone = %+v", errorResponse)
+	}
+}
+
+func TestVerifyPhoneAttemptsAreLimited(t *testing.T) {
+	verificationService, accounts, notifier, _ := newTestVerificationService(t)
+	ctx := context.Background()
+	register(t, verificationService, accounts)
+	code := phoneCode(t, notifier.last(t, model.VerificationChannelPhone))
+
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+	for i := 0; i < testVerification.MaxCodeAttempts; i++ {
+		verificationService.VerifyPhone(ctx, "user-1", wrong)
+	}
+	_, errorResponse := verificationService.VerifyPhone(ctx, "user-1", code)
+	if errorResponse == nil || errorResponse.Status != http.StatusTooManyRequests {
+		t.Fatalf("VerifyPhone after too many attempts = %+v", errorResponse)
+	}
+	// The code is gone, the user has to ask for another one.
+	_, errorResponse = verificationService.VerifyPhone(ctx, "user-1", code)
+	if errorResponse == nil || errorResponse.Status != http.StatusUnauthorized {
+		t.Fatalf("VerifyPhone of a dropped code = %+v", errorResponse)
+	}
+}
+
+func TestResendIsRateLimited(t *testing.T) {
+	verificationService, accounts, notifier, server := newTestVerificationService(t)
+	ctx := context.Background()
+	register(t, verificationService, accounts)
+	first := emailToken(t, notifier.last(t, model.VerificationChannelEmail))
+
+	_, errorResponse := verificationService.Resend(ctx, "user-1", model.VerificationChannelEmail)
+	if errorResponse == nil || errorResponse.Status != http.StatusTooManyRequests || errorResponse.RetryAfter != 60 {
+		t.Fatalf("Resend within the cooldown = %+v", errorResponse)
+	}
+
+	server.FastForward(time.Minute)
+	successResponse, errorResponse := verificationService.Resend(ctx, "user-1", model.VerificationChannelEmail)
+	if errorResponse != nil || successResponse.Status != http.StatusAccepted {
+		t.Fatalf("Resend = %+v %+v", successResponse, errorResponse)
+	}
+	// The new link replaces the previous one.
+	if _, errorResponse := verificationService.VerifyEmail(ctx, first); errorResponse == nil {
+		t.Fatal("replaced link still verifies")
+	}
+
+	server.FastForward(time.Minute)
+	if _, errorResponse := verificationService.Resend(ctx, "user-1", model.VerificationChannelEmail); errorResponse != nil {
+		t.Fatalf("third send = %+v", errorResponse)
+	}
+	server.FastForward(time.Minute)
+	_, errorResponse = verificationService.Resend(ctx, "user-1", model.VerificationChannelEmail)
+	if errorResponse == nil || errorResponse.Status != http.StatusTooManyRequests || errorResponse.RetryAfter <= 60 {
+		t.Fatalf("Resend over the window limit = %+v", errorResponse)
+	}
+
+	// Channels are limited separately.
+	server.FastForward(time.Minute)
+	if _, errorResponse := verificationService.Resend(ctx, "user-1", model.VerificationChannelPhone); errorResponse != nil {
+		t.Fatalf("phone resend = %+v", errorResponse)
+	}
+}
+
+func TestResendWhenVerified(t *testing.T) {
+	verificationService, accounts, notifier, _ := newTestVerificationService(t)
+	ctx := context.Background()
+	register(t, verificationService, accounts)
+	verificationService.VerifyEmail(ctx, emailToken(t, notifier.last(t, model.VerificationChannelEmail)))
+
+	_, errorResponse := verificationService.Resend(ctx, "user-1", model.VerificationChannelEmail)
+	if errorResponse == nil || errorResponse.Status != http.StatusConflict {
+		t.Fatalf("Resend to a verified email = %+v", errorResponse)
+	}
+
+	accounts.users["user-1"].PhoneNumber = ""
+	_, errorResponse = verificationService.Resend(ctx, "user-1", model.VerificationChannelPhone)
+	if errorResponse == nil || errorResponse.Status != http.StatusUnprocessableEntity {
+		t.Fatalf("Resend without a phone number = %+v", errorResponse)
+	}
+}
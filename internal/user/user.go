@@ -3,15 +3,27 @@ package user
 import (
 	"crypto/tls"
 	"fmt"
+	"net"
 	"net/http"
 	"sync"
 	"time"
 
+	"graduation-thesis/internal/user/grpc_handler"
 	"graduation-thesis/internal/user/handler"
-	"graduation-thesis/internal/user/repository/token"
+	"graduation-thesis/internal/user/repository/attempt"
+	"graduation-thesis/internal/user/repository/audit"
+	"graduation-thesis/internal/user/repository/block"
+	"graduation-thesis/internal/user/repository/identity"
 	"graduation-thesis/internal/user/repository/user"
+	"graduation-thesis/internal/user/repository/verification"
 	"graduation-thesis/internal/user/service"
 	"graduation-thesis/pkg/custom_error"
+	"graduation-thesis/pkg/logger"
+	"graduation-thesis/pkg/mail"
+	"graduation-thesis/pkg/middleware"
+	"graduation-thesis/pkg/notify"
+	"graduation-thesis/pkg/pb/userpb"
+	"graduation-thesis/pkg/rpc"
 	"graduation-thesis/pkg/storage"
 
 	"github.com/spf13/viper"
@@ -34,17 +46,71 @@ func Run() {
 	redisClient := storage.GetRedisClient(viper.GetString("redis.url"))
 	defer redisClient.Close()
 
+	logger, err := logger.GetLogger(
+		viper.GetString("logger.level"),
+		viper.GetString("logger.path"),
+	)
+	if err != nil {
+		panic(err)
+	}
+
 	userRepoPostgres := user.NewUserRepoPostgres(postgres)
 	userRepoRedis := user.NewUserRepoRedis(redisClient)
-	tokenRepo := token.NewTokenRepo(redisClient)
+	blockRepo := block.NewBlockRepo(postgres)
+	attemptRepo := attempt.NewAttemptRepo(redisClient)
+	auditRepo := audit.NewAuditRepo(postgres)
+
+	loginGuardService := service.NewLoginGuardService(attemptRepo, auditRepo, service.LoginProtectionConfig{
+		Window:          viper.GetDuration("login_protection.window"),
+		DelayAfter:      viper.GetInt("login_protection.delay_after"),
+		BaseDelay:       viper.GetDuration("login_protection.base_delay"),
+		MaxDelay:        viper.GetDuration("login_protection.max_delay"),
+		LockoutAfter:    viper.GetInt("login_protection.lockout_after"),
+		LockoutDuration: viper.GetDuration("login_protection.lockout_duration"),
+		IPLockoutAfter:  viper.GetInt("login_protection.ip_lockout_after"),
+	}, viper.GetStringSlice("admin.user_ids"), custom_error.MappingError(), logger)
+	notifier, err := notify.NewNotifier(notify.Config{
+		Driver: viper.GetString("notify.driver"),
+		Path:   viper.GetString("notify.path"),
+		Mail: mail.Config{
+			Driver: viper.GetString("notify.mail.driver"),
+			From:   viper.GetString("notify.mail.from"),
+			Path:   viper.GetString("notify.mail.path"),
+			SMTP: mail.SMTPConfig{
+				Host:     viper.GetString("notify.mail.smtp.host"),
+				Port:     viper.GetInt("notify.mail.smtp.port"),
+				Username: viper.GetString("notify.mail.smtp.username"),
+				Password: viper.GetString("notify.mail.smtp.password"),
+			},
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+	verificationService := service.NewVerificationService(verification.NewVerificationRepo(redisClient), userRepoPostgres, notifier, service.VerificationConfig{
+		EmailTokenTTL:   viper.GetDuration("verification.email_token_ttl"),
+		PhoneCodeTTL:    viper.GetDuration("verification.phone_code_ttl"),
+		MaxCodeAttempts: viper.GetInt("verification.max_code_attempts"),
+		ResendCooldown:  viper.GetDuration("verification.resend_cooldown"),
+		ResendWindow:    viper.GetDuration("verification.resend_window"),
+		MaxSends:        viper.GetInt("verification.max_sends"),
+		VerifyURL:       viper.GetString("verification.url"),
+	}, custom_error.MappingError(), logger)
+	userService := service.NewUserService(postgres, userRepoPostgres, userRepoRedis, loginGuardService, verificationService, custom_error.MappingError())
+	blockService := service.NewBlockService(blockRepo, custom_error.MappingError())
+	identityService := service.NewIdentityService(identity.NewIdentityRepo(postgres), userRepoPostgres, verificationService, custom_error.MappingError())
 
-	userService := service.NewUserService(postgres, userRepoPostgres, userRepoRedis, custom_error.MappingError())
-	tokenService := service.NewTokenService(tokenRepo, viper.GetInt64("token.at_expires"), viper.GetInt64("token.rt_expires"), viper.GetString("token.access_secret"), viper.GetString("token.refresh_secret"))
-	authService := service.NewAuthService(userRepoPostgres, tokenService)
+	userHandler := handler.NewUserHandler(userService, viper.GetString("authenticator.address"))
+	blockHandler := handler.NewBlockHandler(blockService, viper.GetString("authenticator.address"))
+	loginGuardHandler := handler.NewLoginGuardHandler(loginGuardService, viper.GetString("authenticator.address"))
+	verificationHandler := handler.NewVerificationHandler(verificationService, viper.GetString("authenticator.address"))
+	router := handler.GetRouter(userHandler, blockHandler, loginGuardHandler, verificationHandler)
+	if err := middleware.TrustProxies(router, viper.GetStringSlice("trusted_proxies")); err != nil {
+		panic(err)
+	}
 
-	userHandler := handler.NewUserHandler(userService, viper.GetString("authenticator.url"))
-	authHandler := handler.NewAuthHandler(authService, tokenService, userService)
-	router := handler.GetRouter(authHandler, userHandler)
+	grpcSrv := rpc.NewServer()
+	userpb.RegisterUserServiceServer(grpcSrv, grpc_handler.NewUserServer(blockService, userService, identityService))
 
 	TLSConfig := &tls.Config{
 		PreferServerCipherSuites: true,
@@ -91,9 +157,21 @@ func Run() {
 		}
 	}
 
+	serveGRPC := func(wg *sync.WaitGroup) {
+		defer wg.Done()
+		lis, err := net.Listen("tcp", fmt.Sprintf(":%d", viper.GetInt("app.grpc_port")))
+		if err != nil {
+			panic(err)
+		}
+		if err := grpcSrv.Serve(lis); err != nil {
+			panic(err)
+		}
+	}
+
 	wg := sync.WaitGroup{}
-	wg.Add(2)
+	wg.Add(3)
 	go serveHTTP(&wg)
 	go serveHTTPS(&wg)
+	go serveGRPC(&wg)
 	wg.Wait()
 }
@@ -1,10 +1,9 @@
 package handler
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"net/http"
-	"time"
 
 	"github.com/gin-gonic/gin"
 
@@ -17,64 +16,37 @@ import (
 type WebsocketForwarder struct {
 	websocketManagerUrl string
 	errorMap            map[error]int
-	timeout             time.Duration
-	maxRetries          int
-	retryInterval       time.Duration
+	client              *request.Client
 	logger              logger.Logger
 }
 
 func NewWebsocketForwarder(
 	websocketManagerUrl string,
 	errorMap map[error]int,
-	timeout time.Duration,
-	maxRetries int,
-	retryInterval time.Duration,
+	client *request.Client,
 	logger logger.Logger) *WebsocketForwarder {
 	return &WebsocketForwarder{
 		websocketManagerUrl: websocketManagerUrl,
 		errorMap:            errorMap,
-		timeout:             timeout,
-		maxRetries:          maxRetries,
-		retryInterval:       retryInterval,
+		client:              client,
 		logger:              logger,
 	}
 }
 
-func (w *WebsocketForwarder) getListWebsocketHandlers() ([]model.WebsocketHandler, error) {
-	var (
-		result interface{}
-		err    error
+func (w *WebsocketForwarder) getListWebsocketHandlers(ctx context.Context) ([]model.WebsocketHandler, error) {
+	return request.Get[[]model.WebsocketHandler](
+		ctx,
+		w.client,
+		fmt.Sprintf("%s/websocket_handler", w.websocketManagerUrl),
+		"",
 	)
-	for i := 1; i <= w.maxRetries; i++ {
-		result, err = request.HTTPRequestCall(
-			fmt.Sprintf("%s/websocket_handler", w.websocketManagerUrl),
-			http.MethodGet,
-			"",
-			nil,
-			w.timeout,
-		)
-		if err != nil {
-			time.Sleep(w.retryInterval)
-			continue
-		}
-		break
-	}
-
-	if err != nil {
-		return nil, err
-	}
-
-	listWebsocketHandlersJSON, _ := json.Marshal(result)
-	var listWebsocketHandlers []model.WebsocketHandler
-	json.Unmarshal(listWebsocketHandlersJSON, &listWebsocketHandlers)
-	return listWebsocketHandlers, nil
 }
 
 func (w *WebsocketForwarder) HandleRequest(c *gin.Context) {
-	websocketHandlers, err := w.getListWebsocketHandlers()
+	websocketHandlers, err := w.getListWebsocketHandlers(c.Request.Context())
 	if err != nil {
 		errorMessage := responseModel.ErrorResponse{
-			Status:       w.errorMap[err],
+			Status:       w.errorMap[request.Cause(err)],
 			ErrorMessage: err.Error(),
 		}
 		c.JSON(errorMessage.Status, errorMessage)
@@ -6,6 +6,7 @@ import (
 	"graduation-thesis/internal/websocket_forwarder/handler"
 	"graduation-thesis/pkg/custom_error"
 	"graduation-thesis/pkg/logger"
+	request "graduation-thesis/pkg/requests"
 	"net/http"
 	"sync"
 	"time"
@@ -36,9 +37,14 @@ func Run() {
 	websocketForwarder := handler.NewWebsocketForwarder(
 		viper.GetString("websocket_manager.url"),
 		errorMap,
-		viper.GetDuration("timeout"),
-		viper.GetInt("max_retries"),
-		viper.GetDuration("retry_interval"),
+		request.NewClient(request.Config{
+			Timeout:          viper.GetDuration("timeout"),
+			MaxRetries:       viper.GetInt("max_retries"),
+			BaseBackoff:      viper.GetDuration("retry_interval"),
+			MaxBackoff:       viper.GetDuration("max_retry_interval"),
+			BreakerThreshold: viper.GetInt("breaker.threshold"),
+			BreakerCooldown:  viper.GetDuration("breaker.cooldown"),
+		}),
 		logger,
 	)
 	router := handler.GetRouter(websocketForwarder)
@@ -1,11 +1,11 @@
 package handler
 
 import (
-	"errors"
+	"context"
 	"graduation-thesis/internal/websocket_handler/worker"
 	"graduation-thesis/pkg/custom_error"
 	responseModel "graduation-thesis/pkg/model"
-	request "graduation-thesis/pkg/requests"
+	"graduation-thesis/pkg/pb/authenticatorpb"
 	"time"
 
 	"net/http"
@@ -15,18 +15,18 @@ import (
 )
 
 type Handler struct {
-	upgrader         websocket.Upgrader
-	worker           *worker.Worker
-	authenticatorURL string
+	upgrader            websocket.Upgrader
+	worker              *worker.Worker
+	authenticatorClient authenticatorpb.AuthenticatorServiceClient
 }
 
-func NewHandler(worker *worker.Worker, authenticatorURL string) *Handler {
+func NewHandler(worker *worker.Worker, authenticatorClient authenticatorpb.AuthenticatorServiceClient) *Handler {
 	upgrader := websocket.Upgrader{}
 	upgrader.CheckOrigin = func(r *http.Request) bool { return true }
 	return &Handler{
-		upgrader:         upgrader,
-		worker:           worker,
-		authenticatorURL: authenticatorURL,
+		upgrader:            upgrader,
+		worker:              worker,
+		authenticatorClient: authenticatorClient,
 	}
 }
 
@@ -56,7 +56,7 @@ func (h *Handler) EstablishConnetionWithPeer(c *gin.Context) {
 func (h *Handler) EstablishConnetionWithUser(c *gin.Context) {
 	// userID := c.MustGet("user_id").(string)
 	authToken := c.Query("user_id")
-	userID, vErr := h.validateToken(authToken)
+	userID, sessionID, vErr := h.validateToken(authToken)
 	if vErr != nil {
 		errorResponse := responseModel.ErrorResponse{
 			Status:       http.StatusBadRequest,
@@ -75,7 +75,7 @@ func (h *Handler) EstablishConnetionWithUser(c *gin.Context) {
 		return
 	}
 
-	if err := h.worker.KeepUsersConnection(conn, userID, authToken); err != nil {
+	if err := h.worker.KeepUsersConnection(conn, userID, sessionID); err != nil {
 		errorResponse := responseModel.ErrorResponse{
 			Status:       http.StatusBadRequest,
 			ErrorMessage: err.Error(),
@@ -85,29 +85,14 @@ func (h *Handler) EstablishConnetionWithUser(c *gin.Context) {
 	}
 }
 
-func (h *Handler) validateToken(authToken string) (string, error) {
-	var (
-		result interface{}
-		err    error
-	)
-	for i := 1; i <= 5; i++ {
-		result, err = request.HTTPRequestCall(
-			h.authenticatorURL,
-			http.MethodPost,
-			authToken,
-			nil,
-			5*time.Second,
-		)
-		if err != nil && !errors.Is(err, custom_error.ErrNoPermission) {
-			continue
-		}
-		break
-	}
+func (h *Handler) validateToken(authToken string) (string, string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
 
+	response, err := h.authenticatorClient.ValidateToken(ctx, &authenticatorpb.ValidateTokenRequest{Token: authToken})
 	if err != nil {
-		return "", err
+		return "", "", custom_error.HandleGRPCError(err)
 	}
 
-	userID := result.(string)
-	return userID, nil
+	return response.UserId, response.SessionId, nil
 }
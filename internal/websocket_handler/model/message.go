@@ -1,13 +1,15 @@
 package model
 
 type Message struct {
-	ConversationID        string `json:"conv_id"`
-	ConversationMessageID int64  `json:"conv_msg_id"`
-	MessageTime           int64  `json:"msg_time"`
-	Sender                string `json:"sender"`
-	Content               string `json:"content"`
-	IV                    string `json:"iv"`
-	Receiver              string `json:"receiver"`
+	ConversationID        string   `json:"conv_id"`
+	ConversationMessageID int64    `json:"conv_msg_id"`
+	MessageTime           int64    `json:"msg_time"`
+	Sender                string   `json:"sender"`
+	Content               string   `json:"content"`
+	IV                    string   `json:"iv"`
+	Receiver              string   `json:"receiver"`
+	Receivers             []string `json:"receivers,omitempty"`
+	Type                  string   `json:"type,omitempty"`
 }
 type SendMessageRequest struct {
 	ConversationID string `json:"conv_id" binding:"required"`
@@ -62,4 +64,5 @@ type UserInbox struct {
 	Sender                string `json:"sender" cql:"sender"`
 	Content               string `json:"content" cql:"content"`
 	IV                    string `json:"iv" cql:"iv"`
+	Type                  string `json:"type,omitempty" cql:"type"`
 }
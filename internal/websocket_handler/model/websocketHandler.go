@@ -21,8 +21,17 @@ type PingRequest struct {
 type Connection struct {
 	Mu                 sync.RWMutex
 	WebsocketHandlerID string
+	SessionID          string
 	WriteChannel       chan Message
-	IsDeleted          bool // For coodinating concurrent reads and writes
+	Revoked            chan struct{} // Closed when the session of the connection is revoked
+	IsDeleted          bool          // For coodinating concurrent reads and writes
+	revokeOnce         sync.Once
+}
+
+func (c *Connection) Revoke() {
+	c.revokeOnce.Do(func() {
+		close(c.Revoked)
+	})
 }
 
 func (c *Connection) CheckDeleted() bool {
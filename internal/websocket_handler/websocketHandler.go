@@ -7,6 +7,13 @@ import (
 	"graduation-thesis/internal/websocket_handler/worker"
 	"graduation-thesis/pkg/custom_error"
 	"graduation-thesis/pkg/logger"
+	"graduation-thesis/pkg/membership"
+	"graduation-thesis/pkg/pb/authenticatorpb"
+	"graduation-thesis/pkg/pb/grouppb"
+	"graduation-thesis/pkg/pb/messagepb"
+	"graduation-thesis/pkg/pb/websocketmanagerpb"
+	"graduation-thesis/pkg/rpc"
+	"graduation-thesis/pkg/session"
 	"graduation-thesis/pkg/storage"
 	"net/http"
 	"os"
@@ -46,20 +53,35 @@ func Run() {
 	interrupt := make(chan os.Signal, 1)
 	signal.Notify(interrupt, os.Interrupt)
 
+	membershipConsumer := storage.NewKafkaBroadcastConsumer(
+		viper.GetString("kafka.bootstrap_server"),
+		fmt.Sprintf("%s-%s", viper.GetString("kafka.membership_group_id"), viper.GetString("id")),
+	)
+	defer membershipConsumer.Close()
+	sessionConsumer := storage.NewKafkaBroadcastConsumer(
+		viper.GetString("kafka.bootstrap_server"),
+		fmt.Sprintf("%s-%s", viper.GetString("kafka.session_group_id"), viper.GetString("id")),
+	)
+	defer sessionConsumer.Close()
+	groupClient := grouppb.NewGroupServiceClient(rpc.GetClientConn(viper.GetString("3rd_party.group_service_address")))
+	membershipCache := membership.NewCache(membership.GroupLoader(groupClient, 5*time.Second), viper.GetDuration("membership_cache_ttl"))
+
 	worker := worker.NewWorker(
 		viper.GetString("id"),
 		kafkaProducer,
 		viper.GetString("kafka.topic"),
-		viper.GetString("3rd_party.group_service_url"),
-		viper.GetString("3rd_party.message_service_url"),
-		viper.GetString("3rd_party.websocket_manager_url"),
+		groupClient,
+		membershipCache,
+		messagepb.NewMessageServiceClient(rpc.GetClientConn(viper.GetString("3rd_party.message_service_address"))),
+		websocketmanagerpb.NewWebsocketManagerServiceClient(rpc.GetClientConn(viper.GetString("3rd_party.websocket_manager_address"))),
 		viper.GetDuration("fetch_interval"),
 		viper.GetDuration("ping_interval"),
 		viper.GetInt("max_retries"),
 		viper.GetDuration("retry_interval"),
 		viper.GetDuration("cache_timeout"),
 		logger)
-	handler := Handler.NewHandler(worker, viper.GetString("3rd_party.authenticator_url"))
+	authenticatorClient := authenticatorpb.NewAuthenticatorServiceClient(rpc.GetClientConn(viper.GetString("3rd_party.authenticator_address")))
+	handler := Handler.NewHandler(worker, authenticatorClient)
 	router := Handler.GetRouter(handler)
 
 	if err := worker.Register(); err != nil {
@@ -96,8 +118,10 @@ func Run() {
 		Handler:      router,
 	}
 
+	membershipDone := make(chan struct{})
+	sessionDone := make(chan struct{})
 	var wg sync.WaitGroup
-	wg.Add(2)
+	wg.Add(4)
 	logger.Info("[MAIN] Starting Websocket Forwarder")
 
 	go func(wg *sync.WaitGroup) {
@@ -114,7 +138,23 @@ func Run() {
 		}
 	}(&wg)
 
+	go func(wg *sync.WaitGroup) {
+		defer wg.Done()
+		if err := membershipCache.Listen(membershipConsumer, viper.GetString("kafka.membership_topic"), logger, membershipDone); err != nil {
+			panic(err)
+		}
+	}(&wg)
+
+	go func(wg *sync.WaitGroup) {
+		defer wg.Done()
+		if err := session.Listen(sessionConsumer, viper.GetString("kafka.session_topic"), worker.RevokeSessions, logger, sessionDone); err != nil {
+			panic(err)
+		}
+	}(&wg)
+
 	wg.Wait()
 	_ = <-interrupt
+	close(membershipDone)
+	close(sessionDone)
 	worker.Shutdown()
 }
@@ -1,12 +1,11 @@
 package worker
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net"
-	"net/http"
 	"net/url"
 	"sync"
 	"time"
@@ -14,7 +13,11 @@ import (
 	"graduation-thesis/internal/websocket_handler/model"
 	"graduation-thesis/pkg/custom_error"
 	"graduation-thesis/pkg/logger"
-	request "graduation-thesis/pkg/requests"
+	"graduation-thesis/pkg/membership"
+	"graduation-thesis/pkg/pb/grouppb"
+	"graduation-thesis/pkg/pb/messagepb"
+	"graduation-thesis/pkg/pb/websocketmanagerpb"
+	"graduation-thesis/pkg/session"
 
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
 	"github.com/gorilla/websocket"
@@ -22,33 +25,35 @@ import (
 )
 
 type Worker struct {
-	id                  string
-	kafkaProducer       *kafka.Producer
-	kafkaTopic          string
-	groupServiceUrl     string
-	messageServiceUrl   string
-	websocketManagerUrl string
-	mapUserPeer         *model.MapUserPeer
-	mapPeer             *model.MapConnection
-	mapUser             *model.MapConnection
-	fetchInterval       time.Duration
-	pingInterval        time.Duration
-	maxRetries          int
-	retryInterval       time.Duration
-	cacheTimeout        time.Duration
-	wg                  *sync.WaitGroup
-	logger              logger.Logger
-	concurrent          chan struct{}
-	done                chan struct{}
+	id                     string
+	kafkaProducer          *kafka.Producer
+	kafkaTopic             string
+	groupClient            grouppb.GroupServiceClient
+	membershipCache        *membership.Cache
+	messageClient          messagepb.MessageServiceClient
+	websocketManagerClient websocketmanagerpb.WebsocketManagerServiceClient
+	mapUserPeer            *model.MapUserPeer
+	mapPeer                *model.MapConnection
+	mapUser                *model.MapConnection
+	fetchInterval          time.Duration
+	pingInterval           time.Duration
+	maxRetries             int
+	retryInterval          time.Duration
+	cacheTimeout           time.Duration
+	wg                     *sync.WaitGroup
+	logger                 logger.Logger
+	concurrent             chan struct{}
+	done                   chan struct{}
 }
 
 func NewWorker(
 	id string,
 	kafkaProducer *kafka.Producer,
 	topic string,
-	groupServiceUrl string,
-	messageServiceUrl string,
-	websocketManagerUrl string,
+	groupClient grouppb.GroupServiceClient,
+	membershipCache *membership.Cache,
+	messageClient messagepb.MessageServiceClient,
+	websocketManagerClient websocketmanagerpb.WebsocketManagerServiceClient,
 	fetchInterval time.Duration,
 	pingInterval time.Duration,
 	maxRetries int,
@@ -56,24 +61,25 @@ func NewWorker(
 	cacheTimeout time.Duration,
 	logger logger.Logger) *Worker {
 	return &Worker{
-		id:                  id,
-		kafkaProducer:       kafkaProducer,
-		kafkaTopic:          topic,
-		groupServiceUrl:     groupServiceUrl,
-		messageServiceUrl:   messageServiceUrl,
-		websocketManagerUrl: websocketManagerUrl,
-		mapUserPeer:         model.NewMapUserPeer(),
-		mapPeer:             model.NewMapConnection(),
-		mapUser:             model.NewMapConnection(),
-		fetchInterval:       fetchInterval,
-		pingInterval:        pingInterval,
-		maxRetries:          maxRetries,
-		retryInterval:       retryInterval,
-		cacheTimeout:        cacheTimeout,
-		wg:                  &sync.WaitGroup{},
-		logger:              logger,
-		concurrent:          make(chan struct{}, 10000),
-		done:                make(chan struct{}),
+		id:                     id,
+		kafkaProducer:          kafkaProducer,
+		kafkaTopic:             topic,
+		groupClient:            groupClient,
+		membershipCache:        membershipCache,
+		messageClient:          messageClient,
+		websocketManagerClient: websocketManagerClient,
+		mapUserPeer:            model.NewMapUserPeer(),
+		mapPeer:                model.NewMapConnection(),
+		mapUser:                model.NewMapConnection(),
+		fetchInterval:          fetchInterval,
+		pingInterval:           pingInterval,
+		maxRetries:             maxRetries,
+		retryInterval:          retryInterval,
+		cacheTimeout:           cacheTimeout,
+		wg:                     &sync.WaitGroup{},
+		logger:                 logger,
+		concurrent:             make(chan struct{}, 10000),
+		done:                   make(chan struct{}),
 	}
 }
 
@@ -136,7 +142,7 @@ func (w *Worker) KeepPeersConnection(conn *websocket.Conn, websocketID string) e
 			}
 
 			// w.concurrent <- struct{}{}
-			go w.ForwardMessage(&message, message.Receiver)
+			w.handleMessageReadFromPeer(message, websocketID)
 		}
 	}(conn, w, websocketID, done)
 
@@ -179,7 +185,32 @@ func (w *Worker) KeepPeersConnection(conn *websocket.Conn, websocketID string) e
 
 }
 
-func (w *Worker) KeepUsersConnection(conn *websocket.Conn, userID, authToken string) error {
+// GroupMessageHandlerID is the websocket_id the group message handler connects
+// to peers with.
+const GroupMessageHandlerID = "group_message_handler"
+
+// handleMessageReadFromPeer forwards a message a peer sent to its receiver.
+// Only the group message handler may list several receivers in one frame, the
+// other peers just pass on what their users sent.
+func (w *Worker) handleMessageReadFromPeer(message model.Message, websocketID string) {
+	if websocketID == GroupMessageHandlerID && len(message.Receivers) > 0 {
+		w.expandMessage(message)
+		return
+	}
+	message.Receivers = nil
+	go w.ForwardMessage(&message, message.Receiver)
+}
+
+func (w *Worker) expandMessage(message model.Message) {
+	for _, receiver := range message.Receivers {
+		userMessage := message
+		userMessage.Receiver = receiver
+		userMessage.Receivers = nil
+		go w.ForwardMessage(&userMessage, receiver)
+	}
+}
+
+func (w *Worker) KeepUsersConnection(conn *websocket.Conn, userID, sessionID string) error {
 	w.logger.Infof("[%v] Connected user %v successfully", userID)
 	defer conn.Close()
 	if err := w.AddNewUser(userID); err != nil {
@@ -191,7 +222,9 @@ func (w *Worker) KeepUsersConnection(conn *websocket.Conn, userID, authToken str
 	defer w.wg.Done()
 	userConnection := model.Connection{
 		WebsocketHandlerID: w.id,
+		SessionID:          sessionID,
 		WriteChannel:       make(chan model.Message, 100),
+		Revoked:            make(chan struct{}),
 		IsDeleted:          false,
 	}
 	w.mapUser.Set(userID, &userConnection)
@@ -224,9 +257,9 @@ func (w *Worker) KeepUsersConnection(conn *websocket.Conn, userID, authToken str
 		}
 	}(conn, w, userID, done)
 
-	// go w.ForwardUnreadMessage(conn, userID, authToken)
+	// go w.ForwardUnreadMessage(conn, userID)
 	go func() {
-		unreadMessages, err := w.GetUnreadMessage(userID, authToken)
+		unreadMessages, err := w.GetUnreadMessage(userID)
 		if err != nil {
 			w.logger.Errorf("[GetUnreadMessage] %v", err.Error())
 			return
@@ -263,6 +296,17 @@ func (w *Worker) KeepUsersConnection(conn *websocket.Conn, userID, authToken str
 			}
 		case <-done:
 			return nil
+		case <-connection.Revoked:
+			w.logger.Infof("[%v] Closing user %v connection because its session was revoked", userID, userID)
+			err := conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "session revoked"))
+			if err != nil {
+				return err
+			}
+			select {
+			case <-done:
+			case <-time.After(time.Second):
+			}
+			return nil
 		case <-w.done:
 			err := conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
 			if err != nil {
@@ -278,7 +322,22 @@ func (w *Worker) KeepUsersConnection(conn *websocket.Conn, userID, authToken str
 	}
 }
 
-func (w *Worker) ForwardUnreadMessage(conn *websocket.Conn, userID, authToken string) {
+// RevokeSessions closes the connection of the user if it was opened with one of
+// the revoked sessions.
+func (w *Worker) RevokeSessions(event session.Event) {
+	connection := w.mapUser.Get(event.UserID)
+	if connection == nil || connection.SessionID == "" {
+		return
+	}
+	for _, sessionID := range event.SessionIDs {
+		if sessionID == connection.SessionID {
+			connection.Revoke()
+			return
+		}
+	}
+}
+
+func (w *Worker) ForwardUnreadMessage(conn *websocket.Conn, userID string) {
 	w.wg.Add(1)
 	defer w.wg.Done()
 
@@ -291,7 +350,7 @@ func (w *Worker) ForwardUnreadMessage(conn *websocket.Conn, userID, authToken st
 		case <-w.done:
 			return
 		case <-timer.C:
-			unreadMessages, err := w.GetUnreadMessage(userID, authToken)
+			unreadMessages, err := w.GetUnreadMessage(userID)
 			if err != nil {
 				w.logger.Errorf("")
 				timer.Reset(w.fetchInterval)
@@ -308,6 +367,11 @@ func (w *Worker) ForwardUnreadMessage(conn *websocket.Conn, userID, authToken st
 	}
 }
 
+// handleMessageReadFromUser stores and forwards a message userID sent over
+// their connection. The sender is always the connected user, whatever the
+// client put in the frame, so the message service checks blocks and posting
+// rights against the right user, and the receivers the client listed are
+// dropped so the message only reaches the conversation.
 func (w *Worker) handleMessageReadFromUser(message *model.Message, userID string) {
 	w.concurrent <- struct{}{}
 	defer func() {
@@ -315,6 +379,8 @@ func (w *Worker) handleMessageReadFromUser(message *model.Message, userID string
 	}()
 	var err error
 
+	message.Sender = userID
+	message.Receivers = nil
 	message.ConversationMessageID, err = w.StoreMessage(message, userID)
 	if err != nil {
 		w.logger.Errorf("[handleMessageReadFromUser] Cannot store user %v's message: %v", userID, err.Error())
@@ -404,47 +470,26 @@ func (w *Worker) RemoveUser(userID string) error {
 }
 
 func (w *Worker) StoreMessage(message *model.Message, userID string) (int64, error) {
-	sendMessageRequest := model.SendMessageRequest{
-		ConversationID: message.ConversationID,
-		Sender:         message.Sender,
-		Content:        message.Content,
-		MessageTime:    message.MessageTime,
-		IV:             message.IV,
-	}
-	body := new(bytes.Buffer)
-	if err := json.NewEncoder(body).Encode(&sendMessageRequest); err != nil {
-		return int64(0), err
-	}
-
-	var (
-		result interface{}
-		err    error
-	)
-
-	for i := 1; i <= w.maxRetries; i++ {
-		result, err = request.HTTPRequestCall(
-			fmt.Sprintf("%s/message", w.messageServiceUrl),
-			http.MethodPost,
-			userID,
-			body,
-			5*time.Second,
-		)
-		if err != nil {
-			w.logger.Errorf("[StoreMessage] Error happen when send message to message service: %v", err.Error())
-			time.Sleep(w.retryInterval)
-			continue
-		}
-		break
-	}
-
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	response, err := w.messageClient.SendMessage(ctx, &messagepb.SendMessageRequest{
+		ConvId:  message.ConversationID,
+		Sender:  userID,
+		Content: message.Content,
+		Iv:      message.IV,
+		MsgTime: message.MessageTime,
+	})
 	if err != nil {
-		return int64(0), err
+		w.logger.Errorf("[StoreMessage] Error happen when send message to message service: %v", err.Error())
+		return int64(0), custom_error.HandleGRPCError(err)
 	}
-	conversationMessageID, _ := result.(float64)
-	return int64(conversationMessageID), nil
+
+	return response.ConvMsgId, nil
 }
-func (w *Worker) GetUnreadMessage(userID, authToken string) ([]model.Message, error) {
-	userInbox, err := w.GetUserInbox(userID, authToken)
+
+func (w *Worker) GetUnreadMessage(userID string) ([]model.Message, error) {
+	userInbox, err := w.GetUserInbox(userID)
 	if err != nil {
 		return nil, err
 	}
@@ -459,6 +504,7 @@ func (w *Worker) GetUnreadMessage(userID, authToken string) ([]model.Message, er
 			Content:               inbox.Content,
 			IV:                    inbox.IV,
 			Receiver:              userID,
+			Type:                  inbox.Type,
 		}
 		messages = append(messages, message)
 	}
@@ -473,7 +519,7 @@ func (w *Worker) GetUnreadMessage(userID, authToken string) ([]model.Message, er
 
 // 	var unreadMessages []model.Message
 // 	for _, conversation := range conversations {
-// 		readReceipt, rErr := w.GetReadReceipt(conversation, userID)
+// 		readReceipt, rErr := w.GetReadReceipt(conversation, userID, userID)
 // 		if rErr != nil {
 // 			w.logger.Errorf("")
 // 			continue
@@ -497,64 +543,30 @@ func (w *Worker) GetUnreadMessage(userID, authToken string) ([]model.Message, er
 // }
 
 func (w *Worker) GetUsersOfConversation(conversationID string) ([]string, error) {
-	var (
-		result interface{}
-		err    error
-	)
-	for i := 1; i <= w.maxRetries; i++ {
-		result, err = request.HTTPRequestCall(
-			fmt.Sprintf("%s/conversation/%s", w.groupServiceUrl, conversationID),
-			http.MethodGet,
-			"",
-			nil,
-			5*time.Second,
-		)
-		if err != nil {
-			w.logger.Errorf("")
-			time.Sleep(w.retryInterval)
-			continue
-		}
-		break
-	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
 
+	members, err := w.membershipCache.Get(ctx, conversationID)
 	if err != nil {
+		w.logger.Errorf("[GetUsersOfConversation] Cannot get users of conversation %v: %v", conversationID, err)
 		return nil, err
 	}
-	users, _ := result.([]string)
-	return users, nil
+
+	return members, nil
 }
 
 func (w *Worker) Register() error {
 	ipAddress := GetLocalIP()
-	registerRequest := model.AddNewWebsocketHandlerRequest{
-		ID:        w.id,
-		IPAddress: fmt.Sprintf("%s:%d", ipAddress, viper.GetInt("app.http_port")),
-	}
-	body := new(bytes.Buffer)
-	if err := json.NewEncoder(body).Encode(&registerRequest); err != nil {
-		w.logger.Errorf("[Register] Cannot register to Websocket Manager: %v", err)
-		return err
-	}
-
-	var err error
-	for i := 1; i <= w.maxRetries; i++ {
-		_, err = request.HTTPRequestCall(
-			fmt.Sprintf("%s/websocket_handler/register", w.websocketManagerUrl),
-			http.MethodPost,
-			"",
-			body,
-			w.pingInterval,
-		)
+	ctx, cancel := context.WithTimeout(context.Background(), w.pingInterval)
+	defer cancel()
 
-		if err != nil {
-			w.logger.Errorf("[Register] Cannot send register to Websocket Manager for %d times: %v", i, err)
-			time.Sleep(w.retryInterval)
-			continue
-		}
-		break
-	}
+	_, err := w.websocketManagerClient.Register(ctx, &websocketmanagerpb.RegisterRequest{
+		Id:        w.id,
+		IpAddress: fmt.Sprintf("%s:%d", ipAddress, viper.GetInt("app.http_port")),
+	})
 	if err != nil {
-		return err
+		w.logger.Errorf("[Register] Cannot send register to Websocket Manager: %v", err)
+		return custom_error.HandleGRPCError(err)
 	}
 
 	go w.HeartBeat()
@@ -569,24 +581,13 @@ func (w *Worker) HeartBeat() {
 	for {
 		select {
 		case <-timer.C:
-			pingRequest := model.PingRequest{
-				ID:        w.id,
-				IPAddress: string(GetLocalIP()),
-			}
-			body := new(bytes.Buffer)
-			if err := json.NewEncoder(body).Encode(&pingRequest); err != nil {
-				w.logger.Errorf("[Heartbeat] Cannot encode ping request: %v", err)
-				timer.Reset(w.pingInterval)
-				continue
-			}
-			_, err := request.HTTPRequestCall(
-				fmt.Sprintf("%s/websocket_handler/ping", w.websocketManagerUrl),
-				http.MethodPost,
-				"",
-				body,
-				w.pingInterval,
-			)
-			if errors.Is(err, custom_error.ErrNotFound) {
+			ctx, cancel := context.WithTimeout(context.Background(), w.pingInterval)
+			_, err := w.websocketManagerClient.Ping(ctx, &websocketmanagerpb.PingRequest{
+				Id:        w.id,
+				IpAddress: GetLocalIP().String(),
+			})
+			cancel()
+			if errors.Is(custom_error.HandleGRPCError(err), custom_error.ErrNotFound) {
 				w.logger.Errorf("[Heartbeat] Websocket Manager gets rid of our existence. Register again")
 				if err := w.Register(); err != nil {
 					w.logger.Errorf("[Heartbeat] Try to Register later because this Register again failed: %v", err)
@@ -663,196 +664,135 @@ func (w *Worker) ForwardMessage(message *model.Message, userID string) error {
 }
 
 func (w *Worker) GetWebsocketHandlerConnectUser(userID string) (*model.WebsocketHandlerClient, error) {
-	var (
-		result           interface{}
-		err              error
-		websocketHandler model.WebsocketHandlerClient
-	)
-	for i := 1; i <= w.maxRetries; i++ {
-		result, err = request.HTTPRequestCall(
-			fmt.Sprintf("%s/user/%s", w.websocketManagerUrl, userID),
-			http.MethodGet,
-			"",
-			nil,
-			5*time.Second,
-		)
-		if err != nil {
-			w.logger.Errorf("[GetWebsocketHandlerConnectUser] Getting Websocket Handler connecting user %v failed for %dth times: %v",
-				userID, i, err)
-			time.Sleep(w.retryInterval)
-			continue
-		}
-		break
-	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
 
+	result, err := w.websocketManagerClient.GetWebsocketHandlerOfUser(ctx, &websocketmanagerpb.GetWebsocketHandlerOfUserRequest{UserId: userID})
 	if err != nil {
-		return nil, err
+		w.logger.Errorf("[GetWebsocketHandlerConnectUser] Getting Websocket Handler connecting user %v failed: %v", userID, err)
+		return nil, custom_error.HandleGRPCError(err)
 	}
 
-	websocketHandlerJSON, _ := json.Marshal(result)
-	if err := json.Unmarshal(websocketHandlerJSON, &websocketHandler); err != nil {
-		w.logger.Errorf("[GetWebsocketHandlerConnectUser] Cannot unmarshal result from websocket manager: %v", err.Error())
-		return nil, err
+	websocketHandler := model.WebsocketHandlerClient{
+		ID:        result.Id,
+		IPAddress: result.IpAddress,
 	}
 	return &websocketHandler, nil
 }
 
 func (w *Worker) GetListConversations(userID string) ([]string, error) {
-	var (
-		result interface{}
-		err    error
-	)
-	for i := 1; i <= w.maxRetries; i++ {
-		result, err = request.HTTPRequestCall(
-			fmt.Sprintf("%s/conversation/user/%s", w.groupServiceUrl, userID),
-			http.MethodGet,
-			"",
-			nil,
-			30*time.Second,
-		)
-		if err != nil {
-			w.logger.Errorf("[GetListConversations] Get list conversations of user %v failed for %dth times: %v",
-				userID, i, err)
-			continue
-		}
-		break
-	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
 
+	response, err := w.groupClient.GetConversationsContainUser(ctx, &grouppb.GetConversationsContainUserRequest{UserId: userID})
 	if err != nil {
-		return nil, err
+		w.logger.Errorf("[GetListConversations] Get list conversations of user %v failed: %v", userID, err)
+		return nil, custom_error.HandleGRPCError(err)
 	}
 
-	conversations, _ := result.([]string)
+	conversations := make([]string, len(response.Conversations))
+	for i, conversation := range response.Conversations {
+		conversations[i] = conversation.ConvId
+	}
 	return conversations, nil
 }
 
-func (w *Worker) GetLastMessage(conversationID string) (*model.Message, error) {
-	var (
-		result interface{}
-		err    error
-	)
-	for i := 1; i <= w.maxRetries; i++ {
-		result, err = request.HTTPRequestCall(
-			fmt.Sprintf("%s/conversation/%s?limit=1", w.messageServiceUrl, conversationID),
-			http.MethodGet,
-			"",
-			nil,
-			30*time.Second,
-		)
-		if err != nil {
-			w.logger.Errorf("")
-			continue
-		}
-		break
-	}
-
+func (w *Worker) GetLastMessage(conversationID, userID string) (*model.Message, error) {
+	messages, err := w.getConversationMessages(conversationID, userID, 1, 0)
 	if err != nil {
 		return nil, err
 	}
 
-	message, _ := result.([]model.Message)
-	return &message[0], nil
-}
-
-func (w *Worker) GetReadReceipt(conversationID, userID string) (*model.ReadReceipt, error) {
-	var (
-		result interface{}
-		err    error
-	)
-
-	readReceiptRequest := model.ReadReceiptRequest{
-		ConversationID: conversationID,
-		UserID:         userID,
-	}
-	body := new(bytes.Buffer)
-	if err := json.NewEncoder(body).Encode(&readReceiptRequest); err != nil {
-		return nil, err
-	}
-
-	for i := 1; i <= w.maxRetries; i++ {
-		result, err = request.HTTPRequestCall(
-			fmt.Sprintf("%s/read_receipt", w.messageServiceUrl),
-			http.MethodPost,
-			"",
-			body,
-			30*time.Second,
-		)
-		if err != nil {
-			w.logger.Errorf("")
-			continue
-		}
-		break
+	if len(messages) == 0 {
+		return nil, custom_error.ErrNotFound
 	}
+	return &messages[0], nil
+}
 
+// GetReadReceipt returns the read receipt of userID as requesterID, the user
+// connected to this handler, may see it.
+func (w *Worker) GetReadReceipt(conversationID, userID, requesterID string) (*model.ReadReceipt, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	response, err := w.messageClient.GetReadReceipt(ctx, &messagepb.GetReadReceiptRequest{
+		ConvId:      conversationID,
+		UserId:      userID,
+		RequesterId: requesterID,
+	})
 	if err != nil {
-		return nil, err
+		w.logger.Errorf("[GetReadReceipt] Cannot get read receipt of user %v in conversation %v: %v", userID, conversationID, err)
+		return nil, custom_error.HandleGRPCError(err)
 	}
 
-	readReceipt, _ := result.(model.ReadReceipt)
+	readReceipt := model.ReadReceipt{
+		ConversationID: response.ConvId,
+		UserID:         response.UserId,
+		MessageID:      response.MsgId,
+	}
 	return &readReceipt, nil
 }
 
-func (w *Worker) GetMessages(conversationID string, lastMessageID int64) ([]model.Message, error) {
-	var (
-		result interface{}
-		err    error
-	)
-
-	for i := 1; i <= w.maxRetries; i++ {
-		result, err = request.HTTPRequestCall(
-			fmt.Sprintf("%s/conversation/%s?before_msg=%v", w.messageServiceUrl, conversationID, lastMessageID),
-			http.MethodGet,
-			"",
-			nil,
-			5*time.Second,
-		)
-		if err != nil {
-			w.logger.Errorf("")
-			continue
-		}
-		break
-	}
+func (w *Worker) GetMessages(conversationID, userID string, lastMessageID int64) ([]model.Message, error) {
+	return w.getConversationMessages(conversationID, userID, 0, lastMessageID)
+}
 
+func (w *Worker) getConversationMessages(conversationID, userID string, limit int32, beforeMsg int64) ([]model.Message, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	response, err := w.messageClient.GetConversationMessages(ctx, &messagepb.GetConversationMessagesRequest{
+		UserId:    userID,
+		ConvId:    conversationID,
+		Limit:     limit,
+		BeforeMsg: beforeMsg,
+	})
 	if err != nil {
-		return nil, err
+		w.logger.Errorf("[GetMessages] Cannot get messages of conversation %v: %v", conversationID, err)
+		return nil, custom_error.HandleGRPCError(err)
+	}
+
+	messages := make([]model.Message, len(response.Messages))
+	for i, message := range response.Messages {
+		messages[i] = model.Message{
+			ConversationID:        message.ConvId,
+			ConversationMessageID: message.ConvMsgId,
+			MessageTime:           message.MsgTime,
+			Sender:                message.Sender,
+			Content:               message.Content,
+			IV:                    message.Iv,
+			Receiver:              userID,
+			Type:                  message.Type,
+		}
 	}
-
-	messages, _ := result.([]model.Message)
 	return messages, nil
 }
 
-func (w *Worker) GetUserInbox(userID, authToken string) ([]*model.UserInbox, error) {
-	var (
-		result   interface{}
-		err      error
-		messages []*model.UserInbox
-	)
-
-	for i := 1; i <= w.maxRetries; i++ {
-		result, err = request.HTTPRequestCall(
-			fmt.Sprintf("%s/message/inbox/%s", w.messageServiceUrl, userID),
-			http.MethodGet,
-			authToken,
-			nil,
-			5*time.Second,
-		)
-		if err != nil {
-			w.logger.Errorf("[GetUserInbox] Cannot get user %v inbox: %v", userID, err)
-			continue
-		}
-		break
-	}
+func (w *Worker) GetUserInbox(userID string) ([]*model.UserInbox, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
 
+	response, err := w.messageClient.GetUserInbox(ctx, &messagepb.GetUserInboxRequest{UserId: userID})
 	if err != nil {
-		return nil, err
-	}
-
-	messagesJson, _ := json.Marshal(result)
-	if err := json.Unmarshal(messagesJson, &messages); err != nil {
-		w.logger.Errorf("[GetUserInbox] Cannot unmarshal user inboxes: %v", err.Error())
-		return nil, err
+		w.logger.Errorf("[GetUserInbox] Cannot get user %v inbox: %v", userID, err)
+		return nil, custom_error.HandleGRPCError(err)
+	}
+
+	userInboxes := make([]*model.UserInbox, len(response.Inboxes))
+	for i, inbox := range response.Inboxes {
+		userInboxes[i] = &model.UserInbox{
+			UserID:                inbox.UserId,
+			InboxMessageID:        inbox.InboxMsgId,
+			ConversationID:        inbox.ConvId,
+			ConversationMessageID: inbox.ConvMsgId,
+			MessageTime:           inbox.MsgTime,
+			Sender:                inbox.Sender,
+			Content:               inbox.Content,
+			IV:                    inbox.Iv,
+			Type:                  inbox.Type,
+		}
 	}
-	return messages, nil
+	return userInboxes, nil
 }
 
 func GetLocalIP() net.IP {
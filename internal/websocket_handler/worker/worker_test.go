@@ -0,0 +1,128 @@
+package worker
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"graduation-thesis/internal/websocket_handler/model"
+	"graduation-thesis/pkg/custom_error"
+	"graduation-thesis/pkg/pb/messagepb"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+// fakeMessageClient stands in for the message service, which refuses messages
+// whose sender was blocked by the other member.
+type fakeMessageClient struct {
+	messagepb.MessageServiceClient
+	blocked  map[string]bool
+	requests []*messagepb.SendMessageRequest
+}
+
+// GetReadReceipt hides the receipts of users who blocked the requester.
+func (f *fakeMessageClient) GetReadReceipt(ctx context.Context, in *messagepb.GetReadReceiptRequest, opts ...grpc.CallOption) (*messagepb.ReadReceipt, error) {
+	readReceipt := &messagepb.ReadReceipt{ConvId: in.ConvId, UserId: in.UserId}
+	if !f.blocked[in.RequesterId] {
+		readReceipt.MsgId = 7
+	}
+	return readReceipt, nil
+}
+
+func (f *fakeMessageClient) SendMessage(ctx context.Context, in *messagepb.SendMessageRequest, opts ...grpc.CallOption) (*messagepb.SendMessageResponse, error) {
+	f.requests = append(f.requests, in)
+	if f.blocked[in.Sender] {
+		return nil, custom_error.StatusToGRPCError(http.StatusUnauthorized, custom_error.ErrNoPermission.Error())
+	}
+	return &messagepb.SendMessageResponse{ConvMsgId: int64(len(f.requests))}, nil
+}
+
+func newTestWorker(messageClient messagepb.MessageServiceClient, users ...string) (*Worker, map[string]chan model.Message) {
+	w := NewWorker("handler-1", nil, "", nil, nil, messageClient, nil, time.Second, time.Second, 0, time.Second, time.Minute, zap.NewNop().Sugar())
+	connections := make(map[string]chan model.Message, len(users))
+	for _, user := range users {
+		connections[user] = make(chan model.Message, 10)
+		w.mapUser.Set(user, &model.Connection{WriteChannel: connections[user], Revoked: make(chan struct{})})
+	}
+	return w, connections
+}
+
+func TestSenderIsTheConnectedUser(t *testing.T) {
+	messageClient := &fakeMessageClient{blocked: map[string]bool{"mallory": true}}
+	w, connections := newTestWorker(messageClient, "alice", "bob", "mallory")
+
+	// Mallory was blocked by Bob and claims to be Alice.
+	w.handleMessageReadFromUser(&model.Message{ConversationID: "conv-1", Sender: "alice", Receiver: "bob", Content: "hi"}, "mallory")
+	if len(messageClient.requests) != 1 || messageClient.requests[0].Sender != "mallory" {
+		t.Fatalf("stored %+v, want it sent by mallory", messageClient.requests)
+	}
+	if len(connections["bob"]) != 0 || len(connections["alice"]) != 0 {
+		t.Fatal("a message of a blocked user was forwarded")
+	}
+
+	w.handleMessageReadFromUser(&model.Message{ConversationID: "conv-1", Sender: "mallory", Receiver: "bob", Content: "hi"}, "alice")
+	if len(connections["bob"]) != 1 {
+		t.Fatal("bob didn't get alice's message")
+	}
+	if message := <-connections["bob"]; message.Sender != "alice" || message.ConversationMessageID != 2 {
+		t.Fatalf("bob got %+v", message)
+	}
+}
+
+func receive(t *testing.T, connection chan model.Message) model.Message {
+	t.Helper()
+	select {
+	case message := <-connection:
+		return message
+	case <-time.After(time.Second):
+		t.Fatal("no message was forwarded")
+	}
+	return model.Message{}
+}
+
+func TestUserCannotListReceivers(t *testing.T) {
+	w, connections := newTestWorker(&fakeMessageClient{}, "alice", "bob", "carol")
+
+	w.handleMessageReadFromUser(&model.Message{ConversationID: "conv-1", Receiver: "bob", Receivers: []string{"carol"}, Content: "hi"}, "alice")
+	if message := receive(t, connections["bob"]); len(message.Receivers) != 0 {
+		t.Fatalf("bob got %+v, want no receivers", message)
+	}
+	if len(connections["carol"]) != 0 {
+		t.Fatal("a receiver listed by the client got the message")
+	}
+}
+
+func TestOnlyGroupMessageHandlerExpandsReceivers(t *testing.T) {
+	w, connections := newTestWorker(&fakeMessageClient{}, "alice", "bob", "carol")
+
+	// A peer only passes on what one of its users sent.
+	w.handleMessageReadFromPeer(model.Message{ConversationID: "conv-1", Sender: "alice", Receiver: "bob", Receivers: []string{"carol"}}, "handler-2")
+	if message := receive(t, connections["bob"]); len(message.Receivers) != 0 {
+		t.Fatalf("bob got %+v, want no receivers", message)
+	}
+	if len(connections["carol"]) != 0 {
+		t.Fatal("a frame of a peer was expanded")
+	}
+
+	w.handleMessageReadFromPeer(model.Message{ConversationID: "group-1", Sender: "alice", Receivers: []string{"bob", "carol"}}, GroupMessageHandlerID)
+	for _, user := range []string{"bob", "carol"} {
+		if message := receive(t, connections[user]); message.Receiver != user || len(message.Receivers) != 0 {
+			t.Fatalf("%s got %+v", user, message)
+		}
+	}
+}
+
+func TestReadReceiptIsReadAsTheConnectedUser(t *testing.T) {
+	w, _ := newTestWorker(&fakeMessageClient{blocked: map[string]bool{"mallory": true}})
+
+	readReceipt, err := w.GetReadReceipt("conv-1", "bob", "mallory")
+	if err != nil || readReceipt.MessageID != 0 {
+		t.Fatalf("mallory read %+v, %v, want nothing read", readReceipt, err)
+	}
+	readReceipt, err = w.GetReadReceipt("conv-1", "bob", "alice")
+	if err != nil || readReceipt.MessageID != 7 {
+		t.Fatalf("alice read %+v, %v", readReceipt, err)
+	}
+}
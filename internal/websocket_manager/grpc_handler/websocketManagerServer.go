@@ -0,0 +1,99 @@
+package grpc_handler
+
+import (
+	"context"
+
+	"graduation-thesis/internal/websocket_manager/model"
+	"graduation-thesis/internal/websocket_manager/service"
+	"graduation-thesis/pkg/custom_error"
+	"graduation-thesis/pkg/pb/websocketmanagerpb"
+)
+
+type WebsocketManagerServer struct {
+	websocketmanagerpb.UnimplementedWebsocketManagerServiceServer
+	userService             *service.UserService
+	websocketManagerService *service.WebsocketManagerService
+}
+
+func NewWebsocketManagerServer(userService *service.UserService, websocketManagerService *service.WebsocketManagerService) *WebsocketManagerServer {
+	return &WebsocketManagerServer{
+		userService:             userService,
+		websocketManagerService: websocketManagerService,
+	}
+}
+
+func (w *WebsocketManagerServer) GetWebsocketHandlerOfUser(ctx context.Context, request *websocketmanagerpb.GetWebsocketHandlerOfUserRequest) (*websocketmanagerpb.WebsocketHandler, error) {
+	successResponse, errorResponse := w.userService.GetWebsocketHandler(ctx, request.UserId)
+	if errorResponse != nil {
+		return nil, custom_error.StatusToGRPCError(errorResponse.Status, errorResponse.ErrorMessage)
+	}
+
+	websocketHandler := successResponse.Result.(*model.WebsocketHandlerClient)
+	return &websocketmanagerpb.WebsocketHandler{
+		Id:        websocketHandler.ID,
+		IpAddress: websocketHandler.IPAddress,
+	}, nil
+}
+
+func (w *WebsocketManagerServer) GetWebsocketHandlersOfUsers(ctx context.Context, request *websocketmanagerpb.GetWebsocketHandlersOfUsersRequest) (*websocketmanagerpb.GetWebsocketHandlersOfUsersResponse, error) {
+	successResponse, errorResponse := w.userService.GetWebsocketHandlers(ctx, request.UserIds)
+	if errorResponse != nil {
+		return nil, custom_error.StatusToGRPCError(errorResponse.Status, errorResponse.ErrorMessage)
+	}
+
+	websocketHandlers := successResponse.Result.(map[string]model.WebsocketHandlerClient)
+	response := &websocketmanagerpb.GetWebsocketHandlersOfUsersResponse{}
+	recipientsByHandler := make(map[string]*websocketmanagerpb.Recipients)
+	for _, userID := range request.UserIds {
+		websocketHandler := websocketHandlers[userID]
+		if websocketHandler.ID == "" {
+			response.OfflineUserIds = append(response.OfflineUserIds, userID)
+			continue
+		}
+
+		recipients, ok := recipientsByHandler[websocketHandler.ID]
+		if !ok {
+			recipients = &websocketmanagerpb.Recipients{
+				WebsocketHandler: &websocketmanagerpb.WebsocketHandler{
+					Id:        websocketHandler.ID,
+					IpAddress: websocketHandler.IPAddress,
+				},
+			}
+			recipientsByHandler[websocketHandler.ID] = recipients
+			response.Recipients = append(response.Recipients, recipients)
+		}
+		recipients.UserIds = append(recipients.UserIds, userID)
+	}
+
+	return response, nil
+}
+
+func (w *WebsocketManagerServer) Register(ctx context.Context, request *websocketmanagerpb.RegisterRequest) (*websocketmanagerpb.WebsocketHandler, error) {
+	addNewWebsocketHandlerRequest := model.AddNewWebsocketHandlerRequest{
+		ID:        request.Id,
+		IPAddress: request.IpAddress,
+	}
+	successResponse, errorResponse := w.websocketManagerService.AddNewWebsocketHandler(ctx, &addNewWebsocketHandlerRequest)
+	if errorResponse != nil {
+		return nil, custom_error.StatusToGRPCError(errorResponse.Status, errorResponse.ErrorMessage)
+	}
+
+	websocketHandler := successResponse.Result.(model.WebsocketHandlerClient)
+	return &websocketmanagerpb.WebsocketHandler{
+		Id:        websocketHandler.ID,
+		IpAddress: websocketHandler.IPAddress,
+	}, nil
+}
+
+func (w *WebsocketManagerServer) Ping(ctx context.Context, request *websocketmanagerpb.PingRequest) (*websocketmanagerpb.PingResponse, error) {
+	pingRequest := model.PingRequest{
+		ID:        request.Id,
+		IPAddress: request.IpAddress,
+	}
+	_, errorResponse := w.websocketManagerService.Pong(ctx, &pingRequest)
+	if errorResponse != nil {
+		return nil, custom_error.StatusToGRPCError(errorResponse.Status, errorResponse.ErrorMessage)
+	}
+
+	return &websocketmanagerpb.PingResponse{}, nil
+}
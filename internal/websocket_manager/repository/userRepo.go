@@ -37,3 +37,24 @@ func (u *UserRepo) Del(ctx context.Context, usersID []string) error {
 	err := u.redis.Del(ctx, usersID...).Err()
 	return custom_error.HandleRedisError(err)
 }
+
+func (u *UserRepo) GetMany(ctx context.Context, usersID []string) (map[string]model.WebsocketHandlerClient, error) {
+	pipe := u.redis.Pipeline()
+	cmds := make([]*redis.MapStringStringCmd, len(usersID))
+	for i, userID := range usersID {
+		cmds[i] = pipe.HGetAll(ctx, userID)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, custom_error.HandleRedisError(err)
+	}
+
+	result := make(map[string]model.WebsocketHandlerClient, len(usersID))
+	for i, cmd := range cmds {
+		var websocketHandlerClient model.WebsocketHandlerClient
+		if err := cmd.Scan(&websocketHandlerClient); err != nil {
+			return nil, custom_error.HandleRedisError(err)
+		}
+		result[usersID[i]] = websocketHandlerClient
+	}
+	return result, nil
+}
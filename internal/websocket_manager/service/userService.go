@@ -35,3 +35,20 @@ func (u *UserService) GetWebsocketHandler(ctx context.Context, userID string) (*
 	}
 	return &successResponse, nil
 }
+
+func (u *UserService) GetWebsocketHandlers(ctx context.Context, usersID []string) (*responseModel.SuccessResponse, *responseModel.ErrorResponse) {
+	websocketHandlers, err := u.userRepo.GetMany(ctx, usersID)
+	if err != nil {
+		errorResponse := responseModel.ErrorResponse{
+			Status:       u.errorMap[err],
+			ErrorMessage: err.Error(),
+		}
+		return nil, &errorResponse
+	}
+
+	successResponse := responseModel.SuccessResponse{
+		Status: http.StatusOK,
+		Result: websocketHandlers,
+	}
+	return &successResponse, nil
+}
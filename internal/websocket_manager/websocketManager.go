@@ -2,13 +2,17 @@ package websocket_manager
 
 import (
 	"fmt"
+	"graduation-thesis/internal/websocket_manager/grpc_handler"
 	"graduation-thesis/internal/websocket_manager/http_handler"
 	"graduation-thesis/internal/websocket_manager/message_consumer"
 	"graduation-thesis/internal/websocket_manager/repository"
 	"graduation-thesis/internal/websocket_manager/service"
 	"graduation-thesis/pkg/custom_error"
 	"graduation-thesis/pkg/logger"
+	"graduation-thesis/pkg/pb/websocketmanagerpb"
+	"graduation-thesis/pkg/rpc"
 	"graduation-thesis/pkg/storage"
+	"net"
 	"net/http"
 	"sync"
 	"time"
@@ -69,6 +73,9 @@ func Run() {
 	)
 	router := http_handler.GetRouter(userHandler, websocketManagerHandler)
 
+	grpcSrv := rpc.NewServer()
+	websocketmanagerpb.RegisterWebsocketManagerServiceServer(grpcSrv, grpc_handler.NewWebsocketManagerServer(userService, websocketManagerService))
+
 	websocketManagerService.MonitorWebsocketHandler()
 
 	srv := http.Server{
@@ -80,7 +87,7 @@ func Run() {
 	}
 
 	var wg sync.WaitGroup
-	wg.Add(2)
+	wg.Add(3)
 
 	go func(wg *sync.WaitGroup) {
 		defer wg.Done()
@@ -96,6 +103,16 @@ func Run() {
 			panic(err)
 		}
 	}(&wg)
+	go func(wg *sync.WaitGroup) {
+		defer wg.Done()
+		lis, err := net.Listen("tcp", fmt.Sprintf(":%d", viper.GetInt("app.grpc_port")))
+		if err != nil {
+			panic(err)
+		}
+		if err := grpcSrv.Serve(lis); err != nil {
+			panic(err)
+		}
+	}(&wg)
 
 	wg.Wait()
 	logger.Info("[MAIN] Shutting down Websocket Manager")
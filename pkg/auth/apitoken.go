@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"graduation-thesis/pkg/custom_error"
+	"graduation-thesis/pkg/pb/authenticatorpb"
+)
+
+const (
+	// APITokenPrefix starts every API token, which tells them apart from
+	// access tokens and makes leaked ones easy to search for.
+	APITokenPrefix = "gtk_"
+	// APITokenCacheTTL is how long a checked API token is trusted without
+	// asking the authenticator again, so revoking a token takes this long to
+	// reach every service.
+	APITokenCacheTTL = 30 * time.Second
+	apiTokenTimeout  = 5 * time.Second
+)
+
+// ErrAPITokensUnavailable is returned when API tokens can't be checked
+// because the authenticator can't be reached.
+var ErrAPITokensUnavailable = errors.New("api tokens unavailable")
+
+func IsAPIToken(token string) bool {
+	return strings.HasPrefix(token, APITokenPrefix)
+}
+
+type cachedAPIToken struct {
+	claims    *Claims
+	expiresAt time.Time
+}
+
+// APITokenVerifier checks API tokens with the AuthenticateAPIToken rpc of the
+// authenticator, which is the only one knowing whether they were revoked.
+// Valid tokens are cached for APITokenCacheTTL.
+type APITokenVerifier struct {
+	authenticatorClient authenticatorpb.AuthenticatorServiceClient
+
+	mu    sync.Mutex
+	cache map[string]cachedAPIToken
+}
+
+func NewAPITokenVerifier(authenticatorClient authenticatorpb.AuthenticatorServiceClient) *APITokenVerifier {
+	return &APITokenVerifier{
+		authenticatorClient: authenticatorClient,
+		cache:               make(map[string]cachedAPIToken),
+	}
+}
+
+// Verify returns the claims of token. Invalid tokens are reported as
+// custom_error.ErrNoPermission.
+func (v *APITokenVerifier) Verify(ctx context.Context, token string) (*Claims, error) {
+	if !IsAPIToken(token) {
+		return nil, fmt.Errorf("%w: not an api token", custom_error.ErrNoPermission)
+	}
+
+	// Only the hash of the token is kept in memory.
+	sum := sha256.Sum256([]byte(token))
+	key := hex.EncodeToString(sum[:])
+	now := time.Now()
+	v.mu.Lock()
+	cached, ok := v.cache[key]
+	v.mu.Unlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached.claims, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, apiTokenTimeout)
+	defer cancel()
+	response, err := v.authenticatorClient.AuthenticateAPIToken(ctx, &authenticatorpb.AuthenticateAPITokenRequest{Token: token})
+	if err != nil {
+		err = custom_error.HandleGRPCError(err)
+		if errors.Is(err, custom_error.ErrNoPermission) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %v", ErrAPITokensUnavailable, err)
+	}
+
+	claims := &Claims{
+		UserID:     response.AccountId,
+		AccessUuid: response.TokenId,
+		Kind:       response.Kind,
+		Scopes:     response.Scopes,
+	}
+	expiresAt := now.Add(APITokenCacheTTL)
+	if response.ExpiresAt != 0 && time.Unix(response.ExpiresAt, 0).Before(expiresAt) {
+		expiresAt = time.Unix(response.ExpiresAt, 0)
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for k, cached := range v.cache {
+		if !now.Before(cached.expiresAt) {
+			delete(v.cache, k)
+		}
+	}
+	v.cache[key] = cachedAPIToken{claims: claims, expiresAt: expiresAt}
+	return claims, nil
+}
@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"graduation-thesis/pkg/custom_error"
+	"graduation-thesis/pkg/pb/authenticatorpb"
+
+	"google.golang.org/grpc"
+)
+
+type fakeAuthenticatorClient struct {
+	authenticatorpb.AuthenticatorServiceClient
+	calls    int
+	response *authenticatorpb.AuthenticateAPITokenResponse
+	err      error
+}
+
+func (f *fakeAuthenticatorClient) AuthenticateAPIToken(ctx context.Context, in *authenticatorpb.AuthenticateAPITokenRequest, opts ...grpc.CallOption) (*authenticatorpb.AuthenticateAPITokenResponse, error) {
+	f.calls++
+	return f.response, f.err
+}
+
+func TestAPITokenVerifier(t *testing.T) {
+	client := &fakeAuthenticatorClient{response: &authenticatorpb.AuthenticateAPITokenResponse{
+		AccountId: "bot-1",
+		TokenId:   "token-1",
+		Kind:      KindBot,
+		Scopes:    []string{ScopeMessagesSend},
+	}}
+	verifier := NewAPITokenVerifier(client)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		claims, err := verifier.Verify(ctx, APITokenPrefix+"secret")
+		if err != nil {
+			t.Fatalf("Verify: %v", err)
+		}
+		if claims.UserID != "bot-1" || !claims.HasScopes(ScopeMessagesSend) || claims.HasScopes(ScopeMessagesRead) {
+			t.Fatalf("claims = %+v", claims)
+		}
+	}
+	if client.calls != 1 {
+		t.Fatalf("asked the authenticator %d times, want the token cached", client.calls)
+	}
+
+	// Tokens that expire sooner are cached until they do.
+	client.response.ExpiresAt = time.Now().Add(-time.Second).Unix()
+	verifier.Verify(ctx, APITokenPrefix+"expiring")
+	verifier.Verify(ctx, APITokenPrefix+"expiring")
+	if client.calls != 3 {
+		t.Fatalf("asked the authenticator %d times, want an expired token checked again", client.calls)
+	}
+
+	if _, err := verifier.Verify(ctx, "eyJhbGciOi.not.an.api.token"); !errors.Is(err, custom_error.ErrNoPermission) {
+		t.Fatalf("Verify of an access token = %v", err)
+	}
+
+	client.err = custom_error.StatusToGRPCError(http.StatusUnauthorized, "revoked")
+	if _, err := verifier.Verify(ctx, APITokenPrefix+"revoked"); !errors.Is(err, custom_error.ErrNoPermission) {
+		t.Fatalf("Verify of a revoked token = %v", err)
+	}
+	client.err = custom_error.StatusToGRPCError(http.StatusServiceUnavailable, "down")
+	if _, err := verifier.Verify(ctx, APITokenPrefix+"other"); !errors.Is(err, ErrAPITokensUnavailable) {
+		t.Fatalf("Verify while the authenticator is down = %v", err)
+	}
+}
+
+func TestHasScopes(t *testing.T) {
+	user := &Claims{UserID: "user-1", Kind: KindUser}
+	if !user.HasScopes(ScopeGroupsWrite, ScopeMessagesSend) {
+		t.Fatal("users may do anything")
+	}
+	if (&Claims{UserID: "bot-1", Scopes: []string{ScopeGroupsWrite}}).HasScopes(ScopeGroupsWrite) {
+		t.Fatal("claims of no known kind have scopes")
+	}
+	service := &Claims{UserID: "service-1", Kind: KindService, Scopes: []string{ScopeGroupsRead}}
+	if !service.HasScopes(ScopeGroupsRead) || service.HasScopes(ScopeGroupsWrite) {
+		t.Fatalf("scopes of %+v", service)
+	}
+}
@@ -0,0 +1,205 @@
+// Package authtest is the conformance suite every way of reaching the token
+// subsystem has to pass, so the library, the rpc and the HTTP API can't drift
+// apart the way the old user service tokens did.
+package authtest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"graduation-thesis/pkg/auth"
+	"graduation-thesis/pkg/custom_error"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+type Tokens struct {
+	AccessToken  string
+	RefreshToken string
+}
+
+// Subject is the token subsystem under test. Refused tokens and credentials
+// must be reported as errors wrapping custom_error.ErrNoPermission.
+type Subject interface {
+	Login(ctx context.Context, username, password string) (*Tokens, error)
+	Refresh(ctx context.Context, refreshToken string) (*Tokens, error)
+	Logout(ctx context.Context, accessToken string) error
+	// Validate checks the token with the authenticator, revocations included.
+	Validate(ctx context.Context, accessToken string) (*auth.Claims, error)
+}
+
+// Harness is a fresh Subject with one account, and the verifier other
+// services would check its access tokens with.
+type Harness struct {
+	Subject  Subject
+	Username string
+	Password string
+	UserID   string
+	Verifier *auth.Verifier
+	// AccessTTL is at_expires, the longest the verifier may go on accepting
+	// an access token of a revoked session.
+	AccessTTL time.Duration
+}
+
+// Run runs the suite, calling setup for a fresh harness in every test.
+func Run(t *testing.T, setup func(t *testing.T) Harness) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, h Harness)
+	}{
+		{"LoginIssuesValidTokens", testLoginIssuesValidTokens},
+		{"LoginRefusesBadCredentials", testLoginRefusesBadCredentials},
+		{"RefreshRotatesTokens", testRefreshRotatesTokens},
+		{"RefreshReuseRevokesSession", testRefreshReuseRevokesSession},
+		{"LogoutRevokesSession", testLogoutRevokesSession},
+		{"RefusesMalformedTokens", testRefusesMalformedTokens},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			test.run(t, setup(t))
+		})
+	}
+}
+
+func login(t *testing.T, h Harness) *Tokens {
+	t.Helper()
+	tokens, err := h.Subject.Login(context.Background(), h.Username, h.Password)
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if tokens.AccessToken == "" || tokens.RefreshToken == "" {
+		t.Fatalf("Login issued %+v", tokens)
+	}
+	return tokens
+}
+
+// valid checks that both the authenticator and the verifier accept the token
+// as the harness user's.
+func valid(t *testing.T, h Harness, accessToken string) *auth.Claims {
+	t.Helper()
+	claims, err := h.Subject.Validate(context.Background(), accessToken)
+	if err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if claims.UserID != h.UserID {
+		t.Fatalf("Validate user = %q, want %q", claims.UserID, h.UserID)
+	}
+
+	verified, err := h.Verifier.Verify(context.Background(), accessToken)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if verified.UserID != claims.UserID {
+		t.Fatalf("Verify user = %q, Validate user = %q", verified.UserID, claims.UserID)
+	}
+	if claims.SessionID != "" && verified.SessionID != claims.SessionID {
+		t.Fatalf("Verify session = %q, Validate session = %q", verified.SessionID, claims.SessionID)
+	}
+	return claims
+}
+
+func refused(t *testing.T, what string, err error) {
+	t.Helper()
+	if !errors.Is(err, custom_error.ErrNoPermission) {
+		t.Fatalf("%s = %v, want %v", what, err, custom_error.ErrNoPermission)
+	}
+}
+
+func testLoginIssuesValidTokens(t *testing.T, h Harness) {
+	tokens := login(t, h)
+	valid(t, h, tokens.AccessToken)
+}
+
+func testLoginRefusesBadCredentials(t *testing.T, h Harness) {
+	ctx := context.Background()
+	_, err := h.Subject.Login(ctx, h.Username, h.Password+"-wrong")
+	refused(t, "Login with a wrong password", err)
+	// Unknown users fail the same way so they can't be told apart.
+	_, err = h.Subject.Login(ctx, h.Username+"-unknown", h.Password)
+	refused(t, "Login of an unknown user", err)
+}
+
+func testRefreshRotatesTokens(t *testing.T, h Harness) {
+	tokens := login(t, h)
+	before := valid(t, h, tokens.AccessToken)
+
+	refreshed, err := h.Subject.Refresh(context.Background(), tokens.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if refreshed.AccessToken == tokens.AccessToken || refreshed.RefreshToken == tokens.RefreshToken {
+		t.Fatal("Refresh did not rotate the tokens")
+	}
+	after := valid(t, h, refreshed.AccessToken)
+	if before.SessionID != after.SessionID {
+		t.Fatalf("Refresh moved the session from %q to %q", before.SessionID, after.SessionID)
+	}
+}
+
+func testRefreshReuseRevokesSession(t *testing.T, h Harness) {
+	ctx := context.Background()
+	tokens := login(t, h)
+	refreshed, err := h.Subject.Refresh(ctx, tokens.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+
+	// A refresh token presented twice has leaked, the session goes with it.
+	_, err = h.Subject.Refresh(ctx, tokens.RefreshToken)
+	refused(t, "Refresh with a used token", err)
+	_, err = h.Subject.Refresh(ctx, refreshed.RefreshToken)
+	refused(t, "Refresh in a revoked session", err)
+	_, err = h.Subject.Validate(ctx, refreshed.AccessToken)
+	refused(t, "Validate in a revoked session", err)
+}
+
+func testLogoutRevokesSession(t *testing.T, h Harness) {
+	ctx := context.Background()
+	tokens := login(t, h)
+	other := login(t, h)
+
+	if err := h.Subject.Logout(ctx, tokens.AccessToken); err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+	_, err := h.Subject.Validate(ctx, tokens.AccessToken)
+	refused(t, "Validate after logout", err)
+	_, err = h.Subject.Refresh(ctx, tokens.RefreshToken)
+	refused(t, "Refresh after logout", err)
+
+	// Services verifying tokens locally don't hear of the logout, and accept
+	// the access token until it expires.
+	if _, err := h.Verifier.Verify(ctx, tokens.AccessToken); err != nil {
+		t.Fatalf("Verify after logout: %v", err)
+	}
+	expiresWithin(t, tokens.AccessToken, h.AccessTTL)
+
+	// Only the session logged out of ends.
+	valid(t, h, other.AccessToken)
+}
+
+func expiresWithin(t *testing.T, accessToken string, ttl time.Duration) {
+	t.Helper()
+	claims := jwt.MapClaims{}
+	if _, _, err := new(jwt.Parser).ParseUnverified(accessToken, claims); err != nil {
+		t.Fatalf("parse access token: %v", err)
+	}
+	expiresAt, ok := claims["exp"].(float64)
+	if !ok || int64(expiresAt) > time.Now().Add(ttl).Unix() {
+		t.Fatalf("access token expires at %v, want within %v", claims["exp"], ttl)
+	}
+}
+
+func testRefusesMalformedTokens(t *testing.T, h Harness) {
+	ctx := context.Background()
+	for _, token := range []string{"", "not-a-token", "a.b.c"} {
+		_, err := h.Subject.Validate(ctx, token)
+		refused(t, "Validate("+token+")", err)
+		_, err = h.Verifier.Verify(ctx, token)
+		refused(t, "Verify("+token+")", err)
+		_, err = h.Subject.Refresh(ctx, token)
+		refused(t, "Refresh("+token+")", err)
+	}
+}
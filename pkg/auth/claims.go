@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"graduation-thesis/pkg/custom_error"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// Claims is what a valid access token says about its bearer. The
+// authenticator signs them with MapClaims and every verifier reads them back
+// with ParseClaims, so both sides agree on the claim names. API tokens of
+// service accounts and bots are read into Claims as well, with the account as
+// UserID and the token id as AccessUuid.
+type Claims struct {
+	UserID     string
+	AccessUuid string
+	SessionID  string
+	Kind       string
+	// Scopes limits what an API token may do. Users may do anything.
+	Scopes []string
+}
+
+// HasScopes reports whether the bearer may do what every one of scopes allows.
+func (c *Claims) HasScopes(scopes ...string) bool {
+	if c.Kind == KindUser {
+		return true
+	}
+	if c.Kind != KindService && c.Kind != KindBot {
+		return false
+	}
+	for _, scope := range scopes {
+		if !contains(c.Scopes, scope) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *Claims) MapClaims(expiresAt int64) jwt.MapClaims {
+	return jwt.MapClaims{
+		"authorized":  true,
+		"user_id":     c.UserID,
+		"access_uuid": c.AccessUuid,
+		"session_id":  c.SessionID,
+		"exp":         expiresAt,
+	}
+}
+
+// ParseClaims reads the claims of a verified access token. Tokens issued
+// before sessions were recorded carry no session id.
+func ParseClaims(mapClaims jwt.MapClaims) (*Claims, error) {
+	userID, _ := mapClaims["user_id"].(string)
+	accessUuid, _ := mapClaims["access_uuid"].(string)
+	if userID == "" || accessUuid == "" {
+		return nil, fmt.Errorf("%w: missing claims", custom_error.ErrNoPermission)
+	}
+	sessionID, _ := mapClaims["session_id"].(string)
+
+	return &Claims{
+		UserID:     userID,
+		AccessUuid: accessUuid,
+		SessionID:  sessionID,
+		Kind:       KindUser,
+	}, nil
+}
+
+// ExtractToken returns the bearer token of the Authorization header.
+func ExtractToken(r *http.Request) string {
+	bearToken := r.Header.Get("Authorization")
+	strArr := strings.Split(bearToken, " ")
+	if len(strArr) == 2 {
+		return strArr[1]
+	}
+
+	return ""
+}
@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"graduation-thesis/pkg/custom_error"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+func TestClaimsRoundTrip(t *testing.T) {
+	claims := &Claims{UserID: "user-1", AccessUuid: "access-1", SessionID: "session-1", Kind: KindUser}
+	parsed, err := ParseClaims(claims.MapClaims(1700000000))
+	if err != nil {
+		t.Fatalf("ParseClaims: %v", err)
+	}
+	if !reflect.DeepEqual(parsed, claims) {
+		t.Fatalf("ParseClaims = %+v, want %+v", parsed, claims)
+	}
+
+	// Tokens from before sessions were recorded are still accepted.
+	parsed, err = ParseClaims(jwt.MapClaims{"user_id": "user-1", "access_uuid": "access-1"})
+	if err != nil || parsed.SessionID != "" {
+		t.Fatalf("ParseClaims without a session = %+v, %v", parsed, err)
+	}
+
+	for _, mapClaims := range []jwt.MapClaims{
+		{"access_uuid": "access-1"},
+		{"user_id": "user-1"},
+		{"user_id": 1, "access_uuid": "access-1"},
+	} {
+		if _, err := ParseClaims(mapClaims); !errors.Is(err, custom_error.ErrNoPermission) {
+			t.Fatalf("ParseClaims(%v) = %v", mapClaims, err)
+		}
+	}
+}
+
+func TestExtractToken(t *testing.T) {
+	for header, want := range map[string]string{
+		"Bearer abc": "abc",
+		"":           "",
+		"abc":        "",
+		"Bearer a b": "",
+	} {
+		r, _ := http.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Authorization", header)
+		if got := ExtractToken(r); got != want {
+			t.Fatalf("ExtractToken(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
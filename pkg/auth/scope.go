@@ -0,0 +1,41 @@
+package auth
+
+// Kinds of bearers. Service accounts are for internal services and bots for
+// third-party integrations; both authenticate with API tokens.
+const (
+	KindUser    = "user"
+	KindService = "service"
+	KindBot     = "bot"
+)
+
+// Scopes an API token may be granted.
+const (
+	ScopeMessagesRead       = "messages:read"
+	ScopeMessagesSend       = "messages:send"
+	ScopeConversationsRead  = "conversations:read"
+	ScopeConversationsWrite = "conversations:write"
+	ScopeGroupsRead         = "groups:read"
+	ScopeGroupsWrite        = "groups:write"
+)
+
+var scopes = []string{
+	ScopeMessagesRead,
+	ScopeMessagesSend,
+	ScopeConversationsRead,
+	ScopeConversationsWrite,
+	ScopeGroupsRead,
+	ScopeGroupsWrite,
+}
+
+func IsScope(scope string) bool {
+	return contains(scopes, scope)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"graduation-thesis/pkg/custom_error"
+	"graduation-thesis/pkg/jwks"
+	"graduation-thesis/pkg/pb/authenticatorpb"
+)
+
+const (
+	// KeySetTTL is how long a fetched key set is used; the authenticator
+	// publishes new keys long before they sign.
+	KeySetTTL = 5 * time.Minute
+	// KeySetMinRefresh bounds how often a token with an unknown kid makes the
+	// key set be fetched again.
+	KeySetMinRefresh = 30 * time.Second
+	keySetTimeout    = 5 * time.Second
+)
+
+// KeySetFetcher fetches the published keys over the GetKeySet rpc of the
+// authenticator. jwks.HTTPFetcher fetches them from /.well-known/jwks.json.
+func KeySetFetcher(authenticatorClient authenticatorpb.AuthenticatorServiceClient) jwks.Fetcher {
+	return func(ctx context.Context) (*jwks.Set, error) {
+		ctx, cancel := context.WithTimeout(ctx, keySetTimeout)
+		defer cancel()
+
+		response, err := authenticatorClient.GetKeySet(ctx, &authenticatorpb.GetKeySetRequest{})
+		if err != nil {
+			return nil, custom_error.HandleGRPCError(err)
+		}
+		var keySet jwks.Set
+		if err := json.Unmarshal(response.Jwks, &keySet); err != nil {
+			return nil, err
+		}
+		return &keySet, nil
+	}
+}
+
+// Verifier checks access tokens locally against the keys the authenticator
+// publishes, cached for KeySetTTL. It does not know about revoked sessions;
+// only the authenticator does, through /v1/validate or the ValidateToken rpc.
+// A token of a revoked session is accepted until it expires, at most
+// at_expires after it was issued.
+type Verifier struct {
+	keys *jwks.Verifier
+}
+
+func NewVerifier(fetch jwks.Fetcher) *Verifier {
+	return &Verifier{
+		keys: jwks.NewVerifier(fetch, KeySetTTL, KeySetMinRefresh),
+	}
+}
+
+// Verify returns the claims of tokenString. Invalid tokens are reported as
+// custom_error.ErrNoPermission; jwks.ErrKeySetUnavailable is passed on so
+// callers can tell an outage from a bad token.
+func (v *Verifier) Verify(ctx context.Context, tokenString string) (*Claims, error) {
+	mapClaims, err := v.keys.Parse(ctx, tokenString)
+	if errors.Is(err, jwks.ErrKeySetUnavailable) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", custom_error.ErrNoPermission, err)
+	}
+
+	return ParseClaims(mapClaims)
+}
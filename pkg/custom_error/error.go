@@ -79,6 +79,8 @@ var (
 	ErrEntityTooLarge      = errors.New("entity too large")
 	ErrConflict            = errors.New("data conflict")
 	ErrChannelHasClosed    = errors.New("channel has closed")
+	ErrGone                = errors.New("no longer available")
+	ErrTooManyRequests     = errors.New("too many requests")
 	ErrUnknown             = errors.New("unknown error")
 )
 
@@ -92,6 +94,8 @@ func MappingError() map[error]int {
 	result[ErrInternalServerError] = http.StatusInternalServerError
 	result[ErrConflict] = http.StatusConflict
 	result[ErrEntityTooLarge] = http.StatusRequestEntityTooLarge
+	result[ErrGone] = http.StatusGone
+	result[ErrTooManyRequests] = http.StatusTooManyRequests
 	result[ErrUnknown] = http.StatusInternalServerError
 	return result
 }
@@ -106,5 +110,7 @@ func MappingStatusError() map[int]error {
 	result[http.StatusInternalServerError] = ErrInternalServerError
 	result[http.StatusConflict] = ErrConflict
 	result[http.StatusRequestEntityTooLarge] = ErrEntityTooLarge
+	result[http.StatusGone] = ErrGone
+	result[http.StatusTooManyRequests] = ErrTooManyRequests
 	return result
 }
@@ -0,0 +1,103 @@
+package custom_error
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func MappingStatusCode() map[int]codes.Code {
+	result := make(map[int]codes.Code)
+	result[http.StatusBadRequest] = codes.InvalidArgument
+	result[http.StatusGatewayTimeout] = codes.DeadlineExceeded
+	result[http.StatusNotFound] = codes.NotFound
+	result[http.StatusUnauthorized] = codes.Unauthenticated
+	result[http.StatusForbidden] = codes.PermissionDenied
+	result[http.StatusServiceUnavailable] = codes.Unavailable
+	result[http.StatusUnprocessableEntity] = codes.InvalidArgument
+	result[http.StatusInternalServerError] = codes.Internal
+	result[http.StatusConflict] = codes.AlreadyExists
+	result[http.StatusRequestEntityTooLarge] = codes.ResourceExhausted
+	result[http.StatusTooManyRequests] = codes.ResourceExhausted
+	result[http.StatusGone] = codes.FailedPrecondition
+	return result
+}
+
+// StatusToGRPCError converts an error response of the service layer into a grpc status error.
+func StatusToGRPCError(httpStatus int, message string) error {
+	code, ok := MappingStatusCode()[httpStatus]
+	if !ok {
+		code = codes.Unknown
+	}
+	return status.Error(code, message)
+}
+
+// StatusToGRPCErrorWithRetry is StatusToGRPCError for refusals that tell how
+// long to wait, in seconds, before trying again. GRPCRetryAfter reads it back.
+func StatusToGRPCErrorWithRetry(httpStatus int, message string, retryAfter int) error {
+	err := StatusToGRPCError(httpStatus, message)
+	if retryAfter <= 0 {
+		return err
+	}
+	withRetry, detailErr := status.Convert(err).WithDetails(&errdetails.RetryInfo{
+		RetryDelay: durationpb.New(time.Duration(retryAfter) * time.Second),
+	})
+	if detailErr != nil {
+		return err
+	}
+	return withRetry.Err()
+}
+
+// GRPCRetryAfter returns how long a grpc error asked to wait before trying
+// again, zero if it did not.
+func GRPCRetryAfter(err error) time.Duration {
+	for _, detail := range status.Convert(err).Details() {
+		if retryInfo, ok := detail.(*errdetails.RetryInfo); ok {
+			return retryInfo.GetRetryDelay().AsDuration()
+		}
+	}
+	return 0
+}
+
+// HandleGRPCError converts an error returned by a grpc client into one of the custom errors.
+func HandleGRPCError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrTimeout
+	}
+
+	switch status.Code(err) {
+	case codes.DeadlineExceeded, codes.Canceled:
+		return ErrTimeout
+	case codes.NotFound:
+		return ErrNotFound
+	case codes.Unauthenticated, codes.PermissionDenied:
+		return ErrNoPermission
+	case codes.Unavailable:
+		return ErrConnectionErr
+	case codes.InvalidArgument:
+		return ErrInvalidParameter
+	case codes.AlreadyExists:
+		return ErrConflict
+	case codes.ResourceExhausted:
+		if status.Convert(err).Message() == ErrTooManyRequests.Error() {
+			return ErrTooManyRequests
+		}
+		return ErrEntityTooLarge
+	case codes.FailedPrecondition:
+		return ErrGone
+	case codes.Internal:
+		return ErrInternalServerError
+	default:
+		return ErrUnknown
+	}
+}
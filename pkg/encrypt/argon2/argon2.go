@@ -24,7 +24,7 @@ type argon2Data struct {
 func HashPassword(password []byte) (string, error) {
 	salt, err := generateSalt()
 	if err != nil {
-		return "", nil
+		return "", err
 	}
 
 	algo := "argon2id"
@@ -37,7 +37,7 @@ func HashPassword(password []byte) (string, error) {
 	b64Hash := base64.StdEncoding.EncodeToString(hash)
 	b64Salt := base64.StdEncoding.EncodeToString(salt)
 
-	return fmt.Sprintf("$%s$v=%d$m=%d, t=%d, p=%d$%s%s", algo, argon2.Version, memory, time, threads, b64Salt, b64Hash), nil
+	return fmt.Sprintf("$%s$v=%d$m=%d, t=%d, p=%d$%s$%s", algo, argon2.Version, memory, time, threads, b64Salt, b64Hash), nil
 }
 
 func HashPasswordSettings(password []byte, salt []byte, algo string, time, memory uint32, threads uint8, keyLength uint32) string {
@@ -52,7 +52,7 @@ func HashPasswordSettings(password []byte, salt []byte, algo string, time, memor
 	b64Hash := base64.StdEncoding.EncodeToString(hash)
 	b64Salt := base64.StdEncoding.EncodeToString(salt)
 
-	return fmt.Sprintf("$%s$v=%d$m=%d, t=%d, p=%d$%s%s", algo, argon2.Version, memory, time, threads, b64Salt, b64Hash)
+	return fmt.Sprintf("$%s$v=%d$m=%d, t=%d, p=%d$%s$%s", algo, argon2.Version, memory, time, threads, b64Salt, b64Hash)
 }
 
 func generateSalt() ([]byte, error) {
@@ -109,6 +109,9 @@ func split(encodedHash string) (*argon2Data, error) {
 	for _, v := range splits {
 		parts = append(parts, strings.TrimSuffix(v, "$"))
 	}
+	if len(parts) != 5 {
+		return nil, fmt.Errorf("invalid argon2 hash")
+	}
 
 	versionStr := strings.Split(parts[1], "=")[1]
 	_, err := strconv.Atoi(versionStr)
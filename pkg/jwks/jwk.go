@@ -0,0 +1,118 @@
+package jwks
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+var ErrUnsupportedKey = errors.New("unsupported key")
+
+// JWK is the public half of a signing key as published in a JSON Web Key Set
+// (RFC 7517). RSA keys use N and E, Ed25519 keys (RFC 8037) use Curve and X.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use,omitempty"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+}
+
+type Set struct {
+	Keys []JWK `json:"keys"`
+}
+
+func newJWK(publicKey crypto.PublicKey, algorithm string) (JWK, error) {
+	jwk := JWK{
+		Use:       "sig",
+		Algorithm: algorithm,
+	}
+
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(key.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(key)
+	default:
+		return JWK{}, ErrUnsupportedKey
+	}
+
+	thumbprint, err := jwk.Thumbprint()
+	if err != nil {
+		return JWK{}, err
+	}
+	jwk.KeyID = thumbprint
+	return jwk, nil
+}
+
+// Thumbprint returns the RFC 7638 thumbprint of the key, which is used as its
+// key ID.
+func (j JWK) Thumbprint() (string, error) {
+	var members interface{}
+	switch j.KeyType {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{j.E, j.KeyType, j.N}
+	case "OKP":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{j.Curve, j.KeyType, j.X}
+	default:
+		return "", ErrUnsupportedKey
+	}
+
+	encoded, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(encoded)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// PublicKey decodes the key into the type its signing method verifies with.
+func (j JWK) PublicKey() (crypto.PublicKey, error) {
+	switch {
+	case j.KeyType == "RSA" && j.Algorithm == AlgorithmRS256:
+		n, err := base64.RawURLEncoding.DecodeString(j.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(j.E)
+		if err != nil {
+			return nil, err
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if len(n) == 0 || !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("%w: malformed RSA key %s", ErrUnsupportedKey, j.KeyID)
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+	case j.KeyType == "OKP" && j.Curve == "Ed25519" && j.Algorithm == AlgorithmEdDSA:
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%w: malformed Ed25519 key %s", ErrUnsupportedKey, j.KeyID)
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("%w: %s key with algorithm %s", ErrUnsupportedKey, j.KeyType, j.Algorithm)
+}
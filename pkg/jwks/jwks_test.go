@@ -0,0 +1,275 @@
+package jwks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+func generateKey(t *testing.T, algorithm string, activatesAt time.Time) *Key {
+	t.Helper()
+	key, err := GenerateKey(algorithm, activatesAt, activatesAt)
+	if err != nil {
+		t.Fatalf("GenerateKey(%s): %v", algorithm, err)
+	}
+	return key
+}
+
+func staticFetcher(calls *int, keys ...*Key) Fetcher {
+	return func(ctx context.Context) (*Set, error) {
+		*calls++
+		return Publish(keys)
+	}
+}
+
+func sign(t *testing.T, key *Key, userID string) string {
+	t.Helper()
+	token, err := key.Sign(jwt.MapClaims{
+		"user_id": userID,
+		"exp":     time.Now().Add(time.Minute).Unix(),
+	})
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	return token
+}
+
+func TestSignAndVerify(t *testing.T) {
+	for _, algorithm := range []string{AlgorithmEdDSA, AlgorithmRS256} {
+		t.Run(algorithm, func(t *testing.T) {
+			key := generateKey(t, algorithm, time.Now())
+
+			// Keys survive the round trip through the shared key ring.
+			stored, err := json.Marshal([]*Key{key})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var loaded []*Key
+			if err := json.Unmarshal(stored, &loaded); err != nil {
+				t.Fatal(err)
+			}
+
+			var calls int
+			verifier := NewVerifier(staticFetcher(&calls, key), time.Minute, time.Second)
+			claims, err := verifier.Parse(context.Background(), sign(t, loaded[0], "user-1"))
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if claims["user_id"] != "user-1" {
+				t.Errorf("user_id = %v, want user-1", claims["user_id"])
+			}
+		})
+	}
+}
+
+func TestVerifierRejectsForgedTokens(t *testing.T) {
+	key := generateKey(t, AlgorithmRS256, time.Now())
+	other := generateKey(t, AlgorithmEdDSA, time.Now())
+	var calls int
+	verifier := NewVerifier(staticFetcher(&calls, key), time.Minute, 0)
+
+	jwk, err := key.JWK()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// An HMAC token keyed with the public key must not pass as RS256.
+	hmac := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user_id": "user-1"})
+	hmac.Header["kid"] = key.ID
+	hmacToken, err := hmac.SignedString([]byte(jwk.N))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A token signed by an unpublished key that claims a published kid.
+	impostor := jwt.NewWithClaims(SigningMethodEdDSA, jwt.MapClaims{"user_id": "user-1"})
+	impostor.Header["kid"] = key.ID
+	other.parse()
+	impostorToken, err := impostor.SignedString(other.signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expired, err := key.Sign(jwt.MapClaims{"user_id": "user-1", "exp": time.Now().Add(-time.Minute).Unix()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]string{
+		"unpublished key":  sign(t, other, "user-1"),
+		"hmac with pubkey": hmacToken,
+		"algorithm swap":   impostorToken,
+		"expired":          expired,
+		"garbage":          "not-a-token",
+	}
+	for name, token := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := verifier.Parse(context.Background(), token); err == nil {
+				t.Fatal("Parse succeeded")
+			}
+		})
+	}
+}
+
+func TestVerifierCachesKeySet(t *testing.T) {
+	now := time.Now()
+	oldKey := generateKey(t, AlgorithmEdDSA, now)
+	newKey := generateKey(t, AlgorithmEdDSA, now)
+
+	var calls int
+	published := []*Key{oldKey}
+	verifier := NewVerifier(func(ctx context.Context) (*Set, error) {
+		calls++
+		return Publish(published)
+	}, time.Hour, time.Minute)
+	verifier.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if _, err := verifier.Parse(context.Background(), sign(t, oldKey, "user-1")); err != nil {
+			t.Fatalf("Parse: %v", err)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("fetched %d times, want 1", calls)
+	}
+
+	// A token from a key the cached set lacks triggers one refetch.
+	now = now.Add(2 * time.Minute)
+	published = []*Key{oldKey, newKey}
+	if _, err := verifier.Parse(context.Background(), sign(t, newKey, "user-1")); err != nil {
+		t.Fatalf("Parse with new key: %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("fetched %d times, want 2", calls)
+	}
+
+	// Unknown kids do not make the verifier fetch more than once per minRefresh.
+	stranger := generateKey(t, AlgorithmEdDSA, now)
+	for i := 0; i < 3; i++ {
+		if _, err := verifier.Parse(context.Background(), sign(t, stranger, "user-1")); err == nil {
+			t.Fatal("Parse succeeded with an unpublished key")
+		}
+	}
+	if calls != 2 {
+		t.Fatalf("fetched %d times, want 2", calls)
+	}
+
+	// The cached set keeps working while the authenticator is unreachable.
+	now = now.Add(2 * time.Hour)
+	verifier.fetch = func(ctx context.Context) (*Set, error) {
+		calls++
+		return nil, errors.New("unreachable")
+	}
+	if _, err := verifier.Parse(context.Background(), sign(t, oldKey, "user-1")); err != nil {
+		t.Fatalf("Parse with stale set: %v", err)
+	}
+}
+
+func TestVerifierReportsUnavailableKeySet(t *testing.T) {
+	key := generateKey(t, AlgorithmEdDSA, time.Now())
+	verifier := NewVerifier(func(ctx context.Context) (*Set, error) {
+		return nil, errors.New("unreachable")
+	}, time.Minute, 0)
+
+	_, err := verifier.Parse(context.Background(), sign(t, key, "user-1"))
+	if !errors.Is(err, ErrKeySetUnavailable) {
+		t.Fatalf("Parse error = %v, want ErrKeySetUnavailable", err)
+	}
+}
+
+func TestHTTPFetcher(t *testing.T) {
+	key := generateKey(t, AlgorithmRS256, time.Now())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		set, err := Publish([]*Key{key})
+		if err != nil {
+			t.Error(err)
+		}
+		json.NewEncoder(w).Encode(set)
+	}))
+	defer server.Close()
+
+	verifier := NewVerifier(HTTPFetcher(server.Client(), server.URL), time.Minute, time.Second)
+	if _, err := verifier.Parse(context.Background(), sign(t, key, "user-1")); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+}
+
+func TestRotationPolicy(t *testing.T) {
+	policy := RotationPolicy{
+		Algorithm:   AlgorithmEdDSA,
+		Interval:    24 * time.Hour,
+		PublishLead: time.Hour,
+		Overlap:     30 * time.Minute,
+	}
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	rotate := func(keys []*Key, now time.Time) []*Key {
+		t.Helper()
+		keys, _, err := policy.Rotate(keys, now)
+		if err != nil {
+			t.Fatalf("Rotate: %v", err)
+		}
+		return keys
+	}
+	ids := func(keys []*Key) []string {
+		var ids []string
+		for _, key := range keys {
+			ids = append(ids, key.ID)
+		}
+		return ids
+	}
+
+	keys := rotate(nil, start)
+	if len(keys) != 1 || Current(keys, start) != keys[0] {
+		t.Fatalf("first rotation = %v, want one active key", ids(keys))
+	}
+	first := keys[0]
+
+	// Nothing happens until the next key's publish time.
+	keys, changed, _ := policy.Rotate(keys, start.Add(22*time.Hour))
+	if changed || len(keys) != 1 {
+		t.Fatalf("rotation before publish time changed the ring: %v", ids(keys))
+	}
+
+	// The next key is published ahead of time but does not sign yet.
+	publishAt := start.Add(23 * time.Hour)
+	keys = rotate(keys, publishAt)
+	if len(keys) != 2 {
+		t.Fatalf("ring = %v, want the next key published", ids(keys))
+	}
+	second := keys[1]
+	if !second.ActivatesAt.Equal(start.Add(24 * time.Hour)) {
+		t.Errorf("next key activates at %v, want %v", second.ActivatesAt, start.Add(24*time.Hour))
+	}
+	if Current(keys, publishAt) != first {
+		t.Error("the published key signs before its activation")
+	}
+	if again := rotate(keys, publishAt.Add(time.Minute)); len(again) != 2 {
+		t.Errorf("a second replica generated another key: %v", ids(again))
+	}
+
+	// After activation both keys stay published for the overlap.
+	activated := start.Add(24*time.Hour + time.Minute)
+	keys = rotate(keys, activated)
+	if Current(keys, activated) != second || len(keys) != 2 {
+		t.Fatalf("ring after activation = %v", ids(keys))
+	}
+
+	retired := start.Add(24*time.Hour + 30*time.Minute)
+	keys = rotate(keys, retired)
+	if len(keys) != 1 || keys[0] != second {
+		t.Fatalf("ring after overlap = %v, want only %s", ids(keys), second.ID)
+	}
+
+	// An overdue rotation starts signing with the new key right away.
+	late := start.Add(72 * time.Hour)
+	keys = rotate(keys, late)
+	if current := Current(keys, late); current == second || current == nil {
+		t.Fatalf("overdue rotation kept signing with %v", current)
+	}
+}
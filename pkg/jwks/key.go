@@ -0,0 +1,222 @@
+// Package jwks signs access tokens with rotating asymmetric keys and verifies
+// them against the published JSON Web Key Set, so that services which verify
+// tokens cannot mint them.
+package jwks
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const (
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+
+	rsaKeyBits = 2048
+)
+
+// Key is a private signing key. PrivateKey holds its PKCS #8 DER encoding so
+// that the key ring can be stored and shared between replicas.
+type Key struct {
+	ID          string    `json:"kid"`
+	Algorithm   string    `json:"alg"`
+	PrivateKey  []byte    `json:"private_key"`
+	CreatedAt   time.Time `json:"created_at"`
+	ActivatesAt time.Time `json:"activates_at"`
+
+	once   sync.Once
+	signer crypto.Signer
+	jwk    JWK
+	err    error
+}
+
+// GenerateKey creates a key that starts signing at activatesAt.
+func GenerateKey(algorithm string, now, activatesAt time.Time) (*Key, error) {
+	var (
+		signer crypto.Signer
+		err    error
+	)
+	switch algorithm {
+	case AlgorithmRS256:
+		signer, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case AlgorithmEdDSA:
+		_, signer, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("%w: algorithm %s", ErrUnsupportedKey, algorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(signer)
+	if err != nil {
+		return nil, err
+	}
+	jwk, err := newJWK(signer.Public(), algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Key{
+		ID:          jwk.KeyID,
+		Algorithm:   algorithm,
+		PrivateKey:  der,
+		CreatedAt:   now,
+		ActivatesAt: activatesAt,
+	}, nil
+}
+
+func (k *Key) parse() error {
+	k.once.Do(func() {
+		privateKey, err := x509.ParsePKCS8PrivateKey(k.PrivateKey)
+		if err != nil {
+			k.err = err
+			return
+		}
+		signer, ok := privateKey.(crypto.Signer)
+		if !ok {
+			k.err = ErrUnsupportedKey
+			return
+		}
+		jwk, err := newJWK(signer.Public(), k.Algorithm)
+		if err != nil {
+			k.err = err
+			return
+		}
+		if jwk.KeyID != k.ID {
+			k.err = fmt.Errorf("%w: key %s does not match its id", ErrUnsupportedKey, k.ID)
+			return
+		}
+		k.signer, k.jwk = signer, jwk
+	})
+	return k.err
+}
+
+// JWK returns the public half of the key.
+func (k *Key) JWK() (JWK, error) {
+	if err := k.parse(); err != nil {
+		return JWK{}, err
+	}
+	return k.jwk, nil
+}
+
+// PublicKey returns the key to verify tokens signed by k with.
+func (k *Key) PublicKey() (crypto.PublicKey, error) {
+	if err := k.parse(); err != nil {
+		return nil, err
+	}
+	return k.signer.Public(), nil
+}
+
+// Sign signs claims and names the key in the kid header.
+func (k *Key) Sign(claims jwt.Claims) (string, error) {
+	if err := k.parse(); err != nil {
+		return "", err
+	}
+
+	method := jwt.GetSigningMethod(k.Algorithm)
+	if method == nil {
+		return "", fmt.Errorf("%w: algorithm %s", ErrUnsupportedKey, k.Algorithm)
+	}
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = k.ID
+	return token.SignedString(k.signer)
+}
+
+// RotationPolicy schedules the keys of a key ring. A new key is published
+// PublishLead before it starts signing, so that verifiers caching the key set
+// know it before the first token signed with it arrives. It signs for
+// Interval, then stays published for Overlap so that the tokens it signed can
+// still be verified; Overlap must not be shorter than the access token
+// lifetime.
+type RotationPolicy struct {
+	Algorithm   string
+	Interval    time.Duration
+	PublishLead time.Duration
+	Overlap     time.Duration
+}
+
+// Rotate returns the key ring as it should be at now: keys whose overlap has
+// passed are dropped and the next key is generated once its publish time has
+// come. It reports whether the ring changed.
+func (p RotationPolicy) Rotate(keys []*Key, now time.Time) ([]*Key, bool, error) {
+	sorted := make([]*Key, len(keys))
+	copy(sorted, keys)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].ActivatesAt.Before(sorted[j].ActivatesAt)
+	})
+
+	// A key is retired once its successor activates and is dropped Overlap
+	// after that.
+	var ring []*Key
+	for i, key := range sorted {
+		if i+1 < len(sorted) {
+			if !sorted[i+1].ActivatesAt.Add(p.Overlap).After(now) {
+				continue
+			}
+		}
+		ring = append(ring, key)
+	}
+	changed := len(ring) != len(keys)
+
+	current := Current(ring, now)
+	pending := len(ring) > 0 && ring[len(ring)-1].ActivatesAt.After(now)
+	if pending {
+		return ring, changed, nil
+	}
+
+	activatesAt := now
+	if current != nil {
+		activatesAt = current.ActivatesAt.Add(p.Interval)
+		if now.Before(activatesAt.Add(-p.PublishLead)) {
+			return ring, changed, nil
+		}
+		if activatesAt.Before(now) {
+			// Rotation is overdue, so the new key has not been published in
+			// advance; verifiers fetch the key set again when they meet it.
+			activatesAt = now
+		}
+	}
+
+	key, err := GenerateKey(p.Algorithm, now, activatesAt)
+	if err != nil {
+		return nil, false, err
+	}
+	return append(ring, key), true, nil
+}
+
+// Current returns the key that signs at now, or nil if there is none.
+func Current(keys []*Key, now time.Time) *Key {
+	var current *Key
+	for _, key := range keys {
+		if key.ActivatesAt.After(now) {
+			continue
+		}
+		if current == nil || key.ActivatesAt.After(current.ActivatesAt) {
+			current = key
+		}
+	}
+	return current
+}
+
+// Publish returns the key set with the public half of every key in the ring.
+func Publish(keys []*Key) (*Set, error) {
+	set := Set{Keys: make([]JWK, 0, len(keys))}
+	for _, key := range keys {
+		jwk, err := key.JWK()
+		if err != nil {
+			return nil, err
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return &set, nil
+}
@@ -0,0 +1,152 @@
+package jwks
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+var (
+	ErrUnknownKey        = errors.New("unknown signing key")
+	ErrKeySetUnavailable = errors.New("key set unavailable")
+)
+
+// Fetcher loads the published key set.
+type Fetcher func(ctx context.Context) (*Set, error)
+
+// HTTPFetcher fetches the key set from url, e.g. the authenticator's
+// /.well-known/jwks.json.
+func HTTPFetcher(client *http.Client, url string) Fetcher {
+	return func(ctx context.Context) (*Set, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("fetch %s: status %d", url, resp.StatusCode)
+		}
+		var set Set
+		if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+			return nil, err
+		}
+		return &set, nil
+	}
+}
+
+type publicKey struct {
+	algorithm string
+	key       crypto.PublicKey
+}
+
+// Verifier checks access tokens against a cached copy of the key set. The set
+// is fetched again once it is older than ttl, or when a token names a key it
+// does not know, at most once per minRefresh.
+type Verifier struct {
+	fetch      Fetcher
+	ttl        time.Duration
+	minRefresh time.Duration
+	now        func() time.Time
+
+	mu          sync.Mutex
+	keys        map[string]publicKey
+	fetchedAt   time.Time
+	attemptedAt time.Time
+}
+
+func NewVerifier(fetch Fetcher, ttl, minRefresh time.Duration) *Verifier {
+	return &Verifier{
+		fetch:      fetch,
+		ttl:        ttl,
+		minRefresh: minRefresh,
+		now:        time.Now,
+	}
+}
+
+// Parse verifies the signature and expiry of tokenString and returns its
+// claims. It returns ErrKeySetUnavailable if the key set could not be fetched.
+func (v *Verifier) Parse(ctx context.Context, tokenString string) (jwt.MapClaims, error) {
+	var keyErr error
+	parser := jwt.Parser{ValidMethods: []string{AlgorithmRS256, AlgorithmEdDSA}}
+	token, err := parser.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, err := v.key(ctx, kid)
+		if err != nil {
+			keyErr = err
+			return nil, err
+		}
+		if key.algorithm != token.Method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Method.Alg())
+		}
+		return key.key, nil
+	})
+	if errors.Is(keyErr, ErrKeySetUnavailable) {
+		return nil, keyErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, fmt.Errorf("token has expired")
+	}
+	return claims, nil
+}
+
+func (v *Verifier) key(ctx context.Context, kid string) (publicKey, error) {
+	if kid == "" {
+		return publicKey{}, ErrUnknownKey
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	now := v.now()
+	key, known := v.keys[kid]
+	stale := now.Sub(v.fetchedAt) >= v.ttl
+	if (stale || !known) && now.Sub(v.attemptedAt) >= v.minRefresh {
+		v.attemptedAt = now
+		// Keep using the cached set if the authenticator can't be reached.
+		if err := v.refresh(ctx, now); err != nil && len(v.keys) == 0 {
+			return publicKey{}, fmt.Errorf("%w: %v", ErrKeySetUnavailable, err)
+		}
+		key, known = v.keys[kid]
+	}
+	if !known {
+		return publicKey{}, ErrUnknownKey
+	}
+	return key, nil
+}
+
+func (v *Verifier) refresh(ctx context.Context, now time.Time) error {
+	set, err := v.fetch(ctx)
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[string]publicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		key, err := jwk.PublicKey()
+		if err != nil {
+			// Skip keys this verifier can't use rather than rejecting the set.
+			continue
+		}
+		keys[jwk.KeyID] = publicKey{algorithm: jwk.Algorithm, key: key}
+	}
+	v.keys = keys
+	v.fetchedAt = now
+	return nil
+}
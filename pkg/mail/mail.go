@@ -0,0 +1,50 @@
+// Package mail sends the emails of the auth flows.
+package mail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, message Message) error
+}
+
+type Config struct {
+	Driver string // smtp or file
+	From   string
+	Path   string // where the file mailer writes
+	SMTP   SMTPConfig
+}
+
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+}
+
+// NewMailer returns the mailer the config asks for. The file mailer is meant
+// for local setups without a mail server.
+func NewMailer(config Config) (Mailer, error) {
+	switch config.Driver {
+	case "smtp":
+		return NewSMTPMailer(config.SMTP, config.From), nil
+	case "file", "":
+		// The mails hold reset and verification links, which must not end
+		// up in the service's own output.
+		if config.Path == "" {
+			return nil, errors.New("mail path is required by the file driver")
+		}
+		return NewFileMailer(config.Path, config.From), nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", config.Driver)
+	}
+}
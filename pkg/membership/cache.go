@@ -0,0 +1,107 @@
+package membership
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"graduation-thesis/pkg/custom_error"
+	"graduation-thesis/pkg/pb/grouppb"
+)
+
+type Loader func(ctx context.Context, conversationID string) ([]string, error)
+
+// entry holds the members of a conversation, or is a tombstone left by
+// Invalidate until ttl passes. version counts the invalidations of the
+// conversation, so a list loaded before one of them is not stored.
+type entry struct {
+	members   []string
+	loaded    bool
+	version   uint64
+	expiresAt time.Time
+}
+
+// Cache keeps conversation members in memory. Entries are dropped when a
+// membership event for the conversation arrives; ttl only bounds staleness if
+// an event is lost. Expired entries and tombstones are swept at most once per
+// ttl.
+type Cache struct {
+	mu      sync.RWMutex
+	entries map[string]entry
+	sweptAt time.Time
+	ttl     time.Duration
+	loader  Loader
+}
+
+func NewCache(loader Loader, ttl time.Duration) *Cache {
+	return &Cache{
+		entries: make(map[string]entry),
+		sweptAt: time.Now(),
+		ttl:     ttl,
+		loader:  loader,
+	}
+}
+
+func GroupLoader(groupClient grouppb.GroupServiceClient, timeout time.Duration) Loader {
+	return func(ctx context.Context, conversationID string) ([]string, error) {
+		callContext, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		conversation, err := groupClient.GetConversation(callContext, &grouppb.GetConversationRequest{ConvId: conversationID})
+		if err != nil {
+			return nil, custom_error.HandleGRPCError(err)
+		}
+		return conversation.Members, nil
+	}
+}
+
+func (c *Cache) Get(ctx context.Context, conversationID string) ([]string, error) {
+	c.mu.RLock()
+	e, ok := c.entries[conversationID]
+	c.mu.RUnlock()
+	if ok && e.loaded && time.Now().Before(e.expiresAt) {
+		return e.members, nil
+	}
+
+	members, err := c.loader(ctx, conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	now := time.Now()
+	// Skip the write if the conversation was invalidated while loading,
+	// otherwise the stale list could outlive the event.
+	if current := c.entries[conversationID]; current.version == e.version {
+		c.entries[conversationID] = entry{
+			members:   members,
+			loaded:    true,
+			version:   e.version,
+			expiresAt: now.Add(c.ttl),
+		}
+	}
+	if now.Sub(c.sweptAt) >= c.ttl {
+		for id, e := range c.entries {
+			if !now.Before(e.expiresAt) {
+				delete(c.entries, id)
+			}
+		}
+		c.sweptAt = now
+	}
+	c.mu.Unlock()
+
+	return members, nil
+}
+
+// Invalidate drops the members of conversationID. The tombstone left in their
+// place outlives any load started before, so only loads of this conversation
+// are discarded.
+func (c *Cache) Invalidate(conversationID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[conversationID] = entry{
+		version:   c.entries[conversationID].version + 1,
+		expiresAt: time.Now().Add(c.ttl),
+	}
+}
@@ -0,0 +1,132 @@
+package membership
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func members(n int) []string {
+	users := make([]string, n)
+	for i := range users {
+		users[i] = fmt.Sprintf("user-%d", i)
+	}
+	return users
+}
+
+func isMember(users []string, userID string) bool {
+	for _, user := range users {
+		if user == userID {
+			return true
+		}
+	}
+	return false
+}
+
+func TestCacheInvalidate(t *testing.T) {
+	loads := &atomic.Int32{}
+	cache := NewCache(func(ctx context.Context, conversationID string) ([]string, error) {
+		loads.Add(1)
+		return members(3), nil
+	}, time.Minute)
+	ctx := context.Background()
+
+	cache.Get(ctx, "conv-1")
+	cache.Get(ctx, "conv-1")
+	if loads.Load() != 1 {
+		t.Fatalf("loaded %d times, want the members cached", loads.Load())
+	}
+	cache.Invalidate("conv-1")
+	cache.Get(ctx, "conv-1")
+	if loads.Load() != 2 {
+		t.Fatalf("loaded %d times, want the members loaded again", loads.Load())
+	}
+}
+
+func TestCacheDropsListsLoadedBeforeAnEvent(t *testing.T) {
+	var cache *Cache
+	cache = NewCache(func(ctx context.Context, conversationID string) ([]string, error) {
+		cache.Invalidate(conversationID)
+		return members(3), nil
+	}, time.Minute)
+
+	cache.Get(context.Background(), "conv-1")
+	if cache.entries["conv-1"].loaded {
+		t.Fatalf("cached %v, which predates the event", cache.entries["conv-1"])
+	}
+}
+
+func TestCacheInvalidateKeepsOtherConversationsLoading(t *testing.T) {
+	var cache *Cache
+	cache = NewCache(func(ctx context.Context, conversationID string) ([]string, error) {
+		if conversationID == "conv-1" {
+			cache.Invalidate("conv-2")
+		}
+		return members(3), nil
+	}, time.Minute)
+	ctx := context.Background()
+
+	cache.Get(ctx, "conv-1")
+	if !cache.entries["conv-1"].loaded {
+		t.Fatal("an event of another conversation dropped the members of conv-1")
+	}
+	cache.Get(ctx, "conv-2")
+	if !cache.entries["conv-2"].loaded {
+		t.Fatal("the members of conv-2 loaded after its event were not cached")
+	}
+}
+
+func TestCacheSweepsExpiredEntries(t *testing.T) {
+	cache := NewCache(func(ctx context.Context, conversationID string) ([]string, error) {
+		return members(3), nil
+	}, 10*time.Millisecond)
+	ctx := context.Background()
+
+	for i := 0; i < 100; i++ {
+		cache.Get(ctx, fmt.Sprintf("conv-%d", i))
+	}
+	time.Sleep(20 * time.Millisecond)
+	cache.Get(ctx, "conv-last")
+	if len(cache.entries) != 1 {
+		t.Fatalf("kept %d entries, want the expired ones swept", len(cache.entries))
+	}
+}
+
+// The benchmarks check whether the last of 500 members belongs to the
+// conversation, the worst case of every membership check.
+func BenchmarkCacheGet(b *testing.B) {
+	users := members(500)
+	cache := NewCache(func(ctx context.Context, conversationID string) ([]string, error) {
+		return users, nil
+	}, time.Minute)
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			members, _ := cache.Get(ctx, "conv-1")
+			if !isMember(members, "user-499") {
+				b.Fatal("user-499 is a member")
+			}
+		}
+	})
+}
+
+func BenchmarkCacheGetInvalidated(b *testing.B) {
+	users := members(500)
+	cache := NewCache(func(ctx context.Context, conversationID string) ([]string, error) {
+		return append([]string(nil), users...), nil
+	}, time.Minute)
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		cache.Invalidate("conv-1")
+		members, _ := cache.Get(ctx, "conv-1")
+		if !isMember(members, "user-499") {
+			b.Fatal("user-499 is a member")
+		}
+	}
+}
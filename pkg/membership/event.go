@@ -0,0 +1,18 @@
+package membership
+
+const (
+	ActionCreate = "create"
+	ActionAdd    = "add"
+	ActionRemove = "remove"
+	ActionDelete = "delete"
+)
+
+// Event is published by the group service whenever the members of a
+// conversation change. It is keyed by ConversationID so events of the same
+// conversation stay ordered within a partition.
+type Event struct {
+	ConversationID string   `json:"conv_id"`
+	Action         string   `json:"action"`
+	Users          []string `json:"users"`
+	Timestamp      int64    `json:"timestamp"`
+}
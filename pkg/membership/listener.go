@@ -0,0 +1,38 @@
+package membership
+
+import (
+	"encoding/json"
+
+	"graduation-thesis/pkg/logger"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// Listen invalidates cached conversations as membership events arrive. Every
+// instance needs its own consumer group so that each one sees all events.
+func (c *Cache) Listen(consumer *kafka.Consumer, topic string, logger logger.Logger, done <-chan struct{}) error {
+	if err := consumer.SubscribeTopics([]string{topic}, nil); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-done:
+			return nil
+		default:
+		}
+
+		event := consumer.Poll(100)
+		switch e := event.(type) {
+		case *kafka.Message:
+			var membershipEvent Event
+			if err := json.Unmarshal(e.Value, &membershipEvent); err != nil {
+				logger.Errorf("[Membership] Cannot unmarshal event at %d[%d]: %v", e.TopicPartition.Partition, e.TopicPartition.Offset, err)
+				continue
+			}
+			c.Invalidate(membershipEvent.ConversationID)
+		case kafka.Error:
+			logger.Errorf("[Membership] Error: %v", e)
+		}
+	}
+}
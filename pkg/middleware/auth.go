@@ -2,98 +2,84 @@ package middleware
 
 import (
 	"errors"
-	"fmt"
 	"net/http"
-	"strings"
-	"time"
+	"sync"
 
-	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
-	"github.com/spf13/viper"
 
-	"graduation-thesis/pkg/custom_error"
-	request "graduation-thesis/pkg/requests"
+	"graduation-thesis/pkg/auth"
+	"graduation-thesis/pkg/jwks"
+	"graduation-thesis/pkg/pb/authenticatorpb"
+	"graduation-thesis/pkg/rpc"
 )
 
-func extractTokenFromRequest(r *http.Request) string {
-	bearToken := r.Header.Get("Authorization")
-	strArr := strings.Split(bearToken, " ")
-	if len(strArr) == 2 {
-		return strArr[1]
-	}
-
-	return ""
+type verifier struct {
+	tokens    *auth.Verifier
+	apiTokens *auth.APITokenVerifier
 }
 
-func validateToken(tokenString string) (*jwt.Token, error) {
-	token, err := jwt.Parse(tokenString, func(_token *jwt.Token) (interface{}, error) {
-		if _, ok := _token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", _token.Header["alg"])
-		}
-
-		return []byte(viper.GetString("app.access_secret")), nil
-	})
-	if err != nil {
-		return nil, err
-	}
+var (
+	verifiersMu sync.Mutex
+	verifiers   = make(map[string]*verifier)
+)
 
-	if _, ok := token.Claims.(jwt.MapClaims); !ok || !token.Valid {
-		return nil, fmt.Errorf("token has expired")
+// getVerifier returns the verifier shared by every route that authenticates
+// against the authenticator at authenticatorAddress.
+func getVerifier(authenticatorAddress string) *verifier {
+	verifiersMu.Lock()
+	defer verifiersMu.Unlock()
+	if v, ok := verifiers[authenticatorAddress]; ok {
+		return v
 	}
 
-	return token, nil
-}
-
-func AuthMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		tokenString := extractTokenFromRequest(c.Request)
-		token, err := validateToken(tokenString)
-		if err != nil {
-			c.AbortWithStatus(http.StatusUnauthorized)
-			return
-		}
-
-		claims, _ := token.Claims.(jwt.MapClaims)
-		accessUuid := claims["access_uuid"]
-		userId := claims["user_id"]
-
-		c.Set("access_uuid", accessUuid)
-		c.Set("user_id", userId)
-		c.Next()
+	authenticatorClient := authenticatorpb.NewAuthenticatorServiceClient(rpc.GetClientConn(authenticatorAddress))
+	v := &verifier{
+		tokens:    auth.NewVerifier(auth.KeySetFetcher(authenticatorClient)),
+		apiTokens: auth.NewAPITokenVerifier(authenticatorClient),
 	}
+	verifiers[authenticatorAddress] = v
+	return v
 }
 
-func AuthMiddlewareV2(authenticatorURL string) gin.HandlerFunc {
+// AuthMiddlewareV2 verifies the access token against the authenticator's
+// published keys, which are cached, and passes the user on in X-User-ID and
+// the kind of bearer in X-Auth-Kind. Routes naming scopes also accept API
+// tokens of service accounts and bots granted all of them, which are passed on
+// as the account; other routes are for users only.
+//
+// Access tokens are not checked for revocation: a token of a session that was
+// logged out or revoked is accepted until it expires, at most at_expires after
+// it was issued. The websocket handlers close connections of revoked sessions
+// at once through the session events.
+func AuthMiddlewareV2(authenticatorAddress string, scopes ...string) gin.HandlerFunc {
+	verifier := getVerifier(authenticatorAddress)
 	return func(c *gin.Context) {
-		tokenString := extractTokenFromRequest(c.Request)
-		var (
-			result interface{}
-			err    error
-		)
-		for i := 1; i <= 5; i++ {
-			result, err = request.HTTPRequestCall(
-				authenticatorURL,
-				http.MethodPost,
-				tokenString,
-				nil,
-				5*time.Second,
-			)
-			if err != nil && !errors.Is(err, custom_error.ErrNoPermission) {
-				continue
+		token := auth.ExtractToken(c.Request)
+		var claims *auth.Claims
+		var err error
+		if auth.IsAPIToken(token) {
+			if len(scopes) == 0 {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
 			}
-			break
+			claims, err = verifier.apiTokens.Verify(c, token)
+		} else {
+			claims, err = verifier.tokens.Verify(c, token)
 		}
-
-		if errors.Is(err, custom_error.ErrNoPermission) {
-			c.AbortWithStatus(http.StatusUnauthorized)
+		if errors.Is(err, jwks.ErrKeySetUnavailable) || errors.Is(err, auth.ErrAPITokensUnavailable) {
+			c.AbortWithStatus(http.StatusInternalServerError)
 			return
 		} else if err != nil {
-			c.AbortWithStatus(http.StatusInternalServerError)
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		if !claims.HasScopes(scopes...) {
+			c.AbortWithStatus(http.StatusForbidden)
 			return
 		}
 
-		userID := result.(string)
-		c.Request.Header.Add("X-User-ID", userID)
+		c.Request.Header.Set("X-User-ID", claims.UserID)
+		c.Request.Header.Set("X-Auth-Kind", claims.Kind)
 		c.Next()
 	}
 }
@@ -0,0 +1,17 @@
+package middleware
+
+import "github.com/gin-gonic/gin"
+
+// TrustProxies makes c.ClientIP() take the client address from
+// X-Forwarded-For only on requests coming through one of proxies, the
+// addresses or CIDRs of the gateways in front of the service. Other requests
+// get the address of the connection, so clients can't choose the IP their
+// failed logins are counted against.
+func TrustProxies(r *gin.Engine, proxies []string) error {
+	r.RemoteIPHeaders = []string{"X-Forwarded-For"}
+	r.TrustedPlatform = ""
+	if len(proxies) == 0 {
+		proxies = nil
+	}
+	return r.SetTrustedProxies(proxies)
+}
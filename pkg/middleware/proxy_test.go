@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func clientIP(t *testing.T, proxies []string, remoteAddr string, header http.Header) string {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	if err := TrustProxies(r, proxies); err != nil {
+		t.Fatalf("TrustProxies: %v", err)
+	}
+	r.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, c.ClientIP())
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = remoteAddr
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Body.String()
+}
+
+func TestClientIPIgnoresSpoofedHeaders(t *testing.T) {
+	spoofed := http.Header{
+		"X-Forwarded-For":  {"198.51.100.1"},
+		"X-Real-Ip":        {"198.51.100.2"},
+		"Cf-Connecting-Ip": {"198.51.100.3"},
+	}
+	if ip := clientIP(t, nil, "203.0.113.7:4242", spoofed); ip != "203.0.113.7" {
+		t.Fatalf("ClientIP without trusted proxies = %s, want the connection address", ip)
+	}
+	if ip := clientIP(t, []string{"10.0.0.0/8"}, "203.0.113.7:4242", spoofed); ip != "203.0.113.7" {
+		t.Fatalf("ClientIP from an untrusted address = %s, want the connection address", ip)
+	}
+}
+
+func TestClientIPBehindTrustedProxy(t *testing.T) {
+	proxies := []string{"10.0.0.0/8"}
+	forwarded := http.Header{"X-Forwarded-For": {"198.51.100.1"}}
+	if ip := clientIP(t, proxies, "10.1.2.3:4242", forwarded); ip != "198.51.100.1" {
+		t.Fatalf("ClientIP = %s, want the forwarded address", ip)
+	}
+
+	// The proxy appends the address it saw to whatever the client sent.
+	spoofed := http.Header{"X-Forwarded-For": {"192.0.2.1, 198.51.100.1"}}
+	if ip := clientIP(t, proxies, "10.1.2.3:4242", spoofed); ip != "198.51.100.1" {
+		t.Fatalf("ClientIP = %s, want the address the proxy saw", ip)
+	}
+
+	realIP := http.Header{"X-Real-Ip": {"192.0.2.1"}}
+	if ip := clientIP(t, proxies, "10.1.2.3:4242", realIP); ip != "10.1.2.3" {
+		t.Fatalf("ClientIP = %s, want X-Real-IP ignored", ip)
+	}
+}
@@ -10,4 +10,7 @@ type ErrorResponse struct {
 	Status       int      `json:"status"`
 	ErrorMessage string   `json:"error_message"`
 	InfoMessages []string `json:"info_messages"`
+	// RetryAfter is the number of seconds to wait before trying again, sent
+	// along with 429 responses.
+	RetryAfter int `json:"retry_after,omitempty"`
 }
@@ -0,0 +1,48 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileNotifier appends messages to a file instead of delivering them, so the
+// links and codes in them can be used on a local setup.
+type FileNotifier struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileNotifier(path string) *FileNotifier {
+	return &FileNotifier{
+		path: path,
+	}
+}
+
+func (f *FileNotifier) SendEmail(ctx context.Context, to, subject, body string) error {
+	return f.write(fmt.Sprintf("Date: %s\nChannel: email\nTo: %s\nSubject: %s\n\n%s\n\n", time.Now().Format(time.RFC1123Z), to, subject, body))
+}
+
+func (f *FileNotifier) SendSMS(ctx context.Context, to, body string) error {
+	return f.write(fmt.Sprintf("Date: %s\nChannel: sms\nTo: %s\n\n%s\n\n", time.Now().Format(time.RFC1123Z), to, body))
+}
+
+func (f *FileNotifier) write(content string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(f.path), 0o755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.WriteString(content)
+	return err
+}
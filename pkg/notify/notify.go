@@ -0,0 +1,66 @@
+// Package notify delivers the verification messages of the user service by
+// email and SMS.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"graduation-thesis/pkg/mail"
+)
+
+type Notifier interface {
+	SendEmail(ctx context.Context, to, subject, body string) error
+	SendSMS(ctx context.Context, to, body string) error
+}
+
+type Config struct {
+	Driver string // mail or file
+	Path   string // where the file notifier writes
+	Mail   mail.Config
+}
+
+// NewNotifier returns the notifier the config asks for. There is no SMS
+// gateway yet, so text messages always go to the file notifier.
+func NewNotifier(config Config) (Notifier, error) {
+	// The messages hold verification links and codes, which must not end up
+	// in the service's own output.
+	if config.Path == "" {
+		return nil, errors.New("notify path is required")
+	}
+	switch config.Driver {
+	case "mail":
+		mailer, err := mail.NewMailer(config.Mail)
+		if err != nil {
+			return nil, err
+		}
+		return NewMailNotifier(mailer, NewFileNotifier(config.Path)), nil
+	case "file", "":
+		return NewFileNotifier(config.Path), nil
+	default:
+		return nil, fmt.Errorf("unknown notify driver %q", config.Driver)
+	}
+}
+
+// MailNotifier sends emails through a mailer and hands text messages on to
+// another notifier.
+type MailNotifier struct {
+	mailer mail.Mailer
+	sms    Notifier
+}
+
+func NewMailNotifier(mailer mail.Mailer, sms Notifier) *MailNotifier {
+	return &MailNotifier{
+		mailer: mailer,
+		sms:    sms,
+	}
+}
+
+func (m *MailNotifier) SendEmail(ctx context.Context, to, subject, body string) error {
+	return m.mailer.Send(ctx, mail.Message{To: to, Subject: subject, Body: body})
+}
+
+func (m *MailNotifier) SendSMS(ctx context.Context, to, body string) error {
+	return m.sms.SendSMS(ctx, to, body)
+}
@@ -0,0 +1,118 @@
+package oidc_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"graduation-thesis/pkg/oidc"
+	"graduation-thesis/pkg/oidc/oidctest"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const redirectURL = "http://localhost/v1/oidc/corp/callback"
+
+func newProvider(t *testing.T) (*oidctest.Provider, *oidc.Provider) {
+	t.Helper()
+	mock := oidctest.NewProvider(t, "chat", "s3cret")
+	mock.LogIn(&oidctest.User{Subject: "sub-1", Claims: map[string]interface{}{
+		"email":          "alice@corp.example.com",
+		"email_verified": "true",
+	}})
+	provider := oidc.NewProvider(oidc.Config{
+		Issuer:       mock.Issuer(),
+		ClientID:     "chat",
+		ClientSecret: "s3cret",
+		RedirectURL:  redirectURL,
+	}, http.DefaultClient)
+	return mock, provider
+}
+
+// authorize starts a login and returns the code the provider sent back.
+func authorize(t *testing.T, mock *oidctest.Provider, provider *oidc.Provider, nonce, codeVerifier string) string {
+	t.Helper()
+	authURL, err := provider.AuthCodeURL(context.Background(), "state-1", nonce, codeVerifier)
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	callback := mock.Authorize(t, authURL)
+	if callback.Query().Get("state") != "state-1" || callback.Query().Get("code") == "" {
+		t.Fatalf("provider sent the user back to %s", callback)
+	}
+	return callback.Query().Get("code")
+}
+
+func TestLogin(t *testing.T) {
+	mock, provider := newProvider(t)
+	ctx := context.Background()
+
+	code := authorize(t, mock, provider, "nonce-1", "verifier-1")
+	idToken, err := provider.Exchange(ctx, code, "verifier-1", "nonce-1")
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if idToken.Subject != "sub-1" || idToken.String("email") != "alice@corp.example.com" || !idToken.Bool("email_verified") {
+		t.Fatalf("id token = %+v", idToken)
+	}
+
+	// Codes are single use.
+	if _, err := provider.Exchange(ctx, code, "verifier-1", "nonce-1"); !errors.Is(err, oidc.ErrExchange) {
+		t.Fatalf("second Exchange = %v", err)
+	}
+}
+
+func TestExchangeNeedsCodeVerifier(t *testing.T) {
+	mock, provider := newProvider(t)
+
+	code := authorize(t, mock, provider, "nonce-1", "verifier-1")
+	if _, err := provider.Exchange(context.Background(), code, "verifier-2", "nonce-1"); !errors.Is(err, oidc.ErrExchange) {
+		t.Fatalf("Exchange with another verifier = %v", err)
+	}
+}
+
+func TestExchangeRejectsBadIDTokens(t *testing.T) {
+	for name, tamper := range map[string]func(jwt.MapClaims){
+		"other issuer":   func(claims jwt.MapClaims) { claims["iss"] = "https://evil.example.com" },
+		"other audience": func(claims jwt.MapClaims) { claims["aud"] = "someone-else" },
+		"shared audience without azp": func(claims jwt.MapClaims) {
+			claims["aud"] = []interface{}{"chat", "someone-else"}
+		},
+		"expired":        func(claims jwt.MapClaims) { claims["exp"] = time.Now().Add(-time.Minute).Unix() },
+		"no expiry":      func(claims jwt.MapClaims) { delete(claims, "exp") },
+		"issued later":   func(claims jwt.MapClaims) { claims["iat"] = time.Now().Add(time.Hour).Unix() },
+		"no subject":     func(claims jwt.MapClaims) { delete(claims, "sub") },
+		"replayed nonce": func(claims jwt.MapClaims) { claims["nonce"] = "nonce-0" },
+	} {
+		t.Run(name, func(t *testing.T) {
+			mock, provider := newProvider(t)
+			mock.Tamper = tamper
+
+			code := authorize(t, mock, provider, "nonce-1", "verifier-1")
+			if _, err := provider.Exchange(context.Background(), code, "verifier-1", "nonce-1"); !errors.Is(err, oidc.ErrInvalidToken) {
+				t.Fatalf("Exchange = %v, want %v", err, oidc.ErrInvalidToken)
+			}
+		})
+	}
+
+	// An audience list naming the client as the authorized party is fine.
+	mock, provider := newProvider(t)
+	mock.Tamper = func(claims jwt.MapClaims) {
+		claims["aud"] = []interface{}{"chat", "someone-else"}
+		claims["azp"] = "chat"
+	}
+	code := authorize(t, mock, provider, "nonce-1", "verifier-1")
+	if _, err := provider.Exchange(context.Background(), code, "verifier-1", "nonce-1"); err != nil {
+		t.Fatalf("Exchange with azp = %v", err)
+	}
+}
+
+func TestDiscoveryChecksIssuer(t *testing.T) {
+	mock, _ := newProvider(t)
+	provider := oidc.NewProvider(oidc.Config{Issuer: mock.Issuer() + "/", ClientID: "chat", RedirectURL: redirectURL}, http.DefaultClient)
+	if _, err := provider.AuthCodeURL(context.Background(), "state", "nonce", "verifier"); !errors.Is(err, oidc.ErrDiscovery) {
+		t.Fatalf("AuthCodeURL = %v, want %v", err, oidc.ErrDiscovery)
+	}
+}
@@ -0,0 +1,294 @@
+// Package oidc logs users in with an OpenID Connect provider using the
+// authorization code flow with PKCE.
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"graduation-thesis/pkg/jwks"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const (
+	keySetTTL        = time.Hour
+	keySetMinRefresh = time.Minute
+	// maxClockSkew is how far in the future an id token may have been issued.
+	maxClockSkew = time.Minute
+)
+
+var (
+	ErrDiscovery    = errors.New("oidc discovery failed")
+	ErrExchange     = errors.New("oidc code exchange failed")
+	ErrInvalidToken = errors.New("invalid id token")
+)
+
+type Config struct {
+	// Issuer is where the discovery document is, at
+	// Issuer/.well-known/openid-configuration.
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// Metadata is the part of the discovery document the flow uses.
+type Metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// IDToken is a verified id token.
+type IDToken struct {
+	Subject string
+	Claims  jwt.MapClaims
+}
+
+// String returns the claim name if it is a string.
+func (t *IDToken) String(name string) string {
+	value, _ := t.Claims[name].(string)
+	return value
+}
+
+// Bool returns the claim name if it is a boolean. Some providers send
+// booleans as strings.
+func (t *IDToken) Bool(name string) bool {
+	switch value := t.Claims[name].(type) {
+	case bool:
+		return value
+	case string:
+		return value == "true"
+	}
+	return false
+}
+
+// Provider is one identity provider. Its discovery document is fetched on
+// first use and its signing keys are cached like the authenticator's own.
+type Provider struct {
+	config Config
+	client *http.Client
+
+	mu       sync.Mutex
+	metadata *Metadata
+	verifier *jwks.Verifier
+}
+
+func NewProvider(config Config, client *http.Client) *Provider {
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"openid", "email", "profile"}
+	}
+	return &Provider{
+		config: config,
+		client: client,
+	}
+}
+
+func (p *Provider) discover(ctx context.Context) (*Metadata, *jwks.Verifier, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.metadata != nil {
+		return p.metadata, p.verifier, nil
+	}
+
+	discoveryURL := strings.TrimSuffix(p.config.Issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, discoveryURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrDiscovery, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("%w: status %d", ErrDiscovery, resp.StatusCode)
+	}
+
+	var metadata Metadata
+	if err := json.NewDecoder(resp.Body).Decode(&metadata); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrDiscovery, err)
+	}
+	// The document must be the issuer's own (OpenID Connect Discovery 4.3).
+	if metadata.Issuer != p.config.Issuer {
+		return nil, nil, fmt.Errorf("%w: issuer %q does not match %q", ErrDiscovery, metadata.Issuer, p.config.Issuer)
+	}
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JWKSURI == "" {
+		return nil, nil, fmt.Errorf("%w: incomplete metadata", ErrDiscovery)
+	}
+
+	p.metadata = &metadata
+	p.verifier = jwks.NewVerifier(keySetFetcher(p.client, metadata.JWKSURI), keySetTTL, keySetMinRefresh)
+	return p.metadata, p.verifier, nil
+}
+
+// keySetFetcher fills in the algorithm of keys published without one, which
+// some providers do, from the key type.
+func keySetFetcher(client *http.Client, jwksURI string) jwks.Fetcher {
+	fetch := jwks.HTTPFetcher(client, jwksURI)
+	return func(ctx context.Context) (*jwks.Set, error) {
+		set, err := fetch(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for i, key := range set.Keys {
+			if key.Algorithm != "" {
+				continue
+			}
+			switch {
+			case key.KeyType == "RSA":
+				set.Keys[i].Algorithm = jwks.AlgorithmRS256
+			case key.KeyType == "OKP" && key.Curve == "Ed25519":
+				set.Keys[i].Algorithm = jwks.AlgorithmEdDSA
+			}
+		}
+		return set, nil
+	}
+}
+
+// AuthCodeURL returns where to send the user to log in. The provider sends
+// the user back to the redirect url with state and a code to Exchange.
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, codeVerifier string) (string, error) {
+	metadata, _, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	authURL, err := url.Parse(metadata.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrDiscovery, err)
+	}
+	query := authURL.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", p.config.ClientID)
+	query.Set("redirect_uri", p.config.RedirectURL)
+	query.Set("scope", strings.Join(p.config.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", CodeChallenge(codeVerifier))
+	query.Set("code_challenge_method", "S256")
+	authURL.RawQuery = query.Encode()
+	return authURL.String(), nil
+}
+
+// Exchange redeems code and returns the id token that came with it, once it
+// is verified to be for this client and the login that sent nonce.
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*IDToken, error) {
+	metadata, verifier, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.config.RedirectURL)
+	form.Set("code_verifier", codeVerifier)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrExchange, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrExchange, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: status %d: %s", ErrExchange, resp.StatusCode, body)
+	}
+
+	var tokenResponse struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.Unmarshal(body, &tokenResponse); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrExchange, err)
+	}
+	if tokenResponse.IDToken == "" {
+		return nil, fmt.Errorf("%w: no id token", ErrExchange)
+	}
+
+	return p.verify(ctx, verifier, metadata, tokenResponse.IDToken, nonce)
+}
+
+// verify checks the id token as OpenID Connect Core 3.1.3.7 asks.
+func (p *Provider) verify(ctx context.Context, verifier *jwks.Verifier, metadata *Metadata, rawToken, nonce string) (*IDToken, error) {
+	claims, err := verifier.Parse(ctx, rawToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	if issuer, _ := claims["iss"].(string); issuer != metadata.Issuer {
+		return nil, fmt.Errorf("%w: issuer %q", ErrInvalidToken, issuer)
+	}
+	aud := audiences(claims)
+	if !contains(aud, p.config.ClientID) {
+		return nil, fmt.Errorf("%w: not for this client", ErrInvalidToken)
+	}
+	if len(aud) > 1 {
+		if party, _ := claims["azp"].(string); party != p.config.ClientID {
+			return nil, fmt.Errorf("%w: authorized party %q", ErrInvalidToken, party)
+		}
+	}
+	if _, ok := claims["exp"]; !ok {
+		return nil, fmt.Errorf("%w: no expiry", ErrInvalidToken)
+	}
+	if !claims.VerifyIssuedAt(time.Now().Add(maxClockSkew).Unix(), false) {
+		return nil, fmt.Errorf("%w: issued in the future", ErrInvalidToken)
+	}
+	if tokenNonce, _ := claims["nonce"].(string); tokenNonce == "" || tokenNonce != nonce {
+		return nil, fmt.Errorf("%w: nonce does not match", ErrInvalidToken)
+	}
+
+	idToken := &IDToken{Claims: claims}
+	idToken.Subject = idToken.String("sub")
+	if idToken.Subject == "" {
+		return nil, fmt.Errorf("%w: no subject", ErrInvalidToken)
+	}
+	return idToken, nil
+}
+
+// audiences returns the aud claim, which is a string or an array of strings.
+// jwt.MapClaims.VerifyAudience only knows the former.
+func audiences(claims jwt.MapClaims) []string {
+	switch aud := claims["aud"].(type) {
+	case string:
+		return []string{aud}
+	case []interface{}:
+		result := make([]string, 0, len(aud))
+		for _, value := range aud {
+			if s, ok := value.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,183 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: authenticator/authenticator.proto
+
+package authenticatorpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	AuthenticatorService_ValidateToken_FullMethodName        = "/authenticator.AuthenticatorService/ValidateToken"
+	AuthenticatorService_GetKeySet_FullMethodName            = "/authenticator.AuthenticatorService/GetKeySet"
+	AuthenticatorService_AuthenticateAPIToken_FullMethodName = "/authenticator.AuthenticatorService/AuthenticateAPIToken"
+)
+
+// AuthenticatorServiceClient is the client API for AuthenticatorService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type AuthenticatorServiceClient interface {
+	ValidateToken(ctx context.Context, in *ValidateTokenRequest, opts ...grpc.CallOption) (*ValidateTokenResponse, error)
+	GetKeySet(ctx context.Context, in *GetKeySetRequest, opts ...grpc.CallOption) (*GetKeySetResponse, error)
+	AuthenticateAPIToken(ctx context.Context, in *AuthenticateAPITokenRequest, opts ...grpc.CallOption) (*AuthenticateAPITokenResponse, error)
+}
+
+type authenticatorServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAuthenticatorServiceClient(cc grpc.ClientConnInterface) AuthenticatorServiceClient {
+	return &authenticatorServiceClient{cc}
+}
+
+func (c *authenticatorServiceClient) ValidateToken(ctx context.Context, in *ValidateTokenRequest, opts ...grpc.CallOption) (*ValidateTokenResponse, error) {
+	out := new(ValidateTokenResponse)
+	err := c.cc.Invoke(ctx, AuthenticatorService_ValidateToken_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authenticatorServiceClient) GetKeySet(ctx context.Context, in *GetKeySetRequest, opts ...grpc.CallOption) (*GetKeySetResponse, error) {
+	out := new(GetKeySetResponse)
+	err := c.cc.Invoke(ctx, AuthenticatorService_GetKeySet_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authenticatorServiceClient) AuthenticateAPIToken(ctx context.Context, in *AuthenticateAPITokenRequest, opts ...grpc.CallOption) (*AuthenticateAPITokenResponse, error) {
+	out := new(AuthenticateAPITokenResponse)
+	err := c.cc.Invoke(ctx, AuthenticatorService_AuthenticateAPIToken_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AuthenticatorServiceServer is the server API for AuthenticatorService service.
+// All implementations must embed UnimplementedAuthenticatorServiceServer
+// for forward compatibility
+type AuthenticatorServiceServer interface {
+	ValidateToken(context.Context, *ValidateTokenRequest) (*ValidateTokenResponse, error)
+	GetKeySet(context.Context, *GetKeySetRequest) (*GetKeySetResponse, error)
+	AuthenticateAPIToken(context.Context, *AuthenticateAPITokenRequest) (*AuthenticateAPITokenResponse, error)
+	mustEmbedUnimplementedAuthenticatorServiceServer()
+}
+
+// UnimplementedAuthenticatorServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedAuthenticatorServiceServer struct {
+}
+
+func (UnimplementedAuthenticatorServiceServer) ValidateToken(context.Context, *ValidateTokenRequest) (*ValidateTokenResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ValidateToken not implemented")
+}
+func (UnimplementedAuthenticatorServiceServer) GetKeySet(context.Context, *GetKeySetRequest) (*GetKeySetResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetKeySet not implemented")
+}
+func (UnimplementedAuthenticatorServiceServer) AuthenticateAPIToken(context.Context, *AuthenticateAPITokenRequest) (*AuthenticateAPITokenResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AuthenticateAPIToken not implemented")
+}
+func (UnimplementedAuthenticatorServiceServer) mustEmbedUnimplementedAuthenticatorServiceServer() {}
+
+// UnsafeAuthenticatorServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AuthenticatorServiceServer will
+// result in compilation errors.
+type UnsafeAuthenticatorServiceServer interface {
+	mustEmbedUnimplementedAuthenticatorServiceServer()
+}
+
+func RegisterAuthenticatorServiceServer(s grpc.ServiceRegistrar, srv AuthenticatorServiceServer) {
+	s.RegisterService(&AuthenticatorService_ServiceDesc, srv)
+}
+
+func _AuthenticatorService_ValidateToken_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ValidateTokenRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthenticatorServiceServer).ValidateToken(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AuthenticatorService_ValidateToken_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthenticatorServiceServer).ValidateToken(ctx, req.(*ValidateTokenRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AuthenticatorService_GetKeySet_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetKeySetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthenticatorServiceServer).GetKeySet(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AuthenticatorService_GetKeySet_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthenticatorServiceServer).GetKeySet(ctx, req.(*GetKeySetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AuthenticatorService_AuthenticateAPIToken_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AuthenticateAPITokenRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthenticatorServiceServer).AuthenticateAPIToken(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AuthenticatorService_AuthenticateAPIToken_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthenticatorServiceServer).AuthenticateAPIToken(ctx, req.(*AuthenticateAPITokenRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// AuthenticatorService_ServiceDesc is the grpc.ServiceDesc for AuthenticatorService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var AuthenticatorService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "authenticator.AuthenticatorService",
+	HandlerType: (*AuthenticatorServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ValidateToken",
+			Handler:    _AuthenticatorService_ValidateToken_Handler,
+		},
+		{
+			MethodName: "GetKeySet",
+			Handler:    _AuthenticatorService_GetKeySet_Handler,
+		},
+		{
+			MethodName: "AuthenticateAPIToken",
+			Handler:    _AuthenticatorService_AuthenticateAPIToken_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "authenticator/authenticator.proto",
+}
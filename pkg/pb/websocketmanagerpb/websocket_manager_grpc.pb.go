@@ -0,0 +1,221 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: websocket_manager/websocket_manager.proto
+
+package websocketmanagerpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	WebsocketManagerService_GetWebsocketHandlerOfUser_FullMethodName   = "/websocket_manager.WebsocketManagerService/GetWebsocketHandlerOfUser"
+	WebsocketManagerService_GetWebsocketHandlersOfUsers_FullMethodName = "/websocket_manager.WebsocketManagerService/GetWebsocketHandlersOfUsers"
+	WebsocketManagerService_Register_FullMethodName                    = "/websocket_manager.WebsocketManagerService/Register"
+	WebsocketManagerService_Ping_FullMethodName                        = "/websocket_manager.WebsocketManagerService/Ping"
+)
+
+// WebsocketManagerServiceClient is the client API for WebsocketManagerService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type WebsocketManagerServiceClient interface {
+	GetWebsocketHandlerOfUser(ctx context.Context, in *GetWebsocketHandlerOfUserRequest, opts ...grpc.CallOption) (*WebsocketHandler, error)
+	GetWebsocketHandlersOfUsers(ctx context.Context, in *GetWebsocketHandlersOfUsersRequest, opts ...grpc.CallOption) (*GetWebsocketHandlersOfUsersResponse, error)
+	Register(ctx context.Context, in *RegisterRequest, opts ...grpc.CallOption) (*WebsocketHandler, error)
+	Ping(ctx context.Context, in *PingRequest, opts ...grpc.CallOption) (*PingResponse, error)
+}
+
+type websocketManagerServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewWebsocketManagerServiceClient(cc grpc.ClientConnInterface) WebsocketManagerServiceClient {
+	return &websocketManagerServiceClient{cc}
+}
+
+func (c *websocketManagerServiceClient) GetWebsocketHandlerOfUser(ctx context.Context, in *GetWebsocketHandlerOfUserRequest, opts ...grpc.CallOption) (*WebsocketHandler, error) {
+	out := new(WebsocketHandler)
+	err := c.cc.Invoke(ctx, WebsocketManagerService_GetWebsocketHandlerOfUser_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *websocketManagerServiceClient) GetWebsocketHandlersOfUsers(ctx context.Context, in *GetWebsocketHandlersOfUsersRequest, opts ...grpc.CallOption) (*GetWebsocketHandlersOfUsersResponse, error) {
+	out := new(GetWebsocketHandlersOfUsersResponse)
+	err := c.cc.Invoke(ctx, WebsocketManagerService_GetWebsocketHandlersOfUsers_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *websocketManagerServiceClient) Register(ctx context.Context, in *RegisterRequest, opts ...grpc.CallOption) (*WebsocketHandler, error) {
+	out := new(WebsocketHandler)
+	err := c.cc.Invoke(ctx, WebsocketManagerService_Register_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *websocketManagerServiceClient) Ping(ctx context.Context, in *PingRequest, opts ...grpc.CallOption) (*PingResponse, error) {
+	out := new(PingResponse)
+	err := c.cc.Invoke(ctx, WebsocketManagerService_Ping_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// WebsocketManagerServiceServer is the server API for WebsocketManagerService service.
+// All implementations must embed UnimplementedWebsocketManagerServiceServer
+// for forward compatibility
+type WebsocketManagerServiceServer interface {
+	GetWebsocketHandlerOfUser(context.Context, *GetWebsocketHandlerOfUserRequest) (*WebsocketHandler, error)
+	GetWebsocketHandlersOfUsers(context.Context, *GetWebsocketHandlersOfUsersRequest) (*GetWebsocketHandlersOfUsersResponse, error)
+	Register(context.Context, *RegisterRequest) (*WebsocketHandler, error)
+	Ping(context.Context, *PingRequest) (*PingResponse, error)
+	mustEmbedUnimplementedWebsocketManagerServiceServer()
+}
+
+// UnimplementedWebsocketManagerServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedWebsocketManagerServiceServer struct {
+}
+
+func (UnimplementedWebsocketManagerServiceServer) GetWebsocketHandlerOfUser(context.Context, *GetWebsocketHandlerOfUserRequest) (*WebsocketHandler, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetWebsocketHandlerOfUser not implemented")
+}
+func (UnimplementedWebsocketManagerServiceServer) GetWebsocketHandlersOfUsers(context.Context, *GetWebsocketHandlersOfUsersRequest) (*GetWebsocketHandlersOfUsersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetWebsocketHandlersOfUsers not implemented")
+}
+func (UnimplementedWebsocketManagerServiceServer) Register(context.Context, *RegisterRequest) (*WebsocketHandler, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Register not implemented")
+}
+func (UnimplementedWebsocketManagerServiceServer) Ping(context.Context, *PingRequest) (*PingResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Ping not implemented")
+}
+func (UnimplementedWebsocketManagerServiceServer) mustEmbedUnimplementedWebsocketManagerServiceServer() {
+}
+
+// UnsafeWebsocketManagerServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to WebsocketManagerServiceServer will
+// result in compilation errors.
+type UnsafeWebsocketManagerServiceServer interface {
+	mustEmbedUnimplementedWebsocketManagerServiceServer()
+}
+
+func RegisterWebsocketManagerServiceServer(s grpc.ServiceRegistrar, srv WebsocketManagerServiceServer) {
+	s.RegisterService(&WebsocketManagerService_ServiceDesc, srv)
+}
+
+func _WebsocketManagerService_GetWebsocketHandlerOfUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetWebsocketHandlerOfUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WebsocketManagerServiceServer).GetWebsocketHandlerOfUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WebsocketManagerService_GetWebsocketHandlerOfUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WebsocketManagerServiceServer).GetWebsocketHandlerOfUser(ctx, req.(*GetWebsocketHandlerOfUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WebsocketManagerService_GetWebsocketHandlersOfUsers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetWebsocketHandlersOfUsersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WebsocketManagerServiceServer).GetWebsocketHandlersOfUsers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WebsocketManagerService_GetWebsocketHandlersOfUsers_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WebsocketManagerServiceServer).GetWebsocketHandlersOfUsers(ctx, req.(*GetWebsocketHandlersOfUsersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WebsocketManagerService_Register_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegisterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WebsocketManagerServiceServer).Register(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WebsocketManagerService_Register_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WebsocketManagerServiceServer).Register(ctx, req.(*RegisterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WebsocketManagerService_Ping_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WebsocketManagerServiceServer).Ping(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WebsocketManagerService_Ping_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WebsocketManagerServiceServer).Ping(ctx, req.(*PingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// WebsocketManagerService_ServiceDesc is the grpc.ServiceDesc for WebsocketManagerService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var WebsocketManagerService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "websocket_manager.WebsocketManagerService",
+	HandlerType: (*WebsocketManagerServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetWebsocketHandlerOfUser",
+			Handler:    _WebsocketManagerService_GetWebsocketHandlerOfUser_Handler,
+		},
+		{
+			MethodName: "GetWebsocketHandlersOfUsers",
+			Handler:    _WebsocketManagerService_GetWebsocketHandlersOfUsers_Handler,
+		},
+		{
+			MethodName: "Register",
+			Handler:    _WebsocketManagerService_Register_Handler,
+		},
+		{
+			MethodName: "Ping",
+			Handler:    _WebsocketManagerService_Ping_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "websocket_manager/websocket_manager.proto",
+}
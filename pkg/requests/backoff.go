@@ -0,0 +1,35 @@
+package request
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// backoff returns a random duration in [0, min(maxInterval, baseInterval*2^attempt)].
+func backoff(attempt int, baseInterval, maxInterval time.Duration) time.Duration {
+	if baseInterval <= 0 {
+		return 0
+	}
+
+	interval := baseInterval
+	for i := 0; i < attempt && (maxInterval <= 0 || interval < maxInterval); i++ {
+		interval *= 2
+	}
+	if maxInterval > 0 && interval > maxInterval {
+		interval = maxInterval
+	}
+	return time.Duration(rand.Int63n(int64(interval) + 1))
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
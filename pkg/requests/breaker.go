@@ -0,0 +1,84 @@
+package request
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	stateClosed = iota
+	stateOpen
+	stateHalfOpen
+)
+
+// circuitBreaker opens after threshold consecutive failures and lets a single
+// probe request through once cooldown has elapsed.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	state     int
+	failures  int
+	threshold int
+	cooldown  time.Duration
+	openedAt  time.Time
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		state:     stateClosed,
+		threshold: threshold,
+		cooldown:  cooldown,
+	}
+}
+
+func (b *circuitBreaker) allow() bool {
+	if b.threshold <= 0 {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case stateOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = stateHalfOpen
+		return true
+	case stateHalfOpen:
+		return false
+	}
+	return true
+}
+
+// record counts the outcome of a request. Failures the upstream isn't to blame
+// for, e.g. a 404, count as successes, except for the probe: the breaker only
+// closes again once a request has gone through.
+func (b *circuitBreaker) record(retryable, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if retryable || (b.state == stateHalfOpen && failed) {
+		b.failure()
+	} else {
+		b.success()
+	}
+}
+
+// success and failure expect b.mu to be held.
+func (b *circuitBreaker) success() {
+	b.state = stateClosed
+	b.failures = 0
+}
+
+func (b *circuitBreaker) failure() {
+	if b.threshold <= 0 {
+		return
+	}
+
+	b.failures++
+	if b.state == stateHalfOpen || b.failures >= b.threshold {
+		b.state = stateOpen
+		b.openedAt = time.Now()
+	}
+}
@@ -0,0 +1,241 @@
+package request
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"graduation-thesis/pkg/custom_error"
+	"graduation-thesis/pkg/model"
+)
+
+type Config struct {
+	// Timeout bounds a single attempt; the caller's context bounds the whole call.
+	Timeout          time.Duration
+	MaxRetries       int
+	BaseBackoff      time.Duration
+	MaxBackoff       time.Duration
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Timeout:          5 * time.Second,
+		MaxRetries:       3,
+		BaseBackoff:      100 * time.Millisecond,
+		MaxBackoff:       2 * time.Second,
+		BreakerThreshold: 5,
+		BreakerCooldown:  10 * time.Second,
+	}
+}
+
+type Client struct {
+	httpClient *http.Client
+	config     Config
+
+	mu       sync.Mutex
+	breakers map[string]*circuitBreaker
+}
+
+func NewClient(config Config) *Client {
+	return &Client{
+		httpClient: &http.Client{},
+		config:     config,
+		breakers:   make(map[string]*circuitBreaker),
+	}
+}
+
+type Request struct {
+	Method string
+	URL    string
+	// Body is encoded as JSON when it is not nil.
+	Body   interface{}
+	Token  string
+	Header http.Header
+	// Idempotent lets a POST or PATCH be retried. Other methods are idempotent
+	// by definition; a request that isn't is sent once, as a failed attempt
+	// may still have reached the upstream.
+	Idempotent bool
+}
+
+func (r Request) idempotent() bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return r.Idempotent
+}
+
+// Do sends req and decodes the result field of the success envelope into T.
+func Do[T any](ctx context.Context, c *Client, req Request) (T, error) {
+	var result T
+
+	raw, err := c.do(ctx, req)
+	if err != nil {
+		return result, err
+	}
+
+	if len(raw) == 0 || string(raw) == "null" {
+		return result, nil
+	}
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return result, fmt.Errorf("%w: decode result: %v", custom_error.ErrInternalServerError, err)
+	}
+	return result, nil
+}
+
+func Get[T any](ctx context.Context, c *Client, url, token string) (T, error) {
+	return Do[T](ctx, c, Request{Method: http.MethodGet, URL: url, Token: token})
+}
+
+func Post[T any](ctx context.Context, c *Client, url, token string, body interface{}) (T, error) {
+	return Do[T](ctx, c, Request{Method: http.MethodPost, URL: url, Token: token, Body: body})
+}
+
+func Put[T any](ctx context.Context, c *Client, url, token string, body interface{}) (T, error) {
+	return Do[T](ctx, c, Request{Method: http.MethodPut, URL: url, Token: token, Body: body})
+}
+
+func Delete[T any](ctx context.Context, c *Client, url, token string) (T, error) {
+	return Do[T](ctx, c, Request{Method: http.MethodDelete, URL: url, Token: token})
+}
+
+func (c *Client) do(ctx context.Context, req Request) (json.RawMessage, error) {
+	target, err := url.Parse(req.URL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", custom_error.ErrInvalidParameter, err)
+	}
+
+	var body []byte
+	if req.Body != nil {
+		body, err = json.Marshal(req.Body)
+		if err != nil {
+			return nil, fmt.Errorf("%w: encode body: %v", custom_error.ErrInvalidParameter, err)
+		}
+	}
+
+	breaker := c.getBreaker(target.Host)
+	for attempt := 0; ; attempt++ {
+		if !breaker.allow() {
+			return nil, fmt.Errorf("%w: %s: %w", custom_error.ErrConnectionErr, target.Host, ErrCircuitOpen)
+		}
+
+		result, retryable, err := c.attempt(ctx, req, body)
+		breaker.record(retryable, err != nil)
+		if err == nil || !retryable || !req.idempotent() || attempt >= c.config.MaxRetries {
+			return result, err
+		}
+
+		if sleepErr := sleep(ctx, backoff(attempt, c.config.BaseBackoff, c.config.MaxBackoff)); sleepErr != nil {
+			return nil, contextError(sleepErr)
+		}
+	}
+}
+
+// attempt performs a single round trip. The boolean reports whether the failure
+// is worth retrying: transport errors, per-attempt timeouts and 429/502/503/504
+// without a Retry-After.
+func (c *Client) attempt(ctx context.Context, req Request, body []byte) (json.RawMessage, bool, error) {
+	attemptCtx := ctx
+	if c.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		attemptCtx, cancel = context.WithTimeout(ctx, c.config.Timeout)
+		defer cancel()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	httpReq, err := http.NewRequestWithContext(attemptCtx, req.Method, req.URL, reader)
+	if err != nil {
+		return nil, false, fmt.Errorf("%w: %v", custom_error.ErrInvalidParameter, err)
+	}
+	for key, values := range req.Header {
+		for _, value := range values {
+			httpReq.Header.Add(key, value)
+		}
+	}
+	httpReq.Header.Set("Accept", "application/json")
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	if req.Token != "" {
+		httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", req.Token))
+	}
+
+	res, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, false, contextError(ctx.Err())
+		}
+		if errors.Is(err, os.ErrDeadlineExceeded) || errors.Is(err, context.DeadlineExceeded) {
+			return nil, true, fmt.Errorf("%w: %v", custom_error.ErrTimeout, err)
+		}
+		return nil, true, fmt.Errorf("%w: %v", custom_error.ErrConnectionErr, err)
+	}
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, false, contextError(ctx.Err())
+		}
+		return nil, true, fmt.Errorf("%w: read body: %v", custom_error.ErrConnectionErr, err)
+	}
+
+	if res.StatusCode < 400 {
+		// 204 and the like carry no envelope.
+		if len(bytes.TrimSpace(resBody)) == 0 {
+			return nil, false, nil
+		}
+		var successResponse struct {
+			Result json.RawMessage `json:"result"`
+		}
+		if err := json.Unmarshal(resBody, &successResponse); err != nil {
+			return nil, false, fmt.Errorf("%w: decode response: %v", custom_error.ErrInternalServerError, err)
+		}
+		return successResponse.Result, false, nil
+	}
+
+	var errorResponse model.ErrorResponse
+	message := string(bytes.TrimSpace(resBody))
+	if err := json.Unmarshal(resBody, &errorResponse); err == nil && errorResponse.ErrorMessage != "" {
+		message = errorResponse.ErrorMessage
+	}
+	responseError := newResponseError(res.StatusCode, message)
+	responseError.RetryAfter = parseRetryAfter(res.Header.Get("Retry-After"))
+	// An upstream that says when to come back is turning this request down,
+	// e.g. a locked out login, rather than failing, so it is neither retried
+	// nor counted against the breaker.
+	retryable := isRetryableStatus(res.StatusCode) && responseError.RetryAfter == 0
+	return nil, retryable, responseError
+}
+
+func (c *Client) getBreaker(host string) *circuitBreaker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	breaker, ok := c.breakers[host]
+	if !ok {
+		breaker = newCircuitBreaker(c.config.BreakerThreshold, c.config.BreakerCooldown)
+		c.breakers[host] = breaker
+	}
+	return breaker
+}
+
+func contextError(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %v", custom_error.ErrTimeout, err)
+	}
+	return fmt.Errorf("%w: %v", custom_error.ErrConnectionErr, err)
+}
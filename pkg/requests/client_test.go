@@ -0,0 +1,275 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"graduation-thesis/pkg/custom_error"
+)
+
+func testConfig() Config {
+	return Config{
+		Timeout:     time.Second,
+		MaxRetries:  2,
+		BaseBackoff: time.Millisecond,
+		MaxBackoff:  5 * time.Millisecond,
+	}
+}
+
+// newTestServer counts the requests it gets and answers them with handler.
+func newTestServer(t *testing.T, handler http.HandlerFunc) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	hits := &atomic.Int32{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server, hits
+}
+
+func respond(w http.ResponseWriter, status int, body string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write([]byte(body))
+}
+
+// dropConnection fails the request at the transport level.
+func dropConnection(w http.ResponseWriter, r *http.Request) {
+	conn, _, err := w.(http.Hijacker).Hijack()
+	if err == nil {
+		conn.Close()
+	}
+}
+
+func TestRetriesUntilSuccess(t *testing.T) {
+	attempts := &atomic.Int32{}
+	server, hits := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			respond(w, http.StatusUnauthorized, `{"error_message":"no token"}`)
+			return
+		}
+		if attempts.Add(1) < 3 {
+			respond(w, http.StatusServiceUnavailable, "")
+			return
+		}
+		respond(w, http.StatusOK, `{"status":200,"result":"ok"}`)
+	})
+
+	result, err := Get[string](context.Background(), NewClient(testConfig()), server.URL, "token")
+	if err != nil || result != "ok" {
+		t.Fatalf("Get = %q, %v", result, err)
+	}
+	if hits.Load() != 3 {
+		t.Fatalf("sent %d requests, want 3", hits.Load())
+	}
+}
+
+func TestGivesUpAfterMaxRetries(t *testing.T) {
+	server, hits := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		respond(w, http.StatusBadGateway, `{"error_message":"upstream down"}`)
+	})
+
+	_, err := Get[string](context.Background(), NewClient(testConfig()), server.URL, "")
+	var responseError *ResponseError
+	if !errors.As(err, &responseError) || responseError.StatusCode != http.StatusBadGateway || responseError.Message != "upstream down" {
+		t.Fatalf("Get = %v", err)
+	}
+	if hits.Load() != 3 {
+		t.Fatalf("sent %d requests, want 3", hits.Load())
+	}
+}
+
+func TestDoesNotRetryRejectedRequests(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		status int
+		header string
+		want   error
+	}{
+		{"not found", http.StatusNotFound, "", custom_error.ErrNotFound},
+		{"conflict", http.StatusConflict, "", custom_error.ErrConflict},
+		{"retry after", http.StatusTooManyRequests, "3", custom_error.ErrTooManyRequests},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			server, hits := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				if test.header != "" {
+					w.Header().Set("Retry-After", test.header)
+				}
+				respond(w, test.status, `{"error_message":"rejected"}`)
+			})
+
+			_, err := Get[string](context.Background(), NewClient(testConfig()), server.URL, "")
+			if Cause(err) != test.want || err.Error() != "rejected" {
+				t.Fatalf("Get = %v, want %v", err, test.want)
+			}
+			if hits.Load() != 1 {
+				t.Fatalf("sent %d requests, want 1", hits.Load())
+			}
+			if test.header != "" && RetryAfter(err) != 3*time.Second {
+				t.Fatalf("RetryAfter = %v", RetryAfter(err))
+			}
+		})
+	}
+}
+
+func TestRetriesOnlyIdempotentRequests(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		req  Request
+		want int32
+	}{
+		{"get", Request{Method: http.MethodGet}, 3},
+		{"put", Request{Method: http.MethodPut, Body: map[string]string{"name": "a"}}, 3},
+		{"post", Request{Method: http.MethodPost, Body: map[string]string{"name": "a"}}, 1},
+		{"idempotent post", Request{Method: http.MethodPost, Body: map[string]string{"name": "a"}, Idempotent: true}, 3},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			server, hits := newTestServer(t, dropConnection)
+			test.req.URL = server.URL
+
+			_, err := Do[string](context.Background(), NewClient(testConfig()), test.req)
+			if !errors.Is(err, custom_error.ErrConnectionErr) {
+				t.Fatalf("Do = %v", err)
+			}
+			if hits.Load() != test.want {
+				t.Fatalf("sent %d requests, want %d", hits.Load(), test.want)
+			}
+		})
+	}
+}
+
+func TestAttemptTimeout(t *testing.T) {
+	server, hits := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	})
+	config := testConfig()
+	config.Timeout = 20 * time.Millisecond
+
+	_, err := Get[string](context.Background(), NewClient(config), server.URL, "")
+	if !errors.Is(err, custom_error.ErrTimeout) {
+		t.Fatalf("Get = %v", err)
+	}
+	if hits.Load() != 3 {
+		t.Fatalf("sent %d requests, want every attempt to time out", hits.Load())
+	}
+}
+
+func TestContextBoundsRetries(t *testing.T) {
+	server, hits := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		respond(w, http.StatusServiceUnavailable, "")
+	})
+	config := testConfig()
+	config.BaseBackoff = time.Minute
+	config.MaxBackoff = time.Minute
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// A backoff is drawn from [0, BaseBackoff], so a few short ones may fit in
+	// ctx, but the call can't outlive it.
+	start := time.Now()
+	_, err := Get[string](ctx, NewClient(config), server.URL, "")
+	if !errors.Is(err, custom_error.ErrTimeout) && !errors.Is(err, custom_error.ErrConnectionErr) {
+		t.Fatalf("Get = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Get returned after %v, want it to give up with ctx", elapsed)
+	}
+	if hits.Load() > 3 {
+		t.Fatalf("sent %d requests", hits.Load())
+	}
+}
+
+func TestEmptyResponse(t *testing.T) {
+	server, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	result, err := Delete[map[string]string](context.Background(), NewClient(testConfig()), server.URL, "")
+	if err != nil || result != nil {
+		t.Fatalf("Delete = %v, %v", result, err)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	base, max := 10*time.Millisecond, 100*time.Millisecond
+	for attempt := 0; attempt < 10; attempt++ {
+		limit := base << attempt
+		if limit > max {
+			limit = max
+		}
+		for i := 0; i < 100; i++ {
+			if d := backoff(attempt, base, max); d < 0 || d > limit {
+				t.Fatalf("backoff(%d) = %v, want at most %v", attempt, d, limit)
+			}
+		}
+	}
+	if d := backoff(3, 0, max); d != 0 {
+		t.Fatalf("backoff without a base = %v", d)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	status := &atomic.Int32{}
+	status.Store(http.StatusServiceUnavailable)
+	server, hits := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		respond(w, int(status.Load()), `{"status":200,"result":"ok"}`)
+	})
+	config := testConfig()
+	config.MaxRetries = 0
+	config.BreakerThreshold = 2
+	config.BreakerCooldown = 50 * time.Millisecond
+	client := NewClient(config)
+	ctx := context.Background()
+
+	get := func() error {
+		_, err := Get[string](ctx, client, server.URL, "")
+		return err
+	}
+	expectOpen := func() {
+		t.Helper()
+		before := hits.Load()
+		if err := get(); !errors.Is(err, ErrCircuitOpen) || !errors.Is(err, custom_error.ErrConnectionErr) {
+			t.Fatalf("Get with the breaker open = %v", err)
+		}
+		if hits.Load() != before {
+			t.Fatal("the breaker let a request through")
+		}
+	}
+
+	get()
+	get()
+	expectOpen()
+
+	// A probe that fails for any reason opens the breaker again.
+	status.Store(http.StatusNotFound)
+	time.Sleep(config.BreakerCooldown)
+	if err := get(); !errors.Is(err, custom_error.ErrNotFound) {
+		t.Fatalf("probe = %v", err)
+	}
+	expectOpen()
+
+	status.Store(http.StatusOK)
+	time.Sleep(config.BreakerCooldown)
+	for i := 0; i < 3; i++ {
+		if err := get(); err != nil {
+			t.Fatalf("Get after the upstream recovered = %v", err)
+		}
+	}
+
+	// Once closed, failures the upstream isn't to blame for don't count.
+	status.Store(http.StatusNotFound)
+	for i := 0; i < 3; i++ {
+		if err := get(); !errors.Is(err, custom_error.ErrNotFound) {
+			t.Fatalf("Get = %v", err)
+		}
+	}
+}
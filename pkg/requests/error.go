@@ -0,0 +1,108 @@
+package request
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"graduation-thesis/pkg/custom_error"
+)
+
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// ResponseError keeps the upstream status and message instead of collapsing
+// them into a bare custom_error value. Unwrap returns the matching custom_error.
+type ResponseError struct {
+	StatusCode int
+	Message    string
+	Err        error
+	// RetryAfter is taken from the Retry-After header, zero if there is none.
+	RetryAfter time.Duration
+}
+
+func (e *ResponseError) Error() string {
+	if e.Message == "" {
+		return e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *ResponseError) Unwrap() error {
+	return e.Err
+}
+
+// Cause returns the custom_error value behind err so it can be used as a key of
+// custom_error.MappingError.
+func Cause(err error) error {
+	for _, target := range []error{
+		custom_error.ErrTimeout,
+		custom_error.ErrNotFound,
+		custom_error.ErrNoPermission,
+		custom_error.ErrConnectionErr,
+		custom_error.ErrInvalidParameter,
+		custom_error.ErrInternalServerError,
+		custom_error.ErrConflict,
+		custom_error.ErrEntityTooLarge,
+		custom_error.ErrTooManyRequests,
+	} {
+		if errors.Is(err, target) {
+			return target
+		}
+	}
+	return custom_error.ErrUnknown
+}
+
+func newResponseError(statusCode int, message string) *ResponseError {
+	err, ok := custom_error.MappingStatusError()[statusCode]
+	if !ok {
+		switch {
+		case statusCode == http.StatusTooManyRequests || statusCode == http.StatusBadGateway:
+			err = custom_error.ErrConnectionErr
+		case statusCode == http.StatusForbidden:
+			err = custom_error.ErrNoPermission
+		case statusCode >= 400 && statusCode < 500:
+			err = custom_error.ErrInvalidParameter
+		default:
+			err = custom_error.ErrUnknown
+		}
+	}
+	if message == "" {
+		message = fmt.Sprintf("upstream responded with status %d", statusCode)
+	}
+	return &ResponseError{
+		StatusCode: statusCode,
+		Message:    message,
+		Err:        err,
+	}
+}
+
+// RetryAfter returns how long the upstream asked to wait before trying again.
+func RetryAfter(err error) time.Duration {
+	var responseError *ResponseError
+	if errors.As(err, &responseError) {
+		return responseError.RetryAfter
+	}
+	return 0
+}
+
+// parseRetryAfter only understands the delay-seconds form of Retry-After.
+func parseRetryAfter(value string) time.Duration {
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+func isRetryableStatus(statusCode int) bool {
+	switch statusCode {
+	case http.StatusTooManyRequests,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
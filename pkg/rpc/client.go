@@ -0,0 +1,61 @@
+package rpc
+
+import (
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+)
+
+// retryServiceConfig lets grpc retry calls that failed before reaching the
+// server, so callers do not need to hand-roll retry loops.
+const retryServiceConfig = `{
+	"methodConfig": [{
+		"name": [{}],
+		"retryPolicy": {
+			"maxAttempts": 5,
+			"initialBackoff": "0.2s",
+			"maxBackoff": "2s",
+			"backoffMultiplier": 2,
+			"retryableStatusCodes": ["UNAVAILABLE"]
+		}
+	}]
+}`
+
+var (
+	mu          sync.Mutex
+	clientConns = make(map[string]*grpc.ClientConn)
+)
+
+func newClientConn(target string) *grpc.ClientConn {
+	conn, err := grpc.Dial(
+		target,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultServiceConfig(retryServiceConfig),
+		grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                30 * time.Second,
+			Timeout:             10 * time.Second,
+			PermitWithoutStream: true,
+		}),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	return conn
+}
+
+// GetClientConn returns the shared connection to target, dialing it on first use.
+func GetClientConn(target string) *grpc.ClientConn {
+	mu.Lock()
+	defer mu.Unlock()
+	if conn, ok := clientConns[target]; ok {
+		return conn
+	}
+
+	conn := newClientConn(target)
+	clientConns[target] = conn
+	return conn
+}
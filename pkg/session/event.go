@@ -0,0 +1,10 @@
+package session
+
+// Event is published by the authenticator when sessions are revoked. It is
+// keyed by UserID so that a user's revocations stay ordered within a
+// partition.
+type Event struct {
+	UserID     string   `json:"user_id"`
+	SessionIDs []string `json:"session_ids"`
+	Timestamp  int64    `json:"timestamp"`
+}
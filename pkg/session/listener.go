@@ -0,0 +1,38 @@
+package session
+
+import (
+	"encoding/json"
+
+	"graduation-thesis/pkg/logger"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// Listen calls revoke for every revocation event. Every instance needs its own
+// consumer group so that each one sees all events.
+func Listen(consumer *kafka.Consumer, topic string, revoke func(Event), logger logger.Logger, done <-chan struct{}) error {
+	if err := consumer.SubscribeTopics([]string{topic}, nil); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-done:
+			return nil
+		default:
+		}
+
+		event := consumer.Poll(100)
+		switch e := event.(type) {
+		case *kafka.Message:
+			var sessionEvent Event
+			if err := json.Unmarshal(e.Value, &sessionEvent); err != nil {
+				logger.Errorf("[Session] Cannot unmarshal event at %d[%d]: %v", e.TopicPartition.Partition, e.TopicPartition.Offset, err)
+				continue
+			}
+			revoke(sessionEvent)
+		case kafka.Error:
+			logger.Errorf("[Session] Error: %v", e)
+		}
+	}
+}
@@ -13,3 +13,33 @@ func NewKafkaConsumer(bootstrapServers, groupID string) *kafka.Consumer {
 
 	return consumer
 }
+
+// NewKafkaManualCommitConsumer leaves committing offsets to the caller, so a
+// message is only marked as consumed once it has really been handled.
+func NewKafkaManualCommitConsumer(bootstrapServers, groupID string) *kafka.Consumer {
+	consumer, err := kafka.NewConsumer(&kafka.ConfigMap{
+		"bootstrap.servers":  bootstrapServers,
+		"group.id":           groupID,
+		"auto.offset.reset":  "smallest",
+		"enable.auto.commit": false})
+	if err != nil {
+		panic(err)
+	}
+
+	return consumer
+}
+
+// NewKafkaBroadcastConsumer starts from the latest offset and never commits,
+// for consumers that only care about events produced while they are running.
+func NewKafkaBroadcastConsumer(bootstrapServers, groupID string) *kafka.Consumer {
+	consumer, err := kafka.NewConsumer(&kafka.ConfigMap{
+		"bootstrap.servers":  bootstrapServers,
+		"group.id":           groupID,
+		"auto.offset.reset":  "latest",
+		"enable.auto.commit": false})
+	if err != nil {
+		panic(err)
+	}
+
+	return consumer
+}
@@ -0,0 +1,904 @@
+// Package redistest provides an in-memory stand-in for a Redis server so that
+// code built on go-redis can be tested without a running Redis.
+package redistest
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Server speaks enough of RESP2 to serve the strings, sets, hashes, expiry and
+// MULTI/EXEC/WATCH commands the services use. Unknown commands get an error
+// reply.
+type Server struct {
+	listener net.Listener
+
+	mu       sync.Mutex
+	strings  map[string]string
+	sets     map[string]map[string]struct{}
+	hashes   map[string]map[string]string
+	expires  map[string]time.Time
+	versions map[string]uint64
+	offset   time.Duration
+
+	wg     sync.WaitGroup
+	closed chan struct{}
+}
+
+// NewServer starts a server on a random local port.
+func NewServer() (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		listener: listener,
+		strings:  make(map[string]string),
+		sets:     make(map[string]map[string]struct{}),
+		hashes:   make(map[string]map[string]string),
+		expires:  make(map[string]time.Time),
+		versions: make(map[string]uint64),
+		closed:   make(chan struct{}),
+	}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// NewClient starts a server and returns a client connected to it. Both are
+// closed when the test ends.
+func NewClient(t testing.TB) (*redis.Client, *Server) {
+	t.Helper()
+	s, err := NewServer()
+	if err != nil {
+		t.Fatalf("redistest: %v", err)
+	}
+	client := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	t.Cleanup(func() {
+		client.Close()
+		s.Close()
+	})
+	return client, s
+}
+
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *Server) Close() {
+	close(s.closed)
+	s.listener.Close()
+	s.wg.Wait()
+}
+
+// FastForward moves the server clock forward so keys expire without waiting.
+func (s *Server) FastForward(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.offset += d
+}
+
+// Exists reports whether key is set and not expired.
+func (s *Server) Exists(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.exists(key)
+}
+
+// Keys returns the live keys in sorted order.
+func (s *Server) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var keys []string
+	for key := range s.strings {
+		if s.exists(key) {
+			keys = append(keys, key)
+		}
+	}
+	for key := range s.sets {
+		if s.exists(key) {
+			keys = append(keys, key)
+		}
+	}
+	for key := range s.hashes {
+		if s.exists(key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+		}()
+	}
+}
+
+type reply interface{}
+
+type (
+	status     string
+	errorReply string
+	nilReply   struct{}
+	nilArray   struct{}
+)
+
+type session struct {
+	queue   [][]string
+	inMulti bool
+	watched map[string]uint64
+	dirty   bool
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	go func() {
+		<-s.closed
+		conn.Close()
+	}()
+
+	reader := bufio.NewReader(conn)
+	writer := bufio.NewWriter(conn)
+	sess := &session{}
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+		writeReply(writer, s.dispatch(sess, args))
+		if reader.Buffered() == 0 {
+			if err := writer.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *Server) dispatch(sess *session, args []string) reply {
+	if len(args) == 0 {
+		return errorReply("ERR empty command")
+	}
+	name := strings.ToUpper(args[0])
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch name {
+	case "MULTI":
+		if sess.inMulti {
+			return errorReply("ERR MULTI calls can not be nested")
+		}
+		sess.inMulti = true
+		sess.queue = nil
+		return status("OK")
+	case "DISCARD":
+		if !sess.inMulti {
+			return errorReply("ERR DISCARD without MULTI")
+		}
+		sess.reset()
+		return status("OK")
+	case "EXEC":
+		if !sess.inMulti {
+			return errorReply("ERR EXEC without MULTI")
+		}
+		defer sess.reset()
+		if sess.dirty || s.watchBroken(sess) {
+			return nilArray{}
+		}
+		replies := make([]reply, 0, len(sess.queue))
+		for _, queued := range sess.queue {
+			replies = append(replies, s.execute(strings.ToUpper(queued[0]), queued[1:]))
+		}
+		return replies
+	case "WATCH":
+		if sess.inMulti {
+			return errorReply("ERR WATCH inside MULTI is not allowed")
+		}
+		if sess.watched == nil {
+			sess.watched = make(map[string]uint64)
+		}
+		for _, key := range args[1:] {
+			s.exists(key)
+			sess.watched[key] = s.versions[key]
+		}
+		return status("OK")
+	case "UNWATCH":
+		sess.watched = nil
+		return status("OK")
+	}
+
+	if sess.inMulti {
+		if _, ok := commands[name]; !ok {
+			sess.dirty = true
+			return errorReply(fmt.Sprintf("ERR unknown command '%s'", args[0]))
+		}
+		sess.queue = append(sess.queue, args)
+		return status("QUEUED")
+	}
+	return s.execute(name, args[1:])
+}
+
+func (sess *session) reset() {
+	sess.inMulti = false
+	sess.dirty = false
+	sess.queue = nil
+	sess.watched = nil
+}
+
+func (s *Server) watchBroken(sess *session) bool {
+	for key, version := range sess.watched {
+		s.exists(key)
+		if s.versions[key] != version {
+			return true
+		}
+	}
+	return false
+}
+
+type command func(s *Server, args []string) reply
+
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"PING":      cmdPing,
+		"CLIENT":    cmdOK,
+		"SELECT":    cmdOK,
+		"FLUSHALL":  cmdFlushAll,
+		"GET":       cmdGet,
+		"SET":       cmdSet,
+		"GETDEL":    cmdGetDel,
+		"MGET":      cmdMGet,
+		"INCR":      cmdIncr,
+		"DEL":       cmdDel,
+		"EXISTS":    cmdExists,
+		"EXPIRE":    cmdExpire,
+		"EXPIREAT":  cmdExpireAt,
+		"TTL":       cmdTTL,
+		"SADD":      cmdSAdd,
+		"SREM":      cmdSRem,
+		"SMEMBERS":  cmdSMembers,
+		"SISMEMBER": cmdSIsMember,
+		"SCARD":     cmdSCard,
+		"SPOP":      cmdSPop,
+		"HSET":      cmdHSet,
+		"HGET":      cmdHGet,
+		"HGETALL":   cmdHGetAll,
+		"HDEL":      cmdHDel,
+		"HINCRBY":   cmdHIncrBy,
+	}
+}
+
+func (s *Server) execute(name string, args []string) reply {
+	cmd, ok := commands[name]
+	if !ok {
+		// HELLO lands here as well, which makes go-redis fall back to RESP2.
+		return errorReply(fmt.Sprintf("ERR unknown command '%s'", strings.ToLower(name)))
+	}
+	return cmd(s, args)
+}
+
+func (s *Server) now() time.Time {
+	return time.Now().Add(s.offset)
+}
+
+// exists drops key if it has expired and reports whether it is still set.
+func (s *Server) exists(key string) bool {
+	if expiresAt, ok := s.expires[key]; ok && !s.now().Before(expiresAt) {
+		s.delete(key)
+	}
+	_, isString := s.strings[key]
+	_, isSet := s.sets[key]
+	_, isHash := s.hashes[key]
+	return isString || isSet || isHash
+}
+
+func (s *Server) delete(key string) bool {
+	_, isString := s.strings[key]
+	_, isSet := s.sets[key]
+	_, isHash := s.hashes[key]
+	delete(s.strings, key)
+	delete(s.sets, key)
+	delete(s.hashes, key)
+	delete(s.expires, key)
+	existed := isString || isSet || isHash
+	if existed {
+		s.touch(key)
+	}
+	return existed
+}
+
+func (s *Server) touch(key string) {
+	s.versions[key]++
+}
+
+func (s *Server) set(key string) (map[string]struct{}, reply) {
+	if !s.exists(key) {
+		return nil, nil
+	}
+	set, ok := s.sets[key]
+	if !ok {
+		return nil, wrongType
+	}
+	return set, nil
+}
+
+var wrongType = errorReply("WRONGTYPE Operation against a key holding the wrong kind of value")
+
+func wrongArgs(name string) reply {
+	return errorReply(fmt.Sprintf("ERR wrong number of arguments for '%s' command", name))
+}
+
+func cmdPing(_ *Server, args []string) reply {
+	if len(args) > 0 {
+		return args[0]
+	}
+	return status("PONG")
+}
+
+func cmdOK(_ *Server, _ []string) reply {
+	return status("OK")
+}
+
+func cmdFlushAll(s *Server, _ []string) reply {
+	for key := range s.strings {
+		s.delete(key)
+	}
+	for key := range s.sets {
+		s.delete(key)
+	}
+	for key := range s.hashes {
+		s.delete(key)
+	}
+	return status("OK")
+}
+
+func cmdGet(s *Server, args []string) reply {
+	if len(args) != 1 {
+		return wrongArgs("get")
+	}
+	if !s.exists(args[0]) {
+		return nilReply{}
+	}
+	value, ok := s.strings[args[0]]
+	if !ok {
+		return wrongType
+	}
+	return value
+}
+
+// cmdMGet answers nil for keys that are missing or do not hold a string.
+func cmdMGet(s *Server, args []string) reply {
+	if len(args) == 0 {
+		return wrongArgs("mget")
+	}
+	values := make([]reply, 0, len(args))
+	for _, key := range args {
+		value, ok := s.strings[key]
+		if !s.exists(key) || !ok {
+			values = append(values, nilReply{})
+			continue
+		}
+		values = append(values, value)
+	}
+	return values
+}
+
+func cmdSet(s *Server, args []string) reply {
+	if len(args) < 2 {
+		return wrongArgs("set")
+	}
+	key, value := args[0], args[1]
+
+	var (
+		expiresAt   time.Time
+		nx, xx, get bool
+		keepTTL     bool
+	)
+	for i := 2; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "NX":
+			nx = true
+		case "XX":
+			xx = true
+		case "GET":
+			get = true
+		case "KEEPTTL":
+			keepTTL = true
+		case "EX", "PX":
+			if i+1 >= len(args) {
+				return errorReply("ERR syntax error")
+			}
+			n, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil || n <= 0 {
+				return errorReply("ERR invalid expire time in 'set' command")
+			}
+			unit := time.Second
+			if strings.ToUpper(args[i]) == "PX" {
+				unit = time.Millisecond
+			}
+			expiresAt = s.now().Add(time.Duration(n) * unit)
+			i++
+		default:
+			return errorReply("ERR syntax error")
+		}
+	}
+
+	exists := s.exists(key)
+	var old reply = nilReply{}
+	if exists {
+		previous, ok := s.strings[key]
+		if !ok && get {
+			return wrongType
+		}
+		if ok {
+			old = previous
+		}
+	}
+	if (nx && exists) || (xx && !exists) {
+		if get {
+			return old
+		}
+		return nilReply{}
+	}
+
+	previousExpiry, hadExpiry := s.expires[key]
+	s.delete(key)
+	s.strings[key] = value
+	if !expiresAt.IsZero() {
+		s.expires[key] = expiresAt
+	} else if keepTTL && hadExpiry {
+		s.expires[key] = previousExpiry
+	}
+	s.touch(key)
+
+	if get {
+		return old
+	}
+	return status("OK")
+}
+
+func cmdGetDel(s *Server, args []string) reply {
+	if len(args) != 1 {
+		return wrongArgs("getdel")
+	}
+	value := cmdGet(s, args)
+	if _, ok := value.(string); ok {
+		s.delete(args[0])
+	}
+	return value
+}
+
+func cmdIncr(s *Server, args []string) reply {
+	if len(args) != 1 {
+		return wrongArgs("incr")
+	}
+	key := args[0]
+
+	var n int64
+	if s.exists(key) {
+		value, ok := s.strings[key]
+		if !ok {
+			return wrongType
+		}
+		var err error
+		if n, err = strconv.ParseInt(value, 10, 64); err != nil {
+			return errorReply("ERR value is not an integer or out of range")
+		}
+	}
+	n++
+	s.strings[key] = strconv.FormatInt(n, 10)
+	s.touch(key)
+	return n
+}
+
+func cmdDel(s *Server, args []string) reply {
+	if len(args) == 0 {
+		return wrongArgs("del")
+	}
+	var deleted int64
+	for _, key := range args {
+		if s.exists(key) && s.delete(key) {
+			deleted++
+		}
+	}
+	return deleted
+}
+
+func cmdExists(s *Server, args []string) reply {
+	if len(args) == 0 {
+		return wrongArgs("exists")
+	}
+	var count int64
+	for _, key := range args {
+		if s.exists(key) {
+			count++
+		}
+	}
+	return count
+}
+
+func cmdExpire(s *Server, args []string) reply {
+	if len(args) != 2 {
+		return wrongArgs("expire")
+	}
+	seconds, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return errorReply("ERR value is not an integer or out of range")
+	}
+	return s.expireAt(args[0], s.now().Add(time.Duration(seconds)*time.Second))
+}
+
+func cmdExpireAt(s *Server, args []string) reply {
+	if len(args) != 2 {
+		return wrongArgs("expireat")
+	}
+	unix, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return errorReply("ERR value is not an integer or out of range")
+	}
+	return s.expireAt(args[0], time.Unix(unix, 0))
+}
+
+func (s *Server) expireAt(key string, expiresAt time.Time) reply {
+	if !s.exists(key) {
+		return int64(0)
+	}
+	s.expires[key] = expiresAt
+	s.touch(key)
+	s.exists(key)
+	return int64(1)
+}
+
+func cmdTTL(s *Server, args []string) reply {
+	if len(args) != 1 {
+		return wrongArgs("ttl")
+	}
+	if !s.exists(args[0]) {
+		return int64(-2)
+	}
+	expiresAt, ok := s.expires[args[0]]
+	if !ok {
+		return int64(-1)
+	}
+	return int64(expiresAt.Sub(s.now()).Round(time.Second) / time.Second)
+}
+
+func cmdSAdd(s *Server, args []string) reply {
+	if len(args) < 2 {
+		return wrongArgs("sadd")
+	}
+	key := args[0]
+	set, errReply := s.set(key)
+	if errReply != nil {
+		return errReply
+	}
+	if set == nil {
+		set = make(map[string]struct{})
+		s.sets[key] = set
+	}
+
+	var added int64
+	for _, member := range args[1:] {
+		if _, ok := set[member]; !ok {
+			set[member] = struct{}{}
+			added++
+		}
+	}
+	s.touch(key)
+	return added
+}
+
+func cmdSRem(s *Server, args []string) reply {
+	if len(args) < 2 {
+		return wrongArgs("srem")
+	}
+	key := args[0]
+	set, errReply := s.set(key)
+	if errReply != nil {
+		return errReply
+	}
+
+	var removed int64
+	for _, member := range args[1:] {
+		if _, ok := set[member]; ok {
+			delete(set, member)
+			removed++
+		}
+	}
+	if removed > 0 {
+		s.touch(key)
+	}
+	if set != nil && len(set) == 0 {
+		s.delete(key)
+	}
+	return removed
+}
+
+func cmdSMembers(s *Server, args []string) reply {
+	if len(args) != 1 {
+		return wrongArgs("smembers")
+	}
+	set, errReply := s.set(args[0])
+	if errReply != nil {
+		return errReply
+	}
+	members := make([]reply, 0, len(set))
+	for _, member := range sortedMembers(set) {
+		members = append(members, member)
+	}
+	return members
+}
+
+func cmdSIsMember(s *Server, args []string) reply {
+	if len(args) != 2 {
+		return wrongArgs("sismember")
+	}
+	set, errReply := s.set(args[0])
+	if errReply != nil {
+		return errReply
+	}
+	if _, ok := set[args[1]]; ok {
+		return int64(1)
+	}
+	return int64(0)
+}
+
+func cmdSCard(s *Server, args []string) reply {
+	if len(args) != 1 {
+		return wrongArgs("scard")
+	}
+	set, errReply := s.set(args[0])
+	if errReply != nil {
+		return errReply
+	}
+	return int64(len(set))
+}
+
+func cmdSPop(s *Server, args []string) reply {
+	if len(args) != 1 {
+		return wrongArgs("spop")
+	}
+	key := args[0]
+	set, errReply := s.set(key)
+	if errReply != nil {
+		return errReply
+	}
+	if len(set) == 0 {
+		return nilReply{}
+	}
+	member := sortedMembers(set)[0]
+	delete(set, member)
+	s.touch(key)
+	if len(set) == 0 {
+		s.delete(key)
+	}
+	return member
+}
+
+func (s *Server) hash(key string) (map[string]string, reply) {
+	if !s.exists(key) {
+		return nil, nil
+	}
+	hash, ok := s.hashes[key]
+	if !ok {
+		return nil, wrongType
+	}
+	return hash, nil
+}
+
+func cmdHSet(s *Server, args []string) reply {
+	if len(args) < 3 || len(args)%2 != 1 {
+		return wrongArgs("hset")
+	}
+	key := args[0]
+	hash, errReply := s.hash(key)
+	if errReply != nil {
+		return errReply
+	}
+	if hash == nil {
+		hash = make(map[string]string)
+		s.hashes[key] = hash
+	}
+
+	var added int64
+	for i := 1; i < len(args); i += 2 {
+		if _, ok := hash[args[i]]; !ok {
+			added++
+		}
+		hash[args[i]] = args[i+1]
+	}
+	s.touch(key)
+	return added
+}
+
+func cmdHGet(s *Server, args []string) reply {
+	if len(args) != 2 {
+		return wrongArgs("hget")
+	}
+	hash, errReply := s.hash(args[0])
+	if errReply != nil {
+		return errReply
+	}
+	value, ok := hash[args[1]]
+	if !ok {
+		return nilReply{}
+	}
+	return value
+}
+
+func cmdHGetAll(s *Server, args []string) reply {
+	if len(args) != 1 {
+		return wrongArgs("hgetall")
+	}
+	hash, errReply := s.hash(args[0])
+	if errReply != nil {
+		return errReply
+	}
+
+	fields := make([]string, 0, len(hash))
+	for field := range hash {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	values := make([]reply, 0, 2*len(hash))
+	for _, field := range fields {
+		values = append(values, field, hash[field])
+	}
+	return values
+}
+
+func cmdHDel(s *Server, args []string) reply {
+	if len(args) < 2 {
+		return wrongArgs("hdel")
+	}
+	key := args[0]
+	hash, errReply := s.hash(key)
+	if errReply != nil {
+		return errReply
+	}
+
+	var removed int64
+	for _, field := range args[1:] {
+		if _, ok := hash[field]; ok {
+			delete(hash, field)
+			removed++
+		}
+	}
+	if removed > 0 {
+		s.touch(key)
+	}
+	if hash != nil && len(hash) == 0 {
+		s.delete(key)
+	}
+	return removed
+}
+
+func cmdHIncrBy(s *Server, args []string) reply {
+	if len(args) != 3 {
+		return wrongArgs("hincrby")
+	}
+	key := args[0]
+	increment, err := strconv.ParseInt(args[2], 10, 64)
+	if err != nil {
+		return errorReply("ERR value is not an integer or out of range")
+	}
+	hash, errReply := s.hash(key)
+	if errReply != nil {
+		return errReply
+	}
+	if hash == nil {
+		hash = make(map[string]string)
+		s.hashes[key] = hash
+	}
+
+	var n int64
+	if value, ok := hash[args[1]]; ok {
+		if n, err = strconv.ParseInt(value, 10, 64); err != nil {
+			return errorReply("ERR hash value is not an integer")
+		}
+	}
+	n += increment
+	hash[args[1]] = strconv.FormatInt(n, 10)
+	s.touch(key)
+	return n
+}
+
+func sortedMembers(set map[string]struct{}) []string {
+	members := make([]string, 0, len(set))
+	for member := range set {
+		members = append(members, member)
+	}
+	sort.Strings(members)
+	return members
+}
+
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := readLine(reader)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 || line[0] != '*' {
+		return strings.Fields(line), nil
+	}
+
+	count, err := strconv.Atoi(line[1:])
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		header, err := readLine(reader)
+		if err != nil {
+			return nil, err
+		}
+		if len(header) == 0 || header[0] != '$' {
+			return nil, errors.New("redistest: expected bulk string")
+		}
+		size, err := strconv.Atoi(header[1:])
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, buf); err != nil {
+			return nil, err
+		}
+		args = append(args, string(buf[:size]))
+	}
+	return args, nil
+}
+
+func readLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func writeReply(w *bufio.Writer, r reply) {
+	switch v := r.(type) {
+	case status:
+		fmt.Fprintf(w, "+%s\r\n", v)
+	case errorReply:
+		fmt.Fprintf(w, "-%s\r\n", v)
+	case int64:
+		fmt.Fprintf(w, ":%d\r\n", v)
+	case string:
+		fmt.Fprintf(w, "$%d\r\n%s\r\n", len(v), v)
+	case nilReply:
+		w.WriteString("$-1\r\n")
+	case nilArray:
+		w.WriteString("*-1\r\n")
+	case []reply:
+		fmt.Fprintf(w, "*%d\r\n", len(v))
+		for _, item := range v {
+			writeReply(w, item)
+		}
+	default:
+		fmt.Fprintf(w, "-ERR redistest: unsupported reply %T\r\n", r)
+	}
+}
@@ -0,0 +1,69 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The SHA-1 vectors of RFC 6238, appendix B.
+func TestRFC6238Vectors(t *testing.T) {
+	key := []byte("12345678901234567890")
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+	for _, test := range tests {
+		if got := hotp(key, test.unix/Period, 8); got != test.code {
+			t.Errorf("code at %d = %s, want %s", test.unix, got, test.code)
+		}
+	}
+
+	secret := base32.StdEncoding.EncodeToString(key)
+	got, err := Code(secret, time.Unix(59, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "287082" {
+		t.Errorf("Code = %s, want 287082", got)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1700000000, 0)
+	current, _ := Code(secret, now)
+	previous, _ := Code(secret, now.Add(-Period*time.Second))
+	old, _ := Code(secret, now.Add(-3*Period*time.Second))
+
+	if step, ok := Validate(secret, current, now, 1); !ok || step != Step(now) {
+		t.Errorf("current code: step = %d, ok = %v", step, ok)
+	}
+	if step, ok := Validate(strings.ToLower(secret), previous, now, 1); !ok || step != Step(now)-1 {
+		t.Errorf("previous code: step = %d, ok = %v", step, ok)
+	}
+	for _, code := range []string{old, "", "12345", "abcdef"} {
+		if _, ok := Validate(secret, code, now, 1); ok {
+			t.Errorf("Validate(%q) succeeded", code)
+		}
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri := ProvisioningURI("Graduation Thesis", "alice", "JBSWY3DPEHPK3PXP")
+	want := "otpauth://totp/Graduation%20Thesis:alice?algorithm=SHA1&digits=6&issuer=Graduation+Thesis&period=30&secret=JBSWY3DPEHPK3PXP"
+	if uri != want {
+		t.Errorf("uri = %s\nwant  %s", uri, want)
+	}
+}